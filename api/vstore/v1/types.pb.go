@@ -43,6 +43,26 @@ type Transaction struct {
 	Len uint32 `protobuf:"varint,5,opt,name=len,proto3" json:"len,omitempty"`
 	// Contains the transaction body (arbitrary length)
 	Body []byte `protobuf:"bytes,6,opt,name=body,proto3" json:"body,omitempty"`
+	// Contains the chunk information when the body is split (optional)
+	Chunk *Chunk `protobuf:"bytes,7,opt,name=chunk,proto3" json:"chunk,omitempty"`
+	// Contains a reference to a body in external storage, i.e. a URI or a
+	// content hash. The reference is signed instead of the body (optional)
+	BodyRef string `protobuf:"bytes,8,opt,name=body_ref,json=bodyRef,proto3" json:"body_ref,omitempty"`
+	// Contains the hash of the previous transaction of the signer, such that
+	// signers can chain their own transactions. The previous hash is signed
+	// (optional, 32 bytes)
+	PrevHash []byte `protobuf:"bytes,9,opt,name=prev_hash,json=prevHash,proto3" json:"prev_hash,omitempty"`
+	// Contains the media type of the body, e.g. "application/json". The
+	// content type is signed (optional)
+	ContentType string `protobuf:"bytes,10,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	// Contains the identifier of the chain the transaction is signed for, such
+	// that transactions cannot be replayed on another chain. The chain id is
+	// signed (optional)
+	ChainId string `protobuf:"bytes,11,opt,name=chain_id,json=chainId,proto3" json:"chain_id,omitempty"`
+	// Contains the sequence number of the transaction for the signer, which
+	// must be the next sequence of the signer, such that transactions cannot be
+	// replayed. The sequence is signed (optional, starts at 1)
+	Sequence uint64 `protobuf:"varint,12,opt,name=sequence,proto3" json:"sequence,omitempty"`
 }
 
 func (m *Transaction) Reset()         { *m = Transaction{} }
@@ -120,33 +140,221 @@ func (m *Transaction) GetBody() []byte {
 	return nil
 }
 
+func (m *Transaction) GetChunk() *Chunk {
+	if m != nil {
+		return m.Chunk
+	}
+	return nil
+}
+
+func (m *Transaction) GetBodyRef() string {
+	if m != nil {
+		return m.BodyRef
+	}
+	return ""
+}
+
+func (m *Transaction) GetPrevHash() []byte {
+	if m != nil {
+		return m.PrevHash
+	}
+	return nil
+}
+
+func (m *Transaction) GetContentType() string {
+	if m != nil {
+		return m.ContentType
+	}
+	return ""
+}
+
+func (m *Transaction) GetChainId() string {
+	if m != nil {
+		return m.ChainId
+	}
+	return ""
+}
+
+func (m *Transaction) GetSequence() uint64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+// Chunk describes the position of a transaction body in a chunked body.
+// Chunked bodies are split across several linked transactions which are
+// reassembled under the root hash once all chunks have been committed.
+type Chunk struct {
+	// Contains the merkle root of the SHA-256 chunk digests (32 bytes)
+	Root []byte `protobuf:"bytes,1,opt,name=root,proto3" json:"root,omitempty"`
+	// Contains the position of this chunk in the body (starts at 0)
+	Index uint32 `protobuf:"varint,2,opt,name=index,proto3" json:"index,omitempty"`
+	// Contains the total number of chunks in the body
+	Total uint32 `protobuf:"varint,3,opt,name=total,proto3" json:"total,omitempty"`
+}
+
+func (m *Chunk) Reset()         { *m = Chunk{} }
+func (m *Chunk) String() string { return proto.CompactTextString(m) }
+func (*Chunk) ProtoMessage()    {}
+func (*Chunk) Descriptor() ([]byte, []int) {
+	return fileDescriptor_be4df92a94422b46, []int{1}
+}
+func (m *Chunk) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Chunk) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Chunk.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Chunk) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Chunk.Merge(m, src)
+}
+func (m *Chunk) XXX_Size() int {
+	return m.Size()
+}
+func (m *Chunk) XXX_DiscardUnknown() {
+	xxx_messageInfo_Chunk.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Chunk proto.InternalMessageInfo
+
+func (m *Chunk) GetRoot() []byte {
+	if m != nil {
+		return m.Root
+	}
+	return nil
+}
+
+func (m *Chunk) GetIndex() uint32 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *Chunk) GetTotal() uint32 {
+	if m != nil {
+		return m.Total
+	}
+	return 0
+}
+
+// TransactionList represents a list of transactions as returned by index
+// queries, i.e. by block height or by signer public key.
+type TransactionList struct {
+	// Contains the transactions (in order of commitment)
+	Transactions []Transaction `protobuf:"bytes,1,rep,name=transactions,proto3" json:"transactions"`
+	// Contains the total number of transactions in the index
+	Total uint64 `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	// Contains the hash of the last transaction of the page, which is the
+	// cursor of the next page ("after" parameter). Empty on the last page
+	Next []byte `protobuf:"bytes,3,opt,name=next,proto3" json:"next,omitempty"`
+}
+
+func (m *TransactionList) Reset()         { *m = TransactionList{} }
+func (m *TransactionList) String() string { return proto.CompactTextString(m) }
+func (*TransactionList) ProtoMessage()    {}
+func (*TransactionList) Descriptor() ([]byte, []int) {
+	return fileDescriptor_be4df92a94422b46, []int{2}
+}
+func (m *TransactionList) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *TransactionList) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_TransactionList.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *TransactionList) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TransactionList.Merge(m, src)
+}
+func (m *TransactionList) XXX_Size() int {
+	return m.Size()
+}
+func (m *TransactionList) XXX_DiscardUnknown() {
+	xxx_messageInfo_TransactionList.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TransactionList proto.InternalMessageInfo
+
+func (m *TransactionList) GetTransactions() []Transaction {
+	if m != nil {
+		return m.Transactions
+	}
+	return nil
+}
+
+func (m *TransactionList) GetTotal() uint64 {
+	if m != nil {
+		return m.Total
+	}
+	return 0
+}
+
+func (m *TransactionList) GetNext() []byte {
+	if m != nil {
+		return m.Next
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*Transaction)(nil), "vstore.v1.Transaction")
+	proto.RegisterType((*Chunk)(nil), "vstore.v1.Chunk")
+	proto.RegisterType((*TransactionList)(nil), "vstore.v1.TransactionList")
 }
 
 func init() { proto.RegisterFile("vstore/v1/types.proto", fileDescriptor_be4df92a94422b46) }
 
 var fileDescriptor_be4df92a94422b46 = []byte{
-	// 301 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x44, 0x90, 0x41, 0x4b, 0xc3, 0x30,
-	0x1c, 0xc5, 0x1b, 0x57, 0x87, 0xcb, 0x14, 0x24, 0x28, 0x84, 0xe1, 0xb2, 0xa1, 0x97, 0x9d, 0x12,
-	0xa6, 0x17, 0xc1, 0x5b, 0xaf, 0x5e, 0xa4, 0xec, 0xe4, 0xad, 0xad, 0x59, 0x57, 0x6c, 0x9b, 0x92,
-	0xa4, 0x85, 0x7e, 0x8b, 0x7d, 0xac, 0x1d, 0x77, 0xf4, 0x20, 0x2a, 0xed, 0x17, 0x91, 0xa4, 0xad,
-	0xde, 0x5e, 0x5e, 0xfe, 0xef, 0xff, 0xcb, 0x0b, 0xbc, 0xae, 0x94, 0x16, 0x92, 0xb3, 0x6a, 0xcd,
-	0x74, 0x5d, 0x70, 0x45, 0x0b, 0x29, 0xb4, 0x40, 0x93, 0xce, 0xa6, 0xd5, 0x7a, 0x76, 0x15, 0x8b,
-	0x58, 0x58, 0x97, 0x19, 0xd5, 0x0d, 0xcc, 0x16, 0xb1, 0x10, 0x71, 0xca, 0x99, 0x3d, 0x85, 0xe5,
-	0x96, 0xe9, 0x24, 0xe3, 0x4a, 0x07, 0x59, 0xd1, 0x0f, 0xcc, 0x23, 0x91, 0x71, 0x1d, 0x6e, 0x35,
-	0x8b, 0x64, 0x5d, 0x68, 0x61, 0x08, 0xef, 0xbc, 0xee, 0x01, 0xb7, 0x9f, 0x00, 0x4e, 0x37, 0x32,
-	0xc8, 0x55, 0x10, 0xe9, 0x44, 0xe4, 0xe8, 0x09, 0x8e, 0x55, 0x12, 0xe7, 0x5c, 0x62, 0xb0, 0x04,
-	0xab, 0xe9, 0xfd, 0x9c, 0x0e, 0x79, 0xda, 0xe5, 0x69, 0xb5, 0xa6, 0x2f, 0x65, 0x98, 0x26, 0xd1,
-	0x33, 0xaf, 0x3d, 0xf7, 0xf0, 0xb5, 0x70, 0xfc, 0x3e, 0x82, 0x6e, 0xe0, 0xc4, 0xa8, 0x40, 0x97,
-	0x92, 0xe3, 0x93, 0x25, 0x58, 0x9d, 0xfb, 0xff, 0x06, 0x42, 0xd0, 0xdd, 0x05, 0x6a, 0x87, 0x47,
-	0xf6, 0xc2, 0x6a, 0xf4, 0x08, 0x5d, 0xf3, 0x60, 0xec, 0x5a, 0xd8, 0x8c, 0x76, 0x6d, 0xe8, 0xd0,
-	0x86, 0x6e, 0x86, 0x36, 0xde, 0x99, 0x21, 0xed, 0xbf, 0x17, 0xc0, 0xb7, 0x09, 0x74, 0x09, 0x47,
-	0x29, 0xcf, 0xf1, 0xe9, 0x12, 0xac, 0x2e, 0x7c, 0x23, 0xcd, 0xfe, 0x50, 0xbc, 0xd5, 0x78, 0xdc,
-	0xed, 0x37, 0xda, 0xbb, 0x3b, 0x34, 0x04, 0x1c, 0x1b, 0x02, 0x7e, 0x1a, 0x02, 0xf6, 0x2d, 0x71,
-	0x8e, 0x2d, 0x71, 0x3e, 0x5a, 0xe2, 0xbc, 0x4e, 0xfe, 0x3e, 0x3c, 0x1c, 0x5b, 0xdc, 0xc3, 0x6f,
-	0x00, 0x00, 0x00, 0xff, 0xff, 0x08, 0xe2, 0x89, 0x4a, 0x84, 0x01, 0x00, 0x00,
+	// 494 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x54, 0x52, 0xbb, 0x8e, 0xd3, 0x40,
+	0x14, 0xcd, 0x6c, 0x1e, 0x1b, 0x4f, 0x12, 0xb1, 0x1a, 0x2d, 0x68, 0x08, 0xac, 0x63, 0x82, 0x84,
+	0x5c, 0x8d, 0x95, 0xa5, 0x41, 0xa2, 0x41, 0xa1, 0x00, 0x04, 0x05, 0xb2, 0x52, 0xd1, 0x44, 0x8e,
+	0x73, 0x93, 0x58, 0x9b, 0xcc, 0x18, 0x7b, 0x12, 0xad, 0x0b, 0xfe, 0x61, 0x1b, 0xfe, 0x69, 0xcb,
+	0x2d, 0xa9, 0x00, 0x25, 0x3f, 0x82, 0xee, 0x8c, 0xf3, 0xa0, 0x3b, 0xf7, 0xdc, 0x39, 0xf7, 0x75,
+	0x86, 0x3e, 0xde, 0xe4, 0x5a, 0x65, 0x10, 0x6c, 0x06, 0x81, 0x2e, 0x52, 0xc8, 0x45, 0x9a, 0x29,
+	0xad, 0x98, 0x63, 0x69, 0xb1, 0x19, 0x74, 0x2f, 0xe7, 0x6a, 0xae, 0x0c, 0x1b, 0x20, 0xb2, 0x0f,
+	0xba, 0xbd, 0xb9, 0x52, 0xf3, 0x25, 0x04, 0x26, 0x9a, 0xac, 0x67, 0x81, 0x4e, 0x56, 0x90, 0xeb,
+	0x68, 0x95, 0x96, 0x0f, 0xae, 0x62, 0xb5, 0x02, 0x3d, 0x99, 0xe9, 0x20, 0xce, 0x8a, 0x54, 0x2b,
+	0xec, 0x70, 0x03, 0x45, 0xd9, 0xa0, 0xff, 0xb3, 0x4a, 0x5b, 0xa3, 0x2c, 0x92, 0x79, 0x14, 0xeb,
+	0x44, 0x49, 0xf6, 0x96, 0x36, 0xf2, 0x64, 0x2e, 0x21, 0xe3, 0xc4, 0x23, 0x7e, 0xeb, 0xfa, 0x4a,
+	0xec, 0xf5, 0xc2, 0xea, 0xc5, 0x66, 0x20, 0xbe, 0xae, 0x27, 0xcb, 0x24, 0xfe, 0x0c, 0xc5, 0xb0,
+	0x76, 0xff, 0xbb, 0x57, 0x09, 0x4b, 0x09, 0x7b, 0x4e, 0x1d, 0x44, 0x91, 0x5e, 0x67, 0xc0, 0xcf,
+	0x3c, 0xe2, 0xb7, 0xc3, 0x23, 0xc1, 0x18, 0xad, 0x2d, 0xa2, 0x7c, 0xc1, 0xab, 0x26, 0x61, 0x30,
+	0x7b, 0x43, 0x6b, 0x38, 0x30, 0xaf, 0x99, 0x66, 0x5d, 0x61, 0xb7, 0x11, 0xfb, 0x6d, 0xc4, 0x68,
+	0xbf, 0xcd, 0xb0, 0x89, 0x9d, 0xee, 0xfe, 0xf4, 0x48, 0x68, 0x14, 0xec, 0x82, 0x56, 0x97, 0x20,
+	0x79, 0xdd, 0x23, 0x7e, 0x27, 0x44, 0x88, 0xf5, 0x27, 0x6a, 0x5a, 0xf0, 0x86, 0xad, 0x8f, 0x98,
+	0xbd, 0xa2, 0xf5, 0x78, 0xb1, 0x96, 0x37, 0xfc, 0xdc, 0x34, 0xb8, 0x10, 0x87, 0x7b, 0x8a, 0xf7,
+	0xc8, 0x87, 0x36, 0xcd, 0x9e, 0xd2, 0x26, 0xbe, 0x1f, 0x67, 0x30, 0xe3, 0x4d, 0x8f, 0xf8, 0x4e,
+	0x78, 0x8e, 0x71, 0x08, 0x33, 0xf6, 0x8c, 0x3a, 0x69, 0x06, 0x9b, 0xb1, 0x99, 0xdd, 0x31, 0xb5,
+	0x9b, 0x48, 0x7c, 0xc4, 0xf9, 0x5f, 0xd0, 0x76, 0xac, 0xa4, 0x06, 0xa9, 0xc7, 0x68, 0x1b, 0xa7,
+	0x46, 0xdb, 0x2a, 0xb9, 0x51, 0x91, 0x02, 0x96, 0x8e, 0x17, 0x51, 0x22, 0xc7, 0xc9, 0x94, 0xb7,
+	0x6c, 0x69, 0x13, 0x7f, 0x9a, 0x62, 0x2a, 0x87, 0xef, 0x6b, 0x90, 0x31, 0xf0, 0xb6, 0x47, 0xfc,
+	0xda, 0x21, 0xd5, 0xff, 0x40, 0xeb, 0x66, 0x40, 0xdc, 0x2a, 0x53, 0x4a, 0x1b, 0x3b, 0xda, 0xa1,
+	0xc1, 0xec, 0x92, 0xd6, 0x13, 0x39, 0x85, 0x5b, 0x73, 0xe3, 0x4e, 0x68, 0x03, 0x64, 0xb5, 0xd2,
+	0xd1, 0xd2, 0x1c, 0xb8, 0x13, 0xda, 0xa0, 0xff, 0x83, 0x3e, 0x3a, 0xf1, 0xf7, 0x4b, 0x92, 0x6b,
+	0xf6, 0x8e, 0xb6, 0xf5, 0x91, 0xca, 0x39, 0xf1, 0xaa, 0x7e, 0xeb, 0xfa, 0xc9, 0xc9, 0x6d, 0x4e,
+	0x14, 0xa5, 0xc5, 0xff, 0x29, 0x8e, 0xad, 0xce, 0xcc, 0xd4, 0x36, 0xc0, 0x51, 0x25, 0xdc, 0xea,
+	0xbd, 0xc1, 0x88, 0x87, 0x2f, 0xef, 0xb7, 0x2e, 0x79, 0xd8, 0xba, 0xe4, 0xef, 0xd6, 0x25, 0x77,
+	0x3b, 0xb7, 0xf2, 0xb0, 0x73, 0x2b, 0xbf, 0x76, 0x6e, 0xe5, 0x9b, 0x73, 0xf8, 0xf1, 0x93, 0x86,
+	0xf1, 0xfb, 0xf5, 0xbf, 0x01, 0x00, 0x88, 0x9d, 0x7b, 0xf7, 0x05, 0x03, 0x00, 0x00,
 }
 
 func (m *Transaction) Marshal() (dAtA []byte, err error) {
@@ -169,6 +377,51 @@ func (m *Transaction) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.Sequence != 0 {
+		i = encodeVarintTypes(dAtA, i, uint64(m.Sequence))
+		i--
+		dAtA[i] = 0x60
+	}
+	if len(m.ChainId) > 0 {
+		i -= len(m.ChainId)
+		copy(dAtA[i:], m.ChainId)
+		i = encodeVarintTypes(dAtA, i, uint64(len(m.ChainId)))
+		i--
+		dAtA[i] = 0x5a
+	}
+	if len(m.ContentType) > 0 {
+		i -= len(m.ContentType)
+		copy(dAtA[i:], m.ContentType)
+		i = encodeVarintTypes(dAtA, i, uint64(len(m.ContentType)))
+		i--
+		dAtA[i] = 0x52
+	}
+	if len(m.PrevHash) > 0 {
+		i -= len(m.PrevHash)
+		copy(dAtA[i:], m.PrevHash)
+		i = encodeVarintTypes(dAtA, i, uint64(len(m.PrevHash)))
+		i--
+		dAtA[i] = 0x4a
+	}
+	if len(m.BodyRef) > 0 {
+		i -= len(m.BodyRef)
+		copy(dAtA[i:], m.BodyRef)
+		i = encodeVarintTypes(dAtA, i, uint64(len(m.BodyRef)))
+		i--
+		dAtA[i] = 0x42
+	}
+	if m.Chunk != nil {
+		{
+			size, err := m.Chunk.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintTypes(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x3a
+	}
 	if len(m.Body) > 0 {
 		i -= len(m.Body)
 		copy(dAtA[i:], m.Body)
@@ -181,12 +434,12 @@ func (m *Transaction) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i--
 		dAtA[i] = 0x28
 	}
-	n1, err1 := github_com_cosmos_gogoproto_types.StdTimeMarshalTo(m.Time, dAtA[i-github_com_cosmos_gogoproto_types.SizeOfStdTime(m.Time):])
-	if err1 != nil {
-		return 0, err1
+	n2, err2 := github_com_cosmos_gogoproto_types.StdTimeMarshalTo(m.Time, dAtA[i-github_com_cosmos_gogoproto_types.SizeOfStdTime(m.Time):])
+	if err2 != nil {
+		return 0, err2
 	}
-	i -= n1
-	i = encodeVarintTypes(dAtA, i, uint64(n1))
+	i -= n2
+	i = encodeVarintTypes(dAtA, i, uint64(n2))
 	i--
 	dAtA[i] = 0x22
 	if len(m.Hash) > 0 {
@@ -216,6 +469,95 @@ func (m *Transaction) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
+func (m *Chunk) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Chunk) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Chunk) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Total != 0 {
+		i = encodeVarintTypes(dAtA, i, uint64(m.Total))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.Index != 0 {
+		i = encodeVarintTypes(dAtA, i, uint64(m.Index))
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Root) > 0 {
+		i -= len(m.Root)
+		copy(dAtA[i:], m.Root)
+		i = encodeVarintTypes(dAtA, i, uint64(len(m.Root)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *TransactionList) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *TransactionList) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *TransactionList) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Next) > 0 {
+		i -= len(m.Next)
+		copy(dAtA[i:], m.Next)
+		i = encodeVarintTypes(dAtA, i, uint64(len(m.Next)))
+		i--
+		dAtA[i] = 0x1a
+	}
+	if m.Total != 0 {
+		i = encodeVarintTypes(dAtA, i, uint64(m.Total))
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Transactions) > 0 {
+		for iNdEx := len(m.Transactions) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Transactions[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintTypes(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
 func encodeVarintTypes(dAtA []byte, offset int, v uint64) int {
 	offset -= sovTypes(v)
 	base := offset
@@ -252,6 +594,70 @@ func (m *Transaction) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovTypes(uint64(l))
 	}
+	if m.Chunk != nil {
+		l = m.Chunk.Size()
+		n += 1 + l + sovTypes(uint64(l))
+	}
+	l = len(m.BodyRef)
+	if l > 0 {
+		n += 1 + l + sovTypes(uint64(l))
+	}
+	l = len(m.PrevHash)
+	if l > 0 {
+		n += 1 + l + sovTypes(uint64(l))
+	}
+	l = len(m.ContentType)
+	if l > 0 {
+		n += 1 + l + sovTypes(uint64(l))
+	}
+	l = len(m.ChainId)
+	if l > 0 {
+		n += 1 + l + sovTypes(uint64(l))
+	}
+	if m.Sequence != 0 {
+		n += 1 + sovTypes(uint64(m.Sequence))
+	}
+	return n
+}
+
+func (m *Chunk) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Root)
+	if l > 0 {
+		n += 1 + l + sovTypes(uint64(l))
+	}
+	if m.Index != 0 {
+		n += 1 + sovTypes(uint64(m.Index))
+	}
+	if m.Total != 0 {
+		n += 1 + sovTypes(uint64(m.Total))
+	}
+	return n
+}
+
+func (m *TransactionList) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Transactions) > 0 {
+		for _, e := range m.Transactions {
+			l = e.Size()
+			n += 1 + l + sovTypes(uint64(l))
+		}
+	}
+	if m.Total != 0 {
+		n += 1 + sovTypes(uint64(m.Total))
+	}
+	l = len(m.Next)
+	if l > 0 {
+		n += 1 + l + sovTypes(uint64(l))
+	}
 	return n
 }
 
@@ -477,6 +883,450 @@ func (m *Transaction) Unmarshal(dAtA []byte) error {
 				m.Body = []byte{}
 			}
 			iNdEx = postIndex
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Chunk", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTypes
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTypes
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTypes
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Chunk == nil {
+				m.Chunk = &Chunk{}
+			}
+			if err := m.Chunk.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field BodyRef", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTypes
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTypes
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTypes
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.BodyRef = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PrevHash", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTypes
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTypes
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTypes
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.PrevHash = append(m.PrevHash[:0], dAtA[iNdEx:postIndex]...)
+			if m.PrevHash == nil {
+				m.PrevHash = []byte{}
+			}
+			iNdEx = postIndex
+		case 10:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ContentType", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTypes
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTypes
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTypes
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ContentType = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 11:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ChainId", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTypes
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTypes
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTypes
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ChainId = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 12:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Sequence", wireType)
+			}
+			m.Sequence = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTypes
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Sequence |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTypes(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTypes
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Chunk) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTypes
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Chunk: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Chunk: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Root", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTypes
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTypes
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTypes
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Root = append(m.Root[:0], dAtA[iNdEx:postIndex]...)
+			if m.Root == nil {
+				m.Root = []byte{}
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Index", wireType)
+			}
+			m.Index = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTypes
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Index |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Total", wireType)
+			}
+			m.Total = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTypes
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Total |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTypes(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTypes
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *TransactionList) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTypes
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: TransactionList: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: TransactionList: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Transactions", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTypes
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTypes
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTypes
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Transactions = append(m.Transactions, Transaction{})
+			if err := m.Transactions[len(m.Transactions)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Total", wireType)
+			}
+			m.Total = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTypes
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Total |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Next", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTypes
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTypes
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTypes
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Next = append(m.Next[:0], dAtA[iNdEx:postIndex]...)
+			if m.Next == nil {
+				m.Next = []byte{}
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipTypes(dAtA[iNdEx:])
@@ -0,0 +1,121 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	rpc "github.com/cometbft/cometbft/rpc/client/http"
+	jsonrpcclient "github.com/cometbft/cometbft/rpc/jsonrpc/client"
+)
+
+// Auth describes the credentials that are presented to nodes which require
+// authentication, e.g. behind an authenticating reverse proxy. A bearer token
+// and a client certificate (mutual TLS) can be combined.
+type Auth struct {
+	// Token is sent in the Authorization header as a bearer token.
+	Token string
+
+	// CertFile and KeyFile are the PEM files of the client certificate.
+	CertFile string
+	KeyFile  string
+
+	// CAFile is the PEM file of the certificate authorities that are trusted
+	// to sign the node certificate. If empty, the system pool is used.
+	CAFile string
+}
+
+// Empty returns true if no credentials are configured.
+func (a Auth) Empty() bool {
+	return a.Token == "" && a.CertFile == "" && a.KeyFile == "" && a.CAFile == ""
+}
+
+// NewHTTPWithAuth creates an RPC client for the CometBFT node at addr which
+// presents the credentials of auth with every request. The address is
+// validated as with NewHTTP.
+func NewHTTPWithAuth(addr string, auth Auth) (*rpc.HTTP, error) {
+	if auth.Empty() {
+		return NewHTTP(addr)
+	}
+
+	if err := validateNodeAddr(addr); err != nil {
+		return nil, err
+	}
+
+	httpClient, err := jsonrpcclient.DefaultHTTPClient(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	transport, ok := httpClient.Transport.(*http.Transport)
+	if !ok {
+		return nil, errors.New("unsupported http transport")
+	}
+
+	tlsConfig, err := auth.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	transport.TLSClientConfig = tlsConfig
+	httpClient.Transport = &bearerTransport{token: auth.Token, base: transport}
+	return rpc.NewWithClient(addr, "/websocket", httpClient)
+}
+
+// --------------------------------------------------------------------------
+// Private helpers
+
+// tlsConfig returns the TLS configuration of the client certificate and the
+// trusted certificate authorities, or nil if none is configured.
+func (a Auth) tlsConfig() (*tls.Config, error) {
+	if a.CertFile == "" && a.KeyFile == "" && a.CAFile == "" {
+		return nil, nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if a.CertFile != "" || a.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(a.CertFile, a.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %v", err)
+		}
+
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if a.CAFile != "" {
+		pem, err := os.ReadFile(a.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read certificate authorities: %v", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("could not parse certificate authorities: %s", a.CAFile)
+		}
+
+		config.RootCAs = pool
+	}
+
+	return config, nil
+}
+
+// bearerTransport adds a bearer token to the Authorization header of requests.
+type bearerTransport struct {
+	token string
+	base  http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.token == "" {
+		return t.base.RoundTrip(req)
+	}
+
+	// Requests must not be modified by a RoundTripper
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,142 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// authHandler responds to JSON-RPC requests with an empty ABCI query result
+// and rejects requests without the bearer token with 401 Unauthorized.
+func authHandler(token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" && r.Header.Get("Authorization") != "Bearer "+token {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			ID json.RawMessage `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":` + string(req.ID) + `,"result":{"response":{}}}`))
+	})
+}
+
+func TestClientAuthToken(t *testing.T) {
+	server := httptest.NewServer(authHandler("secret-token"))
+	defer server.Close()
+
+	ctx := context.Background()
+
+	// Authorized request
+	cli, err := NewHTTPWithAuth(server.URL, Auth{Token: "secret-token"})
+	require.NoError(t, err)
+	_, err = cli.ABCIQuery(ctx, "/hash", []byte{})
+	assert.NoError(t, err, "should send the bearer token")
+
+	// Unauthorized requests
+	for _, auth := range []Auth{{}, {Token: "wrong-token"}} {
+		cli, err := NewHTTPWithAuth(server.URL, auth)
+		require.NoError(t, err)
+		_, err = cli.ABCIQuery(ctx, "/hash", []byte{})
+		assert.Error(t, err, "should be rejected with token %q", auth.Token)
+	}
+}
+
+func TestClientAuthMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, clientCert := writeClientCertificate(t, dir)
+
+	server := httptest.NewUnstartedServer(authHandler(""))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	// Node certificate is trusted with --node-ca
+	caFile := filepath.Join(dir, "ca.pem")
+	writePEM(t, caFile, "CERTIFICATE", server.Certificate().Raw)
+
+	ctx := context.Background()
+
+	// Authorized request
+	cli, err := NewHTTPWithAuth(server.URL, Auth{CertFile: certFile, KeyFile: keyFile, CAFile: caFile})
+	require.NoError(t, err)
+	_, err = cli.ABCIQuery(ctx, "/hash", []byte{})
+	assert.NoError(t, err, "should present the client certificate")
+
+	// Unauthorized request, without client certificate
+	cli, err = NewHTTPWithAuth(server.URL, Auth{CAFile: caFile})
+	require.NoError(t, err)
+	_, err = cli.ABCIQuery(ctx, "/hash", []byte{})
+	assert.Error(t, err, "should be rejected without client certificate")
+
+	// Invalid credential files
+	_, err = NewHTTPWithAuth(server.URL, Auth{CertFile: caFile, KeyFile: caFile})
+	assert.Error(t, err)
+	_, err = NewHTTPWithAuth(server.URL, Auth{CAFile: filepath.Join(dir, "missing.pem")})
+	assert.Error(t, err)
+}
+
+// writeClientCertificate writes a self-signed client certificate and its key
+// to dir and returns the paths of both files and the certificate.
+func writeClientCertificate(t *testing.T, dir string) (string, string, *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "vstore-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile, keyFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDer)
+	return certFile, keyFile, cert
+}
+
+// writePEM writes a PEM block to file.
+func writePEM(t *testing.T, file, blockType string, der []byte) {
+	t.Helper()
+
+	bz := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	require.NoError(t, os.WriteFile(file, bz, 0600))
+}
@@ -0,0 +1,309 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	vfsp2p "github.com/securesharelabs/vstore/api/vstore/v1"
+	vfs "github.com/securesharelabs/vstore/vfs"
+
+	"github.com/cometbft/cometbft/crypto/ed25519"
+	cmtbytes "github.com/cometbft/cometbft/libs/bytes"
+	rpc "github.com/cometbft/cometbft/rpc/client/http"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	"github.com/cometbft/cometbft/types"
+)
+
+// ErrNotFound is returned by queries when no transaction matches.
+var ErrNotFound = errors.New("transaction not found")
+
+// RPC describes the CometBFT RPC methods that are used by the client. The
+// HTTP client of CometBFT implements RPC.
+type RPC interface {
+	// BroadcastTxCommit broadcasts a transaction and waits for it to be
+	// committed in a block.
+	BroadcastTxCommit(ctx context.Context, tx types.Tx) (*coretypes.ResultBroadcastTxCommit, error)
+
+	// ABCIQuery queries the ABCI application at path.
+	ABCIQuery(ctx context.Context, path string, data cmtbytes.HexBytes) (*coretypes.ResultABCIQuery, error)
+}
+
+var _ RPC = (*rpc.HTTP)(nil)
+
+// TxError describes a transaction that was rejected by the application,
+// either in CheckTx or in FinalizeBlock.
+type TxError struct {
+	Code uint32
+	Log  string
+}
+
+// Error implements error
+func (e *TxError) Error() string {
+	return fmt.Sprintf("transaction rejected: %s (code %d): %s", vfs.CodeToString(e.Code), e.Code, e.Log)
+}
+
+// QueryError describes a query that failed in the application, e.g. for a
+// transaction which is committed but whose body was pruned.
+type QueryError struct {
+	Code uint32
+	Log  string
+}
+
+// Error implements error
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("query failed: %s (code %d): %s", vfs.CodeToString(e.Code), e.Code, e.Log)
+}
+
+// TxOption configures a transaction before it is signed.
+type TxOption func(tx *vfsp2p.Transaction)
+
+// WithPrevHash chains a transaction to the previous transaction of the signer
+// with hash prevHash. The previous hash is signed, the node rejects previous
+// hashes that do not exist or that belong to another signer.
+func WithPrevHash(prevHash []byte) TxOption {
+	return func(tx *vfsp2p.Transaction) {
+		tx.PrevHash = prevHash
+	}
+}
+
+// WithContentType declares the media type of the body, e.g. "application/json".
+// The content type is signed, nodes may validate JSON bodies with a schema.
+func WithContentType(contentType string) TxOption {
+	return func(tx *vfsp2p.Transaction) {
+		tx.ContentType = contentType
+	}
+}
+
+// WithChainID signs a transaction for the chain with identifier chainID, i.e.
+// the chain id of the genesis. Nodes with a genesis chain id reject
+// transactions that are signed for another chain, or for no chain at all.
+func WithChainID(chainID string) TxOption {
+	return func(tx *vfsp2p.Transaction) {
+		tx.ChainId = chainID
+	}
+}
+
+// WithSequence sets the sequence of a transaction for its signer, see
+// Client.NextSequence. The sequence is signed, the node rejects sequences
+// that are already used or that are not the next sequence of the signer.
+func WithSequence(sequence uint64) TxOption {
+	return func(tx *vfsp2p.Transaction) {
+		tx.Sequence = sequence
+	}
+}
+
+// Client signs transactions with the identity of a SecretProvider and commits
+// them to a vStore node. Transactions are queried using the same node.
+type Client struct {
+	rpc    RPC
+	signer vfs.SecretProvider
+}
+
+// New creates a client for the CometBFT node at addr which signs transactions
+// using signer. The signer must be open, i.e. its private key is available.
+func New(addr string, signer vfs.SecretProvider) (*Client, error) {
+	cli, err := NewHTTP(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWithRPC(cli, signer), nil
+}
+
+// NewWithRPC creates a client that uses an existing RPC client.
+func NewWithRPC(cli RPC, signer vfs.SecretProvider) *Client {
+	return &Client{rpc: cli, signer: signer}
+}
+
+// NewHTTP creates an RPC client for the CometBFT node at addr. The address
+// must be a valid URL with one of the schemes http, https, tcp or unix, an
+// error is returned otherwise.
+func NewHTTP(addr string) (*rpc.HTTP, error) {
+	if err := validateNodeAddr(addr); err != nil {
+		return nil, err
+	}
+
+	return rpc.New(addr, "/websocket")
+}
+
+// RPC returns the underlying RPC client.
+func (c *Client) RPC() RPC {
+	return c.rpc
+}
+
+// NewTransaction creates a transaction with body and signs it, together with
+// the current time, using the identity of the client.
+func (c *Client) NewTransaction(body []byte, opts ...TxOption) (*vfs.SignedTransaction, error) {
+	return c.newTransaction(body, "", opts)
+}
+
+// NewTransactionWithKey creates a transaction with body and signs it with an
+// unlocked private key, e.g. to sign a batch of transactions without opening
+// the identity of a signer for every transaction.
+func NewTransactionWithKey(priv ed25519.PrivKey, body []byte, opts ...TxOption) (*vfs.SignedTransaction, error) {
+	return signTransaction(priv, body, "", opts)
+}
+
+// NewReferenceTransaction creates a transaction which references a body in
+// external storage, e.g. "sha256:HEX", and signs the reference.
+func (c *Client) NewReferenceTransaction(bodyRef string, opts ...TxOption) (*vfs.SignedTransaction, error) {
+	if len(bodyRef) == 0 {
+		return nil, errors.New("body reference must not be empty")
+	}
+
+	return c.newTransaction([]byte{}, bodyRef, opts)
+}
+
+// Commit signs a transaction with body, broadcasts it and waits until it is
+// committed. The transaction hash and the committed height are returned. A
+// transaction that is rejected by the application returns a *TxError.
+func (c *Client) Commit(ctx context.Context, body []byte, opts ...TxOption) ([]byte, int64, error) {
+	stx, err := c.NewTransaction(body, opts...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	height, err := c.Broadcast(ctx, stx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return stx.Hash, height, nil
+}
+
+// Broadcast broadcasts a signed transaction and waits until it is committed.
+// The committed height is returned.
+func (c *Client) Broadcast(ctx context.Context, stx *vfs.SignedTransaction) (int64, error) {
+	response, err := c.rpc.BroadcastTxCommit(ctx, stx.Bytes())
+	if err != nil {
+		return 0, err
+	}
+
+	// Use the failing result, CheckTx comes first
+	if response.CheckTx.Code != vfs.CodeTypeOK {
+		return 0, &TxError{Code: response.CheckTx.Code, Log: response.CheckTx.Log}
+	}
+
+	if response.TxResult.Code != vfs.CodeTypeOK {
+		return 0, &TxError{Code: response.TxResult.Code, Log: response.TxResult.Log}
+	}
+
+	return response.Height, nil
+}
+
+// QueryByHash returns the committed transaction with hash. ErrNotFound is
+// returned if the transaction does not exist, and a *QueryError if it was
+// committed but its body is not available, e.g. vfs.CodeTypePrunedError.
+func (c *Client) QueryByHash(ctx context.Context, hash []byte) (*vfs.SignedTransaction, error) {
+	response, err := c.rpc.ABCIQuery(ctx, "/hash", hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.Response.Code != vfs.CodeTypeOK {
+		return nil, &QueryError{Code: response.Response.Code, Log: response.Response.Log}
+	}
+
+	if len(response.Response.Value) == 0 {
+		return nil, ErrNotFound
+	}
+
+	stx, err := vfs.FromBytes(response.Response.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(stx.Hash) == 0 {
+		stx.Hash = vfs.ComputeHash(stx.Unresolved())
+	}
+
+	return stx, nil
+}
+
+// NextSequence returns the next sequence of the identity of the client, i.e.
+// the sequence of its next sequenced transaction, see WithSequence.
+func (c *Client) NextSequence(ctx context.Context) (uint64, error) {
+	pub, err := c.signer.Identity().PubKey()
+	if err != nil {
+		return 0, err
+	}
+
+	response, err := c.rpc.ABCIQuery(ctx, "/pubkey/sequence", pub.Bytes())
+	if err != nil {
+		return 0, err
+	}
+
+	if response.Response.Code != vfs.CodeTypeOK {
+		return 0, &QueryError{Code: response.Response.Code, Log: response.Response.Log}
+	}
+
+	return strconv.ParseUint(string(response.Response.Value), 10, 64)
+}
+
+// --------------------------------------------------------------------------
+// Private helpers
+
+// validateNodeAddr returns an error if addr is not a valid node address.
+func validateNodeAddr(addr string) error {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return fmt.Errorf("invalid node address %q: %v", addr, err)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "tcp":
+		if u.Host == "" {
+			return fmt.Errorf("invalid node address %q: missing host", addr)
+		}
+	case "unix":
+		break
+	default:
+		return fmt.Errorf("invalid node address %q: unsupported scheme %q", addr, u.Scheme)
+	}
+
+	return nil
+}
+
+// newTransaction creates and signs a transaction with body or bodyRef.
+func (c *Client) newTransaction(body []byte, bodyRef string, opts []TxOption) (*vfs.SignedTransaction, error) {
+	priv, err := c.signer.Identity().PrivKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return signTransaction(priv, body, bodyRef, opts)
+}
+
+// signTransaction creates a transaction with body or bodyRef, applies the
+// options and signs it with priv.
+func signTransaction(priv ed25519.PrivKey, body []byte, bodyRef string, opts []TxOption) (*vfs.SignedTransaction, error) {
+	// Create a protobuf transaction object
+	tx := new(vfsp2p.Transaction)
+	tx.Signer = vfs.PubKeyToProto(priv.PubKey())
+	tx.Time = time.Now()
+	tx.Len = uint32(len(body))
+	tx.Body = body
+	tx.BodyRef = bodyRef
+
+	for _, opt := range opts {
+		opt(tx)
+	}
+
+	stx, err := vfs.FromProto(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sign data (or the body reference) and timestamp
+	stx.Signature, err = stx.Sign(priv)
+	if err != nil {
+		return nil, err
+	}
+
+	stx.Hash = vfs.ComputeHash(stx)
+	return stx, nil
+}
@@ -0,0 +1,187 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	vfs "github.com/securesharelabs/vstore/vfs"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	cmtbytes "github.com/cometbft/cometbft/libs/bytes"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	"github.com/cometbft/cometbft/types"
+)
+
+// localRPC implements RPC with an in-memory application, every broadcast
+// transaction is committed in a new block.
+type localRPC struct {
+	app    *vfs.VStoreApplication
+	height int64
+}
+
+var _ RPC = (*localRPC)(nil)
+
+func (r *localRPC) BroadcastTxCommit(ctx context.Context, tx types.Tx) (*coretypes.ResultBroadcastTxCommit, error) {
+	resCheckTx, err := r.app.CheckTx(ctx, &abci.RequestCheckTx{Tx: tx})
+	if err != nil {
+		return nil, err
+	}
+
+	if resCheckTx.Code != vfs.CodeTypeOK {
+		return &coretypes.ResultBroadcastTxCommit{CheckTx: *resCheckTx}, nil
+	}
+
+	r.height++
+	resFinBlock, err := r.app.FinalizeBlock(ctx, &abci.RequestFinalizeBlock{
+		Height: r.height,
+		Time:   time.Now(),
+		Txs:    [][]byte{tx},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := r.app.Commit(ctx, &abci.RequestCommit{}); err != nil {
+		return nil, err
+	}
+
+	return &coretypes.ResultBroadcastTxCommit{
+		CheckTx:  *resCheckTx,
+		TxResult: *resFinBlock.TxResults[0],
+		Hash:     tx.Hash(),
+		Height:   r.height,
+	}, nil
+}
+
+func (r *localRPC) ABCIQuery(ctx context.Context, path string, data cmtbytes.HexBytes) (*coretypes.ResultABCIQuery, error) {
+	resQuery, err := r.app.Query(ctx, &abci.RequestQuery{Path: path, Data: data})
+	if err != nil {
+		return nil, err
+	}
+
+	return &coretypes.ResultABCIQuery{Response: *resQuery}, nil
+}
+
+// newTestClient creates a client with a new identity which commits to an
+// in-memory application.
+func newTestClient(t *testing.T) *Client {
+	dir := t.TempDir()
+	pw := []byte("testpassword")
+
+	nodeFile := filepath.Join(dir, "node")
+	vfs.MustGenerateIdentityWithKDF(nodeFile, pw, vfs.KDFSHA256)
+	app := vfs.NewInMemoryVStoreApplication(nodeFile, pw)
+
+	idFile := filepath.Join(dir, "id")
+	vfs.MustGenerateIdentityWithKDF(idFile, pw, vfs.KDFSHA256)
+	id := vfs.NewIdentity(idFile, pw)
+	_, err := id.Open()
+	require.NoError(t, err, "should open the client identity")
+
+	return NewWithRPC(&localRPC{app: app}, id)
+}
+
+func TestClientCommitAndQueryByHash(t *testing.T) {
+	ctx := context.Background()
+	cli := newTestClient(t)
+
+	hash, height, err := cli.Commit(ctx, []byte("This is a message"))
+	require.NoError(t, err, "should commit the transaction")
+	assert.Equal(t, int64(1), height)
+	assert.Len(t, hash, 32)
+
+	stx, err := cli.QueryByHash(ctx, hash)
+	require.NoError(t, err, "should find the committed transaction")
+	assert.Equal(t, hash, stx.Hash)
+	assert.Equal(t, []byte("This is a message"), []byte(stx.Data))
+	assert.True(t, stx.Verify(), "should return a valid signature")
+
+	pub, err := cli.signer.Identity().PubKey()
+	require.NoError(t, err)
+	assert.Equal(t, pub.Bytes(), stx.Signer.Bytes())
+
+	hash, height, err = cli.Commit(ctx, []byte("This is another message"))
+	require.NoError(t, err, "should commit a second transaction")
+	assert.Equal(t, int64(2), height)
+
+	stx, err = cli.QueryByHash(ctx, hash)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("This is another message"), []byte(stx.Data))
+}
+
+func TestClientCommitWithPrevHash(t *testing.T) {
+	ctx := context.Background()
+	cli := newTestClient(t)
+
+	first, _, err := cli.Commit(ctx, []byte("This is a message"))
+	require.NoError(t, err)
+
+	second, _, err := cli.Commit(ctx, []byte("This is another message"), WithPrevHash(first))
+	require.NoError(t, err, "should commit a chained transaction")
+
+	stx, err := cli.QueryByHash(ctx, second)
+	require.NoError(t, err)
+	assert.Equal(t, first, stx.PrevHash)
+
+	// Dangling previous hash
+	_, _, err = cli.Commit(ctx, []byte("This is a third message"), WithPrevHash(make([]byte, 32)))
+	var txErr *TxError
+	require.True(t, errors.As(err, &txErr), "should return a TxError")
+	assert.Equal(t, vfs.CodeTypeInvalidPrevHashError, txErr.Code)
+}
+
+func TestClientCommitWithSequence(t *testing.T) {
+	ctx := context.Background()
+	cli := newTestClient(t)
+
+	next, err := cli.NextSequence(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), next)
+
+	hash, _, err := cli.Commit(ctx, []byte("This is a message"), WithSequence(next))
+	require.NoError(t, err, "should commit a sequenced transaction")
+
+	stx, err := cli.QueryByHash(ctx, hash)
+	require.NoError(t, err)
+	assert.Equal(t, next, stx.Sequence)
+
+	next, err = cli.NextSequence(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), next)
+
+	// Reused sequence
+	_, _, err = cli.Commit(ctx, []byte("This is a message"), WithSequence(1))
+	var txErr *TxError
+	require.True(t, errors.As(err, &txErr), "should return a TxError")
+	assert.Equal(t, vfs.CodeTypeInvalidSequenceError, txErr.Code)
+}
+
+func TestClientErrors(t *testing.T) {
+	ctx := context.Background()
+	cli := newTestClient(t)
+
+	// Unknown transaction hash
+	_, err := cli.QueryByHash(ctx, make([]byte, 32))
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Empty transaction body is rejected by CheckTx
+	_, _, err = cli.Commit(ctx, []byte{})
+	var txErr *TxError
+	require.True(t, errors.As(err, &txErr), "should return a TxError")
+	assert.NotEqual(t, vfs.CodeTypeOK, txErr.Code)
+
+	// Invalid node addresses
+	for _, addr := range []string{"localhost:26657", "http://", "ftp://localhost"} {
+		_, err := New(addr, cli.signer)
+		assert.Error(t, err, "should not accept node address %q", addr)
+	}
+
+	_, err = New("http://localhost:26657", cli.signer)
+	assert.NoError(t, err)
+}
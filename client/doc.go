@@ -0,0 +1,26 @@
+/*
+Package client implements a Go client for vStore nodes.
+
+client signs transactions using the identity of a [vfs.SecretProvider] and
+commits them to a vStore node with the CometBFT RPC, such that Go programs can
+embed vStore submission without using the vstore command.
+
+# Structures
+
+  - [Auth]: Describes the credentials presented to nodes, i.e. a bearer token or mutual TLS.
+  - [Client]: Signs, commits and queries transactions of a vStore node.
+  - [RPC]: Describes the CometBFT RPC methods used by the client.
+  - [TxError]: Describes a transaction that was rejected by the application.
+
+# Examples
+
+	id := vfs.NewIdentity("/tmp/.vfs-home/id", password)
+	cli, err := client.New("http://localhost:26657", id)
+	if err != nil {
+		return err
+	}
+
+	hash, height, err := cli.Commit(ctx, []byte("Message here"))
+	stx, err := cli.QueryByHash(ctx, hash)
+*/
+package client
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+
+	vfs "github.com/securesharelabs/vstore/vfs"
+
+	cmtnet "github.com/cometbft/cometbft/libs/net"
+
+	"github.com/spf13/cobra"
+)
+
+// Admin commands accepted on the admin socket
+const (
+	adminCommandPause  = "pause"
+	adminCommandResume = "resume"
+	adminCommandStatus = "status"
+	adminCommandLogs   = "logs"
+	adminCommandHealth = "health"
+)
+
+// Used for flags
+var adminSocketAddr string
+
+func init() {
+	// e.g.: vstore --admin-socket unix://vfs-admin.sock
+	vstoreCmd.PersistentFlags().StringVar(
+		&adminSocketAddr,
+		"admin-socket",
+		"unix://vfs-admin.sock",
+		"Admin socket address (if empty, the admin socket is disabled)",
+	)
+
+	vstoreCmd.AddCommand(adminCmd)
+}
+
+var adminCmd = &cobra.Command{
+	Use:       "admin [pause|resume|status]",
+	Short:     "Execute an admin command on a running vStore node",
+	ValidArgs: []string{adminCommandPause, adminCommandResume, adminCommandStatus},
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	Long: `Execute an admin command on a running vStore node using the admin socket:
+
+  - pause: new transactions are rejected, queries are still served ; and
+  - resume: new transactions are accepted again ; and
+  - status: print whether the node accepts new transactions.
+`,
+	Example: `  vstore admin pause
+  vstore admin resume --admin-socket unix://vfs-admin.sock`,
+	Run: func(cmd *cobra.Command, args []string) {
+		status, err := sendAdminCommand(adminSocketAddr, args[0])
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+
+		fmt.Printf("Node status: %s\n", status)
+	},
+}
+
+// sendAdminCommand sends a command to the admin socket and returns the
+// response line.
+func sendAdminCommand(socketAddr, command string) (string, error) {
+	proto, addr := cmtnet.ProtocolAndAddress(socketAddr)
+	conn, err := net.Dial(proto, addr)
+	if err != nil {
+		return "", fmt.Errorf("could not connect to admin socket: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := fmt.Fprintln(conn, command); err != nil {
+		return "", fmt.Errorf("could not send admin command: %v", err)
+	}
+
+	response, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("could not read admin response: %v", err)
+	}
+
+	return strings.TrimSpace(response), nil
+}
+
+// serveAdmin listens on the admin socket and executes the admin commands
+// that are received, one command per line. The recent log entries of logs
+// are available with the logs command. A teardown function is returned
+// which closes the listener.
+func serveAdmin(
+	listenAddr string,
+	app *vfs.VStoreApplication,
+	logs *vfs.BufferedLogger,
+) (func(), error) {
+	proto, addr := cmtnet.ProtocolAndAddress(listenAddr)
+	if proto == "unix" {
+		// Remove a stale socket file from a previous run
+		os.Remove(addr)
+	}
+
+	ln, err := net.Listen(proto, addr)
+	if err != nil {
+		return func() {}, err
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return // listener closed
+			}
+
+			go handleAdminConn(conn, app, logs)
+		}
+	}()
+
+	return func() { ln.Close() }, nil
+}
+
+// handleAdminConn executes the admin commands of a connection and responds
+// with the resulting node status. The logs command responds with one JSON
+// log entry per line, followed by an empty line.
+func handleAdminConn(conn net.Conn, app *vfs.VStoreApplication, logs *vfs.BufferedLogger) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		switch strings.TrimSpace(scanner.Text()) {
+		case adminCommandPause:
+			app.Pause()
+			log.Printf("transactions paused")
+		case adminCommandResume:
+			app.Resume()
+			log.Printf("transactions resumed")
+		case adminCommandStatus:
+			break
+		case adminCommandLogs:
+			writeAdminLogs(conn, logs)
+			continue
+		case adminCommandHealth:
+			fmt.Fprintln(conn, adminHealth(app))
+			continue
+		default:
+			fmt.Fprintln(conn, "error: unknown command")
+			continue
+		}
+
+		fmt.Fprintln(conn, adminStatus(app))
+	}
+}
+
+// writeAdminLogs writes the log entries as JSON lines, followed by an empty line.
+func writeAdminLogs(conn net.Conn, logs *vfs.BufferedLogger) {
+	if logs != nil {
+		enc := json.NewEncoder(conn)
+		for _, entry := range logs.Entries() {
+			enc.Encode(entry)
+		}
+	}
+
+	fmt.Fprintln(conn)
+}
+
+// adminHealth returns the health status of the node as printed by the health
+// command, i.e. "ok", "degraded: REASON" or "unhealthy: REASON".
+func adminHealth(app *vfs.VStoreApplication) string {
+	err := app.Health()
+	switch {
+	case err == nil:
+		return healthStatusOK
+	case errors.Is(err, vfs.ErrPaused):
+		return healthStatusDegraded + ": " + err.Error()
+	default:
+		return healthStatusUnhealthy + ": " + err.Error()
+	}
+}
+
+// adminStatus returns the node status as printed by the admin command.
+func adminStatus(app *vfs.VStoreApplication) string {
+	if app.Paused() {
+		return "paused"
+	}
+
+	return "running"
+}
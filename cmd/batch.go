@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	client "github.com/securesharelabs/vstore/client"
+	vfs "github.com/securesharelabs/vstore/vfs"
+
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+const (
+	// batchFormatLines reads one payload per line, empty lines are skipped.
+	batchFormatLines = "lines"
+
+	// batchFormatVarint reads payloads prefixed with their length as an
+	// unsigned varint, such that payloads may contain newlines.
+	batchFormatVarint = "varint"
+
+	// maxBatchPayloadSize is the maximum size of a length-prefixed payload.
+	maxBatchPayloadSize = 16 * 1024 * 1024
+)
+
+// batchResult describes the outcome of a transaction of a batch.
+type batchResult struct {
+	Hash   []byte
+	Height int64
+	Err    error
+}
+
+// readBatchPayloads reads the payloads of a batch file in format, which is
+// either batchFormatLines or batchFormatVarint.
+func readBatchPayloads(r io.Reader, format string) ([][]byte, error) {
+	switch format {
+	case batchFormatLines:
+		return readBatchLines(r)
+	case batchFormatVarint:
+		return readBatchVarint(r)
+	default:
+		return nil, fmt.Errorf("unknown batch format %q (expected %s or %s)", format, batchFormatLines, batchFormatVarint)
+	}
+}
+
+// signBatch signs every payload with priv. Payloads that cannot be signed,
+// e.g. empty payloads, are reported in their result and are skipped, the
+// transactions are nil at their index.
+func signBatch(priv ed25519.PrivKey, payloads [][]byte, opts []client.TxOption) ([]*vfs.SignedTransaction, []batchResult) {
+	stxs := make([]*vfs.SignedTransaction, len(payloads))
+	results := make([]batchResult, len(payloads))
+	for i, payload := range payloads {
+		if len(payload) == 0 {
+			results[i].Err = errors.New("empty payload")
+			continue
+		}
+
+		stx, err := client.NewTransactionWithKey(priv, payload, opts...)
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		stxs[i], results[i].Hash = stx, stx.Hash
+	}
+
+	return stxs, results
+}
+
+// broadcastBatch broadcasts the signed transactions of a batch in order with
+// mode. A failed transaction is reported in its result and does not abort
+// the rest of the batch.
+func broadcastBatch(
+	ctx context.Context,
+	cli txBroadcaster,
+	mode string,
+	stxs []*vfs.SignedTransaction,
+	results []batchResult,
+) {
+	for i, stx := range stxs {
+		if stx == nil {
+			continue // not signed
+		}
+
+		response, err := broadcastWithMode(ctx, cli, mode, stx.Bytes())
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		if !broadcastSucceeded(response) {
+			code := response.CheckTx.Code
+			if code == vfs.CodeTypeOK {
+				code = response.TxResult.Code
+			}
+
+			results[i].Err = fmt.Errorf("%s (code %d)", vfs.CodeToString(code), code)
+			continue
+		}
+
+		results[i].Height = response.Height
+	}
+}
+
+// printBatchSummary prints the hash or the error of every transaction of a
+// batch, followed by the number of successes and failures. It returns the
+// number of failures.
+func printBatchSummary(w io.Writer, results []batchResult) int {
+	failed := 0
+	for i, result := range results {
+		switch {
+		case result.Err != nil:
+			failed++
+			fmt.Fprintf(w, "  #%d: error: %v\n", i+1, result.Err)
+		case result.Height > 0:
+			fmt.Fprintf(w, "  #%d: %X (height %d)\n", i+1, result.Hash, result.Height)
+		default:
+			fmt.Fprintf(w, "  #%d: %X\n", i+1, result.Hash)
+		}
+	}
+
+	fmt.Fprintf(w, "Succeeded: %d\n", len(results)-failed)
+	fmt.Fprintf(w, "   Failed: %d\n", failed)
+	return failed
+}
+
+// runFactoryBatch signs the payloads of the batch file with priv, then prints
+// or broadcasts the signed transactions. The private key is unlocked once for
+// the whole batch and is cleared when the batch is done.
+func runFactoryBatch(ctx context.Context, priv ed25519.PrivKey, file string, opts []client.TxOption) {
+	defer clear(priv)
+
+	f, err := os.Open(file)
+	if err != nil {
+		log.Fatalf("could not open batch file: %v", err)
+	}
+	defer f.Close()
+
+	payloads, err := readBatchPayloads(f, batchFormat)
+	if err != nil {
+		log.Fatalf("could not read batch file: %v", err)
+	}
+
+	stxs, results := signBatch(priv, payloads, opts)
+
+	// In case we don't commit the transactions, print the bytes (--output)
+	if !alsoBroadcastTx {
+		signed := make([]*vfs.SignedTransaction, 0, len(stxs))
+		for _, stx := range stxs {
+			if stx != nil {
+				signed = append(signed, stx)
+			}
+		}
+
+		// Validate locally without a node (--dry-run)
+		if dryRun {
+			failed := printBatchSummary(os.Stderr, results)
+			if writeDryRun(os.Stdout, signed, factoryOutput, factoryChainID, time.Now())+failed > 0 {
+				os.Exit(1)
+			}
+			return
+		}
+
+		if err := writeSignedTransactions(os.Stdout, signed, factoryOutput); err != nil {
+			log.Fatalf("could not output signed transactions: %v", err)
+		}
+
+		// Summary does not mix with the transaction bytes
+		if printBatchSummary(os.Stderr, results) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Prepare the RPC client (--node)
+	// Note: A node must be running at this address
+	cli, err := newRPCClient(nodeAddr)
+	if err != nil {
+		log.Fatalf("could not create RPC client: %v", err)
+	}
+
+	broadcastBatch(ctx, cli, broadcastMode, stxs, results)
+
+	fmt.Printf("Batch of %d transactions broadcast:\n", len(results))
+	if printBatchSummary(os.Stdout, results) > 0 {
+		os.Exit(1)
+	}
+}
+
+// --------------------------------------------------------------------------
+// Private helpers
+
+// readBatchLines reads newline-delimited payloads.
+func readBatchLines(r io.Reader) ([][]byte, error) {
+	payloads := [][]byte{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxBatchPayloadSize)
+	for scanner.Scan() {
+		line := bytes.TrimSuffix(scanner.Bytes(), []byte("\r"))
+		if len(line) == 0 {
+			continue
+		}
+
+		payloads = append(payloads, append([]byte{}, line...))
+	}
+
+	return payloads, scanner.Err()
+}
+
+// readBatchVarint reads payloads that are prefixed with their length.
+func readBatchVarint(r io.Reader) ([][]byte, error) {
+	payloads := [][]byte{}
+
+	br := bufio.NewReader(r)
+	for {
+		size, err := binary.ReadUvarint(br)
+		if err == io.EOF {
+			return payloads, nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("payload #%d: invalid length prefix: %v", len(payloads)+1, err)
+		}
+
+		if size > maxBatchPayloadSize {
+			return nil, fmt.Errorf("payload #%d: size %d exceeds %d bytes", len(payloads)+1, size, maxBatchPayloadSize)
+		}
+
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			return nil, fmt.Errorf("payload #%d: %v", len(payloads)+1, err)
+		}
+
+		payloads = append(payloads, payload)
+	}
+}
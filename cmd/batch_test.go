@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	vfs "github.com/securesharelabs/vstore/vfs"
+
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+func TestReadBatchPayloadsLines(t *testing.T) {
+	input := "first record\r\n\nsecond record\nthird record"
+
+	payloads, err := readBatchPayloads(strings.NewReader(input), batchFormatLines)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{
+		[]byte("first record"),
+		[]byte("second record"),
+		[]byte("third record"),
+	}, payloads, "empty lines must be skipped")
+
+	_, err = readBatchPayloads(strings.NewReader(input), "csv")
+	assert.Error(t, err)
+}
+
+func TestReadBatchPayloadsVarint(t *testing.T) {
+	records := [][]byte{[]byte("multi\nline"), []byte("{\"id\": 1}")}
+
+	var buf bytes.Buffer
+	for _, record := range records {
+		buf.Write(binary.AppendUvarint(nil, uint64(len(record))))
+		buf.Write(record)
+	}
+
+	payloads, err := readBatchPayloads(bytes.NewReader(buf.Bytes()), batchFormatVarint)
+	require.NoError(t, err)
+	assert.Equal(t, records, payloads)
+
+	// Truncated payload
+	_, err = readBatchPayloads(bytes.NewReader(buf.Bytes()[:buf.Len()-1]), batchFormatVarint)
+	assert.Error(t, err)
+}
+
+func TestSignAndBroadcastBatch(t *testing.T) {
+	priv := ed25519.GenPrivKey()
+	payloads := [][]byte{[]byte("first"), {}, []byte("third")}
+
+	stxs, results := signBatch(priv, payloads, nil)
+	require.Len(t, stxs, 3)
+	assert.Nil(t, stxs[1], "empty payloads are not signed")
+	assert.Error(t, results[1].Err)
+
+	for _, i := range []int{0, 2} {
+		assert.True(t, stxs[i].Verify(), "should sign with the unlocked key")
+		assert.Equal(t, stxs[i].Hash, results[i].Hash)
+	}
+
+	// A failed broadcast does not abort the rest of the batch
+	cli := &recordingBroadcaster{}
+	broadcastBatch(context.Background(), cli, broadcastModeCommit, stxs, results)
+	assert.NoError(t, results[0].Err)
+	assert.NoError(t, results[2].Err)
+	assert.Equal(t, int64(12), results[2].Height)
+
+	cli = &recordingBroadcaster{code: vfs.CodeTypeDuplicateHashError}
+	stxs, results = signBatch(priv, payloads, nil)
+	broadcastBatch(context.Background(), cli, broadcastModeSync, stxs, results)
+	assert.ErrorContains(t, results[0].Err, "duplicate hash")
+	assert.ErrorContains(t, results[2].Err, "duplicate hash")
+
+	var out bytes.Buffer
+	assert.Equal(t, 3, printBatchSummary(&out, results))
+	assert.Contains(t, out.String(), "Succeeded: 0")
+}
@@ -0,0 +1,254 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	vfs "github.com/securesharelabs/vstore/vfs"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	"github.com/cometbft/cometbft/types"
+
+	"github.com/spf13/cobra"
+)
+
+// Broadcast modes (--broadcast-mode)
+const (
+	broadcastModeCommit = "commit"
+	broadcastModeSync   = "sync"
+	broadcastModeAsync  = "async"
+)
+
+// broadcastModeUsage documents the reliability of the broadcast modes.
+const broadcastModeUsage = `Broadcast mode: commit, sync or async.
+  commit: wait until the transaction is committed in a block (reliable, slow) ;
+  sync: wait until CheckTx accepted the transaction in the mempool, it may still be dropped ;
+  async: return immediately, CheckTx errors are not reported (fire-and-forget).`
+
+// Used for flags
+var broadcastTx string
+var broadcastFile string
+var broadcastMode string
+
+func init() {
+	// e.g.: vstore broadcast --tx 0x0A220A20...
+	broadcastCmd.PersistentFlags().StringVar(
+		&broadcastTx,
+		"tx",
+		"",
+		"Signed transaction bytes, hexadecimal (optionally 0x-prefixed) or base64.",
+	)
+
+	// e.g.: vstore broadcast --file tx.bin
+	broadcastCmd.PersistentFlags().StringVar(
+		&broadcastFile,
+		"file",
+		"",
+		"File with the signed transaction bytes, as written with --output file:PATH.",
+	)
+
+	// e.g.: vstore broadcast --file tx.bin --broadcast-mode sync
+	broadcastCmd.PersistentFlags().StringVar(
+		&broadcastMode,
+		"broadcast-mode",
+		broadcastModeCommit,
+		broadcastModeUsage,
+	)
+
+	broadcastCmd.MarkFlagsMutuallyExclusive("tx", "file")
+	broadcastCmd.MarkFlagsOneRequired("tx", "file")
+	vstoreCmd.AddCommand(broadcastCmd)
+}
+
+var broadcastCmd = &cobra.Command{
+	Use:   "broadcast",
+	Short: "Broadcast a pre-signed transaction",
+	Long: `Broadcast a transaction that was signed offline with the factory subcommand.
+
+  The identity file and its password are not necessary, such that the machine
+  that signs transactions (with the private key) can be separated from the
+  machine that broadcasts transactions (with network access).`,
+
+	Example: `  vstore factory --data "Message here" --output file:tx.bin
+  vstore broadcast --file tx.bin
+  vstore broadcast --tx 0x0A220A20...`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := validateBroadcastMode(broadcastMode); err != nil {
+			log.Fatalf("could not use provided broadcast mode: %v", err)
+		}
+
+		var txbz []byte
+		var err error
+		if len(broadcastFile) > 0 {
+			txbz, err = os.ReadFile(broadcastFile)
+		} else {
+			txbz, err = decodeSignedTransaction(broadcastTx)
+		}
+
+		if err != nil {
+			log.Fatalf("could not read signed transaction: %v", err)
+		}
+
+		// Transaction hash for future query capacity
+		stx, err := vfs.NewSignedTransactionFromBytes(txbz)
+		if err != nil {
+			log.Fatalf("could not parse signed transaction: %v", err)
+		}
+
+		broadcastTransaction(cmd.Context(), txbz, stx.Hash)
+	},
+}
+
+// decodeSignedTransaction decodes the bytes of a signed transaction from
+// hexadecimal, optionally 0x-prefixed, or from base64.
+func decodeSignedTransaction(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		return nil, errors.New("empty transaction")
+	}
+
+	if bz, err := hex.DecodeString(strings.TrimPrefix(s, "0x")); err == nil {
+		return bz, nil
+	}
+
+	bz, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("transaction must be hexadecimal or base64")
+	}
+
+	return bz, nil
+}
+
+// txBroadcaster describes the broadcast methods of the CometBFT RPC client.
+type txBroadcaster interface {
+	BroadcastTxCommit(ctx context.Context, tx types.Tx) (*coretypes.ResultBroadcastTxCommit, error)
+	BroadcastTxSync(ctx context.Context, tx types.Tx) (*coretypes.ResultBroadcastTx, error)
+	BroadcastTxAsync(ctx context.Context, tx types.Tx) (*coretypes.ResultBroadcastTx, error)
+}
+
+// validateBroadcastMode returns an error if mode is not a broadcast mode.
+func validateBroadcastMode(mode string) error {
+	switch mode {
+	case broadcastModeCommit, broadcastModeSync, broadcastModeAsync:
+		return nil
+	default:
+		break
+	}
+
+	return fmt.Errorf("unknown broadcast mode: %q, want: commit, sync or async", mode)
+}
+
+// broadcastWithMode broadcasts transaction bytes with the RPC call of mode,
+// which must return within --timeout.
+// The results of sync and async broadcasts are returned as the CheckTx of
+// the response, the height is 0 as the transaction is not yet committed.
+func broadcastWithMode(
+	ctx context.Context,
+	cli txBroadcaster,
+	mode string,
+	txbz []byte,
+) (*coretypes.ResultBroadcastTxCommit, error) {
+	if err := validateBroadcastMode(mode); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withNodeTimeout(ctx)
+	defer cancel()
+
+	if mode == broadcastModeCommit {
+		res, err := cli.BroadcastTxCommit(ctx, txbz)
+		return res, nodeError(err)
+	}
+
+	broadcast := cli.BroadcastTxSync
+	if mode == broadcastModeAsync {
+		broadcast = cli.BroadcastTxAsync
+	}
+
+	res, err := broadcast(ctx, txbz)
+	if err != nil {
+		return nil, nodeError(err)
+	}
+
+	return &coretypes.ResultBroadcastTxCommit{
+		CheckTx: abci.ResponseCheckTx{
+			Code:      res.Code,
+			Data:      res.Data,
+			Log:       res.Log,
+			Codespace: res.Codespace,
+		},
+		Hash: res.Hash,
+	}, nil
+}
+
+// broadcastTransaction broadcasts signed transaction bytes with the mode of
+// --broadcast-mode. In commit mode, it waits until the transaction is
+// committed and prints the committed height. The transaction hash is printed,
+// or the CheckTx and TxResult of a failed broadcast.
+func broadcastTransaction(ctx context.Context, txbz []byte, hash []byte) {
+	// Prepare the RPC client (--node)
+	// Note: A node must be running at this address
+	cli, err := newRPCClient(nodeAddr)
+	if err != nil {
+		log.Fatalf("could not create RPC client: %v", err)
+	}
+
+	response, err := broadcastWithMode(ctx, cli, broadcastMode, txbz)
+	if err != nil {
+		log.Fatalf("could not broadcast transaction: %v", err)
+	}
+
+	if !broadcastSucceeded(response) {
+		printBroadcastError(response)
+		return
+	}
+
+	switch broadcastMode {
+	case broadcastModeSync:
+		fmt.Println("Transaction accepted in the mempool!")
+		fmt.Printf("Transaction Hash: %x\n", hash)
+	case broadcastModeAsync:
+		fmt.Println("Transaction submitted!")
+		fmt.Printf("Transaction Hash: %x\n", hash)
+	default:
+		fmt.Println("Transaction successfully broadcast!")
+		fmt.Printf("Transaction Hash: %x\n", hash)
+		fmt.Printf("Committed Height: %d\n", response.Height)
+	}
+}
+
+// broadcastSucceeded returns true if both CheckTx and the TxResult succeeded.
+func broadcastSucceeded(response *coretypes.ResultBroadcastTxCommit) bool {
+	return response.CheckTx.Code == vfs.CodeTypeOK && response.TxResult.Code == vfs.CodeTypeOK
+}
+
+// printBroadcastError prints the CheckTx and TxResult of a failed broadcast.
+func printBroadcastError(response *coretypes.ResultBroadcastTxCommit) {
+	fmt.Println("An error occurred trying to broadcast transaction.")
+
+	// Use the failing result code, CheckTx comes first
+	code := response.CheckTx.Code
+	if code == vfs.CodeTypeOK {
+		code = response.TxResult.Code
+	}
+
+	fmt.Printf("Error: %s (code %d)\n", vfs.CodeToString(code), code)
+
+	resCheckTx, _ := json.MarshalIndent(response.CheckTx, "", "  ")
+	resTxResult, _ := json.MarshalIndent(response.TxResult, "", "  ")
+
+	fmt.Println("CheckTx: ")
+	fmt.Print(string(resCheckTx))
+
+	fmt.Println("TxResult: ")
+	fmt.Print(string(resTxResult))
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	vfs "github.com/securesharelabs/vstore/vfs"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	"github.com/cometbft/cometbft/types"
+)
+
+func TestDecodeSignedTransaction(t *testing.T) {
+	stx := makeSignedTransactions(t, []byte("This is a message"), 1024)[0]
+	txbz := stx.Bytes()
+
+	for _, encoded := range []string{
+		hex.EncodeToString(txbz),
+		"0x" + hex.EncodeToString(txbz),
+		base64.StdEncoding.EncodeToString(txbz),
+		" " + hex.EncodeToString(txbz) + "\n",
+	} {
+		bz, err := decodeSignedTransaction(encoded)
+		require.NoError(t, err)
+		assert.Equal(t, txbz, bz)
+	}
+
+	for _, encoded := range []string{"", "0x123", "not a transaction!"} {
+		_, err := decodeSignedTransaction(encoded)
+		assert.Error(t, err, "should not decode %q", encoded)
+	}
+}
+
+func TestBroadcastSucceeded(t *testing.T) {
+	assert.True(t, broadcastSucceeded(&coretypes.ResultBroadcastTxCommit{}))
+
+	// CheckTx failures have an empty TxResult
+	assert.False(t, broadcastSucceeded(&coretypes.ResultBroadcastTxCommit{
+		CheckTx: abci.ResponseCheckTx{Code: vfs.CodeTypeInvalidSignatureError},
+	}))
+
+	assert.False(t, broadcastSucceeded(&coretypes.ResultBroadcastTxCommit{
+		TxResult: abci.ExecTxResult{Code: vfs.CodeTypeInvalidChunkError},
+	}))
+}
+
+// recordingBroadcaster implements txBroadcaster and records the broadcast
+// method that is called.
+type recordingBroadcaster struct {
+	called string
+	code   uint32
+}
+
+func (b *recordingBroadcaster) BroadcastTxCommit(_ context.Context, tx types.Tx) (*coretypes.ResultBroadcastTxCommit, error) {
+	b.called = broadcastModeCommit
+	return &coretypes.ResultBroadcastTxCommit{
+		CheckTx: abci.ResponseCheckTx{Code: b.code},
+		Hash:    tx.Hash(),
+		Height:  12,
+	}, nil
+}
+
+func (b *recordingBroadcaster) BroadcastTxSync(_ context.Context, tx types.Tx) (*coretypes.ResultBroadcastTx, error) {
+	b.called = broadcastModeSync
+	return &coretypes.ResultBroadcastTx{Code: b.code, Hash: tx.Hash()}, nil
+}
+
+func (b *recordingBroadcaster) BroadcastTxAsync(_ context.Context, tx types.Tx) (*coretypes.ResultBroadcastTx, error) {
+	b.called = broadcastModeAsync
+	return &coretypes.ResultBroadcastTx{Hash: tx.Hash()}, nil
+}
+
+func TestBroadcastWithMode(t *testing.T) {
+	ctx := context.Background()
+	txbz := []byte("signed transaction")
+
+	for _, mode := range []string{broadcastModeCommit, broadcastModeSync, broadcastModeAsync} {
+		cli := &recordingBroadcaster{}
+		response, err := broadcastWithMode(ctx, cli, mode, txbz)
+		require.NoError(t, err)
+		assert.Equal(t, mode, cli.called, "should use the RPC call of mode %q", mode)
+		assert.Equal(t, types.Tx(txbz).Hash(), []byte(response.Hash))
+		assert.True(t, broadcastSucceeded(response))
+
+		// Only commit mode waits for the committed height
+		if mode == broadcastModeCommit {
+			assert.Equal(t, int64(12), response.Height)
+		} else {
+			assert.Zero(t, response.Height)
+		}
+	}
+
+	// Sync mode reports CheckTx errors
+	cli := &recordingBroadcaster{code: vfs.CodeTypeInvalidSignatureError}
+	response, err := broadcastWithMode(ctx, cli, broadcastModeSync, txbz)
+	require.NoError(t, err)
+	assert.False(t, broadcastSucceeded(response))
+
+	// Unknown modes
+	_, err = broadcastWithMode(ctx, cli, "block", txbz)
+	assert.Error(t, err)
+}
@@ -8,16 +8,79 @@ send (commit) transactions to the blockchain or query the verifiable store.
 
   - `vstore`: Default vStore application startup (ABCI application server).
   - `vstore factory`: Create digitally signed transactions for vfs nodes.
+  - `vstore broadcast`: Broadcast a transaction that was signed offline.
   - `vstore version`: Print the version number of your vStore instance.
   - `vstore info`: Print the current node's vStore information (State).
   - `vstore query`: Query your vStore instance for transactions.
+  - `vstore root`: Print the current merkle root of a signer, with a verified proof.
+  - `vstore signers`: Print the public keys of all signers of your vStore instance.
+  - `vstore verify`: Verify a transaction against a recorded app hash, or the State integrity.
+  - `vstore export`: Export a transaction and its inclusion proof in a self-contained bundle.
+  - `vstore verify-export`: Verify a bundle that was written with vstore export, offline.
+  - `vstore admin`: Pause or resume the acceptance of new transactions.
+  - `vstore keys restore`: Restore the vStore identity from a backup.
+  - `vstore rekey`: Change the password of the vStore identity.
+  - `vstore health`: Check that a running vStore node is serving (exit code).
+  - `vstore logs`: Print the recent log entries of a running vStore node.
+  - `vstore key`: Print the database key of a transaction or an index.
+  - `vstore reindex`: Rebuild the vStore indexes from the stored transactions.
+  - `vstore migrate-db`: Migrate the vStore database to another database backend.
+  - `vstore snapshot`: Write a snapshot archive of the vStore database for backups.
+  - `vstore restore`: Restore the vStore database from a snapshot archive.
+  - `vstore prune`: Prune the bodies of old transactions with a retention policy.
+  - `vstore pubkey`: Print the public key of the vStore identity.
 
 # Examples
 
 	vstore --home=/tmp/.vfs-home --socket=unix://vfs.sock
+	vstore --home=/tmp/.vfs-home --kdf argon2id
+	vstore --home=/tmp/.vfs-home --password-file /run/secrets/vstore-password
+	vstore --socket tcp://0.0.0.0:26658 --transport grpc
+	vstore --db-backend memdb
+	vstore --event-webhook https://example.com/hooks/vstore --event-format cloudevents
+	vstore --metrics-addr 127.0.0.1:26660
+	vstore --compression zstd
+	vstore --plaintext
+	vstore --home=/tmp/.vfs-home --observer
+	vstore --snapshot-interval 1000 --snapshot-keep-recent 2
+	vstore --retention-max-age 2160h --retention-interval 1000
+	vstore pubkey --encoding bech32
 	vstore version
+	vstore version --json
 	vstore info --home=/tmp/.vfs-home
+	vstore info --node http://10.0.0.1:26657
+	vstore info --node http://10.0.0.1:26657 --timeout 5s
 	vstore factory --home /tmp/.vfs-home --data "Message here" --commit
+	vstore factory --home /tmp/.vfs-home --data "Message here" --output file:tx.bin
+	vstore factory --home /tmp/.vfs-home --data "Message here" --dry-run
+	vstore factory --home /tmp/.vfs-home --batch-file records.txt --commit
+	vstore factory --home /tmp/.vfs-home --data "Message here" --chain-id vstore-mainnet --commit
+	vstore factory --home /tmp/.vfs-home --data "Message here" --sequence 42 --commit
+	vstore broadcast --file tx.bin
 	vstore query --home /tmp/.vfs-home --hash TRANSACTION_HASH_HEX
+	vstore query --hash TRANSACTION_HASH_BASE64 --hash-encoding base64
+	vstore query --hashes-file hashes.txt
+	vstore query --hash TRANSACTION_HASH_HEX --raw > restored.bin
+	vstore query --node https://vstore.example.com --node-token TOKEN --hash TRANSACTION_HASH_HEX
+	vstore query --pubkey SIGNER_PUBKEY_HEX --count
+	vstore query --pubkey SIGNER_PUBKEY_HEX --next-sequence
+	vstore query --pubkey SIGNER_PUBKEY_HEX --format csv --columns hash,time,size,data --plain
+	vstore query --pubkey SIGNER_PUBKEY_BECH32 --pubkey-encoding bech32
+	vstore query --from 2024-06-04T00:00:00Z --to 2024-06-04T23:59:59Z
+	vstore root --pubkey SIGNER_PUBKEY_HEX
+	vstore signers --json
+	vstore signers --pubkey-encoding bech32 --bech32-prefix vstorepub
+	vstore verify --hash TRANSACTION_HASH_HEX --app-hash APP_HASH_HEX --height 1234
+	vstore verify --integrity --home /tmp/.vfs-home
+	vstore export --hash TRANSACTION_HASH_HEX --file bundle.json
+	vstore verify-export --file bundle.json --app-hash APP_HASH_HEX
+	vstore admin pause --admin-socket unix://vfs-admin.sock
+	vstore logs --level error --since 5m
+	vstore health --admin-socket unix://vfs-admin.sock
+	vstore reindex --home /tmp/.vfs-home
+	vstore migrate-db --from goleveldb --to badgerdb --home /tmp/.vfs-home
+	vstore snapshot --out vstore.snapshot --home /tmp/.vfs-home
+	vstore restore --in vstore.snapshot --db-backend badgerdb
+	vstore prune --max-age 2160h --home /tmp/.vfs-home
 */
 package cmd
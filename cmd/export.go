@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	vfs "github.com/securesharelabs/vstore/vfs"
+
+	rpcclient "github.com/cometbft/cometbft/rpc/client"
+
+	"github.com/spf13/cobra"
+)
+
+// Used for flags
+var exportHash string
+var exportHeight int64
+var exportFile string
+var verifyExportFile string
+var verifyExportAppHash string
+
+func init() {
+	// e.g.: vstore export --hash "3816D803...9E03"
+	exportCmd.PersistentFlags().StringVar(
+		&exportHash,
+		"hash",
+		"",
+		"Hash of the transaction to export.",
+	)
+
+	// e.g.: vstore export --hash "3816D803...9E03" --height 1234
+	exportCmd.PersistentFlags().Int64Var(
+		&exportHeight,
+		"height",
+		0,
+		"Block height of the app hash of the proof (if empty, uses the latest height).",
+	)
+
+	// e.g.: vstore export --hash "3816D803...9E03" --file bundle.json
+	exportCmd.PersistentFlags().StringVar(
+		&exportFile,
+		"file",
+		"",
+		"Write the bundle to this file (if empty, prints the bundle).",
+	)
+
+	// e.g.: vstore verify-export --file bundle.json
+	verifyExportCmd.PersistentFlags().StringVar(
+		&verifyExportFile,
+		"file",
+		"",
+		"Path of the bundle that was written with vstore export.",
+	)
+
+	// e.g.: vstore verify-export --file bundle.json --app-hash "C5D2460E...8F0B"
+	verifyExportCmd.PersistentFlags().StringVar(
+		&verifyExportAppHash,
+		"app-hash",
+		"",
+		"Trusted app hash of the bundle height (hexadecimal), e.g. from a block header.",
+	)
+
+	// e.g.: vstore verify-export --file bundle.json --json
+	verifyExportCmd.PersistentFlags().BoolVarP(
+		&printAsJSON,
+		"json",
+		"j",
+		false,
+		"Display the information in a JSON format.",
+	)
+
+	vstoreCmd.AddCommand(exportCmd)
+	vstoreCmd.AddCommand(verifyExportCmd)
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export a transaction and its inclusion proof in a bundle",
+	Long: `Export a transaction and its inclusion proof in a self-contained JSON
+bundle, e.g. to present it to a third party or to anchor it elsewhere.
+
+  The bundle contains the transaction, the proof that the transaction is
+  part of the app hash, the app hash and its block height. The bundle is
+  verified offline with vstore verify-export.`,
+
+	Example: `  vstore export --hash "XXX"
+  vstore export --hash "XXX" --height 1234 --file bundle.json`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		hbz, err := hex.DecodeString(exportHash)
+		if err != nil || len(hbz) != 32 {
+			log.Fatalf("could not use provided transaction hash: %q", exportHash)
+		}
+
+		cli, err := newRPCClient(nodeAddr)
+		if err != nil {
+			log.Fatalf("could not create RPC client: %v", err)
+		}
+
+		// Fetch the transaction and its proof for the height
+		ctx, cancel := withNodeTimeout(cmd.Context())
+		response, err := cli.ABCIQueryWithOptions(ctx, "/hash", hbz, rpcclient.ABCIQueryOptions{
+			Height: exportHeight,
+			Prove:  true,
+		})
+		cancel()
+		if err != nil {
+			log.Fatalf("error occured on query: %v", nodeError(err))
+		}
+
+		if response.Response.Code != vfs.CodeTypeOK {
+			log.Fatalf("error occured on query: %s (%d - %s)",
+				vfs.CodeToString(response.Response.Code), response.Response.Code, response.Response.Log)
+		}
+
+		if len(response.Response.Value) == 0 || response.Response.ProofOps == nil {
+			log.Fatalf("could not find proof for transaction with hash: %x", hbz)
+		}
+
+		// The proof is created for the app hash of the response height
+		height := response.Response.Height
+		appHash := executeQuery(cmd.Context(), cli, "/apphash/height", []byte(strconv.FormatInt(height, 10))).Value
+
+		bundle, err := vfs.NewExportBundle(response.Response.Value, response.Response.ProofOps, height, appHash)
+		if err != nil {
+			log.Fatalf("could not create bundle: %v", err)
+		}
+
+		bz, _ := json.MarshalIndent(bundle, "", "  ")
+		if exportFile == "" {
+			fmt.Print(string(bz) + "\n")
+			return // Job done.
+		}
+
+		if err := os.WriteFile(exportFile, append(bz, '\n'), 0644); err != nil {
+			log.Fatalf("could not write bundle: %v", err)
+		}
+
+		fmt.Printf("Bundle written to: %s\n", exportFile)
+		fmt.Printf("Transaction Hash: %s\n", bundle.Hash)
+		fmt.Printf("          Height: %d\n", bundle.Height)
+		fmt.Printf("        App Hash: %s\n", bundle.AppHash)
+	},
+}
+
+var verifyExportCmd = &cobra.Command{
+	Use:   "verify-export",
+	Short: "Verify a bundle that was written with vstore export",
+	Long: `Verify a bundle that was written with vstore export, offline.
+
+  The transaction must match the hash and the signer of the bundle, its
+  signature must be valid and the proof must verify against the app hash of
+  the bundle. The app hash is recorded in the block header of the next height
+  and should be compared with a trusted app hash, provided with --app-hash.`,
+
+	Example: `  vstore verify-export --file bundle.json
+  vstore verify-export --file bundle.json --app-hash "XXX" --json`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		bz, err := os.ReadFile(verifyExportFile)
+		if err != nil {
+			log.Fatalf("could not read bundle: %v", err)
+		}
+
+		bundle := vfs.ExportBundle{}
+		if err := json.Unmarshal(bz, &bundle); err != nil {
+			log.Fatalf("could not parse bundle JSON: %v", err)
+		}
+
+		if verifyExportAppHash == "" {
+			err = bundle.Verify()
+		} else {
+			trusted, decodeErr := hex.DecodeString(verifyExportAppHash)
+			if decodeErr != nil || len(trusted) == 0 {
+				log.Fatalf("could not use provided app hash: %q", verifyExportAppHash)
+			}
+
+			err = bundle.VerifyAppHash(trusted)
+		}
+
+		verifyInfo := struct {
+			Hash           string
+			Signer         string
+			Height         int64
+			AppHash        string
+			TrustedAppHash bool
+			Valid          bool
+		}{
+			bundle.Hash,
+			bundle.Signer,
+			bundle.Height,
+			bundle.AppHash,
+			verifyExportAppHash != "",
+			err == nil,
+		}
+
+		if printAsJSON {
+			json, _ := json.MarshalIndent(verifyInfo, "", "  ")
+			fmt.Print(string(json) + "\n")
+		} else {
+			fmt.Printf("vStore %s (vfs v%d) - ABCI: \n", Version, vfs.AppVersion)
+			fmt.Printf("  Transaction Hash: %s\n", verifyInfo.Hash)
+			fmt.Printf("     Signer PubKey: %s\n", verifyInfo.Signer)
+			fmt.Printf("            Height: %d\n", verifyInfo.Height)
+			fmt.Printf("          App Hash: %s\n", verifyInfo.AppHash)
+			fmt.Printf("  Trusted App Hash: %t\n", verifyInfo.TrustedAppHash)
+			fmt.Printf("             Valid: %t\n", verifyInfo.Valid)
+		}
+
+		if err != nil {
+			log.Printf("bundle does not verify: %v", err)
+			os.Exit(1)
+		}
+	},
+}
@@ -1,27 +1,33 @@
 package cmd
 
 import (
-	"bufio"
-	"encoding/json"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"os"
-	"strings"
 	"time"
 
-	vfsp2p "github.com/securesharelabs/vstore/api/vstore/v1"
+	client "github.com/securesharelabs/vstore/client"
 	vfs "github.com/securesharelabs/vstore/vfs"
 
-	cmtlog "github.com/cometbft/cometbft/libs/log"
-	rpc "github.com/cometbft/cometbft/rpc/client/http"
+	"github.com/cometbft/cometbft/crypto/ed25519"
 
 	"github.com/spf13/cobra"
-	"golang.org/x/term"
 )
 
 // Used for flags
 var transactionData string
 var alsoBroadcastTx bool
+var chunkSize int
+var bodyRef string
+var prevHash string
+var contentType string
+var factoryChainID string
+var sequence uint64
+var batchFile string
+var batchFormat string
+var factoryOutput string
+var dryRun bool
 
 // init registers the factory command in vstore
 func init() {
@@ -42,6 +48,95 @@ func init() {
 		"Broadcast and commit the transaction",
 	)
 
+	// e.g.: vstore factory --data "This is a message" --chunk-size 1024
+	factoryCmd.PersistentFlags().IntVar(
+		&chunkSize,
+		"chunk-size",
+		0,
+		"Split the transaction body in chunks of this size (if 0, no chunks)",
+	)
+
+	// e.g.: vstore factory --body-ref sha256:HEX --commit
+	factoryCmd.PersistentFlags().StringVar(
+		&bodyRef,
+		"body-ref",
+		"",
+		"Sign a reference to a body in external storage instead of the body.",
+	)
+
+	// e.g.: vstore factory --data "This is a message" --prev-hash HEX --commit
+	factoryCmd.PersistentFlags().StringVar(
+		&prevHash,
+		"prev-hash",
+		"",
+		"Chain the transaction to your previous transaction with this hash.",
+	)
+
+	// e.g.: vstore factory --data '{"id": 1}' --content-type application/json --commit
+	factoryCmd.PersistentFlags().StringVar(
+		&contentType,
+		"content-type",
+		"",
+		"Declare the media type of the transaction body, e.g. application/json.",
+	)
+
+	// e.g.: vstore factory --data "This is a message" --chain-id vstore-mainnet --commit
+	factoryCmd.PersistentFlags().StringVar(
+		&factoryChainID,
+		"chain-id",
+		"",
+		"Sign the transaction for this chain, required by nodes with a genesis chain id.",
+	)
+
+	// e.g.: vstore factory --data "This is a message" --sequence 42 --commit
+	factoryCmd.PersistentFlags().Uint64Var(
+		&sequence,
+		"sequence",
+		0,
+		"Sign the transaction with this sequence, i.e. your next sequence (vstore query --pubkey XXX --next-sequence).",
+	)
+
+	// e.g.: vstore factory --batch-file records.txt --commit
+	factoryCmd.PersistentFlags().StringVar(
+		&batchFile,
+		"batch-file",
+		"",
+		"Sign (and broadcast) every payload of this file with a single password prompt.",
+	)
+
+	// e.g.: vstore factory --batch-file records.bin --batch-format varint
+	factoryCmd.PersistentFlags().StringVar(
+		&batchFormat,
+		"batch-format",
+		batchFormatLines,
+		"Format of the batch file: lines (one payload per line) or varint (length-prefixed payloads).",
+	)
+
+	// e.g.: vstore factory --data "This is a message" --output json
+	factoryCmd.PersistentFlags().StringVarP(
+		&factoryOutput,
+		"output",
+		"o",
+		outputHex,
+		"Output format of the signed transaction: hex, base64, json or file:PATH (without --commit).",
+	)
+
+	// e.g.: vstore factory --data "This is a message" --dry-run
+	factoryCmd.PersistentFlags().BoolVar(
+		&dryRun,
+		"dry-run",
+		false,
+		"Sign and validate the transaction locally, print its hash, size and validity (never contacts a node).",
+	)
+
+	// e.g.: vstore factory --data "This is a message" --commit --broadcast-mode async
+	factoryCmd.PersistentFlags().StringVar(
+		&broadcastMode,
+		"broadcast-mode",
+		broadcastModeCommit,
+		broadcastModeUsage,
+	)
+
 	// Add the factory subcommand to vstore
 	vstoreCmd.AddCommand(factoryCmd)
 }
@@ -51,19 +146,28 @@ var factoryCmd = &cobra.Command{
 	Short: "Use the vstore transaction factory",
 	Long:  `Use the vstore transaction factory to create digitally signed datasets.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		// Read password to encrypt/decrypt identity file
-		fmt.Printf("Enter your password: ")
-		pw, err := term.ReadPassword(0)
-		if err != nil {
-			log.Fatalf("could not read password: %v", err)
+		if err := validateOutput(factoryOutput); err != nil {
+			log.Fatalf("could not use provided output: %v", err)
+		}
+
+		if err := validateBroadcastMode(broadcastMode); err != nil {
+			log.Fatalf("could not use provided broadcast mode: %v", err)
 		}
-		fmt.Printf("\n")
 
-		// Generate and encrypt identity if necessary
-		if _, err := os.Stat(idFile); os.IsNotExist(err) {
-			vfs.MustGenerateIdentity(idFile, pw)
+		// A dry run never contacts a node
+		if dryRun && alsoBroadcastTx {
+			log.Fatalf("could not create transaction: --dry-run and --commit are mutually exclusive")
 		}
 
+		// Batches are read from a file, payloads are not chunked nor chained
+		if len(batchFile) > 0 && (len(transactionData) > 0 || len(bodyRef) > 0 || chunkSize > 0 || len(prevHash) > 0 || sequence > 0) {
+			log.Fatalf("could not create batch: --batch-file cannot be combined with --data, --body-ref, --chunk-size, --prev-hash or --sequence")
+		}
+
+		// Read password to encrypt/decrypt identity file, the identity is
+		// generated and encrypted if necessary
+		pw := readIdentityPassword(idFile)
+
 		id, err := openIdentity(idFile, pw)
 		if err != nil {
 			log.Fatalf("could not open identity: %v", err)
@@ -74,82 +178,204 @@ var factoryCmd = &cobra.Command{
 			log.Fatalf("could not unlock private key: %v", err)
 		}
 
-		// Ask for data if not provided with --data
-		if len(transactionData) == 0 {
-			fmt.Printf("Enter the data to sign: ")
-			reader := bufio.NewReader(os.Stdin)
-			input, err := reader.ReadString('\n')
+		// Sign every payload of the batch file with the unlocked key
+		if len(batchFile) > 0 {
+			opts := []client.TxOption{}
+			if len(contentType) > 0 {
+				opts = append(opts, client.WithContentType(contentType))
+			}
+
+			if len(factoryChainID) > 0 {
+				opts = append(opts, client.WithChainID(factoryChainID))
+			}
+
+			runFactoryBatch(cmd.Context(), priv, batchFile, opts)
+			return
+		}
+
+		if len(bodyRef) > 0 && len(transactionData) > 0 {
+			log.Fatalf("could not create transaction: --data and --body-ref are mutually exclusive")
+		}
+
+		// Ask for data if not provided with --data, piped data is read
+		// entirely, e.g.: cat payload.bin | vstore factory --commit
+		if len(transactionData) == 0 && len(bodyRef) == 0 {
+			input, err := readTransactionData(os.Stdin, stdinIsTerminal())
 			if err != nil {
 				log.Fatalf("could not read transaction data: %v", err)
 			}
 
-			transactionData = strings.TrimSuffix(input, "\n")
+			transactionData = string(input)
 		}
 
-		// Sign data
-		sig, err := priv.Sign([]byte(transactionData))
-		if err != nil {
-			log.Fatalf("could not sign transaction: %v", err)
+		// Split large bodies in chunks of linked transactions
+		if chunkSize > 0 && len(transactionData) > chunkSize {
+			if len(prevHash) > 0 {
+				log.Fatalf("could not create transaction: --prev-hash is not supported with chunks")
+			}
+
+			if len(contentType) > 0 {
+				log.Fatalf("could not create transaction: --content-type is not supported with chunks")
+			}
+
+			if sequence > 0 {
+				log.Fatalf("could not create transaction: --sequence is not supported with chunks")
+			}
+
+			broadcastChunkedTransactions(cmd, priv, []byte(transactionData))
+			return
+		}
+
+		// Chain the transaction to a previous transaction (--prev-hash)
+		opts := []client.TxOption{}
+		if len(prevHash) > 0 {
+			pbz, err := hex.DecodeString(prevHash)
+			if err != nil || len(pbz) != 32 {
+				log.Fatalf("could not use provided previous hash: must be 32 bytes hex")
+			}
+
+			opts = append(opts, client.WithPrevHash(pbz))
+		}
+
+		// Declare the media type of the body (--content-type)
+		if len(contentType) > 0 {
+			opts = append(opts, client.WithContentType(contentType))
 		}
 
-		// Create a protobuf transaction object
-		tx := new(vfsp2p.Transaction)
-		tx.Signer = vfs.PubKeyToProto(priv.PubKey())
-		tx.Signature = sig
-		tx.Time = time.Now()
-		tx.Len = uint32(len(transactionData))
-		tx.Body = []byte(transactionData)
+		// Sign for the chain of the node (--chain-id)
+		if len(factoryChainID) > 0 {
+			opts = append(opts, client.WithChainID(factoryChainID))
+		}
+
+		// Protect against replays with the next sequence (--sequence)
+		if sequence > 0 {
+			opts = append(opts, client.WithSequence(sequence))
+		}
+
+		// Sign data (or the body reference) and timestamp
+		signer := client.NewWithRPC(nil, id)
+		var stx *vfs.SignedTransaction
+		if len(bodyRef) > 0 {
+			stx, err = signer.NewReferenceTransaction(bodyRef, opts...)
+		} else {
+			stx, err = signer.NewTransaction([]byte(transactionData), opts...)
+		}
 
-		stx, err := vfs.FromProto(tx)
 		if err != nil {
 			log.Fatalf("could not create signed transaction: %v", err)
 		}
 
-		txbz := stx.Bytes()
-
-		// Compute the transaction hash for future query capacity
-		stxHash := vfs.ComputeHash(stx)
+		// Validate locally without a node (--dry-run)
+		if dryRun {
+			exitDryRun([]*vfs.SignedTransaction{stx})
+		}
 
-		// In case we don't commit the transaction, print the bytes
+		// In case we don't commit the transaction, print the bytes (--output)
 		if !alsoBroadcastTx {
-			fmt.Println("Signed transaction bytes: ")
-			fmt.Printf("0x%x\n", txbz)
+			if err := writeSignedTransactions(os.Stdout, []*vfs.SignedTransaction{stx}, factoryOutput); err != nil {
+				log.Fatalf("could not output signed transaction: %v", err)
+			}
 			return
 		}
 
-		// Prepare the local RPC client
-		// Note: A node must be running in the background
-		// TODO: Permit overwrite of RPC remote address
-		logger := cmtlog.NewTMLogger(cmtlog.NewSyncWriter(os.Stdout))
-		cli, err := rpc.New("http://localhost:26657", "/websocket")
-		if err != nil {
-			log.Fatalf("could not connect to RPC server: %v", err)
+		// Broadcast the transaction, the hash is used for queries
+		broadcastTransaction(cmd.Context(), stx.Bytes(), stx.Hash)
+	},
+}
+
+// signForChain sets the chain id of a signed transaction, then signs it again
+// and recomputes its hash.
+func signForChain(priv ed25519.PrivKey, stx *vfs.SignedTransaction, chainID string) error {
+	stx.ChainID = chainID
+	sig, err := priv.Sign(stx.SignBytes())
+	if err != nil {
+		return err
+	}
+
+	stx.Signature = sig
+	stx.Hash = vfs.ComputeHash(stx)
+	return nil
+}
+
+// broadcastChunkedTransactions splits the data in chunks, signs each chunk
+// and broadcasts the chunk transactions in order. Each chunk is committed
+// before the next one is broadcast so that the ordering is preserved.
+func broadcastChunkedTransactions(cmd *cobra.Command, priv ed25519.PrivKey, data []byte) {
+	stxs, err := vfs.NewChunkedTransactions(priv, data, chunkSize, time.Now())
+	if err != nil {
+		log.Fatalf("could not create chunked transactions: %v", err)
+	}
+
+	// Sign the chunks for the chain of the node (--chain-id)
+	if len(factoryChainID) > 0 {
+		for _, stx := range stxs {
+			if err := signForChain(priv, stx, factoryChainID); err != nil {
+				log.Fatalf("could not sign chunked transactions: %v", err)
+			}
+		}
+	}
+
+	root := stxs[0].Chunk.Root
+
+	// Validate locally without a node (--dry-run)
+	if dryRun {
+		exitDryRun(stxs)
+	}
+
+	// In case we don't commit the transactions, print the bytes (--output)
+	if !alsoBroadcastTx {
+		if err := writeSignedTransactions(os.Stdout, stxs, factoryOutput); err != nil {
+			log.Fatalf("could not output signed transactions: %v", err)
 		}
-		cli.SetLogger(logger)
 
-		// Broadcast the transaction
-		response, err := cli.BroadcastTxCommit(cmd.Context(), txbz)
+		if factoryOutput == outputHex {
+			fmt.Printf("Chunk Root Hash: %x\n", root)
+		}
+		return
+	}
+
+	// Chunks must be committed in order
+	if broadcastMode != broadcastModeCommit {
+		log.Fatalf("could not broadcast chunks: --broadcast-mode must be commit")
+	}
+
+	// Prepare the RPC client (--node)
+	// Note: A node must be running at this address
+	cli, err := newRPCClient(nodeAddr)
+	if err != nil {
+		log.Fatalf("could not create RPC client: %v", err)
+	}
+
+	// Broadcast the chunks in order
+	var height int64
+	for _, stx := range stxs {
+		response, err := broadcastWithMode(cmd.Context(), cli, broadcastModeCommit, stx.Bytes())
 		if err != nil {
 			log.Fatalf("could not broadcast transaction: %v", err)
 		}
 
-		if response.TxResult.Code == vfs.CodeTypeOK {
-			fmt.Println("Transaction successfully broadcast!")
-			fmt.Printf("Transaction Hash: %x\n", stxHash)
-			fmt.Printf("Committed Height: %d\n", response.Height)
-		} else {
-			fmt.Println("An error occurred trying to broadcast transaction.")
+		if !broadcastSucceeded(response) {
+			printBroadcastError(response)
+			log.Fatalf("could not commit chunk %d/%d", stx.Chunk.Index+1, stx.Chunk.Total)
+		}
 
-			resCheckTx, _ := json.MarshalIndent(response.CheckTx, "", "  ")
-			resTxResult, _ := json.MarshalIndent(response.TxResult, "", "  ")
+		height = response.Height
+	}
 
-			fmt.Println("CheckTx: ")
-			fmt.Print(string(resCheckTx))
+	fmt.Println("Chunked transactions successfully broadcast!")
+	fmt.Printf("Chunk Root Hash: %x\n", root)
+	fmt.Printf("          Chunks: %d\n", len(stxs))
+	fmt.Printf("Committed Height: %d\n", height)
+}
 
-			fmt.Println("TxResult: ")
-			fmt.Print(string(resTxResult))
-		}
-	},
+// exitDryRun validates signed transactions locally, prints the results in the
+// output format and exits, with code 1 if any transaction is invalid.
+func exitDryRun(stxs []*vfs.SignedTransaction) {
+	if writeDryRun(os.Stdout, stxs, factoryOutput, factoryChainID, time.Now()) > 0 {
+		os.Exit(1)
+	}
+
+	os.Exit(0)
 }
 
 // openIdentity opens an encrypted identity file.
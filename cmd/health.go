@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	vfs "github.com/securesharelabs/vstore/vfs"
+
+	"github.com/spf13/cobra"
+)
+
+// Health statuses reported on the admin socket
+const (
+	healthStatusOK        = "ok"
+	healthStatusDegraded  = "degraded"
+	healthStatusUnhealthy = "unhealthy"
+)
+
+// Exit codes of the health command
+const (
+	healthExitOK        = 0
+	healthExitDegraded  = 1
+	healthExitUnhealthy = 2
+)
+
+func init() {
+	// e.g.: vstore health --json
+	healthCmd.PersistentFlags().BoolVarP(
+		&printAsJSON,
+		"json",
+		"j",
+		false,
+		"Display the information in a JSON format.",
+	)
+
+	vstoreCmd.AddCommand(healthCmd)
+}
+
+var healthCmd = &cobra.Command{
+	Use:   "health",
+	Short: "Check that a running vStore node is serving",
+	Long: `Check that a running vStore node is serving using the admin socket.
+
+  Unlike info, which queries the CometBFT RPC, the node checks its internal
+  state: the database must be reachable and the identity secret must unlock.
+  The exit code is 0 when the node is healthy, 1 when it is degraded (i.e.
+  paused, queries are still served) and 2 when it is unhealthy or unreachable.`,
+
+	Example: `  vstore health
+  vstore health --admin-socket unix://vfs-admin.sock --json`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		response, err := sendAdminCommand(adminSocketAddr, adminCommandHealth)
+		if err != nil {
+			response = healthStatusUnhealthy + ": " + err.Error()
+		}
+
+		status, reason, code := parseHealthResponse(response)
+		healthInfo := struct {
+			Status string
+			Reason string `json:",omitempty"`
+		}{
+			status,
+			reason,
+		}
+
+		if printAsJSON {
+			json, _ := json.MarshalIndent(healthInfo, "", "  ")
+			fmt.Print(string(json) + "\n")
+			os.Exit(code)
+		}
+
+		fmt.Printf("vStore %s (vfs v%d) - ABCI: \n", Version, vfs.AppVersion)
+		fmt.Printf("  Status: %s\n", healthInfo.Status)
+		if len(healthInfo.Reason) > 0 {
+			fmt.Printf("  Reason: %s\n", healthInfo.Reason)
+		}
+
+		os.Exit(code)
+	},
+}
+
+// parseHealthResponse returns the status, the reason and the exit code of
+// a health response, e.g. "degraded: transactions paused". Unknown responses
+// are unhealthy, e.g. nodes that do not support the health command.
+func parseHealthResponse(response string) (string, string, int) {
+	status, reason, _ := strings.Cut(response, ":")
+	status, reason = strings.TrimSpace(status), strings.TrimSpace(reason)
+
+	switch status {
+	case healthStatusOK:
+		return status, reason, healthExitOK
+	case healthStatusDegraded:
+		return status, reason, healthExitDegraded
+	case healthStatusUnhealthy:
+		return status, reason, healthExitUnhealthy
+	default:
+		return healthStatusUnhealthy, response, healthExitUnhealthy
+	}
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	vfs "github.com/securesharelabs/vstore/vfs"
+)
+
+func TestParseHealthResponse(t *testing.T) {
+	responses := map[string]struct {
+		status string
+		reason string
+		code   int
+	}{
+		"ok":                            {healthStatusOK, "", healthExitOK},
+		"degraded: transactions paused": {healthStatusDegraded, "transactions paused", healthExitDegraded},
+		"unhealthy: database unreachable: closed": {healthStatusUnhealthy, "database unreachable: closed", healthExitUnhealthy},
+		"error: unknown command":                  {healthStatusUnhealthy, "error: unknown command", healthExitUnhealthy},
+	}
+
+	for response, want := range responses {
+		status, reason, code := parseHealthResponse(response)
+		assert.Equal(t, want.status, status, response)
+		assert.Equal(t, want.reason, reason, response)
+		assert.Equal(t, want.code, code, response)
+	}
+}
+
+func TestAdminHealth(t *testing.T) {
+	dir := t.TempDir()
+	idFile := filepath.Join(dir, "id")
+	vfs.MustGenerateIdentityWithKDF(idFile, []byte("testpassword"), vfs.KDFSHA256)
+
+	app := vfs.NewInMemoryVStoreApplication(idFile, []byte("testpassword"))
+
+	socketAddr := "unix://" + filepath.Join(dir, "admin.sock")
+	teardown, err := serveAdmin(socketAddr, app, nil)
+	require.NoError(t, err)
+	defer teardown()
+
+	response, err := sendAdminCommand(socketAddr, adminCommandHealth)
+	require.NoError(t, err)
+	assert.Equal(t, healthStatusOK, response)
+
+	app.Pause()
+	response, err = sendAdminCommand(socketAddr, adminCommandHealth)
+	require.NoError(t, err)
+	_, _, code := parseHealthResponse(response)
+	assert.Equal(t, healthExitDegraded, code)
+
+	// Unreachable nodes are unhealthy
+	_, err = sendAdminCommand("unix://"+filepath.Join(dir, "missing.sock"), adminCommandHealth)
+	assert.Error(t, err)
+}
@@ -4,13 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"os"
 
 	vfs "github.com/securesharelabs/vstore/vfs"
 
-	cmtlog "github.com/cometbft/cometbft/libs/log"
-	rpc "github.com/cometbft/cometbft/rpc/client/http"
-
 	"github.com/spf13/cobra"
 )
 
@@ -44,23 +40,26 @@ var infoCmd = &cobra.Command{
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 
-		// Prepare the local RPC client
-		// Note: A node must be running in the background
-		// TODO: Permit overwrite of RPC remote address
-		logger := cmtlog.NewTMLogger(cmtlog.NewSyncWriter(os.Stdout))
-		cli, err := rpc.New("http://localhost:26657", "/websocket")
+		// Prepare the RPC client (--node)
+		// Note: A node must be running at this address
+		cli, err := newRPCClient(nodeAddr)
 		if err != nil {
-			log.Fatalf("could not connect to RPC server: %v", err)
+			log.Fatalf("could not create RPC client: %v", err)
 		}
-		cli.SetLogger(logger)
 
 		// Broadcast the transaction
-		response, err := cli.ABCIInfo(cmd.Context())
+		ctx, cancel := withNodeTimeout(cmd.Context())
+		defer cancel()
+
+		response, err := cli.ABCIInfo(ctx)
 		if err != nil {
-			log.Fatalf("could not retrieve ABCI information: %v", err)
+			log.Fatalf("could not retrieve ABCI information: %v", nodeError(err))
 		}
 
-		var state vfs.State
+		var state struct {
+			vfs.State
+			Paused bool `json:"paused"`
+		}
 		err = json.Unmarshal([]byte(response.Response.Data), &state)
 		if err != nil {
 			log.Fatalf("could not parse State JSON from RPC: %v", err)
@@ -73,6 +72,7 @@ var infoCmd = &cobra.Command{
 			Transactions int64
 			MerkleRoots  int64
 			AppHash      string
+			Paused       bool
 		}{
 			response.Response.Version,
 			response.Response.AppVersion,
@@ -80,6 +80,7 @@ var infoCmd = &cobra.Command{
 			state.NumTransactions,
 			int64(len(state.MerkleRoots)),
 			fmt.Sprintf("%x", response.Response.LastBlockAppHash),
+			state.Paused,
 		}
 
 		if printAsJSON {
@@ -88,12 +89,13 @@ var infoCmd = &cobra.Command{
 			return // Job done.
 		}
 
-		fmt.Printf("vStore v1.0 (vfs v%d) - ABCI: \n", vfs.AppVersion)
+		fmt.Printf("vStore %s (vfs v%d) - ABCI: \n", Version, vfs.AppVersion)
 		fmt.Printf("  ABCI Version: %s\n", appInfo.ABCIVersion)
 		fmt.Printf("   App Version: %d\n", appInfo.AppVersion)
 		fmt.Printf("   Last Height: %d\n", appInfo.LastHeight)
 		fmt.Printf("  Transactions: %d\n", appInfo.Transactions)
 		fmt.Printf("  Merkle Roots: %d\n", appInfo.MerkleRoots)
 		fmt.Printf("      App Hash: %s\n", appInfo.AppHash)
+		fmt.Printf("        Paused: %t\n", appInfo.Paused)
 	},
 }
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// stdinIsTerminal returns true if the standard input is a terminal, i.e. it
+// is not piped or redirected.
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// PasswordEnv is the environment variable of the identity password which is
+// used instead of the interactive prompt, e.g. in containers.
+const PasswordEnv = "VSTORE_PASSWORD"
+
+// readPassword returns the password of the --password-file file or of the
+// PasswordEnv environment variable, if any. Otherwise, it prints the prompt
+// and reads the password from the terminal, see promptPassword.
+func readPassword(prompt string) []byte {
+	pw, ok, err := lookupPassword(pwFile, os.Getenv)
+	if err != nil {
+		log.Fatalf("could not read password: %v", err)
+	}
+
+	if ok {
+		return pw
+	}
+
+	return promptPassword(prompt)
+}
+
+// readIdentityPassword reads the password of the identity file, see
+// readPassword. If the identity file does not exist, the identity is generated
+// with a new password, see readNewPassword.
+func readIdentityPassword(file string) []byte {
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		pw := readNewPassword()
+		generateIdentity(file, pw)
+		return pw
+	}
+
+	return readPassword("Enter your password: ")
+}
+
+// readNewPassword returns the password of a new identity. The password of the
+// --password-file file or of the PasswordEnv environment variable is used as
+// is, e.g. in scripts. Otherwise, the password is prompted twice and both
+// entries must match, such that a mistyped password is not used.
+func readNewPassword() []byte {
+	pw, ok, err := lookupPassword(pwFile, os.Getenv)
+	if err != nil {
+		log.Fatalf("could not read password: %v", err)
+	}
+
+	if ok {
+		return pw
+	}
+
+	pw = promptPassword("Enter a new password: ")
+	if err := confirmPassword(pw, promptPassword("Confirm your new password: ")); err != nil {
+		log.Fatalf("could not generate identity: %v", err)
+	}
+
+	return pw
+}
+
+// confirmPassword returns an error if the confirmation of a new password does
+// not match the password.
+func confirmPassword(pw, confirm []byte) error {
+	if !bytes.Equal(pw, confirm) {
+		return errors.New("passwords do not match")
+	}
+
+	return nil
+}
+
+// promptPassword prints the prompt and reads a password from the terminal.
+// When the standard input is piped, the password is read from the controlling
+// terminal instead.
+func promptPassword(prompt string) []byte {
+	fd := int(os.Stdin.Fd())
+	if !stdinIsTerminal() {
+		tty, err := os.Open("/dev/tty")
+		if err != nil {
+			log.Fatalf("could not read password: no terminal: %v", err)
+		}
+		defer tty.Close()
+
+		fd = int(tty.Fd())
+	}
+
+	fmt.Printf("%s", prompt)
+	pw, err := term.ReadPassword(fd)
+	if err != nil {
+		log.Fatalf("could not read password: %v", err)
+	}
+	fmt.Printf("\n")
+
+	return pw
+}
+
+// lookupPassword returns the password of file, without the trailing newline,
+// or of the PasswordEnv environment variable with getenv. The file takes
+// precedence. It returns false if neither is set.
+func lookupPassword(file string, getenv func(string) string) ([]byte, bool, error) {
+	if len(file) > 0 {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if info.Mode().Perm()&0077 != 0 {
+			log.Printf("password file %s is accessible by other users (mode %s)", file, info.Mode().Perm())
+		}
+
+		pw, err := os.ReadFile(file)
+		if err != nil {
+			return nil, false, err
+		}
+
+		pw = bytes.TrimSuffix(bytes.TrimSuffix(pw, []byte("\n")), []byte("\r"))
+		if len(pw) == 0 {
+			return nil, false, fmt.Errorf("password file %s is empty", file)
+		}
+
+		return pw, true, nil
+	}
+
+	if pw := getenv(PasswordEnv); len(pw) > 0 {
+		return []byte(pw), true, nil
+	}
+
+	return nil, false, nil
+}
+
+// readTransactionData reads the transaction body from r. Piped input is read
+// entirely, such that multi-line and binary bodies are preserved. Terminal
+// input is read until the end of the line after a prompt.
+func readTransactionData(r io.Reader, interactive bool) ([]byte, error) {
+	if !interactive {
+		return io.ReadAll(r)
+	}
+
+	fmt.Printf("Enter the data to sign: ")
+	input, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(strings.TrimSuffix(input, "\n")), nil
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	vfs "github.com/securesharelabs/vstore/vfs"
+)
+
+func TestReadTransactionDataPipe(t *testing.T) {
+	// Multi-line body with binary bytes
+	body := []byte("first line\nsecond line\r\n\x00\xff\x10binary\n")
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+
+	go func() {
+		w.Write(body)
+		w.Close()
+	}()
+
+	data, err := readTransactionData(r, false)
+	require.NoError(t, err)
+	assert.Equal(t, body, data, "piped input must be read entirely")
+}
+
+func TestReadTransactionDataInteractive(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+
+	go func() {
+		w.Write([]byte("first line\nsecond line\n"))
+		w.Close()
+	}()
+
+	data, err := readTransactionData(r, true)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("first line"), data, "terminal input is read until the end of the line")
+}
+
+func TestLookupPasswordFile(t *testing.T) {
+	dir := t.TempDir()
+	idFile, _ := vfs.MustGenerateIdentity(filepath.Join(dir, "id"), []byte("testpassword"))
+
+	// The trailing newline of the file is not part of the password
+	pwFile := filepath.Join(dir, "password")
+	require.NoError(t, os.WriteFile(pwFile, []byte("testpassword\n"), 0600))
+
+	// The file takes precedence over the environment
+	getenv := func(string) string { return "wrongpassword" }
+	pw, ok, err := lookupPassword(pwFile, getenv)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, []byte("testpassword"), pw)
+
+	identity, err := vfs.NewIdentityE(idFile, pw)
+	require.NoError(t, err)
+	_, err = identity.Open()
+	assert.NoError(t, err, "should unlock the identity")
+
+	// Missing and empty files are errors
+	_, _, err = lookupPassword(filepath.Join(dir, "missing"), getenv)
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(pwFile, []byte("\n"), 0600))
+	_, _, err = lookupPassword(pwFile, getenv)
+	assert.Error(t, err)
+}
+
+func TestLookupPasswordEnv(t *testing.T) {
+	dir := t.TempDir()
+	idFile, _ := vfs.MustGenerateIdentity(filepath.Join(dir, "id"), []byte("testpassword"))
+
+	t.Setenv(PasswordEnv, "testpassword")
+	pw, ok, err := lookupPassword("", os.Getenv)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	identity, err := vfs.NewIdentityE(idFile, pw)
+	require.NoError(t, err)
+	_, err = identity.Open()
+	assert.NoError(t, err, "should unlock the identity")
+
+	// Without file nor environment variable, the password is prompted
+	t.Setenv(PasswordEnv, "")
+	_, ok, err = lookupPassword("", os.Getenv)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestConfirmPassword(t *testing.T) {
+	assert.NoError(t, confirmPassword([]byte("testpassword"), []byte("testpassword")))
+	assert.EqualError(t, confirmPassword([]byte("testpassword"), []byte("testpasswrod")), "passwords do not match")
+	assert.Error(t, confirmPassword([]byte("testpassword"), []byte{}))
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	defer func(enforce, allowWeak bool) {
+		enforcePasswordPolicy, allowWeakPassword = enforce, allowWeak
+	}(enforcePasswordPolicy, allowWeakPassword)
+
+	// Weak passwords are rejected by default
+	enforcePasswordPolicy, allowWeakPassword = false, false
+	assert.NoError(t, passwordPolicy().Validate([]byte("testpassword")))
+	for _, pw := range []string{"", "secret", "aaaaaaaaaaaa"} {
+		assert.Error(t, passwordPolicy().Validate([]byte(pw)), "should reject password %q", pw)
+	}
+
+	// Scripts may accept weak passwords
+	allowWeakPassword = true
+	assert.NoError(t, passwordPolicy().Validate([]byte("secret")))
+
+	// The strict policy takes precedence
+	enforcePasswordPolicy = true
+	assert.Error(t, passwordPolicy().Validate([]byte("testpassword")))
+	assert.NoError(t, passwordPolicy().Validate([]byte("Correct-Horse-9")))
+}
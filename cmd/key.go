@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	vfs "github.com/securesharelabs/vstore/vfs"
+
+	"github.com/spf13/cobra"
+)
+
+// Used for flags
+var keyHash string
+var keyHeight int64
+var keyPubKey string
+var keyPage int
+
+func init() {
+	// e.g.: vstore key --hash "3816D803...9E03"
+	keyCmd.PersistentFlags().StringVar(
+		&keyHash,
+		"hash",
+		"",
+		"Print the key of the transaction with this hash.",
+	)
+
+	// e.g.: vstore key --height 1234
+	keyCmd.PersistentFlags().Int64Var(
+		&keyHeight,
+		"height",
+		0,
+		"Print the key of the transaction hashes of this block height.",
+	)
+
+	// e.g.: vstore key --pubkey "1AE0F7C4...27A3" --page 1
+	keyCmd.PersistentFlags().StringVar(
+		&keyPubKey,
+		"pubkey",
+		"",
+		"Print the key of the transaction hashes of this signer public key.",
+	)
+
+	keyCmd.PersistentFlags().IntVar(
+		&keyPage,
+		"page",
+		0,
+		"Page of the signer index (--pubkey), the first page is 0.",
+	)
+
+	// e.g.: vstore key --hash "3816D803...9E03" --json
+	keyCmd.PersistentFlags().BoolVarP(
+		&printAsJSON,
+		"json",
+		"j",
+		false,
+		"Display the information in a JSON format.",
+	)
+
+	keyCmd.MarkFlagsMutuallyExclusive("hash", "height", "pubkey")
+	keyCmd.MarkFlagsOneRequired("hash", "height", "pubkey")
+	vstoreCmd.AddCommand(keyCmd)
+}
+
+var keyCmd = &cobra.Command{
+	Use:   "key",
+	Short: "Print the database key of a transaction or an index",
+	Long: `Print the exact database key under which vStore stores a transaction
+or an index, e.g. to read the database with external tooling.
+
+  Transactions are stored encrypted with the key "vfs:" followed by the
+  transaction hash bytes. Block indexes use "vfs:height:block-" followed by
+  the base10 height, signer indexes use "vfs:pubkey:" followed by the public
+  key bytes (and "/N" for pages after the first one).`,
+
+	Example: `  vstore key --hash "XXX"
+  vstore key --height 1234
+  vstore key --pubkey "XXX" --page 1`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		var keyType string
+		var key []byte
+
+		switch {
+		case len(keyHash) > 0:
+			hbz, err := hex.DecodeString(keyHash)
+			if err != nil || len(hbz) == 0 {
+				log.Fatalf("could not use provided transaction hash: %v", err)
+			}
+
+			keyType, key = "transaction", vfs.TransactionKey(hbz)
+		case keyHeight > 0:
+			keyType, key = "height index", vfs.HeightIndexKey(keyHeight)
+		case len(keyPubKey) > 0:
+			pbz, err := hex.DecodeString(keyPubKey)
+			if err != nil || len(pbz) == 0 {
+				log.Fatalf("could not use provided public key: %v", err)
+			}
+
+			keyType, key = "signer index", vfs.SignerIndexKey(pbz, keyPage)
+		default:
+			log.Fatalf("could not compute key: --height must be positive")
+		}
+
+		keyInfo := struct {
+			Type   string
+			Key    string
+			String string
+		}{
+			keyType,
+			fmt.Sprintf("%x", key),
+			fmt.Sprintf("%q", key),
+		}
+
+		if printAsJSON {
+			json, _ := json.MarshalIndent(keyInfo, "", "  ")
+			fmt.Print(string(json) + "\n")
+			return // Job done.
+		}
+
+		fmt.Printf("vStore %s (vfs v%d) - ABCI: \n", Version, vfs.AppVersion)
+		fmt.Printf("          Type: %s\n", keyInfo.Type)
+		fmt.Printf("     Key (hex): %s\n", keyInfo.Key)
+		fmt.Printf("  Key (string): %s\n", keyInfo.String)
+	},
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	vfs "github.com/securesharelabs/vstore/vfs"
+
+	"github.com/spf13/cobra"
+)
+
+// Used for flags
+var restoreFrom string
+
+func init() {
+	// e.g.: vstore keys restore --from /mnt/backups/.vstore/id-20240601T123015Z.backup
+	keysRestoreCmd.PersistentFlags().StringVar(
+		&restoreFrom,
+		"from",
+		"",
+		"Path to the identity backup file.",
+	)
+	keysRestoreCmd.MarkPersistentFlagRequired("from")
+
+	keysCmd.AddCommand(keysRestoreCmd)
+	vstoreCmd.AddCommand(keysCmd)
+}
+
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Manage the vStore identity",
+}
+
+var keysRestoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore the vStore identity from a backup",
+	Long: `Restore the vStore identity from a backup file as created with --backup-dir.
+
+  The backup is protected with the password of the identity, which is
+  required to restore it. An existing identity file is never overwritten.`,
+	Example: `  vstore keys restore --from /mnt/backups/.vstore/id-20240601T123015Z.backup
+  vstore keys restore --from ./id.backup --id /tmp/.vstore/id`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Read password to decrypt backup file
+		pw := readPassword("Enter your password: ")
+
+		restoredFile, pubFile, err := vfs.RestoreIdentity(restoreFrom, idFile, pw)
+		if err != nil {
+			log.Fatalf("could not restore identity: %v", err)
+		}
+
+		fmt.Println("Identity successfully restored!")
+		fmt.Printf("Identity File: %s\n", restoredFile)
+		fmt.Printf("  PubKey File: %s\n", pubFile)
+	},
+}
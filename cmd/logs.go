@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	vfs "github.com/securesharelabs/vstore/vfs"
+
+	cmtnet "github.com/cometbft/cometbft/libs/net"
+
+	"github.com/spf13/cobra"
+)
+
+// Used for flags
+var logsLevel string
+var logsSince time.Duration
+
+func init() {
+	// e.g.: vstore logs --level error
+	logsCmd.PersistentFlags().StringVar(
+		&logsLevel,
+		"level",
+		"",
+		"Minimum log level: debug, info or error (if empty, all levels).",
+	)
+
+	// e.g.: vstore logs --since 5m
+	logsCmd.PersistentFlags().DurationVar(
+		&logsSince,
+		"since",
+		0,
+		"Only print log entries more recent than this duration (if 0, all entries).",
+	)
+
+	// e.g.: vstore logs --json
+	logsCmd.PersistentFlags().BoolVarP(
+		&printAsJSON,
+		"json",
+		"j",
+		false,
+		"Display the log entries in a JSON format.",
+	)
+
+	vstoreCmd.AddCommand(logsCmd)
+}
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Print the recent log entries of a running vStore node",
+	Long: `Print the recent log entries of a running vStore node using the admin socket.
+
+  The node keeps the most recent log entries in memory, which can be filtered
+  by log level and by age without shelling into the host.`,
+	Example: `  vstore logs
+  vstore logs --level error --since 5m
+  vstore logs --admin-socket unix://vfs-admin.sock --json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		filter := vfs.LogFilter{Level: logsLevel, Since: logsSince}
+		if err := filter.Validate(); err != nil {
+			log.Fatalf("invalid log filter: %v", err)
+		}
+
+		proto, addr := cmtnet.ProtocolAndAddress(adminSocketAddr)
+		conn, err := net.Dial(proto, addr)
+		if err != nil {
+			log.Fatalf("could not connect to admin socket: %v", err)
+		}
+		defer conn.Close()
+
+		if _, err := fmt.Fprintln(conn, adminCommandLogs); err != nil {
+			log.Fatalf("could not send admin command: %v", err)
+		}
+
+		// Log entries are terminated by an empty line
+		entries := []vfs.LogEntry{}
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() && len(scanner.Bytes()) > 0 {
+			entry, err := vfs.ParseLogLine(scanner.Bytes())
+			if err != nil {
+				log.Fatalf("could not parse log entry: %v", err)
+			}
+
+			entries = append(entries, entry)
+		}
+
+		if err := scanner.Err(); err != nil {
+			log.Fatalf("could not read log entries: %v", err)
+		}
+
+		entries = filter.Apply(entries, time.Now())
+
+		if printAsJSON {
+			json, _ := json.MarshalIndent(entries, "", "  ")
+			fmt.Print(string(json) + "\n")
+			return // Job done.
+		}
+
+		for _, entry := range entries {
+			printLogEntry(entry)
+		}
+	},
+}
+
+// printLogEntry prints a log entry on one line, fields are sorted by key.
+func printLogEntry(entry vfs.LogEntry) {
+	keys := make([]string, 0, len(entry.Fields))
+	for k := range entry.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fields := make([]string, len(keys))
+	for i, k := range keys {
+		fields[i] = k + "=" + entry.Fields[k]
+	}
+
+	fmt.Printf("%s %-5s %s %s\n",
+		entry.Time.Format(time.RFC3339),
+		strings.ToUpper(entry.Level),
+		entry.Message,
+		strings.Join(fields, " "),
+	)
+}
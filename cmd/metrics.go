@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metricsPath is the HTTP path of the Prometheus metrics.
+const metricsPath = "/metrics"
+
+// serveMetrics listens on listenAddr, e.g. "127.0.0.1:26660", and serves the
+// metrics of the registry on /metrics. A teardown function is returned which
+// closes the server.
+func serveMetrics(listenAddr string, registry *prometheus.Registry) (func(), error) {
+	ln, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return func() {}, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// Serve returns when the server is closed
+	go server.Serve(ln)
+
+	return func() { server.Close() }, nil
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	vfs "github.com/securesharelabs/vstore/vfs"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+)
+
+func TestServeMetrics(t *testing.T) {
+	dir := t.TempDir()
+	idFile := filepath.Join(dir, "id")
+	vfs.MustGenerateIdentityWithKDF(idFile, []byte("testpassword"), vfs.KDFSHA256)
+
+	app := vfs.NewInMemoryVStoreApplication(idFile, []byte("testpassword"))
+
+	registry := prometheus.NewRegistry()
+	metrics, err := vfs.NewMetrics(registry)
+	require.NoError(t, err)
+	app.SetMetrics(metrics)
+
+	addr := freeTCPAddr(t)
+	teardown, err := serveMetrics(addr, registry)
+	require.NoError(t, err)
+	defer teardown()
+
+	scrape := func() string {
+		resp, err := http.Get("http://" + addr + metricsPath)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	assert.Contains(t, scrape(), "vstore_txs_committed_total 0")
+
+	// Commit one transaction
+	stx := makeSignedTransactions(t, []byte("This is a message"), 1024)[0]
+	ctx := context.Background()
+	_, err = app.FinalizeBlock(ctx, &abci.RequestFinalizeBlock{Height: 1, Txs: [][]byte{stx.Bytes()}})
+	require.NoError(t, err)
+	_, err = app.Commit(ctx, &abci.RequestCommit{})
+	require.NoError(t, err)
+
+	body := scrape()
+	assert.Contains(t, body, "vstore_txs_committed_total 1")
+	assert.Contains(t, body, "vstore_height 1")
+	assert.NotContains(t, body, "vstore_bytes_stored_total 0")
+
+	// Duplicates are rejected in CheckTx in stateful mode
+	app.SetCheckTxMode(vfs.CheckTxStateful)
+	resp, err := app.CheckTx(ctx, &abci.RequestCheckTx{Tx: stx.Bytes()})
+	require.NoError(t, err)
+	require.Equal(t, vfs.CodeTypeDuplicateHashError, resp.Code)
+	assert.Contains(t, scrape(), "vstore_duplicate_rejections_total 1")
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	vfs "github.com/securesharelabs/vstore/vfs"
+
+	cmtdb "github.com/cometbft/cometbft-db"
+
+	"github.com/spf13/cobra"
+)
+
+// Used for flags
+var migrateFrom string
+var migrateTo string
+var migrateTarget string
+var migrateDryRun bool
+var migrateResume bool
+
+func init() {
+	// e.g.: vstore migrate-db --from goleveldb --to badgerdb
+	migrateDBCmd.PersistentFlags().StringVar(
+		&migrateFrom,
+		"from",
+		"goleveldb",
+		"Database backend of the existing database.",
+	)
+
+	migrateDBCmd.PersistentFlags().StringVar(
+		&migrateTo,
+		"to",
+		"",
+		"Database backend of the new database.",
+	)
+	migrateDBCmd.MarkPersistentFlagRequired("to")
+
+	// e.g.: vstore migrate-db --to badgerdb --target /data/badgerdb
+	migrateDBCmd.PersistentFlags().StringVar(
+		&migrateTarget,
+		"target",
+		"",
+		"Directory of the new database (if empty, uses the backend name in --home).",
+	)
+
+	// e.g.: vstore migrate-db --to badgerdb --dry-run
+	migrateDBCmd.PersistentFlags().BoolVar(
+		&migrateDryRun,
+		"dry-run",
+		false,
+		"Count the key-value pairs to copy without writing the new database.",
+	)
+
+	// e.g.: vstore migrate-db --to badgerdb --resume
+	migrateDBCmd.PersistentFlags().BoolVar(
+		&migrateResume,
+		"resume",
+		false,
+		"Continue an interrupted migration from the last copied key.",
+	)
+
+	// e.g.: vstore migrate-db --to badgerdb --json
+	migrateDBCmd.PersistentFlags().BoolVarP(
+		&printAsJSON,
+		"json",
+		"j",
+		false,
+		"Display the information in a JSON format.",
+	)
+
+	vstoreCmd.AddCommand(migrateDBCmd)
+}
+
+var migrateDBCmd = &cobra.Command{
+	Use:   "migrate-db",
+	Short: "Migrate the vStore database to another database backend",
+	Long: `Migrate the vStore database to another database backend.
+
+  All key-value pairs are copied verbatim, transactions are never decrypted
+  such that the identity password is not necessary. The number of keys of
+  both databases is compared after the copy. The node must be stopped.
+
+  Backends other than goleveldb and memdb require a build with the matching
+  build tag, e.g. badgerdb, pebbledb or rocksdb.`,
+
+	Example: `  vstore migrate-db --from goleveldb --to badgerdb --home /tmp/.vfs-home
+  vstore migrate-db --to pebbledb --dry-run
+  vstore migrate-db --to badgerdb --target /data/badgerdb --resume`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		if migrateFrom == migrateTo && migrateTarget == "" {
+			log.Fatalf("could not migrate database: --from and --to must differ")
+		}
+
+		target := migrateTarget
+		if target == "" {
+			target = databaseDir(homeDir, migrateTo)
+		}
+
+		src, srcPath, teardownSrc, err := openDatabaseBackend("vfs", migrateFrom, databaseDir(homeDir, migrateFrom))
+		if err != nil {
+			log.Fatalf("could not open source database: %v", err)
+		}
+		defer teardownSrc()
+
+		// Dry-run never creates the destination database
+		dst, dstPath, teardownDst := cmtdb.DB(cmtdb.NewMemDB()), target, func() {}
+		if !migrateDryRun {
+			dst, dstPath, teardownDst, err = openDatabaseBackend("vfs", migrateTo, target)
+			if err != nil {
+				log.Fatalf("could not open destination database: %v", err)
+			}
+		}
+		defer teardownDst()
+
+		result, err := vfs.MigrateDB(src, dst, vfs.MigrateOptions{
+			DryRun: migrateDryRun,
+			Resume: migrateResume,
+		})
+		if err != nil {
+			log.Fatalf("could not migrate database: %v", err)
+		}
+
+		migrateInfo := struct {
+			Source      string
+			Destination string
+			DryRun      bool
+			Copied      int64
+			Bytes       int64
+			SourceKeys  int64
+			DestKeys    int64
+		}{
+			fmt.Sprintf("%s (%s)", srcPath, migrateFrom),
+			fmt.Sprintf("%s (%s)", dstPath, migrateTo),
+			migrateDryRun,
+			result.Copied,
+			result.Bytes,
+			result.SourceKeys,
+			result.DestKeys,
+		}
+
+		if printAsJSON {
+			json, _ := json.MarshalIndent(migrateInfo, "", "  ")
+			fmt.Print(string(json) + "\n")
+			return // Job done.
+		}
+
+		fmt.Printf("vStore %s (vfs v%d) - ABCI: \n", Version, vfs.AppVersion)
+		fmt.Printf("       Source: %s\n", migrateInfo.Source)
+		fmt.Printf("  Destination: %s\n", migrateInfo.Destination)
+		fmt.Printf("      Dry-run: %t\n", migrateInfo.DryRun)
+		fmt.Printf("       Copied: %d keys (%d bytes)\n", migrateInfo.Copied, migrateInfo.Bytes)
+		fmt.Printf("  Source Keys: %d\n", migrateInfo.SourceKeys)
+		fmt.Printf("    Dest Keys: %d\n", migrateInfo.DestKeys)
+	},
+}
@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	vfs "github.com/securesharelabs/vstore/vfs"
+)
+
+// Output formats of signed transactions (--output)
+const (
+	outputHex        = "hex"
+	outputBase64     = "base64"
+	outputJSON       = "json"
+	outputFilePrefix = "file:"
+)
+
+// signedTransactionJSON describes the JSON output of a signed transaction.
+// Hashes, keys and signatures are hexadecimal, the body and the transaction
+// bytes are base64 encoded.
+type signedTransactionJSON struct {
+	Signer      string     `json:"signer"`
+	Signature   string     `json:"signature"`
+	Hash        string     `json:"hash"`
+	Time        time.Time  `json:"time"`
+	Len         uint32     `json:"len"`
+	Body        []byte     `json:"body,omitempty"`
+	BodyRef     string     `json:"body_ref,omitempty"`
+	Chunk       *chunkJSON `json:"chunk,omitempty"`
+	PrevHash    string     `json:"prev_hash,omitempty"`
+	ContentType string     `json:"content_type,omitempty"`
+	ChainID     string     `json:"chain_id,omitempty"`
+	Sequence    uint64     `json:"sequence,omitempty"`
+	Tx          []byte     `json:"tx"`
+}
+
+// chunkJSON describes the JSON output of the chunk of a signed transaction.
+type chunkJSON struct {
+	Root  string `json:"root"`
+	Index uint32 `json:"index"`
+	Total uint32 `json:"total"`
+}
+
+// validateOutput returns an error if output is not a known output format.
+func validateOutput(output string) error {
+	switch output {
+	case outputHex, outputBase64, outputJSON:
+		return nil
+	default:
+		break
+	}
+
+	if strings.HasPrefix(output, outputFilePrefix) && len(output) > len(outputFilePrefix) {
+		return nil
+	}
+
+	return fmt.Errorf("unknown output format: %q, want: hex, base64, json or file:PATH", output)
+}
+
+// writeSignedTransactions writes signed transactions to w in the output
+// format. The hex format is printed with a header, base64 prints one
+// transaction per line and json prints an object (an array for chunked
+// bodies). The file:PATH format writes the transaction bytes to PATH. The hex,
+// json and file:PATH formats also print the hash that the node computes for
+// the committed transaction, i.e. the hash of queries.
+func writeSignedTransactions(w io.Writer, stxs []*vfs.SignedTransaction, output string) error {
+	if err := validateOutput(output); err != nil {
+		return err
+	}
+
+	switch output {
+	case outputHex:
+		fmt.Fprintln(w, "Signed transaction bytes: ")
+		for _, stx := range stxs {
+			fmt.Fprintf(w, "0x%x\n", stx.Bytes())
+			fmt.Fprintf(w, "Transaction Hash: %x\n", vfs.ComputeHash(stx.Unresolved()))
+		}
+	case outputBase64:
+		for _, stx := range stxs {
+			fmt.Fprintln(w, base64.StdEncoding.EncodeToString(stx.Bytes()))
+		}
+	case outputJSON:
+		txs := make([]signedTransactionJSON, len(stxs))
+		for i, stx := range stxs {
+			txs[i] = newSignedTransactionJSON(stx)
+		}
+
+		var v interface{} = txs
+		if len(txs) == 1 {
+			v = txs[0]
+		}
+
+		json, _ := json.MarshalIndent(v, "", "  ")
+		fmt.Fprint(w, string(json)+"\n")
+	default:
+		// Transaction bytes can be broadcast later
+		if len(stxs) != 1 {
+			return fmt.Errorf("file output supports a single transaction, got: %d", len(stxs))
+		}
+
+		path := strings.TrimPrefix(output, outputFilePrefix)
+		if err := os.WriteFile(path, stxs[0].Bytes(), 0600); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "Signed transaction written to: %s\n", path)
+		fmt.Fprintf(w, "Transaction Hash: %x\n", vfs.ComputeHash(stxs[0].Unresolved()))
+	}
+
+	return nil
+}
+
+// newSignedTransactionJSON returns the JSON output of a signed transaction.
+func newSignedTransactionJSON(stx *vfs.SignedTransaction) signedTransactionJSON {
+	pb := stx.ToProto()
+
+	tx := signedTransactionJSON{
+		Signer:      hex.EncodeToString(pb.Signer.GetEd25519()),
+		Signature:   hex.EncodeToString(pb.Signature),
+		Hash:        hex.EncodeToString(vfs.ComputeHash(stx.Unresolved())),
+		Time:        pb.Time.UTC(),
+		Len:         pb.Len,
+		Body:        pb.Body,
+		BodyRef:     pb.BodyRef,
+		PrevHash:    hex.EncodeToString(pb.PrevHash),
+		ContentType: pb.ContentType,
+		ChainID:     pb.ChainId,
+		Sequence:    pb.Sequence,
+		Tx:          stx.Bytes(),
+	}
+
+	if pb.Chunk != nil {
+		tx.Chunk = &chunkJSON{
+			Root:  hex.EncodeToString(pb.Chunk.Root),
+			Index: pb.Chunk.Index,
+			Total: pb.Chunk.Total,
+		}
+	}
+
+	return tx
+}
+
+// dryRunJSON describes the JSON output of a signed transaction in a dry run.
+type dryRunJSON struct {
+	Hash  string `json:"hash"`
+	Size  int    `json:"size"`
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+}
+
+// writeDryRun validates signed transactions locally, as in CheckTx without the
+// state of a node, and writes their hash, size and validity to w. The hash is
+// the hash of the committed transaction. The json output format prints an
+// array, other formats print a summary. It returns the number of invalid
+// transactions. Transactions must be signed for chainID, i.e. --chain-id.
+func writeDryRun(w io.Writer, stxs []*vfs.SignedTransaction, output string, chainID string, now time.Time) int {
+	results := make([]dryRunJSON, len(stxs))
+	invalid := 0
+	for i, stx := range stxs {
+		txbz := stx.Bytes()
+		code := vfs.ValidateTransaction(txbz, chainID, now)
+
+		results[i] = dryRunJSON{
+			Hash:  hex.EncodeToString(vfs.ComputeHash(stx.Unresolved())),
+			Size:  len(txbz),
+			Valid: code == vfs.CodeTypeOK,
+		}
+
+		if code != vfs.CodeTypeOK {
+			results[i].Error = fmt.Sprintf("%s (code %d)", vfs.CodeToString(code), code)
+			invalid++
+		}
+	}
+
+	if output == outputJSON {
+		json, _ := json.MarshalIndent(results, "", "  ")
+		fmt.Fprint(w, string(json)+"\n")
+		return invalid
+	}
+
+	fmt.Fprintln(w, "Dry run, nothing was broadcast.")
+	for _, res := range results {
+		fmt.Fprintf(w, "Transaction Hash: %s\n", res.Hash)
+		fmt.Fprintf(w, "            Size: %d bytes\n", res.Size)
+		fmt.Fprintf(w, "           Valid: %t\n", res.Valid)
+		if !res.Valid {
+			fmt.Fprintf(w, "           Error: %s\n", res.Error)
+		}
+	}
+
+	return invalid
+}
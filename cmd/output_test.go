@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	client "github.com/securesharelabs/vstore/client"
+	vfs "github.com/securesharelabs/vstore/vfs"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+// makeSignedTransactions creates the signed transactions of a body, in chunks
+// of chunkSize bytes.
+func makeSignedTransactions(t *testing.T, body []byte, chunkSize int) []*vfs.SignedTransaction {
+	t.Helper()
+
+	stxs, err := vfs.NewChunkedTransactions(ed25519.GenPrivKey(), body, chunkSize, time.Now())
+	require.NoError(t, err)
+	return stxs
+}
+
+// committedHash commits a signed transaction with a vfs application and
+// returns the hash that the node computes for the transaction.
+func committedHash(t *testing.T, stx *vfs.SignedTransaction) []byte {
+	t.Helper()
+
+	idFile, _ := vfs.MustGenerateIdentity(filepath.Join(t.TempDir(), "id"), []byte("testpassword"))
+	app := vfs.NewInMemoryVStoreApplication(idFile, []byte("testpassword"))
+	resFinalize, err := app.FinalizeBlock(context.Background(), &abci.RequestFinalizeBlock{
+		Height: 1,
+		Time:   time.Now(),
+		Txs:    [][]byte{stx.Bytes()},
+	})
+	require.NoError(t, err)
+	require.Equal(t, vfs.CodeTypeOK, resFinalize.TxResults[0].Code)
+
+	_, err = app.Commit(context.Background(), &abci.RequestCommit{})
+	require.NoError(t, err)
+	return resFinalize.TxResults[0].Data
+}
+
+func TestWriteSignedTransactionsHex(t *testing.T) {
+	stxs := makeSignedTransactions(t, []byte("This is a message"), 1024)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeSignedTransactions(&buf, stxs, outputHex))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "0x"+hex.EncodeToString(stxs[0].Bytes()), lines[1])
+	assert.Equal(t, "Transaction Hash: "+hex.EncodeToString(committedHash(t, stxs[0])), lines[2])
+}
+
+func TestWriteSignedTransactionsHash(t *testing.T) {
+	// Transactions are signed like in vstore factory
+	idFile, _ := vfs.MustGenerateIdentity(filepath.Join(t.TempDir(), "id"), []byte("testpassword"))
+	signer := client.NewWithRPC(nil, vfs.NewIdentity(idFile, []byte("testpassword")))
+	stx, err := signer.NewTransaction([]byte("This is a message"))
+	require.NoError(t, err)
+
+	expected := hex.EncodeToString(committedHash(t, stx))
+	for _, output := range []string{outputHex, outputFilePrefix + filepath.Join(t.TempDir(), "tx.bin")} {
+		var buf bytes.Buffer
+		require.NoError(t, writeSignedTransactions(&buf, []*vfs.SignedTransaction{stx}, output))
+		assert.Contains(t, buf.String(), "Transaction Hash: "+expected, "output %q must print the hash", output)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeSignedTransactions(&buf, []*vfs.SignedTransaction{stx}, outputJSON))
+
+	tx := signedTransactionJSON{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &tx))
+	assert.Equal(t, expected, tx.Hash)
+}
+
+func TestWriteSignedTransactionsBase64(t *testing.T) {
+	stxs := makeSignedTransactions(t, []byte("This is a message"), 8)
+	require.Len(t, stxs, 3)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeSignedTransactions(&buf, stxs, outputBase64))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, len(stxs), "one transaction per line")
+	for i, line := range lines {
+		bz, err := base64.StdEncoding.DecodeString(line)
+		require.NoError(t, err)
+		assert.Equal(t, stxs[i].Bytes(), bz)
+	}
+}
+
+func TestWriteSignedTransactionsJSON(t *testing.T) {
+	stxs := makeSignedTransactions(t, []byte("This is a message"), 1024)
+	stx := stxs[0]
+
+	var buf bytes.Buffer
+	require.NoError(t, writeSignedTransactions(&buf, stxs, outputJSON))
+
+	var decoded signedTransactionJSON
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, hex.EncodeToString(stx.Signer.Bytes()), decoded.Signer)
+	assert.Equal(t, hex.EncodeToString(stx.Signature), decoded.Signature)
+	assert.Equal(t, hex.EncodeToString(stx.Hash), decoded.Hash)
+	assert.Equal(t, stx.Time.Unix(), decoded.Time.Unix())
+	assert.Equal(t, uint32(len("This is a message")), decoded.Len)
+	assert.Equal(t, []byte("This is a message"), decoded.Body)
+	require.NotNil(t, decoded.Chunk)
+	assert.Equal(t, hex.EncodeToString(stx.Chunk.Root), decoded.Chunk.Root)
+
+	// Transaction bytes can be broadcast as is
+	fromJSON, err := vfs.NewSignedTransactionFromBytes(decoded.Tx)
+	require.NoError(t, err)
+	assert.True(t, fromJSON.Verify())
+
+	// Chunked bodies are printed as an array
+	buf.Reset()
+	stxs = makeSignedTransactions(t, []byte("This is a message"), 8)
+	require.NoError(t, writeSignedTransactions(&buf, stxs, outputJSON))
+
+	var list []signedTransactionJSON
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &list))
+	assert.Len(t, list, len(stxs))
+
+	// Chain id and sequence are omitted unless they are signed
+	assert.NotContains(t, buf.String(), "chain_id")
+	assert.NotContains(t, buf.String(), "sequence")
+
+	priv := ed25519.GenPrivKey()
+	stxs, err = vfs.NewChunkedTransactions(priv, []byte("This is a message"), 1024, time.Now())
+	require.NoError(t, err)
+	stxs[0].Sequence = 42
+	require.NoError(t, signForChain(priv, stxs[0], "chainA"))
+
+	buf.Reset()
+	require.NoError(t, writeSignedTransactions(&buf, stxs, outputJSON))
+
+	decoded = signedTransactionJSON{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, "chainA", decoded.ChainID)
+	assert.Equal(t, uint64(42), decoded.Sequence)
+}
+
+func TestWriteSignedTransactionsFile(t *testing.T) {
+	stxs := makeSignedTransactions(t, []byte("This is a message"), 1024)
+	path := filepath.Join(t.TempDir(), "tx.bin")
+
+	var buf bytes.Buffer
+	require.NoError(t, writeSignedTransactions(&buf, stxs, outputFilePrefix+path))
+	assert.Contains(t, buf.String(), path)
+
+	bz, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, stxs[0].Bytes(), bz)
+
+	// Chunked bodies can not be written to a single file
+	stxs = makeSignedTransactions(t, []byte("This is a message"), 8)
+	assert.Error(t, writeSignedTransactions(&buf, stxs, outputFilePrefix+path))
+
+	// Unknown output formats
+	for _, output := range []string{"", "xml", "file:"} {
+		assert.Error(t, validateOutput(output), "should not accept output %q", output)
+	}
+}
+
+func TestWriteDryRun(t *testing.T) {
+	stxs := makeSignedTransactions(t, []byte("This is a message"), 1024)
+
+	// The hash is the hash of the committed transaction
+	var buf bytes.Buffer
+	assert.Zero(t, writeDryRun(&buf, stxs, outputHex, "", time.Now()))
+	assert.Contains(t, buf.String(), "Transaction Hash: "+hex.EncodeToString(committedHash(t, stxs[0])))
+	assert.Contains(t, buf.String(), fmt.Sprintf("Size: %d bytes", len(stxs[0].Bytes())))
+	assert.Contains(t, buf.String(), "Valid: true")
+
+	// Invalid transactions are reported with the code of CheckTx
+	tampered := makeSignedTransactions(t, []byte("This is a message"), 1024)
+	tampered[0].Signature = make([]byte, 64)
+
+	buf.Reset()
+	assert.Equal(t, 1, writeDryRun(&buf, append(stxs, tampered...), outputJSON, "", time.Now()))
+
+	results := []dryRunJSON{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &results))
+	require.Len(t, results, 2)
+	assert.True(t, results[0].Valid)
+	assert.False(t, results[1].Valid)
+	assert.Contains(t, results[1].Error, fmt.Sprintf("code %d", vfs.CodeTypeInvalidSignatureError))
+
+	// Timestamps in the future are invalid
+	buf.Reset()
+	assert.Equal(t, 1, writeDryRun(&buf, stxs, outputHex, "", time.Now().Add(-time.Hour)))
+	assert.Contains(t, buf.String(), "Valid: false")
+}
+
+func TestWriteDryRunChainID(t *testing.T) {
+	priv := ed25519.GenPrivKey()
+	stxs, err := vfs.NewChunkedTransactions(priv, []byte("This is a message"), 1024, time.Now())
+	require.NoError(t, err)
+	stx := stxs[0]
+	require.NoError(t, signForChain(priv, stx, "chainA"))
+
+	// Transactions signed with --chain-id are valid for this chain only
+	var buf bytes.Buffer
+	assert.Zero(t, writeDryRun(&buf, []*vfs.SignedTransaction{stx}, outputHex, "chainA", time.Now()))
+	assert.Contains(t, buf.String(), "Valid: true")
+
+	for _, chainID := range []string{"", "chainB"} {
+		buf.Reset()
+		assert.Equal(t, 1, writeDryRun(&buf, []*vfs.SignedTransaction{stx}, outputHex, chainID, time.Now()))
+		assert.Contains(t, buf.String(), fmt.Sprintf("code %d", vfs.CodeTypeInvalidChainIDError))
+	}
+}
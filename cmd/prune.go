@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	vfs "github.com/securesharelabs/vstore/vfs"
+
+	"github.com/spf13/cobra"
+)
+
+// Used for flags
+var pruneMaxAge time.Duration
+var pruneMaxBytes int64
+
+func init() {
+	// e.g.: vstore prune --max-age 2160h
+	pruneCmd.PersistentFlags().DurationVar(
+		&pruneMaxAge,
+		"max-age",
+		0,
+		"Prune the transactions older than this duration (if 0, no maximum age).",
+	)
+
+	// e.g.: vstore prune --max-bytes 10737418240
+	pruneCmd.PersistentFlags().Int64Var(
+		&pruneMaxBytes,
+		"max-bytes",
+		0,
+		"Prune the oldest transactions until the stored transactions fit this size in bytes (if 0, no maximum size).",
+	)
+
+	// e.g.: vstore prune --max-age 2160h --json
+	pruneCmd.PersistentFlags().BoolVarP(
+		&printAsJSON,
+		"json",
+		"j",
+		false,
+		"Display the information in a JSON format.",
+	)
+
+	vstoreCmd.AddCommand(pruneCmd)
+}
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Prune the bodies of old transactions with a retention policy",
+	Long: `Prune the bodies of old transactions with a retention policy.
+
+  The oldest transactions, by timestamp, are pruned first. Their hashes,
+  signers, indexes and the merkle roots are kept, such that the app hash is
+  unchanged and that inclusion proofs still hold. Queries for pruned
+  transactions return a "pruned transaction" error. The node must be stopped,
+  see --retention-interval to prune while the node is running.`,
+
+	Example: `  vstore prune --max-age 2160h --home /tmp/.vfs-home
+  vstore prune --max-bytes 10737418240 --json`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		if pruneMaxAge <= 0 && pruneMaxBytes <= 0 {
+			log.Fatalf("missing retention policy: use --max-age or --max-bytes")
+		}
+
+		if err := validateDBBackend(dbBackend); err != nil {
+			log.Fatalf("could not use provided database backend: %v", err)
+		}
+
+		// Read password to decrypt the identity and transactions
+		pw := readPassword("Enter your password: ")
+
+		db, dbPath, teardownDb, err := openDatabase("vfs", homeDir)
+		if err != nil {
+			log.Fatalf("could not open database: %v", err)
+		}
+		defer teardownDb()
+
+		app, err := vfs.NewVStoreApplicationE(db, idFile, pw)
+		if err != nil {
+			log.Fatalf("could not open vfs application: %v", err)
+		}
+
+		result, err := app.Prune(vfs.RetentionPolicy{
+			MaxAge:   pruneMaxAge,
+			MaxBytes: pruneMaxBytes,
+		}, time.Now())
+		if err != nil {
+			log.Fatalf("could not prune transactions: %v", err)
+		}
+
+		pruneInfo := struct {
+			Database      string
+			Pruned        int64
+			Bytes         int64
+			Retained      int64
+			RetainedBytes int64
+		}{
+			dbPath,
+			result.Pruned,
+			result.Bytes,
+			result.Retained,
+			result.RetainedBytes,
+		}
+
+		if printAsJSON {
+			json, _ := json.MarshalIndent(pruneInfo, "", "  ")
+			fmt.Print(string(json) + "\n")
+			return // Job done.
+		}
+
+		fmt.Printf("vStore %s (vfs v%d) - ABCI: \n", Version, vfs.AppVersion)
+		fmt.Printf("  Database: %s\n", pruneInfo.Database)
+		fmt.Printf("    Pruned: %d (%d bytes)\n", pruneInfo.Pruned, pruneInfo.Bytes)
+		fmt.Printf("  Retained: %d (%d bytes)\n", pruneInfo.Retained, pruneInfo.RetainedBytes)
+	},
+}
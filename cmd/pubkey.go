@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	vfs "github.com/securesharelabs/vstore/vfs"
+
+	"github.com/cometbft/cometbft/crypto/ed25519"
+	"github.com/spf13/cobra"
+)
+
+// Used for flags
+var pubKeyEncoding string
+var pubKeyFromIdentity bool
+var pubKeyDisplay string
+var bech32Prefix string
+
+func init() {
+	// e.g.: vstore query --pubkey "1AE0F7C4...27A3" --pubkey-encoding bech32
+	vstoreCmd.PersistentFlags().StringVar(
+		&pubKeyDisplay,
+		"pubkey-encoding",
+		"hex",
+		"Encoding of the printed signer public keys: hex or bech32 (--pubkey accepts both)",
+	)
+
+	// e.g.: vstore signers --pubkey-encoding bech32 --bech32-prefix vstorepub
+	vstoreCmd.PersistentFlags().StringVar(
+		&bech32Prefix,
+		"bech32-prefix",
+		vfs.DefaultBech32Prefix,
+		"Human-readable part of bech32 public keys",
+	)
+
+	// e.g.: vstore pubkey --encoding bech32
+	pubKeyCmd.PersistentFlags().StringVar(
+		&pubKeyEncoding,
+		"encoding",
+		"hex",
+		"Encoding of the public key: hex, base64 or bech32.",
+	)
+
+	// e.g.: vstore pubkey --from-identity
+	pubKeyCmd.PersistentFlags().BoolVar(
+		&pubKeyFromIdentity,
+		"from-identity",
+		false,
+		"Read the public key from the encrypted identity file rather than the .pub file (asks for the password).",
+	)
+
+	vstoreCmd.AddCommand(pubKeyCmd)
+}
+
+var pubKeyCmd = &cobra.Command{
+	Use:   "pubkey",
+	Short: "Print the public key of the vStore identity",
+	Long: `Print the ed25519 public key of the vStore identity, e.g. to share it
+with peers or to verify transactions.
+
+  The public key is read from the co-located .pub file of the identity, which
+  does not require the password. With --from-identity, or if the .pub file is
+  missing, the identity file is decrypted with the password instead.`,
+
+	Example: `  vstore pubkey
+  vstore pubkey --encoding base64
+  vstore pubkey --encoding bech32 --id /tmp/.vstore/id`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		var (
+			pub []byte
+			err error
+		)
+
+		if !pubKeyFromIdentity {
+			pub, err = readPubKeyFile(idFile + ".pub")
+		}
+
+		if pubKeyFromIdentity || errors.Is(err, os.ErrNotExist) {
+			pw := readPassword("Enter your password: ")
+			pub, err = openIdentityPubKey(idFile, pw)
+		}
+
+		if err != nil {
+			log.Fatalf("could not read public key: %v", err)
+		}
+
+		encoded, err := encodePubKey(pub, pubKeyEncoding)
+		if err != nil {
+			log.Fatalf("could not encode public key: %v", err)
+		}
+
+		fmt.Println(encoded)
+	},
+}
+
+// readPubKeyFile reads the base64-encoded public key of a .pub file.
+func readPubKeyFile(pubFile string) ([]byte, error) {
+	b64, err := os.ReadFile(pubFile)
+	if err != nil {
+		return nil, err
+	}
+
+	pub, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(b64)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key file %s: %v", pubFile, err)
+	}
+
+	if len(pub) != ed25519.PubKeySize {
+		return nil, fmt.Errorf("invalid public key file %s: expected %d bytes, got %d", pubFile, ed25519.PubKeySize, len(pub))
+	}
+
+	return pub, nil
+}
+
+// openIdentityPubKey decrypts the identity file with the password pw and
+// returns its public key.
+func openIdentityPubKey(idFile string, pw []byte) ([]byte, error) {
+	provider, err := openIdentity(idFile, pw)
+	if err != nil {
+		return nil, err
+	}
+
+	priv, err := provider.Identity().PrivKey()
+	if err != nil {
+		return nil, err
+	}
+	defer clear(priv)
+
+	return priv.PubKey().Bytes(), nil
+}
+
+// encodePubKey encodes the public key pub in hex (uppercase), base64 or
+// bech32 with the --bech32-prefix.
+func encodePubKey(pub []byte, encoding string) (string, error) {
+	switch encoding {
+	case "hex":
+		return fmt.Sprintf("%X", pub), nil
+	case "base64":
+		return base64.StdEncoding.EncodeToString(pub), nil
+	case "bech32":
+		return vfs.PubKeyToBech32(bech32Prefix, pub)
+	default:
+		return "", fmt.Errorf("unsupported encoding %q: expected hex, base64 or bech32", encoding)
+	}
+}
+
+// parsePubKey decodes a signer public key in hex or, if it starts with the
+// --bech32-prefix, in bech32.
+func parsePubKey(value string) ([]byte, error) {
+	if strings.HasPrefix(strings.ToLower(value), bech32Prefix+"1") {
+		return vfs.PubKeyFromBech32(bech32Prefix, value)
+	}
+
+	return hex.DecodeString(value)
+}
+
+// formatPubKey returns the signer public key pub in hex (lowercase) or, with
+// --pubkey-encoding bech32, in bech32.
+func formatPubKey(pub []byte) string {
+	if pubKeyDisplay == "bech32" {
+		if encoded, err := vfs.PubKeyToBech32(bech32Prefix, pub); err == nil {
+			return encoded
+		}
+	}
+
+	return hex.EncodeToString(pub)
+}
+
+// validatePubKeyDisplay returns an error if the --pubkey-encoding is not
+// supported.
+func validatePubKeyDisplay(encoding string) error {
+	if encoding != "hex" && encoding != "bech32" {
+		return fmt.Errorf("unsupported public key encoding %q: expected hex or bech32", encoding)
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	vfsp2p "github.com/securesharelabs/vstore/api/vstore/v1"
+	vfs "github.com/securesharelabs/vstore/vfs"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+
+	"github.com/cosmos/gogoproto/proto"
+)
+
+func TestPubKeyMatchesIdentity(t *testing.T) {
+	dir := t.TempDir()
+	idFile, pubFile := vfs.MustGenerateIdentity(filepath.Join(dir, "id"), []byte("testpassword"))
+
+	// The .pub file and the encrypted identity file contain the same key
+	expected, err := openIdentityPubKey(idFile, []byte("testpassword"))
+	require.NoError(t, err)
+
+	pub, err := readPubKeyFile(pubFile)
+	require.NoError(t, err)
+	assert.Equal(t, expected, pub)
+
+	_, err = openIdentityPubKey(idFile, []byte("wrongpassword"))
+	assert.Error(t, err)
+
+	_, err = readPubKeyFile(filepath.Join(dir, "missing.pub"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// Malformed .pub files are rejected
+	invalidFile := filepath.Join(dir, "invalid.pub")
+	require.NoError(t, os.WriteFile(invalidFile, []byte(base64.StdEncoding.EncodeToString(pub[:16])), 0644))
+	_, err = readPubKeyFile(invalidFile)
+	assert.Error(t, err)
+}
+
+func TestEncodePubKey(t *testing.T) {
+	dir := t.TempDir()
+	idFile, _ := vfs.MustGenerateIdentity(filepath.Join(dir, "id"), []byte("testpassword"))
+
+	pub, err := openIdentityPubKey(idFile, []byte("testpassword"))
+	require.NoError(t, err)
+
+	encoded, err := encodePubKey(pub, "hex")
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%X", pub), encoded)
+
+	encoded, err = encodePubKey(pub, "base64")
+	require.NoError(t, err)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(pub), encoded)
+
+	encoded, err = encodePubKey(pub, "bech32")
+	require.NoError(t, err)
+
+	decoded, err := vfs.PubKeyFromBech32(vfs.DefaultBech32Prefix, encoded)
+	require.NoError(t, err)
+	assert.Equal(t, pub, decoded)
+
+	_, err = encodePubKey(pub, "base58")
+	assert.Error(t, err)
+}
+
+func TestQueryByBech32PubKey(t *testing.T) {
+	ctx := context.Background()
+	idFile, _ := vfs.MustGenerateIdentity(filepath.Join(t.TempDir(), "id"), []byte("testpassword"))
+	app := vfs.NewInMemoryVStoreApplication(idFile, []byte("testpassword"))
+
+	stxs := makeSignedTransactions(t, []byte("This is a message"), 8)
+	txs := make([][]byte, len(stxs))
+	for i, stx := range stxs {
+		txs[i] = stx.Bytes()
+	}
+
+	_, err := app.FinalizeBlock(ctx, &abci.RequestFinalizeBlock{Height: 1, Time: time.Now(), Txs: txs})
+	require.NoError(t, err)
+	_, err = app.Commit(ctx, &abci.RequestCommit{})
+	require.NoError(t, err)
+
+	// Hex and bech32 public keys resolve to the same signer
+	signer := stxs[0].Signer.Bytes()
+	encoded, err := vfs.PubKeyToBech32(bech32Prefix, signer)
+	require.NoError(t, err)
+
+	for _, value := range []string{hex.EncodeToString(signer), encoded, strings.ToUpper(encoded)} {
+		pbz, err := parsePubKey(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, signer, pbz)
+
+		response, err := (&localQuerier{app: app}).ABCIQuery(ctx, "/pubkey", pbz)
+		require.NoError(t, err)
+
+		list := new(vfsp2p.TransactionList)
+		require.NoError(t, proto.Unmarshal(response.Response.Value, list))
+		assert.Len(t, list.Transactions, len(stxs))
+	}
+
+	// Bech32 public keys of another prefix are rejected
+	other, err := vfs.PubKeyToBech32("other", signer)
+	require.NoError(t, err)
+	_, err = parsePubKey(other)
+	assert.Error(t, err)
+
+	// Signers are printed in hex by default
+	assert.Equal(t, hex.EncodeToString(signer), formatPubKey(signer))
+
+	pubKeyDisplay = "bech32"
+	defer func() { pubKeyDisplay = "hex" }()
+	assert.Equal(t, encoded, formatPubKey(signer))
+
+	assert.NoError(t, validatePubKeyDisplay("bech32"))
+	assert.Error(t, validatePubKeyDisplay("base64"))
+}
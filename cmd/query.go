@@ -2,17 +2,24 @@ package cmd
 
 import (
 	"bufio"
+	"context"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	vfsp2p "github.com/securesharelabs/vstore/api/vstore/v1"
 	vfs "github.com/securesharelabs/vstore/vfs"
 
-	cmtlog "github.com/cometbft/cometbft/libs/log"
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/crypto/ed25519"
 	rpc "github.com/cometbft/cometbft/rpc/client/http"
 
 	"github.com/cosmos/gogoproto/proto"
@@ -21,15 +28,154 @@ import (
 
 // Used for flags
 var transactionHash string
+var transactionHashes []string
+var hashesFile string
+var hashEncoding string
+var chunkRootHash string
+var queryHeight int64
+var queryPubKey string
+var verifyRoot string
+var queryBlockTime bool
+var queryRoot bool
+var queryCount bool
+var queryNextSequence bool
+var queryOffset int
+var queryLimit int
+var queryAfter string
+var queryFrom string
+var queryTo string
 var printDataAsText bool
+var queryFormat string
+var queryColumns string
+var printRawBody bool
 
 func init() {
 	// e.g.: vstore query --hash "3816D803...9E03"
-	queryCmd.PersistentFlags().StringVar(
-		&transactionHash,
+	queryCmd.PersistentFlags().StringArrayVar(
+		&transactionHashes,
 		"hash",
+		[]string{},
+		"Build a query by transaction hash (repeat to query several transactions, printed as a JSON array).",
+	)
+
+	// e.g.: vstore query --hashes-file hashes.txt
+	queryCmd.PersistentFlags().StringVar(
+		&hashesFile,
+		"hashes-file",
+		"",
+		"Query the transactions of a file of newline-delimited transaction hashes, printed as a JSON array.",
+	)
+
+	// e.g.: vstore query --hash "OBbYA...ngM=" --hash-encoding base64
+	queryCmd.PersistentFlags().StringVar(
+		&hashEncoding,
+		"hash-encoding",
+		"hex",
+		"Encoding of the transaction hash (--hash) and of the chunk root hash (--chunks): hex or base64.",
+	)
+
+	// e.g.: vstore query --height 1234
+	queryCmd.PersistentFlags().Int64Var(
+		&queryHeight,
+		"height",
+		0,
+		"Build a query by block height (returns all transactions of the block).",
+	)
+
+	// e.g.: vstore query --pubkey "1AE0F7C4...27A3"
+	queryCmd.PersistentFlags().StringVar(
+		&queryPubKey,
+		"pubkey",
+		"",
+		"Build a query by signer public key in hex or bech32 (returns all transactions of the signer).",
+	)
+
+	// e.g.: vstore query --pubkey "1AE0F7C4...27A3" --verify-root "C5D2460E...8F0B"
+	queryCmd.PersistentFlags().StringVar(
+		&verifyRoot,
+		"verify-root",
 		"",
-		"Build a query by transaction hash.",
+		"Verify that the merkle root of the signer (--pubkey) matches.",
+	)
+
+	// e.g.: vstore query --height 1234 --block-time
+	queryCmd.PersistentFlags().BoolVar(
+		&queryBlockTime,
+		"block-time",
+		false,
+		"Print the block time of the block height (--height) instead of transactions.",
+	)
+
+	// e.g.: vstore query --pubkey "XXX" --count
+	queryCmd.PersistentFlags().BoolVar(
+		&queryCount,
+		"count",
+		false,
+		"Print the number of transactions of the block height (--height) or of the signer (--pubkey) instead of transactions.",
+	)
+
+	// e.g.: vstore query --pubkey "XXX" --next-sequence
+	queryCmd.PersistentFlags().BoolVar(
+		&queryNextSequence,
+		"next-sequence",
+		false,
+		"Print the next sequence of the signer (--pubkey) instead of transactions, e.g. for vstore factory --sequence.",
+	)
+
+	// e.g.: vstore query --root
+	queryCmd.PersistentFlags().BoolVar(
+		&queryRoot,
+		"root",
+		false,
+		"Print the aggregate merkle root of all signers (matches the app hash).",
+	)
+
+	// e.g.: vstore query --pubkey "1AE0F7C4...27A3" --offset 50 --limit 50
+	queryCmd.PersistentFlags().IntVar(
+		&queryOffset,
+		"offset",
+		0,
+		"Skip this number of transactions (--height, --pubkey and time range queries).",
+	)
+
+	// e.g.: vstore query --pubkey "1AE0F7C4...27A3" --limit 10
+	queryCmd.PersistentFlags().IntVar(
+		&queryLimit,
+		"limit",
+		vfs.DefaultQueryLimit,
+		"Maximum number of transactions returned (--height, --pubkey and time range queries).",
+	)
+
+	// e.g.: vstore query --pubkey "1AE0F7C4...27A3" --after "3816D803...9E03" --limit 50
+	queryCmd.PersistentFlags().StringVar(
+		&queryAfter,
+		"after",
+		"",
+		"Return the transactions after this transaction hash, i.e. the next cursor of the previous page (--height, --pubkey and time range queries).",
+	)
+
+	// e.g.: vstore query --from 2024-06-04T00:00:00Z --to 2024-06-04T23:59:59Z
+	queryCmd.PersistentFlags().StringVar(
+		&queryFrom,
+		"from",
+		"",
+		"Build a query by time range, from this RFC3339 timestamp (inclusive).",
+	)
+
+	// e.g.: vstore query --to 2024-06-04T23:59:59Z
+	queryCmd.PersistentFlags().StringVar(
+		&queryTo,
+		"to",
+		"",
+		"Build a query by time range, to this RFC3339 timestamp (inclusive).",
+	)
+
+	// e.g.: vstore query --chunks "5F0A3E21...C4B1" --pubkey "1AE0F7C4...27A3"
+	queryCmd.PersistentFlags().StringVar(
+		&chunkRootHash,
+		"chunks",
+		"",
+		"Build a query by chunk root hash of the signer (--pubkey), reassembles the chunked body.",
 	)
 
 	// e.g.: vstore query --hash "3816D803...9E03" --json
@@ -41,6 +187,22 @@ func init() {
 		"Display the information in a JSON format.",
 	)
 
+	// e.g.: vstore query --pubkey "1AE0F7C4...27A3" --format csv > transactions.csv
+	queryCmd.PersistentFlags().StringVar(
+		&queryFormat,
+		"format",
+		"text",
+		"Output format: text, json, csv or tsv (csv and tsv are available for --height, --pubkey and time range queries).",
+	)
+
+	// e.g.: vstore query --pubkey "1AE0F7C4...27A3" --format csv --columns hash,time,data --plain
+	queryCmd.PersistentFlags().StringVar(
+		&queryColumns,
+		"columns",
+		DefaultCSVColumns,
+		"Columns of the csv and tsv formats: hash, signer, signature, signature_check, time, size or data.",
+	)
+
 	// e.g.: vstore query --hash "3816D803...9E03" --plain
 	queryCmd.PersistentFlags().BoolVarP(
 		&printDataAsText,
@@ -50,6 +212,14 @@ func init() {
 		"Display the transaction body in UTF-8 format.",
 	)
 
+	// e.g.: vstore query --hash "3816D803...9E03" --raw > restored.bin
+	queryCmd.PersistentFlags().BoolVar(
+		&printRawBody,
+		"raw",
+		false,
+		"Write only the transaction body to stdout, without decoration (--hash and --chunks queries).",
+	)
+
 	vstoreCmd.AddCommand(queryCmd)
 }
 
@@ -60,25 +230,367 @@ var queryCmd = &cobra.Command{
 
 	- the transaction hash as returned by the factory subcommand ; or
 	- the block height in which the transaction was included ; or
-	- the signer public key attached to the transaction.`,
+	- the signer public key attached to the transaction ; or
+	- a range of transaction timestamps (--from and --to, both inclusive).`,
 
 	Example: `  vstore query
-  vstore query --hash "XXX"`,
+  vstore query --hash "XXX"
+  vstore query --hash "XXX" --hash-encoding base64
+  vstore query --hash "XXX" --hash "XXX"
+  vstore query --hashes-file hashes.txt
+  vstore query --hash "XXX" --raw > restored.bin
+  vstore query --height 1234
+  vstore query --height 1234 --block-time
+  vstore query --height 1234 --count
+  vstore query --pubkey "XXX"
+  vstore query --pubkey "XXX" --count
+  vstore query --pubkey "XXX" --next-sequence
+  vstore query --pubkey "XXX" --height 1234
+  vstore query --pubkey "XXX" --verify-root "XXX"
+  vstore query --pubkey "XXX" --after "XXX" --limit 50
+  vstore query --pubkey "XXX" --format csv --columns hash,time,size,data
+  vstore query --from 2024-06-04T00:00:00Z --to 2024-06-04T23:59:59Z
+  vstore query --root
+  vstore query --chunks "XXX" --pubkey "XXX"`,
 
 	Run: func(cmd *cobra.Command, args []string) {
+		if err := validatePubKeyDisplay(pubKeyDisplay); err != nil {
+			log.Fatalf("could not use provided public key encoding: %v", err)
+		}
 
-		// Prepare the local RPC client
-		// Note: A node must be running in the background
-		// TODO: Permit overwrite of RPC remote address
-		logger := cmtlog.NewTMLogger(cmtlog.NewSyncWriter(os.Stdout))
-		cli, err := rpc.New("http://localhost:26657", "/websocket")
+		// Tabular formats are written by printTransactionList
+		if err := validateQueryFormat(queryFormat); err != nil {
+			log.Fatalf("could not use provided format: %v", err)
+		}
+
+		if _, err := parseCSVColumns(queryColumns); err != nil {
+			log.Fatalf("could not use provided columns: %v", err)
+		}
+
+		if queryFormat == "json" {
+			printAsJSON = true
+		}
+
+		// Cursors are transaction hashes (--after)
+		if len(queryAfter) > 0 {
+			if abz, err := hex.DecodeString(queryAfter); err != nil || len(abz) != 32 {
+				log.Fatalf("could not use provided cursor: must be 32 bytes hex")
+			}
+		}
+
+		// Time ranges are RFC3339 timestamps (--from, --to)
+		for _, ts := range []string{queryFrom, queryTo} {
+			if _, err := time.Parse(time.RFC3339, ts); len(ts) > 0 && err != nil {
+				log.Fatalf("could not use provided time range: %v", err)
+			}
+		}
+
+		// Prepare the RPC client (--node)
+		// Note: A node must be running at this address
+		cli, err := newRPCClient(nodeAddr)
 		if err != nil {
-			log.Fatalf("could not connect to RPC server: %v", err)
+			log.Fatalf("could not create RPC client: %v", err)
+		}
+
+		// Transactions can be queried by time range
+		if len(queryFrom) > 0 || len(queryTo) > 0 {
+			response := executeQuery(cmd.Context(), cli, timeQueryPath(queryFrom, queryTo), []byte{})
+			if len(response.Value) == 0 {
+				log.Fatalf("could not find transactions from: %q to: %q", queryFrom, queryTo)
+			}
+
+			list := new(vfsp2p.TransactionList)
+			err = proto.Unmarshal(response.Value, list)
+			if err != nil {
+				log.Fatalf("could not parse TransactionList bytes: %v", err)
+			}
+
+			printTransactionList(list)
+			return
+		}
+
+		// Merkle roots can be verified by signer public key
+		if len(verifyRoot) > 0 {
+			pbz, err := parsePubKey(queryPubKey)
+			if err != nil || len(pbz) == 0 {
+				log.Fatalf("could not use provided public key: %v", err)
+			}
+
+			rbz, err := hex.DecodeString(verifyRoot)
+			if err != nil {
+				log.Fatalf("could not use provided merkle root: %v", err)
+			}
+
+			response := executeQuery(cmd.Context(), cli, "/root/verify", append(pbz, rbz...))
+			rootInfo := struct {
+				Signer     string
+				MerkleRoot string
+				Match      bool
+			}{
+				formatPubKey(pbz),
+				fmt.Sprintf("%x", rbz),
+				len(response.Value) == 1 && response.Value[0] == 1,
+			}
+
+			if printAsJSON {
+				json, _ := json.MarshalIndent(rootInfo, "", "  ")
+				fmt.Print(string(json) + "\n")
+				return // Job done.
+			}
+
+			fmt.Printf("vStore %s (vfs v%d) - ABCI: \n", Version, vfs.AppVersion)
+			fmt.Printf("  Signer PubKey: %s\n", rootInfo.Signer)
+			fmt.Printf("    Merkle Root: %s\n", rootInfo.MerkleRoot)
+			fmt.Printf("          Match: %t\n", rootInfo.Match)
+			return
+		}
+
+		// Aggregate merkle root of all signers (app hash)
+		if queryRoot {
+			response := executeQuery(cmd.Context(), cli, "/root", []byte{})
+			rootInfo := struct {
+				Height     int64
+				MerkleRoot string
+			}{
+				response.Height,
+				fmt.Sprintf("%X", response.Value),
+			}
+
+			if printAsJSON {
+				json, _ := json.MarshalIndent(rootInfo, "", "  ")
+				fmt.Print(string(json) + "\n")
+				return // Job done.
+			}
+
+			fmt.Printf("vStore %s (vfs v%d) - ABCI: \n", Version, vfs.AppVersion)
+			fmt.Printf("       Height: %d\n", rootInfo.Height)
+			fmt.Printf("  Merkle Root: %s\n", rootInfo.MerkleRoot)
+			return
+		}
+
+		// Transactions can be queried by signer public key and block height
+		if len(queryPubKey) > 0 && queryHeight > 0 {
+			pbz, err := parsePubKey(queryPubKey)
+			if err != nil {
+				log.Fatalf("could not use provided public key: %v", err)
+			}
+
+			key := append(pbz, []byte(strconv.FormatInt(queryHeight, 10))...)
+			response := executeQuery(cmd.Context(), cli, indexQueryPath("/signer/height"), key)
+			if len(response.Value) == 0 {
+				log.Fatalf("could not find transactions with signer: %x at height: %d", pbz, queryHeight)
+			}
+
+			list := new(vfsp2p.TransactionList)
+			err = proto.Unmarshal(response.Value, list)
+			if err != nil {
+				log.Fatalf("could not parse TransactionList bytes: %v", err)
+			}
+
+			printTransactionList(list)
+			return
+		}
+
+		// Number of transactions can be queried by signer public key
+		if len(queryPubKey) > 0 && queryCount {
+			pbz, err := parsePubKey(queryPubKey)
+			if err != nil {
+				log.Fatalf("could not use provided public key: %v", err)
+			}
+
+			response := executeQuery(cmd.Context(), cli, "/pubkey/count", pbz)
+
+			count, err := strconv.ParseInt(string(response.Value), 10, 64)
+			if err != nil {
+				log.Fatalf("could not parse transaction count: %v", err)
+			}
+
+			countInfo := struct {
+				Signer          string
+				NumTransactions int64
+			}{
+				formatPubKey(pbz),
+				count,
+			}
+
+			if printAsJSON {
+				json, _ := json.MarshalIndent(countInfo, "", "  ")
+				fmt.Print(string(json) + "\n")
+				return // Job done.
+			}
+
+			fmt.Printf("vStore %s (vfs v%d) - ABCI: \n", Version, vfs.AppVersion)
+			fmt.Printf("  Signer PubKey: %s\n", countInfo.Signer)
+			fmt.Printf("   Transactions: %d\n", countInfo.NumTransactions)
+			return
+		}
+
+		// Next sequence can be queried by signer public key
+		if len(queryPubKey) > 0 && queryNextSequence {
+			pbz, err := parsePubKey(queryPubKey)
+			if err != nil {
+				log.Fatalf("could not use provided public key: %v", err)
+			}
+
+			response := executeQuery(cmd.Context(), cli, "/pubkey/sequence", pbz)
+
+			next, err := strconv.ParseUint(string(response.Value), 10, 64)
+			if err != nil {
+				log.Fatalf("could not parse next sequence: %v", err)
+			}
+
+			sequenceInfo := struct {
+				Signer       string
+				NextSequence uint64
+			}{
+				formatPubKey(pbz),
+				next,
+			}
+
+			if printAsJSON {
+				json, _ := json.MarshalIndent(sequenceInfo, "", "  ")
+				fmt.Print(string(json) + "\n")
+				return // Job done.
+			}
+
+			fmt.Printf("vStore %s (vfs v%d) - ABCI: \n", Version, vfs.AppVersion)
+			fmt.Printf("  Signer PubKey: %s\n", sequenceInfo.Signer)
+			fmt.Printf("  Next Sequence: %d\n", sequenceInfo.NextSequence)
+			return
+		}
+
+		// Transactions can be queried by signer public key
+		if len(queryPubKey) > 0 && len(chunkRootHash) == 0 {
+			pbz, err := parsePubKey(queryPubKey)
+			if err != nil {
+				log.Fatalf("could not use provided public key: %v", err)
+			}
+
+			response := executeQuery(cmd.Context(), cli, indexQueryPath("/pubkey"), pbz)
+			if len(response.Value) == 0 {
+				log.Fatalf("could not find transactions with signer: %x", pbz)
+			}
+
+			list := new(vfsp2p.TransactionList)
+			err = proto.Unmarshal(response.Value, list)
+			if err != nil {
+				log.Fatalf("could not parse TransactionList bytes: %v", err)
+			}
+
+			printTransactionList(list)
+			return
+		}
+
+		// Number of transactions can be queried by block height
+		if queryHeight > 0 && queryCount {
+			key := []byte(strconv.FormatInt(queryHeight, 10))
+			response := executeQuery(cmd.Context(), cli, "/height/count", key)
+
+			count, err := strconv.ParseInt(string(response.Value), 10, 64)
+			if err != nil {
+				log.Fatalf("could not parse transaction count: %v", err)
+			}
+
+			countInfo := struct {
+				Height          int64
+				NumTransactions int64
+			}{
+				queryHeight,
+				count,
+			}
+
+			if printAsJSON {
+				json, _ := json.MarshalIndent(countInfo, "", "  ")
+				fmt.Print(string(json) + "\n")
+				return // Job done.
+			}
+
+			fmt.Printf("vStore %s (vfs v%d) - ABCI: \n", Version, vfs.AppVersion)
+			fmt.Printf("        Height: %d\n", countInfo.Height)
+			fmt.Printf("  Transactions: %d\n", countInfo.NumTransactions)
+			return
+		}
+
+		// Block time (consensus time) can be queried by block height
+		if queryHeight > 0 && queryBlockTime {
+			key := []byte(strconv.FormatInt(queryHeight, 10))
+			response := executeQuery(cmd.Context(), cli, "/blocktime/height", key)
+			if len(response.Value) == 0 {
+				log.Fatalf("could not find block time at height: %d", queryHeight)
+			}
+
+			timeInfo := struct {
+				Height    int64
+				BlockTime string
+			}{
+				queryHeight,
+				string(response.Value),
+			}
+
+			if printAsJSON {
+				json, _ := json.MarshalIndent(timeInfo, "", "  ")
+				fmt.Print(string(json) + "\n")
+				return // Job done.
+			}
+
+			fmt.Printf("vStore %s (vfs v%d) - ABCI: \n", Version, vfs.AppVersion)
+			fmt.Printf("      Height: %d\n", timeInfo.Height)
+			fmt.Printf("  Block Time: %s\n", timeInfo.BlockTime)
+			return
+		}
+
+		// Transactions can be queried by block height
+		if queryHeight > 0 {
+			key := []byte(strconv.FormatInt(queryHeight, 10))
+			response := executeQuery(cmd.Context(), cli, indexQueryPath("/height"), key)
+			if len(response.Value) == 0 {
+				log.Fatalf("could not find transactions at height: %d", queryHeight)
+			}
+
+			list := new(vfsp2p.TransactionList)
+			err = proto.Unmarshal(response.Value, list)
+			if err != nil {
+				log.Fatalf("could not parse TransactionList bytes: %v", err)
+			}
+
+			printTransactionList(list)
+			return
+		}
+
+		// Tabular formats describe lists of transactions
+		if _, ok := tabularFormats[queryFormat]; ok {
+			log.Fatalf("could not query transactions: --format %s requires --height, --pubkey or a time range", queryFormat)
+		}
+
+		// Several transactions are queried with the same client
+		if len(transactionHashes) > 1 || len(hashesFile) > 0 {
+			if printRawBody {
+				log.Fatalf("could not query transactions: --raw cannot be combined with several hashes")
+			}
+
+			runQueryBatch(cmd.Context(), cli, transactionHashes, hashesFile)
+			return
+		}
+
+		if len(transactionHashes) == 1 {
+			transactionHash = transactionHashes[0]
+		}
+
+		// Chunked bodies are queried by signer and chunk root hash
+		queryPath := "/hash"
+		var signer []byte
+		if len(chunkRootHash) > 0 {
+			pbz, err := parsePubKey(queryPubKey)
+			if err != nil || len(pbz) == 0 {
+				log.Fatalf("could not query chunked body: --chunks requires the signer public key (--pubkey): %v", err)
+			}
+
+			queryPath = "/chunks"
+			transactionHash = chunkRootHash
+			signer = pbz
 		}
-		cli.SetLogger(logger)
 
 		// Ask for hash if not provided with --hash
-		// TODO: Permit using height or pubkey indexes
 		if len(transactionHash) == 0 {
 			fmt.Printf("Enter the transaction hash: ")
 			reader := bufio.NewReader(os.Stdin)
@@ -91,55 +603,272 @@ var queryCmd = &cobra.Command{
 		}
 
 		// Parse transaction hash (for query key)
-		hbz, err := hex.DecodeString(transactionHash)
+		hbz, err := decodeTransactionHash(transactionHash, hashEncoding)
 		if err != nil {
 			log.Fatalf("could not use provided transaction hash: %v", err)
 		}
 
 		// Execute query using RPC client
-		response, err := cli.ABCIQuery(cmd.Context(), "/hash", hbz)
-
-		if err != nil || response.Response.Code != vfs.CodeTypeOK {
-			log.Fatalf("error occured on query: (%d - %s) with error: %v", response.Response.Code, response.Response.Log, err)
-		}
-
-		if len(response.Response.Value) == 0 {
+		response := executeQuery(cmd.Context(), cli, queryPath, append(append([]byte{}, signer...), hbz...))
+		if len(response.Value) == 0 {
 			log.Fatalf("could not find transaction with hash: %x", hbz)
 		}
 
 		tx := new(vfsp2p.Transaction)
-		err = proto.Unmarshal(response.Response.Value, tx)
+		err = proto.Unmarshal(response.Value, tx)
 		if err != nil {
 			log.Fatalf("could not parse Transaction bytes: %v", err)
 		}
 
-		txBody := string(tx.Body)
-		if !printDataAsText {
-			txBody = fmt.Sprintf("%x", tx.Body)
-		}
-
-		txInfo := struct {
-			Signer    string
-			Signature string
-			Size      int64
-			Data      string
-		}{
-			fmt.Sprintf("%x", tx.Signer.GetEd25519()),
-			fmt.Sprintf("%x", tx.Signature),
-			int64(tx.Len),
-			txBody,
+		// Body bytes only, e.g. to restore the committed payload (--raw)
+		if printRawBody {
+			if err := writeRawBody(os.Stdout, tx.Body, printDataAsText); err != nil {
+				log.Fatalf("could not write transaction body: %v", err)
+			}
+			return // Job done.
 		}
 
+		txInfo := newTransactionInfo(tx)
 		if printAsJSON {
 			json, _ := json.MarshalIndent(txInfo, "", "  ")
 			fmt.Print(string(json) + "\n")
 			return // Job done.
 		}
 
-		fmt.Printf("vStore v1.0 (vfs v%d) - ABCI: \n", vfs.AppVersion)
-		fmt.Printf("  Signer PubKey: %s\n", txInfo.Signer)
-		fmt.Printf("      Signature: %s\n", txInfo.Signature)
-		fmt.Printf("           Size: %d\n", txInfo.Size)
-		fmt.Printf("           Data: %s\n", txInfo.Data)
+		fmt.Printf("vStore %s (vfs v%d) - ABCI: \n", Version, vfs.AppVersion)
+		printTransactionInfo(os.Stdout, txInfo)
 	},
 }
+
+// decodeTransactionHash decodes a transaction hash with encoding, i.e. "hex" or
+// "base64", and returns an error if the decoded hash is not 32 bytes.
+func decodeTransactionHash(value, encoding string) ([]byte, error) {
+	var (
+		hbz []byte
+		err error
+	)
+
+	switch encoding {
+	case "hex":
+		hbz, err = hex.DecodeString(value)
+	case "base64":
+		hbz, err = base64.StdEncoding.DecodeString(value)
+	default:
+		return nil, fmt.Errorf("unsupported hash encoding %q: expected hex or base64", encoding)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", encoding, err)
+	}
+
+	if len(hbz) != 32 {
+		return nil, fmt.Errorf("hash must be 32 bytes, got %d bytes", len(hbz))
+	}
+
+	return hbz, nil
+}
+
+// writeRawBody writes a transaction body to w without decoration, i.e. without
+// a trailing newline. The bytes are written unchanged, or as UTF-8 text with
+// plain, where invalid sequences are replaced with the replacement character.
+func writeRawBody(w io.Writer, body []byte, plain bool) error {
+	if plain {
+		_, err := io.WriteString(w, strings.ToValidUTF8(string(body), "\uFFFD"))
+		return err
+	}
+
+	_, err := w.Write(body)
+	return err
+}
+
+// Results of the client-side verification of transaction signatures.
+const (
+	signatureValid   = "VALID"
+	signatureLegacy  = "VALID (legacy, timestamp not signed)"
+	signatureInvalid = "INVALID"
+)
+
+// transactionInfo describes the transaction fields displayed by queries. The
+// JSON field names are stable, such that scripts can rely on them.
+type transactionInfo struct {
+	Hash           string `json:"Hash"`
+	Signer         string `json:"Signer"`
+	Signature      string `json:"Signature"`
+	SignatureCheck string `json:"SignatureCheck,omitempty"`
+	Time           string `json:"Time"`
+	Size           int64  `json:"Size"`
+	Data           string `json:"Data"`
+}
+
+// newTransactionInfo creates the displayed information for a transaction. The
+// timestamp is formatted with RFC3339 in UTC.
+func newTransactionInfo(tx *vfsp2p.Transaction) transactionInfo {
+	txBody := string(tx.Body)
+	if !printDataAsText {
+		txBody = fmt.Sprintf("%x", tx.Body)
+	}
+
+	// Transactions of older nodes may not contain their hash
+	txHash := tx.Hash
+	if len(txHash) == 0 {
+		if stx, err := vfs.FromProto(tx); err == nil {
+			txHash = vfs.ComputeHash(stx.Unresolved())
+		}
+	}
+
+	return transactionInfo{
+		Hash:           fmt.Sprintf("%X", txHash),
+		Signer:         formatPubKey(tx.Signer.GetEd25519()),
+		Signature:      fmt.Sprintf("%x", tx.Signature),
+		SignatureCheck: verifyTransactionSignature(tx),
+		Time:           tx.Time.UTC().Format(time.RFC3339Nano),
+		Size:           int64(tx.Len),
+		Data:           txBody,
+	}
+}
+
+// verifyTransactionSignature verifies the signature of a transaction that was
+// returned by a node, such that a compromised or faulty node is detected by
+// the client. Reassembled chunked bodies are not signed as a whole, the
+// result is empty.
+func verifyTransactionSignature(tx *vfsp2p.Transaction) string {
+	if len(tx.Signature) == 0 && tx.Chunk != nil {
+		return ""
+	}
+
+	stx, err := vfs.FromProto(tx)
+	if err != nil || len(stx.Signer) != ed25519.PubKeySize {
+		return signatureInvalid
+	}
+
+	// Bodies resolved from external storage are not signed
+	stx = stx.Unresolved()
+	if stx.Verify() {
+		return signatureValid
+	}
+
+	if stx.VerifyLegacy() {
+		return signatureLegacy
+	}
+
+	return signatureInvalid
+}
+
+// printTransactionInfo prints the transaction information in a human
+// readable format to w.
+func printTransactionInfo(w io.Writer, txInfo transactionInfo) {
+	fmt.Fprintf(w, "           Hash: %s\n", txInfo.Hash)
+	fmt.Fprintf(w, "  Signer PubKey: %s\n", txInfo.Signer)
+	fmt.Fprintf(w, "      Signature: %s\n", txInfo.Signature)
+	if len(txInfo.SignatureCheck) > 0 {
+		fmt.Fprintf(w, "Signature Check: %s\n", txInfo.SignatureCheck)
+	}
+	fmt.Fprintf(w, "      Timestamp: %s\n", txInfo.Time)
+	fmt.Fprintf(w, "           Size: %d\n", txInfo.Size)
+	fmt.Fprintf(w, "           Data: %s\n", txInfo.Data)
+}
+
+// printTransactionList prints a list of transactions as returned by index
+// queries, using the JSON, CSV or TSV format if requested.
+func printTransactionList(list *vfsp2p.TransactionList) {
+	txInfos := make([]transactionInfo, len(list.Transactions))
+	for i := range list.Transactions {
+		txInfos[i] = newTransactionInfo(&list.Transactions[i])
+	}
+
+	// Tabular formats contain no pagination, one row per transaction
+	if _, ok := tabularFormats[queryFormat]; ok {
+		columns, _ := parseCSVColumns(queryColumns)
+		if err := writeTransactionsCSV(os.Stdout, txInfos, columns, queryFormat); err != nil {
+			log.Fatalf("could not write transactions: %v", err)
+		}
+		return // Job done.
+	}
+
+	listInfo := struct {
+		Total        uint64
+		Offset       int
+		Next         string `json:",omitempty"`
+		Transactions []transactionInfo
+	}{
+		list.Total,
+		queryOffset,
+		fmt.Sprintf("%x", list.Next),
+		txInfos,
+	}
+
+	if printAsJSON {
+		json, _ := json.MarshalIndent(listInfo, "", "  ")
+		fmt.Print(string(json) + "\n")
+		return // Job done.
+	}
+
+	fmt.Printf("vStore %s (vfs v%d) - ABCI: \n", Version, vfs.AppVersion)
+	fmt.Printf("   Transactions: %d (offset: %d, total: %d)\n", len(txInfos), listInfo.Offset, listInfo.Total)
+	if len(listInfo.Next) > 0 {
+		fmt.Printf("    Next cursor: %s\n", listInfo.Next)
+	}
+	for _, txInfo := range txInfos {
+		fmt.Printf("\n")
+		printTransactionInfo(os.Stdout, txInfo)
+	}
+}
+
+// indexQueryPath adds the pagination parameters to an index query path.
+func indexQueryPath(path string) string {
+	return path + "?" + paginationParams().Encode()
+}
+
+// timeQueryPath returns the path of a query by time range with the pagination
+// parameters. Empty timestamps are unbounded.
+func timeQueryPath(from, to string) string {
+	params := paginationParams()
+	if len(from) > 0 {
+		params.Set("from", from)
+	}
+
+	if len(to) > 0 {
+		params.Set("to", to)
+	}
+
+	return "/time?" + params.Encode()
+}
+
+// paginationParams returns the pagination parameters of index queries.
+func paginationParams() url.Values {
+	params := url.Values{}
+	params.Set("limit", strconv.Itoa(queryLimit))
+
+	// Cursors replace offsets
+	if len(queryAfter) > 0 {
+		params.Set("after", queryAfter)
+	} else {
+		params.Set("offset", strconv.Itoa(queryOffset))
+	}
+
+	return params
+}
+
+// executeQuery executes an ABCI query using the RPC client and stops the
+// execution if the query fails.
+func executeQuery(
+	ctx context.Context,
+	cli *rpc.HTTP,
+	path string,
+	data []byte,
+) abci.ResponseQuery {
+	ctx, cancel := withNodeTimeout(ctx)
+	defer cancel()
+
+	response, err := cli.ABCIQuery(ctx, path, data)
+	if err != nil {
+		log.Fatalf("error occured on query: %v", nodeError(err))
+	}
+
+	if response.Response.Code != vfs.CodeTypeOK {
+		log.Fatalf("error occured on query: %s (%d - %s)",
+			vfs.CodeToString(response.Response.Code), response.Response.Code, response.Response.Log)
+	}
+
+	return response.Response
+}
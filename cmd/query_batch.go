@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	vfsp2p "github.com/securesharelabs/vstore/api/vstore/v1"
+	vfs "github.com/securesharelabs/vstore/vfs"
+
+	cmtbytes "github.com/cometbft/cometbft/libs/bytes"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+
+	"github.com/cosmos/gogoproto/proto"
+)
+
+// txQuerier describes the RPC method of batch queries. The HTTP client of
+// CometBFT implements txQuerier.
+type txQuerier interface {
+	ABCIQuery(ctx context.Context, path string, data cmtbytes.HexBytes) (*coretypes.ResultABCIQuery, error)
+}
+
+// hashQueryResult describes the outcome of a transaction hash of a batch
+// query. Transaction is nil if the transaction was not found.
+type hashQueryResult struct {
+	Hash        string
+	Found       bool
+	Transaction *transactionInfo `json:",omitempty"`
+	Error       string           `json:",omitempty"`
+}
+
+// readHashesFile reads newline-delimited transaction hashes, empty lines and
+// surrounding spaces are ignored.
+func readHashesFile(r io.Reader) ([]string, error) {
+	lines, err := readBatchLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if hash := strings.TrimSpace(string(line)); len(hash) > 0 {
+			hashes = append(hashes, hash)
+		}
+	}
+
+	return hashes, nil
+}
+
+// queryHashes queries the transactions of hashes in order with one client. A
+// hash that cannot be decoded with encoding, that is not found or whose query
+// fails is reported in its result and does not abort the rest of the batch.
+func queryHashes(ctx context.Context, cli txQuerier, hashes []string, encoding string) []hashQueryResult {
+	results := make([]hashQueryResult, len(hashes))
+	for i, hash := range hashes {
+		results[i].Hash = hash
+
+		hbz, err := decodeTransactionHash(hash, encoding)
+		if err != nil {
+			results[i].Error = fmt.Sprintf("could not use provided transaction hash: %v", err)
+			continue
+		}
+
+		callCtx, cancel := withNodeTimeout(ctx)
+		response, err := cli.ABCIQuery(callCtx, "/hash", hbz)
+		cancel()
+		if err != nil {
+			results[i].Error = fmt.Sprintf("error occured on query: %v", nodeError(err))
+			continue
+		}
+
+		// Committed transactions may be pruned or unreadable
+		if code := response.Response.Code; code != vfs.CodeTypeOK {
+			results[i].Error = fmt.Sprintf("%s (%d - %s)", vfs.CodeToString(code), code, response.Response.Log)
+			continue
+		}
+
+		if len(response.Response.Value) == 0 {
+			continue // not found
+		}
+
+		tx := new(vfsp2p.Transaction)
+		if err := proto.Unmarshal(response.Response.Value, tx); err != nil {
+			results[i].Error = fmt.Sprintf("could not parse Transaction bytes: %v", err)
+			continue
+		}
+
+		txInfo := newTransactionInfo(tx)
+		results[i].Found = true
+		results[i].Transaction = &txInfo
+	}
+
+	return results
+}
+
+// runQueryBatch queries the transactions of hashes and of the hashes file, if
+// any, over a single RPC client, then prints the results as a JSON array. The
+// process exits with status 1 if a transaction was not found.
+func runQueryBatch(ctx context.Context, cli txQuerier, hashes []string, file string) {
+	if len(file) > 0 {
+		f, err := os.Open(file)
+		if err != nil {
+			log.Fatalf("could not open hashes file: %v", err)
+		}
+
+		fileHashes, err := readHashesFile(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("could not read hashes file: %v", err)
+		}
+
+		hashes = append(hashes, fileHashes...)
+	}
+
+	results := queryHashes(ctx, cli, hashes, hashEncoding)
+
+	json, _ := json.MarshalIndent(results, "", "  ")
+	fmt.Print(string(json) + "\n")
+
+	for _, result := range results {
+		if !result.Found {
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// DefaultCSVColumns are the columns of tabular outputs without --columns.
+const DefaultCSVColumns = "hash,signer,time,size"
+
+// csvColumns maps the column names of tabular outputs to the transaction
+// fields, in the order of the columns of transactionInfo.
+var csvColumns = map[string]func(transactionInfo) string{
+	"hash":            func(tx transactionInfo) string { return tx.Hash },
+	"signer":          func(tx transactionInfo) string { return tx.Signer },
+	"signature":       func(tx transactionInfo) string { return tx.Signature },
+	"signature_check": func(tx transactionInfo) string { return tx.SignatureCheck },
+	"time":            func(tx transactionInfo) string { return tx.Time },
+	"size":            func(tx transactionInfo) string { return strconv.FormatInt(tx.Size, 10) },
+	"data":            func(tx transactionInfo) string { return tx.Data },
+}
+
+// tabularFormats maps the tabular --format values to their field delimiter.
+var tabularFormats = map[string]rune{
+	"csv": ',',
+	"tsv": '\t',
+}
+
+// validateQueryFormat returns an error if the output format is not one of
+// text, json, csv or tsv.
+func validateQueryFormat(format string) error {
+	if _, ok := tabularFormats[format]; ok || format == "text" || format == "json" {
+		return nil
+	}
+
+	return fmt.Errorf("unsupported format %q: expected text, json, csv or tsv", format)
+}
+
+// parseCSVColumns parses a comma-separated list of column names, e.g.
+// "hash,time,data". Column names are case-insensitive.
+func parseCSVColumns(value string) ([]string, error) {
+	columns := []string{}
+	for _, column := range strings.Split(value, ",") {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if _, ok := csvColumns[column]; !ok {
+			return nil, fmt.Errorf("unknown column %q: expected hash, signer, signature, signature_check, time, size or data", column)
+		}
+
+		columns = append(columns, column)
+	}
+
+	return columns, nil
+}
+
+// writeTransactionsCSV writes a header row with the column names and a row per
+// transaction, with the delimiter of the tabular format. Fields that contain
+// the delimiter, quotes or newlines are quoted.
+func writeTransactionsCSV(w io.Writer, txInfos []transactionInfo, columns []string, format string) error {
+	comma, ok := tabularFormats[format]
+	if !ok {
+		return fmt.Errorf("unsupported tabular format %q: expected csv or tsv", format)
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Comma = comma
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+
+	for _, txInfo := range txInfos {
+		row := make([]string, len(columns))
+		for i, column := range columns {
+			row[i] = csvColumns[column](txInfo)
+		}
+
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,312 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	vfsp2p "github.com/securesharelabs/vstore/api/vstore/v1"
+	vfs "github.com/securesharelabs/vstore/vfs"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/crypto/tmhash"
+	cmtbytes "github.com/cometbft/cometbft/libs/bytes"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+
+	"github.com/cosmos/gogoproto/proto"
+)
+
+func TestDecodeTransactionHash(t *testing.T) {
+	hash := tmhash.Sum([]byte("This is a message"))
+
+	for encoding, encoded := range map[string]string{
+		"hex":    hex.EncodeToString(hash),
+		"base64": base64.StdEncoding.EncodeToString(hash),
+	} {
+		hbz, err := decodeTransactionHash(encoded, encoding)
+		require.NoError(t, err, encoding)
+		assert.Equal(t, hash, hbz)
+	}
+
+	// Uppercase hexadecimal hashes are accepted
+	hbz, err := decodeTransactionHash(strings.ToUpper(hex.EncodeToString(hash)), "hex")
+	require.NoError(t, err)
+	assert.Equal(t, hash, hbz)
+
+	// Hashes must be 32 bytes
+	_, err = decodeTransactionHash(hex.EncodeToString(hash[:16]), "hex")
+	assert.ErrorContains(t, err, "32 bytes")
+
+	_, err = decodeTransactionHash(base64.StdEncoding.EncodeToString(hash[:16]), "base64")
+	assert.ErrorContains(t, err, "32 bytes")
+
+	// Encodings are not detected
+	_, err = decodeTransactionHash(base64.StdEncoding.EncodeToString(hash), "hex")
+	assert.Error(t, err)
+
+	_, err = decodeTransactionHash(hex.EncodeToString(hash), "base32")
+	assert.ErrorContains(t, err, "unsupported hash encoding")
+}
+
+// localQuerier implements txQuerier with the Query of a vfs application.
+type localQuerier struct {
+	app   *vfs.VStoreApplication
+	calls int
+}
+
+func (q *localQuerier) ABCIQuery(ctx context.Context, path string, data cmtbytes.HexBytes) (*coretypes.ResultABCIQuery, error) {
+	q.calls++
+	resQuery, err := q.app.Query(ctx, &abci.RequestQuery{Path: path, Data: data})
+	if err != nil {
+		return nil, err
+	}
+
+	return &coretypes.ResultABCIQuery{Response: *resQuery}, nil
+}
+
+func TestReadHashesFile(t *testing.T) {
+	hashes, err := readHashesFile(strings.NewReader("AAAA\n\n  BBBB \r\nCCCC"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"AAAA", "BBBB", "CCCC"}, hashes)
+}
+
+func TestQueryHashes(t *testing.T) {
+	ctx := context.Background()
+	idFile, _ := vfs.MustGenerateIdentity(filepath.Join(t.TempDir(), "id"), []byte("testpassword"))
+	app := vfs.NewInMemoryVStoreApplication(idFile, []byte("testpassword"))
+
+	stxs := makeSignedTransactions(t, []byte("This is a message"), 8)
+	txs := make([][]byte, len(stxs))
+	for i, stx := range stxs {
+		txs[i] = stx.Bytes()
+	}
+
+	_, err := app.FinalizeBlock(ctx, &abci.RequestFinalizeBlock{Height: 1, Time: time.Now(), Txs: txs})
+	require.NoError(t, err)
+	_, err = app.Commit(ctx, &abci.RequestCommit{})
+	require.NoError(t, err)
+
+	missing := tmhash.Sum([]byte("missing"))
+	hashes := []string{
+		hex.EncodeToString(vfs.ComputeHash(stxs[0])),
+		hex.EncodeToString(missing),
+		"not a hash",
+		hex.EncodeToString(vfs.ComputeHash(stxs[1])),
+	}
+
+	cli := &localQuerier{app: app}
+	results := queryHashes(ctx, cli, hashes, "hex")
+	require.Len(t, results, len(hashes))
+	assert.Equal(t, 3, cli.calls, "invalid hashes are not queried")
+
+	for i, result := range results {
+		assert.Equal(t, hashes[i], result.Hash)
+	}
+
+	// Found transactions
+	for i, stx := range map[int]*vfs.SignedTransaction{0: stxs[0], 3: stxs[1]} {
+		assert.True(t, results[i].Found)
+		assert.Empty(t, results[i].Error)
+		require.NotNil(t, results[i].Transaction)
+		assert.Equal(t, fmt.Sprintf("%x", stx.Signer.Bytes()), results[i].Transaction.Signer)
+	}
+
+	// Missing transactions
+	assert.False(t, results[1].Found)
+	assert.Nil(t, results[1].Transaction)
+	assert.Empty(t, results[1].Error)
+
+	assert.False(t, results[2].Found)
+	assert.Contains(t, results[2].Error, "could not use provided transaction hash")
+
+	// Results are printed as a JSON array
+	bz, err := json.Marshal(results)
+	require.NoError(t, err)
+	decoded := []map[string]any{}
+	require.NoError(t, json.Unmarshal(bz, &decoded))
+	assert.Len(t, decoded, len(hashes))
+	assert.Equal(t, false, decoded[1]["Found"])
+	assert.NotContains(t, decoded[1], "Transaction")
+}
+
+func TestWriteRawBody(t *testing.T) {
+	ctx := context.Background()
+	idFile, _ := vfs.MustGenerateIdentity(filepath.Join(t.TempDir(), "id"), []byte("testpassword"))
+	app := vfs.NewInMemoryVStoreApplication(idFile, []byte("testpassword"))
+
+	// Binary bodies are restored unchanged
+	body := []byte{0x00, 0xFF, 'v', 's', 't', 'o', 'r', 'e', 0xC3, '\n'}
+	stx := makeSignedTransactions(t, body, 0)[0]
+	_, err := app.FinalizeBlock(ctx, &abci.RequestFinalizeBlock{Height: 1, Time: time.Now(), Txs: [][]byte{stx.Bytes()}})
+	require.NoError(t, err)
+	_, err = app.Commit(ctx, &abci.RequestCommit{})
+	require.NoError(t, err)
+
+	response, err := (&localQuerier{app: app}).ABCIQuery(ctx, "/hash", vfs.ComputeHash(stx))
+	require.NoError(t, err)
+	tx := new(vfsp2p.Transaction)
+	require.NoError(t, proto.Unmarshal(response.Response.Value, tx))
+
+	var buf bytes.Buffer
+	require.NoError(t, writeRawBody(&buf, tx.Body, false))
+	assert.Equal(t, body, buf.Bytes(), "no decoration nor trailing newline")
+
+	// Text bodies replace invalid UTF-8 sequences
+	buf.Reset()
+	require.NoError(t, writeRawBody(&buf, tx.Body, true))
+	assert.Equal(t, "\x00�vstore�\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, writeRawBody(&buf, []byte("Message here"), true))
+	assert.Equal(t, "Message here", buf.String())
+}
+
+func TestTransactionInfo(t *testing.T) {
+	stx := makeSignedTransactions(t, []byte("Message here"), 0)[0]
+	tx := new(vfsp2p.Transaction)
+	require.NoError(t, proto.Unmarshal(stx.Bytes(), tx))
+
+	txInfo := newTransactionInfo(tx)
+	assert.Equal(t, fmt.Sprintf("%X", vfs.ComputeHash(stx)), txInfo.Hash)
+	assert.Equal(t, fmt.Sprintf("%x", stx.Signer.Bytes()), txInfo.Signer)
+	assert.Equal(t, tx.Time.UTC().Format(time.RFC3339Nano), txInfo.Time)
+	assert.Equal(t, hex.EncodeToString([]byte("Message here")), txInfo.Data)
+
+	ts, err := time.Parse(time.RFC3339, txInfo.Time)
+	require.NoError(t, err)
+	assert.True(t, tx.Time.Equal(ts))
+
+	// Human readable format
+	var buf bytes.Buffer
+	printTransactionInfo(&buf, txInfo)
+	assert.Contains(t, buf.String(), "           Hash: "+txInfo.Hash+"\n")
+	assert.Contains(t, buf.String(), "      Timestamp: "+txInfo.Time+"\n")
+
+	// JSON field names are stable
+	bz, err := json.Marshal(txInfo)
+	require.NoError(t, err)
+	decoded := map[string]any{}
+	require.NoError(t, json.Unmarshal(bz, &decoded))
+	for _, field := range []string{"Hash", "Signer", "Signature", "SignatureCheck", "Time", "Size", "Data"} {
+		assert.Contains(t, decoded, field)
+	}
+	assert.Len(t, decoded, 7)
+}
+
+// tamperingQuerier implements txQuerier and modifies the transaction bodies
+// returned by a vfs application, like a compromised node.
+type tamperingQuerier struct {
+	localQuerier
+}
+
+func (q *tamperingQuerier) ABCIQuery(ctx context.Context, path string, data cmtbytes.HexBytes) (*coretypes.ResultABCIQuery, error) {
+	response, err := q.localQuerier.ABCIQuery(ctx, path, data)
+	if err != nil || len(response.Response.Value) == 0 {
+		return response, err
+	}
+
+	tx := new(vfsp2p.Transaction)
+	if err := proto.Unmarshal(response.Response.Value, tx); err != nil {
+		return nil, err
+	}
+
+	tx.Body[0] ^= 0xFF
+	response.Response.Value, err = tx.Marshal()
+	return response, err
+}
+
+func TestVerifyTransactionSignature(t *testing.T) {
+	ctx := context.Background()
+	idFile, _ := vfs.MustGenerateIdentity(filepath.Join(t.TempDir(), "id"), []byte("testpassword"))
+	app := vfs.NewInMemoryVStoreApplication(idFile, []byte("testpassword"))
+
+	stx := makeSignedTransactions(t, []byte("Message here"), 0)[0]
+	_, err := app.FinalizeBlock(ctx, &abci.RequestFinalizeBlock{Height: 1, Time: time.Now(), Txs: [][]byte{stx.Bytes()}})
+	require.NoError(t, err)
+	_, err = app.Commit(ctx, &abci.RequestCommit{})
+	require.NoError(t, err)
+
+	hashes := []string{hex.EncodeToString(vfs.ComputeHash(stx))}
+
+	// Transactions of an honest node are valid
+	results := queryHashes(ctx, &localQuerier{app: app}, hashes, "hex")
+	require.NotNil(t, results[0].Transaction)
+	assert.Equal(t, signatureValid, results[0].Transaction.SignatureCheck)
+
+	// Tampered bodies are flagged
+	results = queryHashes(ctx, &tamperingQuerier{localQuerier{app: app}}, hashes, "hex")
+	require.NotNil(t, results[0].Transaction)
+	assert.Equal(t, signatureInvalid, results[0].Transaction.SignatureCheck)
+
+	var buf bytes.Buffer
+	printTransactionInfo(&buf, *results[0].Transaction)
+	assert.Contains(t, buf.String(), "Signature Check: INVALID\n")
+
+	// Invalid signer public keys are flagged
+	tx := new(vfsp2p.Transaction)
+	require.NoError(t, proto.Unmarshal(stx.Bytes(), tx))
+	tx.Signer = vfs.PubKeyToProto(stx.Signer[:16])
+	assert.Equal(t, signatureInvalid, verifyTransactionSignature(tx))
+
+	// Reassembled chunked bodies are not signed as a whole
+	tx = &vfsp2p.Transaction{Body: []byte("body"), Chunk: &vfsp2p.Chunk{Total: 2}}
+	assert.Empty(t, verifyTransactionSignature(tx))
+}
+
+func TestWriteTransactionsCSV(t *testing.T) {
+	printDataAsText = true
+	defer func() { printDataAsText = false }()
+
+	// Bodies with delimiters, quotes and newlines
+	bodies := []string{"plain", "a,b\tc", "line \"one\"\nline two"}
+	txInfos := []transactionInfo{}
+	for _, body := range bodies {
+		stx := makeSignedTransactions(t, []byte(body), 0)[0]
+		txInfos = append(txInfos, newTransactionInfo(stx.ToProto()))
+	}
+
+	columns, err := parseCSVColumns("hash, Signer,time,size,data")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hash", "signer", "time", "size", "data"}, columns)
+
+	for format, comma := range tabularFormats {
+		var buf bytes.Buffer
+		require.NoError(t, writeTransactionsCSV(&buf, txInfos, columns, format))
+
+		reader := csv.NewReader(&buf)
+		reader.Comma = comma
+		records, err := reader.ReadAll()
+		require.NoError(t, err, format)
+		require.Len(t, records, len(bodies)+1, "header and one row per transaction")
+		assert.Equal(t, columns, records[0])
+
+		for i, txInfo := range txInfos {
+			assert.Equal(t, []string{
+				txInfo.Hash,
+				txInfo.Signer,
+				txInfo.Time,
+				fmt.Sprintf("%d", txInfo.Size),
+				bodies[i],
+			}, records[i+1], format)
+		}
+	}
+
+	// Columns are validated
+	_, err = parseCSVColumns("hash,body")
+	assert.ErrorContains(t, err, "unknown column")
+
+	assert.NoError(t, validateQueryFormat("csv"))
+	assert.NoError(t, validateQueryFormat("text"))
+	assert.Error(t, validateQueryFormat("xml"))
+}
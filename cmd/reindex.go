@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	vfs "github.com/securesharelabs/vstore/vfs"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	// e.g.: vstore reindex --json
+	reindexCmd.PersistentFlags().BoolVarP(
+		&printAsJSON,
+		"json",
+		"j",
+		false,
+		"Display the information in a JSON format.",
+	)
+
+	vstoreCmd.AddCommand(reindexCmd)
+}
+
+var reindexCmd = &cobra.Command{
+	Use:   "reindex",
+	Short: "Rebuild the vStore indexes from the stored transactions",
+	Long: `Rebuild the vStore indexes from the stored transactions.
+
+  All transactions are decrypted, then the height, signer, time and chunk
+  indexes, the signer transaction counts and the merkle roots of the State
+  are rebuilt from scratch, e.g. after a crash between database writes.
+  Reindexing twice produces the same indexes. The node must be stopped.`,
+
+	Example: `  vstore reindex --home /tmp/.vfs-home
+  vstore reindex --db-backend pebbledb --json`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := validateDBBackend(dbBackend); err != nil {
+			log.Fatalf("could not use provided database backend: %v", err)
+		}
+
+		// Read password to decrypt the identity and transactions
+		pw := readPassword("Enter your password: ")
+
+		db, dbPath, teardownDb, err := openDatabase("vfs", homeDir)
+		if err != nil {
+			log.Fatalf("could not open database: %v", err)
+		}
+		defer teardownDb()
+
+		app, err := vfs.NewVStoreApplicationE(db, idFile, pw)
+		if err != nil {
+			log.Fatalf("could not open vfs application: %v", err)
+		}
+
+		if err := app.Reindex(); err != nil {
+			log.Fatalf("could not reindex database: %v", err)
+		}
+
+		response, err := app.Info(cmd.Context(), &abci.RequestInfo{})
+		if err != nil {
+			log.Fatalf("could not read State: %v", err)
+		}
+
+		var state vfs.State
+		if err := json.Unmarshal([]byte(response.Data), &state); err != nil {
+			log.Fatalf("could not parse State JSON: %v", err)
+		}
+
+		reindexInfo := struct {
+			Database     string
+			Height       int64
+			Transactions int64
+			Signers      int64
+			AppHash      string
+		}{
+			dbPath,
+			state.Height,
+			state.NumTransactions,
+			int64(len(state.MerkleRoots)),
+			fmt.Sprintf("%X", response.LastBlockAppHash),
+		}
+
+		if printAsJSON {
+			json, _ := json.MarshalIndent(reindexInfo, "", "  ")
+			fmt.Print(string(json) + "\n")
+			return // Job done.
+		}
+
+		fmt.Printf("vStore %s (vfs v%d) - ABCI: \n", Version, vfs.AppVersion)
+		fmt.Printf("      Database: %s\n", reindexInfo.Database)
+		fmt.Printf("        Height: %d\n", reindexInfo.Height)
+		fmt.Printf("  Transactions: %d\n", reindexInfo.Transactions)
+		fmt.Printf("       Signers: %d\n", reindexInfo.Signers)
+		fmt.Printf("      App Hash: %s\n", reindexInfo.AppHash)
+	},
+}
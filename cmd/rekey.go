@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	vfs "github.com/securesharelabs/vstore/vfs"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	vstoreCmd.AddCommand(rekeyCmd)
+}
+
+var rekeyCmd = &cobra.Command{
+	Use:   "rekey",
+	Short: "Change the password of the vStore identity",
+	Long: `Change the password of the vStore identity by re-encrypting the identity file.
+
+  The private key is decrypted with the old password and encrypted with a new
+  password and a new salt, using the key derivation function of --kdf. The
+  public key and its .pub file are unchanged. The identity file is replaced
+  atomically.`,
+	Example: `  vstore rekey
+  vstore rekey --id /tmp/.vstore/id --kdf argon2id`,
+	Run: func(cmd *cobra.Command, args []string) {
+		kdf, err := vfs.ParseKDF(kdfName)
+		if err != nil {
+			log.Fatalf("could not rekey identity: %v", err)
+		}
+
+		oldPw := readPassword("Enter your current password: ")
+		// The new password is always prompted, not read from --password-file
+		newPw := promptPassword("Enter your new password: ")
+		if err := confirmPassword(newPw, promptPassword("Confirm your new password: ")); err != nil {
+			log.Fatalf("could not rekey identity: %v", err)
+		}
+
+		if err := passwordPolicy().Validate(newPw); err != nil {
+			log.Fatalf("could not rekey identity: %v", err)
+		}
+
+		if err := vfs.RekeyIdentity(idFile, oldPw, newPw, kdf); err != nil {
+			log.Fatalf("could not rekey identity: %v", err)
+		}
+
+		fmt.Println("Identity password successfully changed!")
+		fmt.Printf("Identity File: %s\n", idFile)
+	},
+}
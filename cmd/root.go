@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	vfs "github.com/securesharelabs/vstore/vfs"
+
+	"github.com/cometbft/cometbft/crypto/ed25519"
+	"github.com/spf13/cobra"
+)
+
+// Used for flags
+var rootPubKey string
+
+func init() {
+	// e.g.: vstore root --pubkey "1AE0F7C4...27A3"
+	merkleRootCmd.PersistentFlags().StringVar(
+		&rootPubKey,
+		"pubkey",
+		"",
+		"Public key of the signer (hexadecimal or bech32).",
+	)
+
+	// e.g.: vstore root --pubkey "1AE0F7C4...27A3" --json
+	merkleRootCmd.PersistentFlags().BoolVarP(
+		&printAsJSON,
+		"json",
+		"j",
+		false,
+		"Display the information in a JSON format.",
+	)
+
+	vstoreCmd.AddCommand(merkleRootCmd)
+}
+
+var merkleRootCmd = &cobra.Command{
+	Use:   "root",
+	Short: "Print the current merkle root of a signer",
+	Long: `Print the current merkle root of a signer, i.e. the cryptographic
+commitment of all transactions signed with the public key.
+
+  The merkle root can be anchored elsewhere, e.g. on a public blockchain.
+  The node returns a proof that the merkle root is part of the app hash of
+  the latest height, which is verified locally against the app hash that
+  is recorded by the node for this height.`,
+
+	Example: `  vstore root --pubkey "XXX"
+  vstore root --pubkey "XXX" --json`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		pbz, err := parsePubKey(rootPubKey)
+		if err != nil || len(pbz) != ed25519.PubKeySize {
+			log.Fatalf("could not use provided public key: %q", rootPubKey)
+		}
+
+		cli, err := newRPCClient(nodeAddr)
+		if err != nil {
+			log.Fatalf("could not create RPC client: %v", err)
+		}
+
+		response := executeQuery(cmd.Context(), cli, "/root", pbz)
+		if len(response.Value) == 0 || response.ProofOps == nil {
+			log.Fatalf("could not find merkle root of signer: %x", pbz)
+		}
+
+		// The proof is verified against the app hash of the response height
+		height := []byte(strconv.FormatInt(response.Height, 10))
+		appHash := executeQuery(cmd.Context(), cli, "/apphash/height", height).Value
+
+		signer := fmt.Sprintf("%X", pbz)
+		err = vfs.VerifySignerRootProof(response.ProofOps, appHash, signer, response.Value)
+
+		rootInfo := struct {
+			Signer     string
+			Height     int64
+			MerkleRoot string
+			AppHash    string
+			Valid      bool
+		}{
+			signer,
+			response.Height,
+			fmt.Sprintf("%X", response.Value),
+			fmt.Sprintf("%X", appHash),
+			err == nil,
+		}
+
+		if printAsJSON {
+			json, _ := json.MarshalIndent(rootInfo, "", "  ")
+			fmt.Print(string(json) + "\n")
+		} else {
+			fmt.Printf("vStore %s (vfs v%d) - ABCI: \n", Version, vfs.AppVersion)
+			fmt.Printf("  Signer PubKey: %s\n", rootInfo.Signer)
+			fmt.Printf("         Height: %d\n", rootInfo.Height)
+			fmt.Printf("    Merkle Root: %s\n", rootInfo.MerkleRoot)
+			fmt.Printf("       App Hash: %s\n", rootInfo.AppHash)
+			fmt.Printf("          Valid: %t\n", rootInfo.Valid)
+		}
+
+		if err != nil {
+			log.Printf("proof does not verify: %v", err)
+			os.Exit(1)
+		}
+	},
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	client "github.com/securesharelabs/vstore/client"
+
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+	rpc "github.com/cometbft/cometbft/rpc/client/http"
+)
+
+// DefaultNodeAddr is the default RPC address of the CometBFT node.
+const DefaultNodeAddr = "http://localhost:26657"
+
+// NodeTokenEnv is the environment variable of the bearer token which is sent
+// to the node when --node-token is empty.
+const NodeTokenEnv = "VSTORE_NODE_TOKEN"
+
+// DefaultNodeTimeout is the default maximum duration of an RPC call to the
+// CometBFT node.
+const DefaultNodeTimeout = 15 * time.Second
+
+// Used for flags
+var nodeAuth client.Auth
+var nodeTimeout time.Duration
+
+func init() {
+	// e.g.: vstore query --hash "XXX" --node http://10.0.0.1:26657 --timeout 5s
+	vstoreCmd.PersistentFlags().DurationVar(
+		&nodeTimeout,
+		"timeout",
+		DefaultNodeTimeout,
+		"Maximum duration of every RPC call to the node (if 0, waits indefinitely)",
+	)
+
+	// e.g.: vstore query --hash "XXX" --node https://vstore.example.com --node-token TOKEN
+	vstoreCmd.PersistentFlags().StringVar(
+		&nodeAuth.Token,
+		"node-token",
+		"",
+		"Bearer token sent to the node (if empty, uses $"+NodeTokenEnv+")",
+	)
+
+	// e.g.: vstore query --hash "XXX" --node-cert client.pem --node-key client.key
+	vstoreCmd.PersistentFlags().StringVar(
+		&nodeAuth.CertFile,
+		"node-cert",
+		"",
+		"Client certificate (PEM) presented to the node (mutual TLS)",
+	)
+
+	vstoreCmd.PersistentFlags().StringVar(
+		&nodeAuth.KeyFile,
+		"node-key",
+		"",
+		"Private key (PEM) of the client certificate (mutual TLS)",
+	)
+
+	// e.g.: vstore query --hash "XXX" --node-ca ca.pem
+	vstoreCmd.PersistentFlags().StringVar(
+		&nodeAuth.CAFile,
+		"node-ca",
+		"",
+		"Certificate authorities (PEM) trusted to sign the node certificate",
+	)
+}
+
+// newRPCClient creates an RPC client for the CometBFT node at addr. The
+// address must be a valid URL with one of the schemes http, https, tcp
+// or unix, an error is returned otherwise. The credentials of the node
+// flags are presented to nodes which require authentication.
+func newRPCClient(addr string) (*rpc.HTTP, error) {
+	auth := nodeAuth
+	if auth.Token == "" {
+		auth.Token = os.Getenv(NodeTokenEnv)
+	}
+
+	cli, err := client.NewHTTPWithAuth(addr, auth)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := cmtlog.NewTMLogger(cmtlog.NewSyncWriter(os.Stdout))
+	cli.SetLogger(logger)
+	return cli, nil
+}
+
+// withNodeTimeout returns a copy of ctx which is cancelled after the duration
+// of --timeout, such that RPC calls to a hung or unreachable node fail. The
+// caller must call the cancel function after the RPC call.
+func withNodeTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if nodeTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, nodeTimeout)
+}
+
+// nodeError returns a clear error if an RPC call failed because the node did
+// not respond within --timeout, or err otherwise.
+func nodeError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("node did not respond within %s", nodeTimeout)
+	}
+
+	return err
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cometbft/cometbft/crypto/tmhash"
+)
+
+func TestNodeTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { nodeTimeout = timeout }(nodeTimeout)
+	nodeTimeout = 100 * time.Millisecond
+
+	// The node accepts connections but never responds
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	cli, err := newRPCClient(server.URL)
+	require.NoError(t, err)
+
+	ctx, cancel := withNodeTimeout(context.Background())
+	defer cancel()
+
+	start := time.Now()
+	_, err = cli.ABCIInfo(ctx)
+	assert.EqualError(t, nodeError(err), "node did not respond within 100ms")
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	// Every RPC call is limited by --timeout
+	_, err = broadcastWithMode(context.Background(), cli, broadcastModeSync, []byte("signed transaction"))
+	assert.EqualError(t, err, "node did not respond within 100ms")
+
+	hash := hex.EncodeToString(tmhash.Sum([]byte("hash")))
+	results := queryHashes(context.Background(), cli, []string{hash}, "hex")
+	assert.False(t, results[0].Found)
+	assert.Contains(t, results[0].Error, "node did not respond within 100ms")
+
+	// Other errors are unchanged
+	assert.Equal(t, context.Canceled, nodeError(context.Canceled))
+	assert.Nil(t, nodeError(nil))
+}
+
+func TestNodeTimeoutDisabled(t *testing.T) {
+	defer func(timeout time.Duration) { nodeTimeout = timeout }(nodeTimeout)
+	nodeTimeout = 0
+
+	ctx, cancel := withNodeTimeout(context.Background())
+	_, ok := ctx.Deadline()
+	assert.False(t, ok, "no deadline without --timeout")
+
+	cancel()
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+}
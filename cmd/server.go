@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	abciserver "github.com/cometbft/cometbft/abci/server"
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/libs/service"
+)
+
+const (
+	// transportSocket serves ABCI with the CometBFT socket protocol.
+	transportSocket = "socket"
+
+	// transportGRPC serves ABCI with gRPC.
+	transportGRPC = "grpc"
+)
+
+// parseABCIAddr splits an ABCI server address in its protocol and address.
+// The address must use the unix:// scheme with a socket path, or the tcp://
+// scheme with a host and a port, e.g. "tcp://0.0.0.0:26658".
+func parseABCIAddr(addr string) (string, string, error) {
+	proto, address, ok := strings.Cut(addr, "://")
+	if !ok {
+		return "", "", fmt.Errorf("missing scheme in ABCI address %q (expected unix:// or tcp://)", addr)
+	}
+
+	switch proto {
+	case "unix":
+		if address == "" {
+			return "", "", fmt.Errorf("missing socket path in ABCI address %q", addr)
+		}
+	case "tcp":
+		// Empty hosts listen on all interfaces, e.g. "tcp://:26658"
+		_, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid ABCI address %q: %v", addr, err)
+		}
+
+		if port == "" {
+			return "", "", fmt.Errorf("missing port in ABCI address %q", addr)
+		}
+	default:
+		return "", "", fmt.Errorf("unsupported scheme %q in ABCI address (expected unix:// or tcp://)", proto)
+	}
+
+	return proto, address, nil
+}
+
+// newABCIServer creates the ABCI server of app for an address and a transport,
+// i.e. transportSocket or transportGRPC. Both transports accept unix:// and
+// tcp:// addresses.
+func newABCIServer(addr, transport string, app abci.Application) (service.Service, error) {
+	if _, _, err := parseABCIAddr(addr); err != nil {
+		return nil, err
+	}
+
+	switch transport {
+	case transportSocket, transportGRPC:
+		return abciserver.NewServer(addr, transport, app)
+	default:
+		return nil, fmt.Errorf("unsupported transport %q (expected %s or %s)", transport, transportSocket, transportGRPC)
+	}
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+)
+
+func TestParseABCIAddr(t *testing.T) {
+	valid := map[string][2]string{
+		"unix://vfs.sock":        {"unix", "vfs.sock"},
+		"unix:///tmp/vfs.sock":   {"unix", "/tmp/vfs.sock"},
+		"tcp://127.0.0.1:26658":  {"tcp", "127.0.0.1:26658"},
+		"tcp://0.0.0.0:26658":    {"tcp", "0.0.0.0:26658"},
+		"tcp://:26658":           {"tcp", ":26658"},
+		"tcp://[::1]:26658":      {"tcp", "[::1]:26658"},
+		"tcp://vstore-app:26658": {"tcp", "vstore-app:26658"},
+	}
+
+	for addr, want := range valid {
+		proto, address, err := parseABCIAddr(addr)
+		require.NoError(t, err, addr)
+		assert.Equal(t, want[0], proto, addr)
+		assert.Equal(t, want[1], address, addr)
+	}
+
+	invalid := []string{
+		"",
+		"vfs.sock",
+		"unix://",
+		"tcp://127.0.0.1",
+		"tcp://127.0.0.1:",
+		"http://127.0.0.1:26658",
+		"udp://127.0.0.1:26658",
+	}
+
+	for _, addr := range invalid {
+		_, _, err := parseABCIAddr(addr)
+		assert.Error(t, err, addr)
+	}
+}
+
+func TestNewABCIServer(t *testing.T) {
+	app := abci.NewBaseApplication()
+	socketFile := filepath.Join(t.TempDir(), "vfs.sock")
+
+	for _, transport := range []string{transportSocket, transportGRPC} {
+		for _, addr := range []string{"tcp://127.0.0.1:0", "unix://" + socketFile} {
+			server, err := newABCIServer(addr, transport, app)
+			require.NoError(t, err, "%s %s", transport, addr)
+			require.NoError(t, server.Start(), "%s %s", transport, addr)
+			require.NoError(t, server.Stop(), "%s %s", transport, addr)
+		}
+	}
+
+	// Servers accept connections on TCP addresses
+	addr := freeTCPAddr(t)
+	server, err := newABCIServer("tcp://"+addr, transportSocket, app)
+	require.NoError(t, err)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	conn.Close()
+
+	// Unsupported transports and schemes
+	_, err = newABCIServer("tcp://127.0.0.1:26658", "http", app)
+	assert.Error(t, err)
+	_, err = newABCIServer("http://127.0.0.1:26658", transportGRPC, app)
+	assert.Error(t, err)
+}
+
+// freeTCPAddr returns a local TCP address that is free to listen on.
+func freeTCPAddr(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	return ln.Addr().String()
+}
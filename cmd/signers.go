@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+
+	vfs "github.com/securesharelabs/vstore/vfs"
+
+	"github.com/spf13/cobra"
+)
+
+// Used for flags
+var signersLimit int
+var signersAfter string
+
+func init() {
+	// e.g.: vstore signers --limit 100
+	signersCmd.PersistentFlags().IntVar(
+		&signersLimit,
+		"limit",
+		0,
+		"Maximum number of signers to print (if empty, prints all signers).",
+	)
+
+	// e.g.: vstore signers --after "1AE0F7C4...27A3"
+	signersCmd.PersistentFlags().StringVar(
+		&signersAfter,
+		"after",
+		"",
+		"Print the signers after this public key (cursor of the previous page).",
+	)
+
+	// e.g.: vstore signers --json
+	signersCmd.PersistentFlags().BoolVarP(
+		&printAsJSON,
+		"json",
+		"j",
+		false,
+		"Display the information in a JSON format (includes transaction counts).",
+	)
+
+	vstoreCmd.AddCommand(signersCmd)
+}
+
+var signersCmd = &cobra.Command{
+	Use:   "signers",
+	Short: "Print the public keys of all signers of your vStore instance",
+	Long: `Print the public keys of all signers which committed transactions
+to your vStore instance, sorted by public key.
+
+  Public keys are printed in hexadecimal, one per line, or in bech32 with
+  --pubkey-encoding bech32. With --json, the number of transactions of
+  every signer is printed as well. Signers are fetched in pages, use
+  --limit and --after to print a single page.`,
+
+	Example: `  vstore signers
+  vstore signers --json
+  vstore signers --pubkey-encoding bech32
+  vstore signers --limit 100 --after "XXX"`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := validatePubKeyDisplay(pubKeyDisplay); err != nil {
+			log.Fatalf("could not use provided public key encoding: %v", err)
+		}
+
+		cli, err := newRPCClient(nodeAddr)
+		if err != nil {
+			log.Fatalf("could not create RPC client: %v", err)
+		}
+
+		list := vfs.SignerList{Signers: []vfs.SignerInfo{}}
+		after := signersAfter
+		for {
+			limit := vfs.MaxQueryLimit
+			if signersLimit > 0 {
+				limit = min(signersLimit-len(list.Signers), vfs.MaxQueryLimit)
+			}
+
+			response := executeQuery(cmd.Context(), cli, signersQueryPath(limit, after), []byte{})
+
+			page := vfs.SignerList{}
+			if err := json.Unmarshal(response.Value, &page); err != nil {
+				log.Fatalf("could not parse signers JSON: %v", err)
+			}
+
+			list.Signers = append(list.Signers, page.Signers...)
+			list.Total, list.Next = page.Total, page.Next
+			if page.Next == "" || (signersLimit > 0 && len(list.Signers) >= signersLimit) {
+				break
+			}
+
+			after = page.Next
+		}
+
+		// Public keys are returned in hex by the node, cursors stay in hex
+		if pubKeyDisplay == "bech32" {
+			for i, signer := range list.Signers {
+				if pbz, err := hex.DecodeString(signer.PubKey); err == nil {
+					list.Signers[i].PubKey = formatPubKey(pbz)
+				}
+			}
+		}
+
+		if printAsJSON {
+			json, _ := json.MarshalIndent(list, "", "  ")
+			fmt.Print(string(json) + "\n")
+			return // Job done.
+		}
+
+		for _, signer := range list.Signers {
+			fmt.Println(signer.PubKey)
+		}
+	},
+}
+
+// signersQueryPath returns the path of a query of a page of signers.
+func signersQueryPath(limit int, after string) string {
+	params := url.Values{}
+	params.Set("limit", strconv.Itoa(limit))
+	if len(after) > 0 {
+		params.Set("after", after)
+	}
+
+	return "/signers?" + params.Encode()
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	vfs "github.com/securesharelabs/vstore/vfs"
+
+	"github.com/spf13/cobra"
+)
+
+// Used for flags
+var snapshotOut string
+var restoreIn string
+
+func init() {
+	// e.g.: vstore snapshot --out vstore.snapshot
+	snapshotCmd.PersistentFlags().StringVar(
+		&snapshotOut,
+		"out",
+		"",
+		"Path of the snapshot archive to write (must not exist).",
+	)
+	snapshotCmd.MarkPersistentFlagRequired("out")
+
+	// e.g.: vstore restore --in vstore.snapshot
+	restoreCmd.PersistentFlags().StringVar(
+		&restoreIn,
+		"in",
+		"",
+		"Path of the snapshot archive to restore.",
+	)
+	restoreCmd.MarkPersistentFlagRequired("in")
+
+	for _, cmd := range []*cobra.Command{snapshotCmd, restoreCmd} {
+		// e.g.: vstore snapshot --out vstore.snapshot --json
+		cmd.PersistentFlags().BoolVarP(
+			&printAsJSON,
+			"json",
+			"j",
+			false,
+			"Display the information in a JSON format.",
+		)
+
+		vstoreCmd.AddCommand(cmd)
+	}
+}
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Write a snapshot archive of the vStore database for backups",
+	Long: `Write a snapshot archive of the vStore database for backups.
+
+  The archive contains all transactions, which are still encrypted, the
+  indexes and the State, such that the identity password is not necessary.
+  Archives are portable across database backends and are loaded with
+  vstore restore. The node must be stopped.
+
+  Note: The identity file is not part of the archive, see --backup-dir.`,
+
+	Example: `  vstore snapshot --out vstore.snapshot --home /tmp/.vfs-home
+  vstore snapshot --out vstore.snapshot --db-backend pebbledb --json`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		db, dbPath, teardownDb, err := openDatabase("vfs", homeDir)
+		if err != nil {
+			log.Fatalf("could not open database: %v", err)
+		}
+		defer teardownDb()
+
+		// Existing archives are never overwritten
+		file, err := os.OpenFile(snapshotOut, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			log.Fatalf("could not create snapshot archive: %v", err)
+		}
+
+		info, err := vfs.SnapshotDB(db, file)
+		if err == nil {
+			err = file.Sync()
+		}
+
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+
+		if err != nil {
+			os.Remove(snapshotOut)
+			log.Fatalf("could not write snapshot archive: %v", err)
+		}
+
+		printSnapshotInfo(dbPath, snapshotOut, info)
+	},
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore the vStore database from a snapshot archive",
+	Long: `Restore the vStore database from a snapshot archive.
+
+  The archive is loaded into a fresh database of the --db-backend backend,
+  i.e. the database must be empty. The number of keys and the app hash of
+  the restored State are compared with the archive. The node must be stopped.
+  The database is left empty if the archive is corrupt.
+
+  The node requires the identity that wrote the transactions of the archive.`,
+
+	Example: `  vstore restore --in vstore.snapshot --home /tmp/.vfs-home
+  vstore restore --in vstore.snapshot --db-backend badgerdb --json`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		file, err := os.Open(restoreIn)
+		if err != nil {
+			log.Fatalf("could not open snapshot archive: %v", err)
+		}
+		defer file.Close()
+
+		db, dbPath, teardownDb, err := openDatabase("vfs", homeDir)
+		if err != nil {
+			log.Fatalf("could not open database: %v", err)
+		}
+		defer teardownDb()
+
+		// The database is left empty on errors, i.e. the restore can be retried
+		info, err := vfs.RestoreDB(file, db)
+		if err != nil {
+			teardownDb()
+			log.Fatalf("could not restore snapshot archive: %v", err)
+		}
+
+		printSnapshotInfo(dbPath, restoreIn, info)
+	},
+}
+
+// printSnapshotInfo prints the information of a snapshot archive.
+func printSnapshotInfo(dbPath, archive string, info vfs.SnapshotInfo) {
+	snapshotInfo := struct {
+		Database string
+		Archive  string
+		Height   int64
+		AppHash  string
+		Keys     int64
+		Bytes    int64
+	}{
+		fmt.Sprintf("%s (%s)", dbPath, dbBackend),
+		archive,
+		info.Height,
+		fmt.Sprintf("%X", info.AppHash),
+		info.Keys,
+		info.Bytes,
+	}
+
+	if printAsJSON {
+		json, _ := json.MarshalIndent(snapshotInfo, "", "  ")
+		fmt.Print(string(json) + "\n")
+		return // Job done.
+	}
+
+	fmt.Printf("vStore %s (vfs v%d) - ABCI: \n", Version, vfs.AppVersion)
+	fmt.Printf("  Database: %s\n", snapshotInfo.Database)
+	fmt.Printf("   Archive: %s\n", snapshotInfo.Archive)
+	fmt.Printf("    Height: %d\n", snapshotInfo.Height)
+	fmt.Printf("  App Hash: %s\n", snapshotInfo.AppHash)
+	fmt.Printf("      Keys: %d (%d bytes)\n", snapshotInfo.Keys, snapshotInfo.Bytes)
+}
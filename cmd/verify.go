@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	vfsp2p "github.com/securesharelabs/vstore/api/vstore/v1"
+	vfs "github.com/securesharelabs/vstore/vfs"
+
+	rpcclient "github.com/cometbft/cometbft/rpc/client"
+
+	"github.com/cosmos/gogoproto/proto"
+	"github.com/spf13/cobra"
+)
+
+// Used for flags
+var verifyHash string
+var verifyAppHash string
+var verifyHeight int64
+var verifyIntegrity bool
+
+func init() {
+	// e.g.: vstore verify --hash "3816D803...9E03"
+	verifyCmd.PersistentFlags().StringVar(
+		&verifyHash,
+		"hash",
+		"",
+		"Hash of the transaction to verify.",
+	)
+
+	// e.g.: vstore verify --hash "3816D803...9E03" --app-hash "C5D2460E...8F0B"
+	verifyCmd.PersistentFlags().StringVar(
+		&verifyAppHash,
+		"app-hash",
+		"",
+		"App hash recorded by the client (hexadecimal), the proof must verify against it.",
+	)
+
+	// e.g.: vstore verify --hash "3816D803...9E03" --app-hash "C5D2460E...8F0B" --height 1234
+	verifyCmd.PersistentFlags().Int64Var(
+		&verifyHeight,
+		"height",
+		0,
+		"Block height of the app hash (if empty, uses the latest height).",
+	)
+
+	// e.g.: vstore verify --integrity --home /tmp/.vfs-home
+	verifyCmd.PersistentFlags().BoolVar(
+		&verifyIntegrity,
+		"integrity",
+		false,
+		"Verify the local State against the stored transactions (the node must be stopped).",
+	)
+
+	// e.g.: vstore verify --hash "3816D803...9E03" --app-hash "C5D2460E...8F0B" --json
+	verifyCmd.PersistentFlags().BoolVarP(
+		&printAsJSON,
+		"json",
+		"j",
+		false,
+		"Display the information in a JSON format.",
+	)
+
+	vstoreCmd.AddCommand(verifyCmd)
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify a transaction against a recorded app hash",
+	Long: `Verify that a transaction is part of a recorded app hash.
+
+  The inclusion proof of the transaction is fetched from the node for the
+  block height of the app hash, and is verified locally against the app hash
+  provided with --app-hash. The app hash of a height is committed in the
+  block header of the next height.
+
+  With --integrity, the local database is verified instead: all transactions
+  are decrypted and the merkle roots of every signer are recomputed, then
+  compared with the merkle roots and the app hash of the State.`,
+
+	Example: `  vstore verify --hash "XXX" --app-hash "XXX"
+  vstore verify --hash "XXX" --app-hash "XXX" --height 1234
+  vstore verify --integrity --home /tmp/.vfs-home`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		if verifyIntegrity {
+			runVerifyIntegrity()
+			return
+		}
+
+		if verifyHash == "" || verifyAppHash == "" {
+			log.Fatalf("could not verify transaction: --hash and --app-hash are required")
+		}
+
+		hbz, err := hex.DecodeString(verifyHash)
+		if err != nil {
+			log.Fatalf("could not use provided transaction hash: %v", err)
+		}
+
+		appHash, err := hex.DecodeString(verifyAppHash)
+		if err != nil || len(appHash) == 0 {
+			log.Fatalf("could not use provided app hash: %v", err)
+		}
+
+		cli, err := newRPCClient(nodeAddr)
+		if err != nil {
+			log.Fatalf("could not create RPC client: %v", err)
+		}
+
+		// Fetch the transaction and its proof for the height
+		ctx, cancel := withNodeTimeout(cmd.Context())
+		response, err := cli.ABCIQueryWithOptions(ctx, "/hash", hbz, rpcclient.ABCIQueryOptions{
+			Height: verifyHeight,
+			Prove:  true,
+		})
+		cancel()
+		if err != nil {
+			log.Fatalf("error occured on query: %v", nodeError(err))
+		}
+
+		if response.Response.Code != vfs.CodeTypeOK {
+			log.Fatalf("error occured on query: %s (%d - %s)",
+				vfs.CodeToString(response.Response.Code), response.Response.Code, response.Response.Log)
+		}
+
+		if len(response.Response.Value) == 0 || response.Response.ProofOps == nil {
+			log.Fatalf("could not find proof for transaction with hash: %x", hbz)
+		}
+
+		tx := new(vfsp2p.Transaction)
+		if err := proto.Unmarshal(response.Response.Value, tx); err != nil {
+			log.Fatalf("could not parse Transaction bytes: %v", err)
+		}
+
+		signer := fmt.Sprintf("%X", tx.Signer.GetEd25519())
+		err = vfs.VerifyTransactionProof(response.Response.ProofOps, appHash, signer, hbz)
+
+		verifyInfo := struct {
+			Hash    string
+			Signer  string
+			Height  int64
+			AppHash string
+			Valid   bool
+		}{
+			fmt.Sprintf("%X", hbz),
+			signer,
+			response.Response.Height,
+			fmt.Sprintf("%X", appHash),
+			err == nil,
+		}
+
+		if printAsJSON {
+			json, _ := json.MarshalIndent(verifyInfo, "", "  ")
+			fmt.Print(string(json) + "\n")
+		} else {
+			fmt.Printf("vStore %s (vfs v%d) - ABCI: \n", Version, vfs.AppVersion)
+			fmt.Printf("  Transaction Hash: %s\n", verifyInfo.Hash)
+			fmt.Printf("     Signer PubKey: %s\n", verifyInfo.Signer)
+			fmt.Printf("            Height: %d\n", verifyInfo.Height)
+			fmt.Printf("          App Hash: %s\n", verifyInfo.AppHash)
+			fmt.Printf("             Valid: %t\n", verifyInfo.Valid)
+		}
+
+		if err != nil {
+			log.Printf("proof does not verify: %v", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// runVerifyIntegrity opens the local database and verifies the integrity of
+// the State. Every mismatch is printed, the process exits with 1 if any.
+func runVerifyIntegrity() {
+	if err := validateDBBackend(dbBackend); err != nil {
+		log.Fatalf("could not use provided database backend: %v", err)
+	}
+
+	// Read password to decrypt the identity and transactions
+	pw := readPassword("Enter your password: ")
+
+	db, _, teardownDb, err := openDatabase("vfs", homeDir)
+	if err != nil {
+		log.Fatalf("could not open database: %v", err)
+	}
+	defer teardownDb()
+
+	app, err := vfs.NewVStoreApplicationE(db, idFile, pw)
+	if err != nil {
+		log.Fatalf("could not open vfs application: %v", err)
+	}
+
+	mismatches := []vfs.IntegrityMismatch{}
+	if err := app.VerifyIntegrity(); err != nil {
+		var integrityErr *vfs.IntegrityError
+		if !errors.As(err, &integrityErr) {
+			log.Fatalf("could not verify integrity: %v", err)
+		}
+
+		mismatches = integrityErr.Mismatches
+	}
+
+	if printAsJSON {
+		json, _ := json.MarshalIndent(struct {
+			Valid      bool
+			Mismatches []vfs.IntegrityMismatch
+		}{len(mismatches) == 0, mismatches}, "", "  ")
+		fmt.Print(string(json) + "\n")
+	} else {
+		fmt.Printf("vStore %s (vfs v%d) - ABCI: \n", Version, vfs.AppVersion)
+		fmt.Printf("  Valid: %t\n", len(mismatches) == 0)
+		for _, m := range mismatches {
+			fmt.Printf("  Mismatch: %s\n", m.Reason)
+			if m.Signer != "" {
+				fmt.Printf("    Signer: %s\n", m.Signer)
+			}
+
+			if len(m.Hash) > 0 {
+				fmt.Printf("      Hash: %X\n", m.Hash)
+			}
+
+			if len(m.Expected) > 0 || len(m.Actual) > 0 {
+				fmt.Printf("  Expected: %X\n", m.Expected)
+				fmt.Printf("    Actual: %X\n", m.Actual)
+			}
+		}
+	}
+
+	if len(mismatches) > 0 {
+		// Deferred teardown is skipped by os.Exit
+		teardownDb()
+		os.Exit(1)
+	}
+}
@@ -1,14 +1,47 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/debug"
 
 	vfs "github.com/securesharelabs/vstore/vfs"
 
+	"github.com/cometbft/cometbft/version"
 	"github.com/spf13/cobra"
 )
 
+// Build metadata, injected with ldflags in release builds, e.g.:
+//
+//	go build -ldflags "-X github.com/securesharelabs/vstore/cmd.Version=v1.0.1"
+//
+// The defaults describe a development build.
+var (
+	// Version is the version of vStore, displayed in the command outputs.
+	Version = "v1.0-dev"
+
+	// GitCommit is the git commit of the build. If empty, the VCS revision
+	// recorded by the Go toolchain is used, or "dev".
+	GitCommit = ""
+
+	// BuildDate is the date of the build, e.g. in RFC3339 format. If empty,
+	// "dev" is displayed.
+	BuildDate = ""
+)
+
 func init() {
+	// e.g.: vstore version --json
+	versionCmd.PersistentFlags().BoolVarP(
+		&printAsJSON,
+		"json",
+		"j",
+		false,
+		"Display the information in a JSON format.",
+	)
+
 	vstoreCmd.AddCommand(versionCmd)
 }
 
@@ -16,7 +49,62 @@ var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print the version number of vStore",
 	Long:  `Print the version number of vStore.`,
+	Example: `  vstore version
+  vstore version --json`,
+
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("vStore v1.0 (vfs v%d)\n", vfs.AppVersion)
+		printVersion(os.Stdout, newVersionInfo())
 	},
 }
+
+// versionInfo describes the version and the build metadata of vStore.
+type versionInfo struct {
+	VStore    string `json:"vstore"`
+	VFS       uint64 `json:"vfs"`
+	ABCI      string `json:"abci"`
+	Go        string `json:"go"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+}
+
+// newVersionInfo returns the version and the build metadata of vStore.
+func newVersionInfo() versionInfo {
+	commit := GitCommit
+	if len(commit) == 0 {
+		commit = "dev"
+		if info, ok := debug.ReadBuildInfo(); ok {
+			for _, setting := range info.Settings {
+				if setting.Key == "vcs.revision" {
+					commit = setting.Value
+				}
+			}
+		}
+	}
+
+	buildDate := BuildDate
+	if len(buildDate) == 0 {
+		buildDate = "dev"
+	}
+
+	return versionInfo{
+		VStore:    Version,
+		VFS:       vfs.AppVersion,
+		ABCI:      version.ABCIVersion,
+		Go:        runtime.Version(),
+		Commit:    commit,
+		BuildDate: buildDate,
+	}
+}
+
+// printVersion prints the version information to w, using the JSON format if
+// requested.
+func printVersion(w io.Writer, info versionInfo) {
+	if printAsJSON {
+		json, _ := json.Marshal(info)
+		fmt.Fprint(w, string(json)+"\n")
+		return // Job done.
+	}
+
+	fmt.Fprintf(w, "vStore %s (vfs v%d)\n", info.VStore, info.VFS)
+	fmt.Fprintf(w, "  commit: %s, built: %s\n", info.Commit, info.BuildDate)
+}
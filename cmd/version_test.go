@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	vfs "github.com/securesharelabs/vstore/vfs"
+)
+
+func TestPrintVersion(t *testing.T) {
+	defer func(commit, date string) { GitCommit, BuildDate = commit, date }(GitCommit, BuildDate)
+	GitCommit, BuildDate = "6d14afc", "2024-06-04T12:00:00Z"
+
+	// Plain output is the default
+	var buf bytes.Buffer
+	printVersion(&buf, newVersionInfo())
+	assert.Equal(t, fmt.Sprintf("vStore %s (vfs v%d)\n  commit: 6d14afc, built: 2024-06-04T12:00:00Z\n", Version, vfs.AppVersion), buf.String())
+
+	defer func(asJSON bool) { printAsJSON = asJSON }(printAsJSON)
+	printAsJSON = true
+
+	buf.Reset()
+	printVersion(&buf, newVersionInfo())
+
+	decoded := map[string]any{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	for _, key := range []string{"vstore", "vfs", "abci", "go", "commit", "build_date"} {
+		assert.Contains(t, decoded, key)
+	}
+
+	assert.Equal(t, Version, decoded["vstore"])
+	assert.EqualValues(t, vfs.AppVersion, decoded["vfs"])
+	assert.Equal(t, runtime.Version(), decoded["go"])
+	assert.Equal(t, "6d14afc", decoded["commit"])
+}
+
+func TestVersionDefaults(t *testing.T) {
+	defer func(commit, date string) { GitCommit, BuildDate = commit, date }(GitCommit, BuildDate)
+	GitCommit, BuildDate = "", ""
+
+	// Development builds are not built with ldflags
+	info := newVersionInfo()
+	assert.Equal(t, "v1.0-dev", info.VStore)
+	assert.NotEmpty(t, info.Commit)
+	assert.Equal(t, "dev", info.BuildDate)
+
+	var buf bytes.Buffer
+	printVersion(&buf, info)
+	assert.Contains(t, buf.String(), "vStore v1.0-dev")
+	assert.Contains(t, buf.String(), "built: dev")
+}
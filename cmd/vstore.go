@@ -1,30 +1,61 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
+	"time"
 
 	vfs "github.com/securesharelabs/vstore/vfs"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/cobra"
 
-	abciserver "github.com/cometbft/cometbft/abci/server"
-
 	cmtdb "github.com/cometbft/cometbft-db"
 	cmtlog "github.com/cometbft/cometbft/libs/log"
-
-	"golang.org/x/term"
+	"github.com/cometbft/cometbft/libs/service"
 )
 
 var (
 	// Used for flags.
 	homeDir    string
 	socketAddr string
+	transport  string
 	idFile     string
+	pwFile     string
+	blobDir    string
+	backupDir  string
+	nodeAddr   string
+	kdfName    string
+	dbBackend  string
+
+	bodySchema   string
+	eventWebhook string
+	eventFormat  string
+	metricsAddr  string
+	compression  string
+
+	enforcePasswordPolicy bool
+	allowWeakPassword     bool
+	legacySignatures      bool
+	statefulCheckTx       bool
+	plaintext             bool
+	observer              bool
+
+	finalizeWorkers int
+	shutdownTimeout time.Duration
+
+	snapshotInterval   int64
+	snapshotKeepRecent int
+
+	retentionMaxAge   time.Duration
+	retentionMaxBytes int64
+	retentionInterval int64
 
 	// e.g. vstore --home /tmp/.vfs-home
 	vstoreCmd = &cobra.Command{
@@ -40,21 +71,31 @@ var (
 
 		Example: `  vstore
   vstore version
-  vstore --home /tmp/.vstore --socket unix://vfs.sock --id /tmp/.vstore/id`,
+  vstore --home /tmp/.vstore --socket unix://vfs.sock --id /tmp/.vstore/id
+  vstore --socket tcp://0.0.0.0:26658 --transport grpc
+  vstore --db-backend memdb
+  vstore --metrics-addr 127.0.0.1:26660`,
 
 		Run: func(cmd *cobra.Command, args []string) {
+			if err := validateDBBackend(dbBackend); err != nil {
+				log.Fatalf("could not use provided database backend: %v", err)
+			}
 
-			// Read password to encrypt/decrypt identity file
-			fmt.Printf("Enter your password: ")
-			pw, err := term.ReadPassword(0)
+			codec, err := vfs.ParseCompression(compression)
 			if err != nil {
-				log.Fatalf("could not read password: %v", err)
+				log.Fatalf("could not use provided compression: %v", err)
+			}
+
+			// Observers run without the identity, state sync snapshots record it
+			if observer && snapshotInterval > 0 {
+				log.Fatalf("could not use state sync snapshots: not available in observer mode")
 			}
-			fmt.Printf("\n")
 
-			// Generate and encrypt identity if necessary
-			if _, err := os.Stat(idFile); os.IsNotExist(err) {
-				vfs.MustGenerateIdentity(idFile, pw)
+			// Read password to encrypt/decrypt identity file, the identity is
+			// generated and encrypted if necessary
+			var pw []byte
+			if !observer {
+				pw = readIdentityPassword(idFile)
 			}
 
 			// Open database connection
@@ -63,29 +104,143 @@ var (
 				log.Fatalf("could not open database: %v", err)
 			}
 
-			defer teardownDb()
+			if dbBackend == string(cmtdb.MemDBBackend) {
+				log.Printf("using database: memdb (ephemeral, data is lost on shutdown)")
+			} else {
+				log.Printf("using database: %s (%s)", dbPath, dbBackend)
+			}
+
+			// Recent log entries are kept for the logs command
+			logger := vfs.NewBufferedLogger(
+				cmtlog.NewTMLogger(cmtlog.NewSyncWriter(os.Stdout)),
+				vfs.DefaultLogBufferSize,
+			)
+
+			// Legacy signatures do not cover the timestamp, new transactions
+			// are compressed before they are encrypted and large blocks are
+			// parsed in parallel, results are in block order
+			opts := []vfs.Option{
+				vfs.WithLogger(logger),
+				vfs.WithLegacySignatures(legacySignatures),
+				vfs.WithCompression(codec),
+				vfs.WithFinalizeWorkers(finalizeWorkers),
+			}
+
+			// Stateful CheckTx reads the database (duplicate hashes)
+			if statefulCheckTx {
+				opts = append(opts, vfs.WithCheckTxMode(vfs.CheckTxStateful))
+			}
+
+			// Store new transactions unencrypted, e.g. public records
+			if plaintext {
+				opts = append(opts, vfs.WithPlaintext(true))
+				log.Printf("using plaintext storage: new transactions are not encrypted")
+			}
+
+			// Write state sync snapshots, other nodes with the same identity
+			// restore them rather than replaying all blocks
+			snapshotDir := filepath.Join(homeDir, "snapshots")
+			opts = append(opts, vfs.WithSnapshots(snapshotDir, snapshotInterval, snapshotKeepRecent))
+			if snapshotInterval > 0 {
+				log.Printf("using state sync snapshots: %s (every %d blocks)", snapshotDir, snapshotInterval)
+			}
+
+			// Prune the bodies of old transactions, commitments are kept
+			opts = append(opts, vfs.WithRetention(vfs.RetentionPolicy{
+				MaxAge:   retentionMaxAge,
+				MaxBytes: retentionMaxBytes,
+			}, retentionInterval))
+			if retentionInterval > 0 && (retentionMaxAge > 0 || retentionMaxBytes > 0) {
+				log.Printf("using retention policy: max age %s, max bytes %d (every %d blocks)", retentionMaxAge, retentionMaxBytes, retentionInterval)
+			}
+
+			// Resolve body references with the external storage
+			if blobDir != "" {
+				opts = append(opts, vfs.WithBlobStore(vfs.NewDirBlobStore(blobDir)))
+				log.Printf("using blob store: %s", blobDir)
+			}
+
+			// Validate JSON bodies with a schema in CheckTx
+			if bodySchema != "" {
+				schema, err := vfs.LoadJSONSchema(bodySchema)
+				if err != nil {
+					log.Fatalf("could not load body schema: %v", err)
+				}
 
-			log.Printf("using database: %s", dbPath)
+				opts = append(opts, vfs.WithBodyValidator(schema))
+				log.Printf("using body schema: %s", bodySchema)
+			}
 
-			// Prepare the vfs application
-			app := vfs.NewVStoreApplication(db, idFile, pw)
+			// Emit an event for committed transactions
+			if eventWebhook != "" {
+				format, err := vfs.ParseEventFormat(eventFormat)
+				if err != nil {
+					log.Fatalf("could not emit events: %v", err)
+				}
+
+				opts = append(opts, vfs.WithEventSink(vfs.NewWebhookSink(eventWebhook), format))
+				log.Printf("using event webhook: %s (%s)", eventWebhook, format)
+			}
+
+			// Serve Prometheus metrics
+			if metricsAddr != "" {
+				registry := prometheus.NewRegistry()
+				metrics, err := vfs.NewMetrics(registry)
+				if err != nil {
+					log.Fatalf("could not register metrics: %v", err)
+				}
+
+				teardownMetrics, err := serveMetrics(metricsAddr, registry)
+				if err != nil {
+					log.Fatalf("error starting metrics server: %v", err)
+				}
+				defer teardownMetrics()
+
+				opts = append(opts, vfs.WithMetrics(metrics))
+				log.Printf("using metrics: http://%s%s", metricsAddr, metricsPath)
+			}
+
+			// Prepare the vfs application, observers skip the identity
+			var app *vfs.VStoreApplication
+			if observer {
+				app, err = vfs.NewObserverVStoreApplication(db, opts...)
+				if err != nil {
+					log.Fatalf("could not open vfs application: %v", err)
+				}
+
+				log.Printf("using observer mode: encrypted transactions are not readable, new transactions are not encrypted")
+			} else {
+				app = vfs.NewVStoreApplication(db, idFile, pw, opts...)
+			}
 
-			// Prepare the ABCI server
-			logger := cmtlog.NewTMLogger(cmtlog.NewSyncWriter(os.Stdout))
-			server := abciserver.NewSocketServer(socketAddr, app)
+			// Start the admin socket (pause/resume, logs)
+			if adminSocketAddr != "" {
+				teardownAdmin, err := serveAdmin(adminSocketAddr, app, logger)
+				if err != nil {
+					log.Fatalf("error starting admin socket: %v", err)
+				}
+				defer teardownAdmin()
+			}
+
+			// Prepare the ABCI server (--socket, --transport)
+			server, err := newABCIServer(socketAddr, transport, app)
+			if err != nil {
+				log.Fatalf("could not create ABCI server: %v", err)
+			}
 			server.SetLogger(logger)
 
 			// Start the ABCI server
 			if err := server.Start(); err != nil {
-				log.Fatalf("error starting socket server: %v", err)
+				log.Fatalf("error starting %s server: %v", transport, err)
 				os.Exit(1)
 			}
-			defer server.Stop()
 
 			// Handle SIGTERM
 			c := make(chan os.Signal, 1)
 			signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 			<-c
+
+			shutdown(server, app, teardownDb, shutdownTimeout)
 		},
 	}
 )
@@ -106,7 +261,23 @@ func init() {
 		&socketAddr,
 		"socket",
 		"unix://vfs.sock",
-		"Unix domain socket address (if empty, uses \"unix://vfs.sock\")",
+		"ABCI server address, unix://PATH or tcp://HOST:PORT (if empty, uses \"unix://vfs.sock\")",
+	)
+
+	// e.g.: vstore --socket tcp://0.0.0.0:26658 --transport grpc
+	vstoreCmd.Flags().StringVar(
+		&transport,
+		"transport",
+		transportSocket,
+		"ABCI server transport: socket or grpc",
+	)
+
+	// e.g.: vstore info --node http://10.0.0.1:26657
+	vstoreCmd.PersistentFlags().StringVar(
+		&nodeAddr,
+		"node",
+		DefaultNodeAddr,
+		"RPC address of the CometBFT node (if empty, uses \"http://localhost:26657\")",
 	)
 
 	// e.g.: vstore --id /tmp/my-ed25519.id
@@ -116,6 +287,192 @@ func init() {
 		"",
 		"Path to the identity file (if empty, uses $HOME/.vstore/id)",
 	)
+
+	// e.g.: vstore --password-file /run/secrets/vstore-password
+	vstoreCmd.PersistentFlags().StringVar(
+		&pwFile,
+		"password-file",
+		"",
+		"Path to a file containing the identity password (if empty, uses $"+PasswordEnv+" or prompts)",
+	)
+
+	// e.g.: vstore --blob-dir /tmp/.vstore/blobs
+	vstoreCmd.Flags().StringVar(
+		&blobDir,
+		"blob-dir",
+		"",
+		"Path to the external storage of bodies (if empty, body references are not resolved)",
+	)
+
+	// e.g.: vstore --legacy-signatures
+	vstoreCmd.Flags().BoolVar(
+		&legacySignatures,
+		"legacy-signatures",
+		false,
+		"Accept transactions with signatures that do not cover the timestamp, e.g. of older clients",
+	)
+
+	// e.g.: vstore --stateful-check-tx
+	vstoreCmd.Flags().BoolVar(
+		&statefulCheckTx,
+		"stateful-check-tx",
+		false,
+		"Check transactions against the state in CheckTx, e.g. duplicate hashes (reads the database)",
+	)
+
+	// e.g.: vstore --plaintext
+	vstoreCmd.Flags().BoolVar(
+		&plaintext,
+		"plaintext",
+		false,
+		"Store new transactions unencrypted, e.g. for public records (existing transactions are still read)",
+	)
+
+	// e.g.: vstore --observer
+	vstoreCmd.Flags().BoolVar(
+		&observer,
+		"observer",
+		false,
+		"Run without the identity password, e.g. a replica: only plaintext transactions are readable and new transactions are stored in plaintext",
+	)
+
+	// e.g.: vstore --finalize-workers 8
+	vstoreCmd.Flags().IntVar(
+		&finalizeWorkers,
+		"finalize-workers",
+		0,
+		"Number of workers that parse transactions in FinalizeBlock (if 0 or 1, parses sequentially)",
+	)
+
+	// e.g.: vstore --snapshot-interval 1000
+	// Snapshots are written in Commit, i.e. the chain stalls while the whole
+	// database is written, which takes longer as the store grows.
+	vstoreCmd.Flags().Int64Var(
+		&snapshotInterval,
+		"snapshot-interval",
+		0,
+		"Number of blocks between state sync snapshots, written to the snapshots directory of --home (if 0, no snapshots). Blocks are not committed while a snapshot is written",
+	)
+
+	// e.g.: vstore --snapshot-interval 1000 --snapshot-keep-recent 2
+	vstoreCmd.Flags().IntVar(
+		&snapshotKeepRecent,
+		"snapshot-keep-recent",
+		2,
+		"Number of recent state sync snapshots to keep (if 0, keeps all snapshots)",
+	)
+
+	// e.g.: vstore --retention-max-age 2160h --retention-interval 1000
+	vstoreCmd.Flags().DurationVar(
+		&retentionMaxAge,
+		"retention-max-age",
+		0,
+		"Prune the bodies of transactions older than this duration, commitments are kept (if 0, no maximum age)",
+	)
+
+	// e.g.: vstore --retention-max-bytes 10737418240 --retention-interval 1000
+	vstoreCmd.Flags().Int64Var(
+		&retentionMaxBytes,
+		"retention-max-bytes",
+		0,
+		"Prune the bodies of the oldest transactions above this size in bytes, commitments are kept (if 0, no maximum size)",
+	)
+
+	// e.g.: vstore --retention-max-age 2160h --retention-interval 1000
+	vstoreCmd.Flags().Int64Var(
+		&retentionInterval,
+		"retention-interval",
+		0,
+		"Number of blocks between runs of the retention policy (if 0, transactions are never pruned)",
+	)
+
+	// e.g.: vstore --body-schema /tmp/.vstore/schema.json
+	vstoreCmd.Flags().StringVar(
+		&bodySchema,
+		"body-schema",
+		"",
+		"JSON schema file that bodies with a JSON content type must conform to (if empty, bodies are not validated)",
+	)
+
+	// e.g.: vstore --shutdown-timeout 1m
+	vstoreCmd.Flags().DurationVar(
+		&shutdownTimeout,
+		"shutdown-timeout",
+		30*time.Second,
+		"Maximum time to wait for active block processing on shutdown",
+	)
+
+	// e.g.: vstore --event-webhook https://example.com/hooks/vstore
+	vstoreCmd.Flags().StringVar(
+		&eventWebhook,
+		"event-webhook",
+		"",
+		"URL that receives an event for every committed transaction (if empty, no events)",
+	)
+
+	// e.g.: vstore --event-format cloudevents
+	vstoreCmd.Flags().StringVar(
+		&eventFormat,
+		"event-format",
+		string(vfs.EventFormatJSON),
+		"Payload format of committed events: json or cloudevents",
+	)
+
+	// e.g.: vstore --compression zstd
+	vstoreCmd.Flags().StringVar(
+		&compression,
+		"compression",
+		vfs.CompressionNone.String(),
+		"Compression of new transactions before encryption: none, gzip or zstd",
+	)
+
+	// e.g.: vstore --metrics-addr 127.0.0.1:26660
+	vstoreCmd.Flags().StringVar(
+		&metricsAddr,
+		"metrics-addr",
+		"",
+		"Address that serves Prometheus metrics on /metrics (if empty, no metrics)",
+	)
+
+	// e.g.: vstore --db-backend memdb
+	vstoreCmd.PersistentFlags().StringVar(
+		&dbBackend,
+		"db-backend",
+		string(cmtdb.GoLevelDBBackend),
+		"Database backend: goleveldb, memdb (ephemeral), or rocksdb, badgerdb, boltdb, pebbledb, cleveldb with their build tag",
+	)
+
+	// e.g.: vstore --backup-dir /mnt/backups/.vstore
+	vstoreCmd.PersistentFlags().StringVar(
+		&backupDir,
+		"backup-dir",
+		"",
+		"Write an encrypted backup of new identities to this directory (if empty, no backup)",
+	)
+
+	// e.g.: vstore --kdf sha256
+	vstoreCmd.PersistentFlags().StringVar(
+		&kdfName,
+		"kdf",
+		vfs.DefaultKDF.String(),
+		"Key derivation function of new identities: argon2id or sha256",
+	)
+
+	// e.g.: vstore --enforce-password-policy
+	vstoreCmd.PersistentFlags().BoolVar(
+		&enforcePasswordPolicy,
+		"enforce-password-policy",
+		false,
+		"Reject weak passwords when generating a new identity",
+	)
+
+	// e.g.: vstore --password-file /run/secrets/vstore-password --allow-weak-password
+	vstoreCmd.PersistentFlags().BoolVar(
+		&allowWeakPassword,
+		"allow-weak-password",
+		false,
+		"Accept passwords shorter than 8 characters or with less than 5 distinct characters when generating a new identity, e.g. in scripts",
+	)
 }
 
 func initConfig() {
@@ -125,6 +482,11 @@ func initConfig() {
 		homeDir = filepath.Join(homeDir, ".vstore") // $HOME/.vstore
 	}
 
+	// Empty node address uses default
+	if nodeAddr == "" {
+		nodeAddr = DefaultNodeAddr
+	}
+
 	// Empty identity file path generates new
 	if idFile == "" {
 		// Create default identity file
@@ -146,15 +508,100 @@ func Execute() {
 	}
 }
 
-// openDatabase creates a new leveldb database using goleveldb in the user's
-// home directory as provided with homeDir. A teardown function is returned
-// as the third return value, you can defer the call to safely close the db.
-func openDatabase(name, homeDir string) (cmtdb.DB, string, func(), error) {
-	dbPath := filepath.Join(homeDir, "leveldb")
-	dbType := cmtdb.BackendType("goleveldb")
+// generateIdentity generates and encrypts a new identity file with the key
+// derivation function of the --kdf flag. Passwords which do not satisfy the
+// passwordPolicy are rejected before the identity is created.
+// If a backup directory is set, an encrypted backup of the identity is written.
+func generateIdentity(file string, pw []byte) {
+	if err := passwordPolicy().Validate(pw); err != nil {
+		log.Fatalf("could not generate identity: %v", err)
+	}
 
-	db, err := cmtdb.NewDB(name, dbType, dbPath)
+	kdf, err := vfs.ParseKDF(kdfName)
 	if err != nil {
+		log.Fatalf("could not generate identity: %v", err)
+	}
+
+	vfs.MustGenerateIdentityWithKDF(file, pw, kdf)
+
+	if backupDir != "" {
+		backupFile, err := vfs.BackupIdentity(file, backupDir)
+		if err != nil {
+			log.Fatalf("could not backup identity: %v", err)
+		}
+
+		log.Printf("identity backup: %s", backupFile)
+	}
+}
+
+// passwordPolicy returns the policy of the passwords of new identities, i.e.
+// vfs.DefaultPasswordPolicy with --enforce-password-policy, no policy with
+// --allow-weak-password and vfs.MinimumPasswordPolicy otherwise.
+func passwordPolicy() vfs.PasswordPolicy {
+	if enforcePasswordPolicy {
+		return vfs.DefaultPasswordPolicy
+	}
+
+	if allowWeakPassword {
+		return vfs.PasswordPolicy{}
+	}
+
+	return vfs.MinimumPasswordPolicy
+}
+
+// shutdown stops the ABCI server such that no new requests are accepted,
+// waits for the active block processing of app for at most timeout, saves
+// the state and closes the database. Each phase is logged.
+func shutdown(server service.Service, app *vfs.VStoreApplication, teardownDb func(), timeout time.Duration) {
+	log.Printf("shutdown: stopping ABCI server")
+	if err := server.Stop(); err != nil {
+		log.Printf("shutdown: could not stop ABCI server: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := app.Shutdown(ctx); err != nil {
+		log.Printf("shutdown: %v", err)
+	}
+
+	log.Printf("shutdown: closing database")
+	teardownDb()
+
+	log.Printf("shutdown: done")
+}
+
+// dbBackends are the database backends of cometbft-db. Backends other than
+// goleveldb and memdb are only available with their build tag.
+var dbBackends = []cmtdb.BackendType{
+	cmtdb.GoLevelDBBackend,
+	cmtdb.MemDBBackend,
+	cmtdb.CLevelDBBackend,
+	cmtdb.BoltDBBackend,
+	cmtdb.RocksDBBackend,
+	cmtdb.BadgerDBBackend,
+	cmtdb.PebbleDBBackend,
+}
+
+// openDatabase creates a new database using the backend of --db-backend in
+// the user's home directory as provided with homeDir. A teardown function is
+// returned as the third return value, you can defer the call to safely close
+// the db.
+func openDatabase(name, homeDir string) (cmtdb.DB, string, func(), error) {
+	return openDatabaseBackend(name, dbBackend, databaseDir(homeDir, dbBackend))
+}
+
+// openDatabaseBackend creates a database using the backend in dbPath. The
+// available backends depend on the build tags, e.g. badgerdb.
+func openDatabaseBackend(name, backend, dbPath string) (cmtdb.DB, string, func(), error) {
+	if err := validateDBBackend(backend); err != nil {
+		return nil, dbPath, func() {}, err
+	}
+
+	db, err := cmtdb.NewDB(name, cmtdb.BackendType(backend), dbPath)
+	if err != nil && strings.HasPrefix(err.Error(), "unknown db_backend") {
+		return nil, dbPath, func() {}, fmt.Errorf("database backend %q is not built into this binary (rebuild with -tags %s)", backend, backend)
+	} else if err != nil {
 		return nil, dbPath, func() {}, err
 	}
 
@@ -164,3 +611,29 @@ func openDatabase(name, homeDir string) (cmtdb.DB, string, func(), error) {
 		}
 	}, nil
 }
+
+// validateDBBackend returns an error if backend is not a database backend
+// of cometbft-db.
+func validateDBBackend(backend string) error {
+	names := make([]string, len(dbBackends))
+	for i, known := range dbBackends {
+		if string(known) == backend {
+			return nil
+		}
+
+		names[i] = string(known)
+	}
+
+	return fmt.Errorf("unsupported database backend %q (expected one of %s)", backend, strings.Join(names, ", "))
+}
+
+// databaseDir returns the database directory of a backend in homeDir. The
+// goleveldb database uses the "leveldb" directory, other backends use the
+// name of the backend, e.g. "badgerdb".
+func databaseDir(homeDir, backend string) string {
+	if backend == "goleveldb" {
+		return filepath.Join(homeDir, "leveldb")
+	}
+
+	return filepath.Join(homeDir, backend)
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenDatabaseBackend(t *testing.T) {
+	dir := t.TempDir()
+
+	// Backends built into every binary
+	for _, backend := range []string{"goleveldb", "memdb"} {
+		db, _, teardown, err := openDatabaseBackend("vfs", backend, databaseDir(dir, backend))
+		require.NoError(t, err, backend)
+		require.NoError(t, db.Set([]byte("key"), []byte("value")))
+		teardown()
+	}
+
+	// Known backends that require a build tag
+	_, _, _, err := openDatabaseBackend("vfs", "rocksdb", filepath.Join(dir, "rocksdb"))
+	assert.ErrorContains(t, err, "not built into this binary")
+
+	// Unknown backends
+	_, _, _, err = openDatabaseBackend("vfs", "postgres", filepath.Join(dir, "postgres"))
+	assert.ErrorContains(t, err, "unsupported database backend")
+}
+
+func TestDatabaseDir(t *testing.T) {
+	assert.Equal(t, filepath.Join("home", "leveldb"), databaseDir("home", "goleveldb"))
+	assert.Equal(t, filepath.Join("home", "badgerdb"), databaseDir("home", "badgerdb"))
+}
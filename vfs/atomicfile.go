@@ -0,0 +1,48 @@
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// writeTempFile writes data to a temporary file. It is a variable such that
+// tests can simulate interrupted writes.
+var writeTempFile = func(f *os.File, data []byte) error {
+	_, err := f.Write(data)
+	return err
+}
+
+// writeFileAtomic writes data to a temporary file in the directory of file and
+// renames it to file, which is atomic on the same filesystem. If the write is
+// interrupted, an existing file is left unchanged and the temporary file is
+// removed.
+func writeFileAtomic(file string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	tmpFile := f.Name()
+	defer os.Remove(tmpFile) // no-op after the rename
+
+	if err := writeTempFile(f, data); err != nil {
+		f.Close()
+		return err
+	}
+
+	// Data must be on disk before the rename
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmpFile, perm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile, file)
+}
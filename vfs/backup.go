@@ -0,0 +1,89 @@
+package vfs
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+// BackupIdentity writes a backup copy of an identity file to the backupDir
+// directory. The backup contains the encrypted private key as found in the
+// identity file, and is thereby protected with the identity password.
+// It returns the path to the backup file.
+func BackupIdentity(idFile string, backupDir string) (string, error) {
+	ctbz, err := os.ReadFile(idFile)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(backupDir, 0700); err != nil {
+		return "", err
+	}
+
+	// e.g.: id-20240601T123015Z.backup
+	backupName := fmt.Sprintf("%s-%s.backup",
+		filepath.Base(idFile), time.Now().UTC().Format("20060102T150405Z"))
+	backupFile := filepath.Join(backupDir, backupName)
+
+	if err := os.WriteFile(backupFile, ctbz, 0600); err != nil {
+		return "", err
+	}
+
+	return backupFile, nil
+}
+
+// RestoreIdentity recovers an identity file from a backup file. The backup
+// must be decrypted with the password pw, an existing identity file is never
+// overwritten. The co-located .pub file is recreated as well.
+// It returns the paths to the restored identity file and public key file.
+func RestoreIdentity(backupFile string, idFile string, pw []byte) (string, string, error) {
+	if len(pw) == 0 {
+		return "", "", errors.New("password must not be empty")
+	}
+
+	if _, err := os.Stat(idFile); err == nil {
+		return "", "", fmt.Errorf("identity file already exists: %s", idFile)
+	}
+
+	if _, err := os.Stat(backupFile); err != nil {
+		return "", "", fmt.Errorf("could not open backup file: %v", err)
+	}
+
+	// Backup must be decrypted with the password
+	backup := &identityFile{Path: backupFile, pw: pw}
+	pbz, err := backup.Open()
+	if err != nil {
+		return "", "", fmt.Errorf("could not decrypt backup file: %v", err)
+	}
+	defer zeroize(pbz)
+
+	if len(pbz) != ed25519.PrivateKeySize {
+		return "", "", errors.New("invalid private key in backup file")
+	}
+
+	ctbz, err := os.ReadFile(backupFile)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(idFile), 0700); err != nil {
+		return "", "", err
+	}
+
+	if err := writeFileAtomic(idFile, ctbz, 0600); err != nil {
+		return "", "", err
+	}
+
+	pubFile := idFile + ".pub"
+	b64_pub := base64.StdEncoding.EncodeToString(ed25519.PrivKey(pbz).PubKey().Bytes())
+	if err := writeFileAtomic(pubFile, []byte(b64_pub), 0644); err != nil {
+		return "", "", err
+	}
+
+	return idFile, pubFile, nil
+}
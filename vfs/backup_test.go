@@ -0,0 +1,69 @@
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVStoreBackupIdentity(t *testing.T) {
+	// create a unique, concurrency-safe test directory under os.TempDir()
+	rootDir, _ := os.MkdirTemp("", "test-vstore-backup_identity")
+	defer os.RemoveAll(rootDir)
+
+	pw := []byte("testpassword")
+	idFile, _ := MustGenerateIdentity(filepath.Join(rootDir, "id"), pw)
+
+	backupFile, err := BackupIdentity(idFile, filepath.Join(rootDir, "backups"))
+	require.NoError(t, err, "should create a backup file")
+
+	// check that backup is a copy of the encrypted identity
+	idBytes, _ := os.ReadFile(idFile)
+	backupBytes, err := os.ReadFile(backupFile)
+	require.NoError(t, err)
+	assert.Equal(t, idBytes, backupBytes)
+
+	fi, err := os.Stat(backupFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	// check that backup is password-protected
+	_, err = NewIdentity(backupFile, []byte("wrongpassword")).Open()
+	assert.Error(t, err, "backup must not be decrypted with a wrong password")
+}
+
+func TestVStoreRestoreIdentity(t *testing.T) {
+	// create a unique, concurrency-safe test directory under os.TempDir()
+	rootDir, _ := os.MkdirTemp("", "test-vstore-restore_identity")
+	defer os.RemoveAll(rootDir)
+
+	pw := []byte("testpassword")
+	idFile, _ := MustGenerateIdentity(filepath.Join(rootDir, "id"), pw)
+	expected, err := NewIdentity(idFile, pw).Identity().PubKey()
+	require.NoError(t, err)
+
+	backupFile, err := BackupIdentity(idFile, filepath.Join(rootDir, "backups"))
+	require.NoError(t, err)
+
+	// Existing identity is never overwritten
+	_, _, err = RestoreIdentity(backupFile, idFile, pw)
+	assert.Error(t, err, "should not overwrite existing identity")
+
+	// Lose the identity and restore it
+	require.NoError(t, os.Remove(idFile))
+
+	_, _, err = RestoreIdentity(backupFile, idFile, []byte("wrongpassword"))
+	assert.Error(t, err, "should not restore with a wrong password")
+
+	restored, pubFile, err := RestoreIdentity(backupFile, idFile, pw)
+	require.NoError(t, err, "should restore identity from backup")
+	assert.Equal(t, idFile, restored)
+	assert.FileExists(t, pubFile)
+
+	pk, err := NewIdentity(restored, pw).Identity().PubKey()
+	require.NoError(t, err, "should open restored identity")
+	assert.Equal(t, expected.Bytes(), pk.Bytes())
+}
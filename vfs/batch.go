@@ -0,0 +1,25 @@
+package vfs
+
+import (
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+// verifyBatch verifies the signatures of transactions in one pass using the
+// ed25519 batch verification. It returns false if any signature is invalid,
+// the failing transactions must then be identified with individual checks.
+// Legacy signatures (which do not cover the timestamp) fail the batch.
+func verifyBatch(stxs []SignedTransaction) bool {
+	if len(stxs) == 0 {
+		return true
+	}
+
+	bv := ed25519.NewBatchVerifier()
+	for _, stx := range stxs {
+		if err := bv.Add(stx.Signer, stx.SignBytes(), stx.Signature); err != nil {
+			return false
+		}
+	}
+
+	ok, _ := bv.Verify()
+	return ok
+}
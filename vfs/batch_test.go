@@ -0,0 +1,113 @@
+package vfs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+)
+
+func TestVStoreProcessProposalBatch(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-process_proposal_batch", 3)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	stxs := []SignedTransaction{}
+	txs := [][]byte{}
+	for i := 0; i < 10; i++ {
+		stx, err := makeTransaction(t, ownerPrivs[i%3], []byte(testComplexValue+strconv.Itoa(i)))
+		require.NoError(t, err, "should create a signed transaction")
+
+		stxs = append(stxs, *stx)
+		txs = append(txs, stx.Bytes())
+	}
+
+	assert.True(t, verifyBatch(stxs), "batch of valid signatures must verify")
+
+	processProposal := func(txs [][]byte) abci.ResponseProcessProposal_ProposalStatus {
+		resp, err := vstore.ProcessProposal(ctx, &abci.RequestProcessProposal{Txs: txs, Time: time.Now()})
+		require.NoError(t, err)
+		return resp.Status
+	}
+
+	assert.Equal(t, abci.ResponseProcessProposal_ACCEPT, processProposal(txs))
+
+	// One invalid signature rejects the proposal
+	forged := stxs[4]
+	forged.Signature = append([]byte{}, forged.Signature...)
+	forged.Signature[0] ^= 0xff
+	assert.False(t, verifyBatch(append(append([]SignedTransaction{}, stxs[:4]...), forged)))
+
+	withForged := append(append([][]byte{}, txs...), forged.Bytes())
+	assert.Equal(t, abci.ResponseProcessProposal_REJECT, processProposal(withForged))
+
+	// Legacy signatures fail the batch, individual checks accept them if
+	// legacy signatures are enabled
+	legacy, err := makeLegacyTransaction(t, ownerPrivs[0], []byte(testSimpleValue))
+	require.NoError(t, err)
+	assert.False(t, verifyBatch([]SignedTransaction{*legacy}))
+
+	withLegacy := append(append([][]byte{}, txs...), legacy.Bytes())
+	assert.Equal(t, abci.ResponseProcessProposal_REJECT, processProposal(withLegacy))
+
+	vstore.SetLegacySignatures(true)
+	assert.Equal(t, abci.ResponseProcessProposal_ACCEPT, processProposal(withLegacy))
+}
+
+// makeBenchmarkProposal creates a proposal of n signed transactions.
+func makeBenchmarkProposal(b *testing.B, n int) (*VStoreApplication, *abci.RequestProcessProposal, func()) {
+	_, cancel, ownerPrivs, vfsDir := ResetTestRoot(b, "bench-vstore-process_proposal", 10)
+
+	app := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	txs := make([][]byte, n)
+	for i := range txs {
+		stx, err := makeTransaction(b, ownerPrivs[i%len(ownerPrivs)], []byte(testComplexValue+strconv.Itoa(i)))
+		require.NoError(b, err)
+		txs[i] = stx.Bytes()
+	}
+
+	return app, &abci.RequestProcessProposal{Txs: txs, Time: time.Now()}, func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}
+}
+
+func BenchmarkProcessProposalBatch(b *testing.B) {
+	app, proposal, teardown := makeBenchmarkProposal(b, 1000)
+	defer teardown()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		resp, _ := app.ProcessProposal(context.Background(), proposal)
+		if resp.Status != abci.ResponseProcessProposal_ACCEPT {
+			b.Fatal("proposal must be accepted")
+		}
+	}
+}
+
+func BenchmarkProcessProposalPerTx(b *testing.B) {
+	app, proposal, teardown := makeBenchmarkProposal(b, 1000)
+	defer teardown()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Previous implementation: full validity check per transaction
+		for _, tx := range proposal.Txs {
+			if code := app.validateTx(tx, proposal.Time); code != CodeTypeOK {
+				b.Fatal("transaction must be valid")
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package vfs
+
+import (
+	"fmt"
+
+	"github.com/btcsuite/btcd/btcutil/bech32"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+// DefaultBech32Prefix is the human-readable part of bech32 public keys.
+const DefaultBech32Prefix = "vstorepub"
+
+// PubKeyToBech32 encodes the ed25519 public key pub in bech32 with the
+// human-readable part hrp, e.g. DefaultBech32Prefix.
+func PubKeyToBech32(hrp string, pub []byte) (string, error) {
+	if len(pub) != ed25519.PubKeySize {
+		return "", fmt.Errorf("public key must be %d bytes, got %d", ed25519.PubKeySize, len(pub))
+	}
+
+	data, err := bech32.ConvertBits(pub, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+
+	return bech32.Encode(hrp, data)
+}
+
+// PubKeyFromBech32 decodes an ed25519 public key which is encoded in bech32
+// with PubKeyToBech32. The human-readable part must be hrp.
+func PubKeyFromBech32(hrp, value string) ([]byte, error) {
+	decodedHrp, data, err := bech32.Decode(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if decodedHrp != hrp {
+		return nil, fmt.Errorf("invalid bech32 prefix %q, expected %q", decodedHrp, hrp)
+	}
+
+	pub, err := bech32.ConvertBits(data, 5, 8, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pub) != ed25519.PubKeySize {
+		return nil, fmt.Errorf("public key must be %d bytes, got %d", ed25519.PubKeySize, len(pub))
+	}
+
+	return pub, nil
+}
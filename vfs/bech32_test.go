@@ -0,0 +1,46 @@
+package vfs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+func TestVStoreBech32PubKey(t *testing.T) {
+	pub := ed25519.GenPrivKey().PubKey().Bytes()
+
+	encoded, err := PubKeyToBech32(DefaultBech32Prefix, pub)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encoded, DefaultBech32Prefix+"1"))
+
+	decoded, err := PubKeyFromBech32(DefaultBech32Prefix, encoded)
+	require.NoError(t, err)
+	assert.Equal(t, pub, decoded)
+
+	// Custom prefixes must match
+	encoded, err = PubKeyToBech32("custom", pub)
+	require.NoError(t, err)
+
+	decoded, err = PubKeyFromBech32("custom", encoded)
+	require.NoError(t, err)
+	assert.Equal(t, pub, decoded)
+
+	_, err = PubKeyFromBech32(DefaultBech32Prefix, encoded)
+	assert.ErrorContains(t, err, "invalid bech32 prefix")
+
+	// Corrupted checksums and invalid sizes are rejected
+	corrupted := encoded[:len(encoded)-1] + "q"
+	if corrupted == encoded {
+		corrupted = encoded[:len(encoded)-1] + "p"
+	}
+
+	_, err = PubKeyFromBech32("custom", corrupted)
+	assert.Error(t, err)
+
+	_, err = PubKeyToBech32(DefaultBech32Prefix, pub[:16])
+	assert.Error(t, err)
+}
@@ -0,0 +1,145 @@
+package vfs
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cometbft/cometbft/crypto/tmhash"
+)
+
+// ContentRefPrefix is the scheme of body references which consist of the
+// SHA-256 content hash of the body, e.g. "sha256:HEX".
+const ContentRefPrefix = "sha256:"
+
+// BlobStore describes an external storage for transaction bodies. Bodies
+// that are stored externally are referenced with the body_ref field of
+// transactions, and are resolved through the blob store upon queries.
+type BlobStore interface {
+	// Get returns the body referenced by ref.
+	Get(ref string) ([]byte, error)
+}
+
+// DirBlobStore implements BlobStore using files in a directory. Bodies are
+// referenced by content hash and are verified when they are read.
+type DirBlobStore struct {
+	dir string
+}
+
+var _ BlobStore = (*DirBlobStore)(nil)
+
+// NewDirBlobStore creates a blob store that uses the files in dir.
+func NewDirBlobStore(dir string) *DirBlobStore {
+	return &DirBlobStore{dir: dir}
+}
+
+// ContentRef returns the content hash reference of a body.
+func ContentRef(body []byte) string {
+	return ContentRefPrefix + hex.EncodeToString(tmhash.Sum(body))
+}
+
+// Put saves a body in the directory and returns its content hash reference.
+func (s *DirBlobStore) Put(body []byte) (string, error) {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return "", err
+	}
+
+	ref := ContentRef(body)
+	file := filepath.Join(s.dir, strings.TrimPrefix(ref, ContentRefPrefix))
+	if err := os.WriteFile(file, body, 0o600); err != nil {
+		return "", err
+	}
+
+	return ref, nil
+}
+
+// Get reads a body from the directory, the body must match its content hash.
+// Get implements BlobStore
+func (s *DirBlobStore) Get(ref string) ([]byte, error) {
+	digest, err := parseContentRef(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := os.ReadFile(filepath.Join(s.dir, hex.EncodeToString(digest)))
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(tmhash.Sum(body), digest) {
+		return nil, fmt.Errorf("blob does not match reference: %s", ref)
+	}
+
+	return body, nil
+}
+
+// verifyResolvedBody verifies that the resolved body of a transaction with a
+// body reference matches its content hash reference. Transactions without a
+// resolved body are valid.
+func verifyResolvedBody(stx *SignedTransaction) error {
+	if len(stx.BodyRef) == 0 || len(stx.Data) == 0 {
+		return nil
+	}
+
+	digest, err := parseContentRef(stx.BodyRef)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(tmhash.Sum(stx.Data), digest) {
+		return fmt.Errorf("body does not match reference: %s", stx.BodyRef)
+	}
+
+	return nil
+}
+
+// parseContentRef returns the SHA-256 digest of a content hash reference.
+func parseContentRef(ref string) ([]byte, error) {
+	if !strings.HasPrefix(ref, ContentRefPrefix) {
+		return nil, fmt.Errorf("unsupported body reference: %s", ref)
+	}
+
+	digest, err := hex.DecodeString(strings.TrimPrefix(ref, ContentRefPrefix))
+	if err != nil || len(digest) != tmhash.Size {
+		return nil, errors.New("invalid content hash reference")
+	}
+
+	return digest, nil
+}
+
+// --------------------------------------------------------------------------
+
+// resolveBodyRef replaces the body of a marshalled transaction with the body
+// referenced in external storage. Transactions without a body reference are
+// returned unchanged, as well as all transactions if no blob store is set.
+// The resolved transaction keeps its body reference and its committed hash,
+// its hash must be computed with SignedTransaction.Unresolved.
+func (app *VStoreApplication) resolveBodyRef(txData []byte) ([]byte, error) {
+	if app.blobs == nil {
+		return txData, nil
+	}
+
+	stx, err := FromBytes(txData)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(stx.BodyRef) == 0 {
+		return txData, nil
+	}
+
+	body, err := app.blobs.Get(stx.BodyRef)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := stx.ToProto()
+	tx.Len = uint32(len(body))
+	tx.Body = body
+
+	return tx.Marshal()
+}
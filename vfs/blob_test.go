@@ -0,0 +1,110 @@
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	vfsp2p "github.com/securesharelabs/vstore/api/vstore/v1"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+	"github.com/cosmos/gogoproto/proto"
+)
+
+func TestVStoreBodyRefResolution(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-body_ref_resolution", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	blobs := NewDirBlobStore(filepath.Join(vfsDir, "blobs"))
+	ref, err := blobs.Put([]byte(testComplexValue))
+	require.NoError(t, err, "should save blob")
+	assert.True(t, strings.HasPrefix(ref, ContentRefPrefix))
+
+	stx := makeBodyRefTransaction(t, ownerPrivs[0], ref)
+	resFinalize := testVStoreCommitTx(ctx, t, vstore, stx.Bytes())
+	assert.Equal(t, CodeTypeOK, resFinalize.TxResults[0].Code)
+	txHash := resFinalize.TxResults[0].Data
+
+	// Without blob store, the reference is returned as is
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: txHash})
+	require.NoError(t, err)
+
+	tx := new(vfsp2p.Transaction)
+	require.NoError(t, proto.Unmarshal(resQuery.Value, tx))
+	assert.Equal(t, ref, tx.BodyRef)
+	assert.Empty(t, tx.Body)
+
+	// With blob store, the reference is resolved
+	vstore.SetBlobStore(blobs)
+	resQuery, err = vstore.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: txHash})
+	require.NoError(t, err)
+
+	tx = new(vfsp2p.Transaction)
+	require.NoError(t, proto.Unmarshal(resQuery.Value, tx))
+	assert.Equal(t, ref, tx.BodyRef)
+	assert.Equal(t, []byte(testComplexValue), tx.Body, "body must be resolved")
+	assert.Equal(t, len(testComplexValue), int(tx.Len))
+
+	// Tampered blobs are not resolved
+	digest := strings.TrimPrefix(ref, ContentRefPrefix)
+	err = os.WriteFile(filepath.Join(vfsDir, "blobs", digest), []byte("tampered"), 0o600)
+	require.NoError(t, err)
+
+	_, err = vstore.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: txHash})
+	assert.Error(t, err, "tampered blob must not be resolved")
+}
+
+func TestVStoreBodyRefWithBodyRejected(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-body_ref_with_body", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	stx := makeBodyRefTransaction(t, ownerPrivs[0], ContentRef([]byte(testSimpleValue)))
+	stx.Data = []byte(testSimpleValue)
+	stx.Size = len(stx.Data)
+
+	checkTxResp, err := vstore.CheckTx(ctx, &abci.RequestCheckTx{Tx: stx.Bytes()})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeInvalidFormatError, checkTxResp.Code)
+
+	resFinalize, _ := makeBlockCommit(ctx, t, vstore, 1, [][]byte{stx.Bytes()})
+	assert.Equal(t, CodeTypeInvalidFormatError, resFinalize.TxResults[0].Code)
+	assert.Zero(t, vstore.state.NumTransactions)
+
+	_, err = NewSignedTransactionFromBytes(stx.Bytes())
+	assert.Error(t, err)
+}
+
+// --------------------------------------------------------------------------
+
+func makeBodyRefTransaction(t *testing.T, privKey []byte, ref string) *SignedTransaction {
+	t.Helper()
+
+	priv := ed25519.PrivKey(privKey)
+	stx := &SignedTransaction{
+		Signer:  priv.PubKey().(ed25519.PubKey),
+		Time:    time.Now(),
+		BodyRef: ref,
+	}
+
+	sig, err := priv.Sign(stx.SignBytes())
+	require.NoError(t, err, "should sign body reference")
+
+	stx.Signature = sig
+	return stx
+}
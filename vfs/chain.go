@@ -0,0 +1,66 @@
+package vfs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+var (
+	errPrevHashSize     = errors.New("previous hash must be 32 bytes")
+	errPrevHashNotFound = errors.New("previous transaction not found")
+	errPrevHashSigner   = errors.New("previous transaction belongs to another signer")
+)
+
+// validatePrevHash validates the previous hash of a chained transaction, i.e.
+// the previous transaction must exist and must belong to the same signer. The
+// previous transaction is searched in staged, then in the database, such that
+// a signer can chain transactions of the same block. Committed transactions are
+// resolved with the signer of their stored record only, see recordSigner: the
+// stored transactions are never decrypted nor resolved in the blob store, such
+// that the result never depends on the secret or on the storage of the node.
+// Transactions without a previous hash are always valid.
+func (app *VStoreApplication) validatePrevHash(stx *SignedTransaction, staged []SignedTransaction) error {
+	if len(stx.PrevHash) == 0 {
+		return nil
+	}
+
+	if len(stx.PrevHash) != 32 {
+		return errPrevHashSize
+	}
+
+	for _, prev := range staged {
+		if bytes.Equal(prev.Hash, stx.PrevHash) {
+			return checkPrevSigner(stx, &prev)
+		}
+	}
+
+	data, err := app.state.db.Get(TransactionKey(stx.PrevHash))
+	if err != nil {
+		return err
+	}
+
+	if len(data) == 0 {
+		return fmt.Errorf("%w: %X", errPrevHashNotFound, stx.PrevHash)
+	}
+
+	signer, err := recordSigner(data)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(signer, stx.Signer.Bytes()) {
+		return errPrevHashSigner
+	}
+
+	return nil
+}
+
+// checkPrevSigner returns an error if prev is not signed by the signer of stx.
+func checkPrevSigner(stx, prev *SignedTransaction) error {
+	if !bytes.Equal(prev.Signer.Bytes(), stx.Signer.Bytes()) {
+		return errPrevHashSigner
+	}
+
+	return nil
+}
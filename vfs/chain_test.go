@@ -0,0 +1,192 @@
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cmtdb "github.com/cometbft/cometbft-db"
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+func TestVStoreChainedTransactions(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-chained_transactions", 2)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	first, err := makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue))
+	require.NoError(t, err)
+	first.Hash = ComputeHash(first)
+	testVStoreCommitTx(ctx, t, vstore, first.Bytes())
+
+	// Valid chaining to a committed transaction of the same signer
+	second := makeChainedTransaction(t, ownerPrivs[0], []byte(testComplexValue), first.Hash)
+	checkTxResp, err := vstore.CheckTx(ctx, &abci.RequestCheckTx{Tx: second.Bytes()})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeOK, checkTxResp.Code)
+
+	// Valid chaining to a transaction of the same block
+	third := makeChainedTransaction(t, ownerPrivs[0], []byte("third"), second.Hash)
+	respFinBlock, _ := makeBlockCommit(ctx, t, vstore, 2, [][]byte{second.Bytes(), third.Bytes()})
+	assert.Equal(t, CodeTypeOK, respFinBlock.TxResults[0].Code)
+	assert.Equal(t, CodeTypeOK, respFinBlock.TxResults[1].Code)
+
+	// The previous hash is returned with the transaction
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: third.Hash})
+	require.NoError(t, err)
+	stx, err := FromBytes(resQuery.Value)
+	require.NoError(t, err)
+	assert.Equal(t, second.Hash, stx.PrevHash)
+	assert.True(t, stx.Verify(), "signature must cover the previous hash")
+
+	// The previous hash is signed
+	forged := makeChainedTransaction(t, ownerPrivs[0], []byte("forged"), first.Hash)
+	forged.PrevHash = second.Hash
+	forged.Hash = ComputeHash(forged)
+	checkTxResp, err = vstore.CheckTx(ctx, &abci.RequestCheckTx{Tx: forged.Bytes()})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeInvalidSignatureError, checkTxResp.Code)
+}
+
+func TestVStoreChainedTransactionsNodeIndependent(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-chained_transactions_node_independent", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	// The body of the reference is missing in the blob store
+	withBlobs := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+	withBlobs.SetBlobStore(NewDirBlobStore(filepath.Join(vfsDir, "blobs")))
+	withoutBlobs := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+	observer, err := NewObserverVStoreApplication(cmtdb.NewMemDB())
+	require.NoError(t, err)
+
+	first := makeBodyRefTransaction(t, ownerPrivs[0], ContentRef([]byte(testComplexValue)))
+	first.Hash = ComputeHash(first)
+	second := makeChainedTransaction(t, ownerPrivs[0], []byte(testSimpleValue), first.Hash)
+
+	// Previous transactions never depend on the blobs or the secret of a node
+	appHashes := [][]byte{}
+	for _, app := range []*VStoreApplication{withBlobs, withoutBlobs, observer} {
+		makeBlockCommit(ctx, t, app, 1, [][]byte{first.Bytes()})
+
+		respFinBlock, _ := makeBlockCommit(ctx, t, app, 2, [][]byte{second.Bytes()})
+		assert.Equal(t, CodeTypeOK, respFinBlock.TxResults[0].Code)
+		appHashes = append(appHashes, respFinBlock.AppHash)
+	}
+
+	assert.Equal(t, appHashes[0], appHashes[1])
+	assert.Equal(t, appHashes[0], appHashes[2])
+}
+
+func TestVStoreChainedTransactionsInvalid(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-chained_transactions_invalid", 2)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	first, err := makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue))
+	require.NoError(t, err)
+	first.Hash = ComputeHash(first)
+	testVStoreCommitTx(ctx, t, vstore, first.Bytes())
+
+	testCases := map[string]struct {
+		stx  *SignedTransaction
+		code uint32
+	}{
+		"dangling": {
+			makeChainedTransaction(t, ownerPrivs[0], []byte(testComplexValue), make([]byte, 32)),
+			CodeTypeInvalidPrevHashError,
+		},
+		"cross-signer": {
+			makeChainedTransaction(t, ownerPrivs[1], []byte(testComplexValue), first.Hash),
+			CodeTypeInvalidPrevHashError,
+		},
+		"invalid size": {
+			makeChainedTransaction(t, ownerPrivs[0], []byte(testComplexValue), first.Hash[:16]),
+			CodeTypeInvalidPrevHashError,
+		},
+	}
+
+	height := 2
+	for name, tc := range testCases {
+		checkTxResp, err := vstore.CheckTx(ctx, &abci.RequestCheckTx{Tx: tc.stx.Bytes()})
+		require.NoError(t, err)
+		assert.Equal(t, tc.code, checkTxResp.Code, "CheckTx should reject %s previous hash", name)
+
+		// FinalizeBlock rejects the transaction as well
+		respFinBlock, _ := makeBlockCommit(ctx, t, vstore, height, [][]byte{tc.stx.Bytes()})
+		assert.Equal(t, tc.code, respFinBlock.TxResults[0].Code, "FinalizeBlock should reject %s previous hash", name)
+		height++
+	}
+
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{
+		Path: "/pubkey",
+		Data: ed25519.PrivKey(ownerPrivs[1]).PubKey().Bytes(),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, resQuery.Value, "rejected transactions must not be committed")
+}
+
+func TestVStoreChainedTransactionsStoredRecords(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-chained_transactions_stored_records", 2)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	// Previous transactions are encrypted, plaintext or pruned records
+	encrypted := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+	plaintext := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+	plaintext.SetPlaintext(true)
+	pruned := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	first, err := makeTransactionAt(t, ownerPrivs[0], []byte(testSimpleValue), time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	first.Hash = ComputeHash(first)
+
+	for name, app := range map[string]*VStoreApplication{"encrypted": encrypted, "plaintext": plaintext, "pruned": pruned} {
+		makeBlockCommit(ctx, t, app, 1, [][]byte{first.Bytes()})
+		if app == pruned {
+			result, err := app.Prune(RetentionPolicy{MaxAge: time.Minute}, time.Now())
+			require.NoError(t, err)
+			require.EqualValues(t, 1, result.Pruned)
+		}
+
+		// The signer of the previous transaction is read from its record
+		crossSigner := makeChainedTransaction(t, ownerPrivs[1], []byte(testComplexValue), first.Hash)
+		respFinBlock, _ := makeBlockCommit(ctx, t, app, 2, [][]byte{crossSigner.Bytes()})
+		assert.Equal(t, CodeTypeInvalidPrevHashError, respFinBlock.TxResults[0].Code, name)
+
+		second := makeChainedTransaction(t, ownerPrivs[0], []byte(testComplexValue), first.Hash)
+		respFinBlock, _ = makeBlockCommit(ctx, t, app, 3, [][]byte{second.Bytes()})
+		assert.Equal(t, CodeTypeOK, respFinBlock.TxResults[0].Code, name)
+	}
+}
+
+// makeChainedTransaction creates a transaction which references prevHash and
+// signs it with privKey.
+func makeChainedTransaction(t testing.TB, privKey, data, prevHash []byte) *SignedTransaction {
+	t.Helper()
+
+	stx, err := makeTransaction(t, privKey, data)
+	require.NoError(t, err)
+
+	stx.PrevHash = prevHash
+	stx.Signature = SignData(ed25519.PrivKey(privKey), stx)
+	stx.Hash = ComputeHash(stx)
+	return stx
+}
@@ -0,0 +1,54 @@
+package vfs
+
+import "time"
+
+// CheckTxMode selects the checks that are performed in CheckTx.
+type CheckTxMode uint8
+
+const (
+	// CheckTxStateless performs the structural, timestamp and signature checks
+	// of transactions. The database is never accessed, such that CheckTx stays
+	// purely CPU-bound for high-throughput nodes, except for the previous hash
+	// of chained transactions. This is the default mode.
+	CheckTxStateless CheckTxMode = iota
+
+	// CheckTxStateful performs the stateless checks, followed by checks against
+	// the state which require database reads, i.e. duplicate transaction hashes.
+	CheckTxStateful
+)
+
+// SetCheckTxMode sets the checks that are performed in CheckTx.
+func (app *VStoreApplication) SetCheckTxMode(mode CheckTxMode) {
+	app.checkTxMode = mode
+}
+
+// ValidateTransaction performs the stateless checks of CheckTx on the bytes of
+// a signed transaction tx with the default timestamp drift window relative to
+// now, e.g. to validate a transaction offline before it is broadcast. The
+// transaction must be signed for chainID, the genesis chain id of the node, or
+// for no chain if chainID is empty. Legacy signatures are rejected. Checks
+// which depend on the settings or the state of a node, e.g. body schemas or
+// duplicate hashes, are not performed.
+func ValidateTransaction(tx []byte, chainID string, now time.Time) uint32 {
+	app := &VStoreApplication{
+		state:          State{ChainID: chainID},
+		maxPastDrift:   DefaultMaxPastDrift,
+		maxFutureDrift: DefaultMaxFutureDrift,
+	}
+
+	return app.validateTx(tx, now)
+}
+
+// validateTxState validates a transaction against the state, i.e. the
+// transaction hash must not exist in the database. This check is only
+// performed in CheckTxStateful mode, after validateTxBasic. It costs one
+// key lookup, such that duplicates are rejected before Commit.
+func (app *VStoreApplication) validateTxState(stx *SignedTransaction) uint32 {
+	// Transaction hash must not exist
+	exists, err := app.state.db.Has(TransactionKey(stx.Hash))
+	if err != nil || exists {
+		return CodeTypeDuplicateHashError
+	}
+
+	return CodeTypeOK
+}
@@ -0,0 +1,137 @@
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cmtdb "github.com/cometbft/cometbft-db"
+	abci "github.com/cometbft/cometbft/abci/types"
+)
+
+// countingDB wraps a database and counts the read accesses.
+type countingDB struct {
+	cmtdb.DB
+	reads atomic.Int64
+}
+
+func (db *countingDB) Get(key []byte) ([]byte, error) {
+	db.reads.Add(1)
+	return db.DB.Get(key)
+}
+
+func (db *countingDB) Has(key []byte) (bool, error) {
+	db.reads.Add(1)
+	return db.DB.Has(key)
+}
+
+func (db *countingDB) Iterator(start, end []byte) (cmtdb.Iterator, error) {
+	db.reads.Add(1)
+	return db.DB.Iterator(start, end)
+}
+
+func (db *countingDB) ReverseIterator(start, end []byte) (cmtdb.Iterator, error) {
+	db.reads.Add(1)
+	return db.DB.ReverseIterator(start, end)
+}
+
+func TestVStoreCheckTxStateless(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-check_tx_stateless", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	db := &countingDB{DB: cmtdb.NewMemDB()}
+	vstore := NewVStoreApplication(db, filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	stx, err := makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue))
+	require.NoError(t, err, "should create a signed transaction")
+	testVStoreCommitTx(ctx, t, vstore, stx.Bytes())
+
+	// Stateless path performs no database access
+	db.reads.Store(0)
+	checkTxResp, err := vstore.CheckTx(ctx, &abci.RequestCheckTx{Tx: stx.Bytes()})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeOK, checkTxResp.Code)
+	assert.Zero(t, db.reads.Load(), "stateless CheckTx must not access the database")
+
+	// Stateful path detects the duplicate hash
+	vstore.SetCheckTxMode(CheckTxStateful)
+	checkTxResp, err = vstore.CheckTx(ctx, &abci.RequestCheckTx{Tx: stx.Bytes()})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeDuplicateHashError, checkTxResp.Code)
+	assert.NotZero(t, db.reads.Load())
+
+	fresh, err := makeTransaction(t, ownerPrivs[0], []byte(testComplexValue))
+	require.NoError(t, err)
+	checkTxResp, err = vstore.CheckTx(ctx, &abci.RequestCheckTx{Tx: fresh.Bytes()})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeOK, checkTxResp.Code)
+}
+
+func TestVStoreCheckTxDuplicate(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-check_tx_duplicate", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+	vstore.SetCheckTxMode(CheckTxStateful)
+
+	stx, err := makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue))
+	require.NoError(t, err)
+
+	// Pending duplicates never enter the same proposal
+	ppResp, err := vstore.PrepareProposal(ctx, &abci.RequestPrepareProposal{
+		Txs: [][]byte{stx.Bytes(), stx.Bytes()},
+	})
+	require.NoError(t, err)
+	assert.Len(t, ppResp.Txs, 1, "duplicate hash must be dropped")
+
+	testVStoreCommitTx(ctx, t, vstore, stx.Bytes())
+
+	// Second submission is rejected at CheckTx, before Commit
+	checkTxResp, err := vstore.CheckTx(ctx, &abci.RequestCheckTx{Tx: stx.Bytes()})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeDuplicateHashError, checkTxResp.Code)
+
+	ppResp, err = vstore.PrepareProposal(ctx, &abci.RequestPrepareProposal{Txs: [][]byte{stx.Bytes()}})
+	require.NoError(t, err)
+	assert.Empty(t, ppResp.Txs, "committed hash must not enter a proposal")
+}
+
+func BenchmarkCheckTxStateless(b *testing.B) {
+	benchmarkCheckTx(b, CheckTxStateless)
+}
+
+func BenchmarkCheckTxStateful(b *testing.B) {
+	benchmarkCheckTx(b, CheckTxStateful)
+}
+
+func benchmarkCheckTx(b *testing.B, mode CheckTxMode) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(b, "bench-vstore-check_tx", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+	vstore.SetCheckTxMode(mode)
+
+	stx, err := makeTransaction(b, ownerPrivs[0], []byte(testComplexValue))
+	require.NoError(b, err)
+	req := &abci.RequestCheckTx{Tx: stx.Bytes()}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := vstore.CheckTx(ctx, req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package vfs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	vfsp2p "github.com/securesharelabs/vstore/api/vstore/v1"
+
+	"github.com/cometbft/cometbft/crypto/ed25519"
+	"github.com/cometbft/cometbft/crypto/merkle"
+	"github.com/cometbft/cometbft/crypto/tmhash"
+)
+
+// Chunk describes the position of a transaction body in a chunked body.
+// Large bodies can be split across several linked transactions which are
+// each signed individually and reassembled under the Root hash.
+type Chunk struct {
+	// Root is the merkle root of the SHA-256 digests of all chunks.
+	Root []byte
+
+	// Index is the position of the chunk in the body (starts at 0).
+	Index uint32
+
+	// Total is the total number of chunks in the body.
+	Total uint32
+}
+
+// chunkManifest describes the chunks of a chunked body that have been
+// committed so far by a signer. The manifest is stored with the prefix
+// "vfs:chunks:", see ChunkManifestKey, and is complete when the number of
+// hashes equals the total.
+type chunkManifest struct {
+	Signer  []byte   `json:"signer"`
+	Total   uint32   `json:"total"`
+	Hashes  [][]byte `json:"hashes"`
+	Digests [][]byte `json:"digests"`
+}
+
+// Complete returns true when all chunks of the body have been committed.
+func (m chunkManifest) Complete() bool {
+	return uint32(len(m.Hashes)) == m.Total
+}
+
+// ValidateBasic checks that the chunk information is consistent.
+func (c Chunk) ValidateBasic() error {
+	if len(c.Root) != tmhash.Size {
+		return fmt.Errorf("invalid chunk root size, want: %d, got: %d", tmhash.Size, len(c.Root))
+	}
+
+	if c.Total == 0 {
+		return errors.New("chunk total must not be zero")
+	}
+
+	if c.Index >= c.Total {
+		return fmt.Errorf("chunk index out of range: %d/%d", c.Index, c.Total)
+	}
+
+	return nil
+}
+
+// Bytes returns the chunk information that is covered by the signature
+// and by the transaction hash: root || index || total.
+func (c Chunk) Bytes() []byte {
+	var buf bytes.Buffer
+	buf.Grow(len(c.Root) + 8)
+	buf.Write(c.Root)
+
+	ibz := make([]byte, 4)
+	binary.BigEndian.PutUint32(ibz, c.Index)
+	buf.Write(ibz)
+
+	tbz := make([]byte, 4)
+	binary.BigEndian.PutUint32(tbz, c.Total)
+	buf.Write(tbz)
+
+	return buf.Bytes()
+}
+
+// ToProto returns a protobuf chunk object.
+func (c Chunk) ToProto() *vfsp2p.Chunk {
+	return &vfsp2p.Chunk{
+		Root:  c.Root,
+		Index: c.Index,
+		Total: c.Total,
+	}
+}
+
+// ChunkRoot computes the root hash of a chunked body. The root is the
+// merkle root of the SHA-256 digests of each chunk, in order.
+func ChunkRoot(chunks [][]byte) []byte {
+	digests := make([][]byte, len(chunks))
+	for i, chunk := range chunks {
+		digests[i] = tmhash.Sum(chunk)
+	}
+
+	return merkle.HashFromByteSlices(digests)
+}
+
+// SplitBody splits a body in chunks of at most chunkSize bytes.
+func SplitBody(body []byte, chunkSize int) [][]byte {
+	if chunkSize <= 0 || len(body) <= chunkSize {
+		return [][]byte{body}
+	}
+
+	chunks := make([][]byte, 0, (len(body)+chunkSize-1)/chunkSize)
+	for start := 0; start < len(body); start += chunkSize {
+		end := min(start+chunkSize, len(body))
+		chunks = append(chunks, body[start:end])
+	}
+
+	return chunks
+}
+
+// NewChunkedTransactions splits a body in chunks of at most chunkSize bytes
+// and creates one signed transaction per chunk. Transactions must be
+// committed in order, the body can be queried using the chunk root hash.
+func NewChunkedTransactions(
+	priv ed25519.PrivKey,
+	body []byte,
+	chunkSize int,
+	t time.Time,
+) ([]*SignedTransaction, error) {
+	if len(body) == 0 {
+		return nil, errors.New("body must not be empty")
+	}
+
+	chunks := SplitBody(body, chunkSize)
+	root := ChunkRoot(chunks)
+
+	txs := make([]*SignedTransaction, len(chunks))
+	for i, data := range chunks {
+		stx := &SignedTransaction{
+			Signer: priv.PubKey().(ed25519.PubKey),
+			Size:   len(data),
+			Time:   t,
+			Data:   data,
+			Chunk: &Chunk{
+				Root:  root,
+				Index: uint32(i),
+				Total: uint32(len(chunks)),
+			},
+		}
+
+		sig, err := priv.Sign(stx.SignBytes())
+		if err != nil {
+			return nil, err
+		}
+
+		stx.Signature = sig
+		stx.Hash = ComputeHash(stx)
+		txs[i] = stx
+	}
+
+	return txs, nil
+}
+
+// --------------------------------------------------------------------------
+
+// stageChunk appends a chunk transaction to the manifest of its body and its
+// signer, such that the chunks of a signer never collide with the chunks of
+// another signer of the same body. Chunks must be received in order. When the
+// last chunk is received, the root hash is verified and the chunk is rejected
+// if the chunk digests do not produce the expected root.
+func (app *VStoreApplication) stageChunk(tx SignedTransaction) error {
+	c := tx.Chunk
+	if err := c.ValidateBasic(); err != nil {
+		return err
+	}
+
+	dbKey := ChunkManifestKey(tx.Signer.Bytes(), c.Root)
+	m, ok := app.chunks[string(dbKey)]
+	if !ok {
+		var err error
+		m, err = app.loadChunkManifest(tx.Signer.Bytes(), c.Root)
+		if err != nil {
+			return err
+		}
+
+		if m == nil {
+			m = &chunkManifest{Signer: tx.Signer.Bytes(), Total: c.Total}
+		}
+	}
+
+	if m.Total != c.Total {
+		return fmt.Errorf("chunk total mismatch, want: %d, got: %d", m.Total, c.Total)
+	}
+
+	if m.Complete() {
+		return errors.New("chunk set already complete")
+	}
+
+	if c.Index != uint32(len(m.Hashes)) {
+		return fmt.Errorf("chunk out of order, want: %d, got: %d", len(m.Hashes), c.Index)
+	}
+
+	// Work on a copy so that a rejected chunk leaves the manifest untouched
+	next := &chunkManifest{
+		Signer:  m.Signer,
+		Total:   m.Total,
+		Hashes:  append(append([][]byte{}, m.Hashes...), tx.Hash),
+		Digests: append(append([][]byte{}, m.Digests...), tmhash.Sum(tx.Data)),
+	}
+
+	// Last chunk received, the set must produce the root hash
+	if next.Complete() && !bytes.Equal(merkle.HashFromByteSlices(next.Digests), c.Root) {
+		return errors.New("chunk root mismatch")
+	}
+
+	app.chunks[string(dbKey)] = next
+	return nil
+}
+
+// commitChunkManifests saves the staged chunk manifests with w. Staged
+// manifests are keyed by their database key.
+func (app *VStoreApplication) commitChunkManifests(w dbWriter) error {
+	for dbKey, m := range app.chunks {
+		bz, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+
+		if err := w.Set([]byte(dbKey), bz); err != nil {
+			return err
+		}
+	}
+
+	app.chunks = make(map[string]*chunkManifest)
+	return nil
+}
+
+// loadChunkManifest reads a chunk manifest from the database, it returns
+// nil if no chunk has been committed for this signer and root.
+func (app *VStoreApplication) loadChunkManifest(signer []byte, root []byte) (*chunkManifest, error) {
+	dbKey := ChunkManifestKey(signer, root)
+	data, err := app.state.db.Get(dbKey)
+	if err != nil || len(data) == 0 {
+		return nil, err
+	}
+
+	m := new(chunkManifest)
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// readChunkedBodyFromDB reassembles a chunked body of a signer from the
+// database. The chunk transactions are decrypted and their bodies concatenated
+// in order. A transaction is returned which contains the complete body and
+// uses the root hash as its hash. Incomplete chunk sets are rejected.
+func (app *VStoreApplication) readChunkedBodyFromDB(signer []byte, root []byte) ([]byte, error) {
+	m, err := app.loadChunkManifest(signer, root)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	if m == nil {
+		return []byte{}, nil
+	}
+
+	if !m.Complete() {
+		return []byte{}, errIncompleteChunks
+	}
+
+	var (
+		body    bytes.Buffer
+		lastTx  *SignedTransaction
+		digests = make([][]byte, 0, len(m.Hashes))
+	)
+
+	for _, hash := range m.Hashes {
+		txData, err := app.readTransactionFromDB(QueryType_Default, hash, pagination{})
+		if err != nil {
+			return []byte{}, err
+		}
+
+		if len(txData) == 0 {
+			return []byte{}, errIncompleteChunks
+		}
+
+		stx, err := FromBytes(txData)
+		if err != nil {
+			return []byte{}, err
+		}
+
+		digests = append(digests, tmhash.Sum(stx.Data))
+		body.Write(stx.Data)
+		lastTx = stx
+	}
+
+	// Stored chunks must still produce the root hash
+	if !bytes.Equal(merkle.HashFromByteSlices(digests), root) {
+		return []byte{}, errors.New("chunk root mismatch")
+	}
+
+	tx := new(vfsp2p.Transaction)
+	tx.Signer = PubKeyToProto(lastTx.Signer)
+	tx.Hash = root
+	tx.Time = time.Unix(lastTx.Time.Unix(), 0)
+	tx.Len = uint32(body.Len())
+	tx.Body = body.Bytes()
+	tx.Chunk = &vfsp2p.Chunk{Root: root, Total: m.Total}
+
+	return tx.Marshal()
+}
+
+// errIncompleteChunks is returned when a chunked body is not complete.
+var errIncompleteChunks = errors.New("incomplete chunk set")
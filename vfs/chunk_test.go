@@ -0,0 +1,191 @@
+package vfs
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	vfsp2p "github.com/securesharelabs/vstore/api/vstore/v1"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+	"github.com/cosmos/gogoproto/proto"
+)
+
+func TestVStoreChunkedCommitAndQuery(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-chunked_commit_and_query", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	body := bytes.Repeat([]byte(testComplexValue), 10)
+	stxs, err := NewChunkedTransactions(ed25519.PrivKey(ownerPrivs[0]), body, 64, time.Now())
+	require.NoError(t, err, "should create chunked transactions")
+	require.Len(t, stxs, (len(body)+63)/64)
+
+	root := stxs[0].Chunk.Root
+	key := chunksQueryKey(stxs[0])
+	for _, stx := range stxs {
+		checkTxResp, err := vstore.CheckTx(ctx, &abci.RequestCheckTx{Tx: stx.Bytes()})
+		require.NoError(t, err)
+		assert.Equal(t, CodeTypeOK, checkTxResp.Code)
+	}
+
+	// First block contains all chunks except the last one
+	txs := make([][]byte, 0, len(stxs))
+	for _, stx := range stxs[:len(stxs)-1] {
+		txs = append(txs, stx.Bytes())
+	}
+
+	resFinalize, _ := makeBlockCommit(ctx, t, vstore, 1, txs)
+	for _, res := range resFinalize.TxResults {
+		assert.Equal(t, CodeTypeOK, res.Code)
+	}
+
+	// Partial sets must not be reassembled
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/chunks", Data: key})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeInvalidChunkError, resQuery.Code)
+	assert.Empty(t, resQuery.Value)
+
+	// Second block contains the last chunk
+	last := stxs[len(stxs)-1]
+	resFinalize, _ = makeBlockCommit(ctx, t, vstore, 2, [][]byte{last.Bytes()})
+	assert.Equal(t, CodeTypeOK, resFinalize.TxResults[0].Code)
+
+	resQuery, err = vstore.Query(ctx, &abci.RequestQuery{Path: "/chunks", Data: key})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeOK, resQuery.Code)
+
+	tx := new(vfsp2p.Transaction)
+	err = proto.Unmarshal(resQuery.Value, tx)
+	require.NoError(t, err, "should unmarshal reassembled transaction")
+	assert.Equal(t, body, tx.Body, "reassembled body must be correct")
+	assert.Equal(t, len(body), int(tx.Len))
+	assert.Equal(t, root, tx.Hash)
+	assert.Equal(t, uint32(len(stxs)), tx.Chunk.Total)
+
+	// Each chunk is also queryable by its own hash
+	testVStoreQuery(ctx, t, vstore, string(last.Data), last, resFinalize.TxResults, vstore.state.Height)
+
+	// Chunked bodies are queried with the signer public key
+	resQuery, err = vstore.Query(ctx, &abci.RequestQuery{Path: "/chunks", Data: root})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeInvalidFormatError, resQuery.Code)
+}
+
+func TestVStoreChunkedOrdering(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-chunked_ordering", 2)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	body := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	stxs, err := NewChunkedTransactions(ed25519.PrivKey(ownerPrivs[0]), body, 10, time.Now())
+	require.NoError(t, err)
+	require.Len(t, stxs, 4)
+
+	// Out of order chunk is rejected
+	resFinalize, _ := makeBlockCommit(ctx, t, vstore, 1, [][]byte{stxs[1].Bytes()})
+	assert.Equal(t, CodeTypeInvalidChunkError, resFinalize.TxResults[0].Code)
+
+	// Chunk of another signer is out of order for the manifest of this signer
+	forged, err := NewChunkedTransactions(ed25519.PrivKey(ownerPrivs[1]), body, 10, time.Now())
+	require.NoError(t, err)
+
+	resFinalize, _ = makeBlockCommit(ctx, t, vstore, 2, [][]byte{stxs[0].Bytes(), forged[1].Bytes()})
+	assert.Equal(t, CodeTypeOK, resFinalize.TxResults[0].Code)
+	assert.Equal(t, CodeTypeInvalidChunkError, resFinalize.TxResults[1].Code)
+
+	// Chunk that does not produce the root hash is rejected
+	tampered := *stxs[3]
+	tampered.Data = []byte("tampered")
+	tampered.Size = len(tampered.Data)
+	sig, err := ed25519.PrivKey(ownerPrivs[0]).Sign(tampered.SignBytes())
+	require.NoError(t, err)
+	tampered.Signature = sig
+	tampered.Hash = ComputeHash(&tampered)
+
+	resFinalize, _ = makeBlockCommit(ctx, t, vstore, 3, [][]byte{stxs[1].Bytes(), stxs[2].Bytes(), tampered.Bytes()})
+	assert.Equal(t, CodeTypeOK, resFinalize.TxResults[0].Code)
+	assert.Equal(t, CodeTypeOK, resFinalize.TxResults[1].Code)
+	assert.Equal(t, CodeTypeInvalidChunkError, resFinalize.TxResults[2].Code)
+
+	// Set is still incomplete
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/chunks", Data: chunksQueryKey(stxs[0])})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeInvalidChunkError, resQuery.Code)
+
+	// Chunk information is covered by the signature
+	moved := *stxs[3]
+	moved.Chunk = &Chunk{Root: moved.Chunk.Root, Index: 0, Total: moved.Chunk.Total}
+	moved.Hash = ComputeHash(&moved)
+	checkTxResp, err := vstore.CheckTx(ctx, &abci.RequestCheckTx{Tx: moved.Bytes()})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeInvalidSignatureError, checkTxResp.Code)
+}
+
+func TestVStoreChunkedSignersShareRoot(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-chunked_signers_share_root", 2)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	body := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	stxs, err := NewChunkedTransactions(ed25519.PrivKey(ownerPrivs[0]), body, 10, time.Now())
+	require.NoError(t, err)
+
+	// Another signer commits the first chunk of the same body first
+	front, err := NewChunkedTransactions(ed25519.PrivKey(ownerPrivs[1]), body, 10, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, stxs[0].Chunk.Root, front[0].Chunk.Root, "chunk roots must be shared")
+
+	resFinalize, _ := makeBlockCommit(ctx, t, vstore, 1, [][]byte{front[0].Bytes()})
+	assert.Equal(t, CodeTypeOK, resFinalize.TxResults[0].Code)
+
+	// Chunks of the signer are not locked out by the other signer
+	txs := make([][]byte, 0, len(stxs))
+	for _, stx := range stxs {
+		txs = append(txs, stx.Bytes())
+	}
+
+	resFinalize, _ = makeBlockCommit(ctx, t, vstore, 2, txs)
+	for _, res := range resFinalize.TxResults {
+		assert.Equal(t, CodeTypeOK, res.Code)
+	}
+
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/chunks", Data: chunksQueryKey(stxs[0])})
+	require.NoError(t, err)
+	require.Equal(t, CodeTypeOK, resQuery.Code)
+
+	tx := new(vfsp2p.Transaction)
+	require.NoError(t, proto.Unmarshal(resQuery.Value, tx))
+	assert.Equal(t, body, tx.Body)
+	assert.Equal(t, stxs[0].Signer.Bytes(), tx.Signer.GetEd25519())
+
+	// The set of the other signer is still incomplete
+	resQuery, err = vstore.Query(ctx, &abci.RequestQuery{Path: "/chunks", Data: chunksQueryKey(front[0])})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeInvalidChunkError, resQuery.Code)
+}
+
+// chunksQueryKey returns the data of a /chunks query for the chunked body of
+// stx, i.e. the signer public key followed by the chunk root hash.
+func chunksQueryKey(stx *SignedTransaction) []byte {
+	key := append([]byte{}, stx.Signer.Bytes()...)
+	return append(key, stx.Chunk.Root...)
+}
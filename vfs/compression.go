@@ -0,0 +1,164 @@
+package vfs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression selects the codec that compresses transactions before they are
+// encrypted in Commit.
+type Compression byte
+
+const (
+	// CompressionNone stores transactions verbatim. This is the default.
+	CompressionNone Compression = iota
+
+	// CompressionGzip compresses transactions with gzip (RFC 1952).
+	CompressionGzip
+
+	// CompressionZstd compresses transactions with Zstandard (RFC 8878).
+	CompressionZstd
+)
+
+// compressionHeaderLimit is the upper bound of the compression header byte.
+// Transactions stored without a header start with the protobuf tag of the
+// signer field (0x0A), and tags below 0x08 are invalid (field number 0), such
+// that a header is never mistaken for the start of a transaction.
+const compressionHeaderLimit = 0x08
+
+// zstd encoders and decoders are safe for concurrent EncodeAll and DecodeAll.
+var (
+	zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) { return zstd.NewWriter(nil) })
+	zstdDecoder = sync.OnceValues(func() (*zstd.Decoder, error) { return zstd.NewReader(nil) })
+)
+
+// String returns the name of the compression codec as used in flags.
+func (c Compression) String() string {
+	switch c {
+	case CompressionNone:
+		return "none"
+	case CompressionGzip:
+		return "gzip"
+	case CompressionZstd:
+		return "zstd"
+	default:
+		break
+	}
+
+	return fmt.Sprintf("unknown(%d)", byte(c))
+}
+
+// ParseCompression returns the compression codec with the name s.
+func ParseCompression(s string) (Compression, error) {
+	switch strings.ToLower(s) {
+	case "none", "":
+		return CompressionNone, nil
+	case "gzip":
+		return CompressionGzip, nil
+	case "zstd":
+		return CompressionZstd, nil
+	default:
+		break
+	}
+
+	return 0, fmt.Errorf("unknown compression codec: %q", s)
+}
+
+// SetCompression sets the codec that compresses new transactions before they
+// are encrypted in Commit. Transactions which do not get smaller, e.g. with
+// bodies that are already compressed, are stored uncompressed. Transactions
+// are read regardless of the codec they were stored with.
+func (app *VStoreApplication) SetCompression(codec Compression) {
+	app.compression = codec
+}
+
+// --------------------------------------------------------------------------
+// Private helpers
+
+// compressTransaction compresses txData with codec and prepends the one-byte
+// compression header. With CompressionNone, txData is returned without header
+// as before compression was introduced. If the compressed transaction is not
+// smaller, txData is returned with the header of CompressionNone.
+func compressTransaction(codec Compression, txData []byte) ([]byte, error) {
+	var compressed []byte
+	switch codec {
+	case CompressionNone:
+		return txData, nil
+	case CompressionGzip:
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(txData); err != nil {
+			return nil, err
+		}
+
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+
+		compressed = buf.Bytes()
+	case CompressionZstd:
+		enc, err := zstdEncoder()
+		if err != nil {
+			return nil, err
+		}
+
+		compressed = enc.EncodeAll(txData, nil)
+	default:
+		return nil, fmt.Errorf("unknown compression codec: %d", byte(codec))
+	}
+
+	if len(compressed) >= len(txData) {
+		codec, compressed = CompressionNone, txData
+	}
+
+	return append([]byte{byte(codec)}, compressed...), nil
+}
+
+// decompressTransaction reads the compression header of data, if any, and
+// returns the decompressed transaction.
+func decompressTransaction(data []byte) ([]byte, error) {
+	if len(data) == 0 || data[0] >= compressionHeaderLimit {
+		return data, nil
+	}
+
+	codec, payload := Compression(data[0]), data[1:]
+	switch codec {
+	case CompressionNone:
+		return payload, nil
+	case CompressionGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("could not decompress transaction: %w", err)
+		}
+		defer zr.Close()
+
+		txData, err := io.ReadAll(zr)
+		if err != nil {
+			return nil, fmt.Errorf("could not decompress transaction: %w", err)
+		}
+
+		return txData, nil
+	case CompressionZstd:
+		dec, err := zstdDecoder()
+		if err != nil {
+			return nil, err
+		}
+
+		txData, err := dec.DecodeAll(payload, nil)
+		if err != nil {
+			return nil, fmt.Errorf("could not decompress transaction: %w", err)
+		}
+
+		return txData, nil
+	default:
+		break
+	}
+
+	return nil, fmt.Errorf("unknown compression codec: %d", byte(codec))
+}
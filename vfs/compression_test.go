@@ -0,0 +1,143 @@
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cmtdb "github.com/cometbft/cometbft-db"
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+func TestCompressTransaction(t *testing.T) {
+	compressible := []byte(strings.Repeat(`{"name":"vstore","values":[1,2,3]}`, 64))
+	incompressible, err := readRandom(4096)
+	require.NoError(t, err)
+
+	for _, codec := range []Compression{CompressionGzip, CompressionZstd} {
+		t.Run(codec.String(), func(t *testing.T) {
+			// Compressible payloads are stored compressed
+			data, err := compressTransaction(codec, compressible)
+			require.NoError(t, err)
+			assert.Equal(t, byte(codec), data[0], "header must record the codec")
+			assert.Less(t, len(data), len(compressible))
+
+			txData, err := decompressTransaction(data)
+			require.NoError(t, err)
+			assert.Equal(t, compressible, txData)
+
+			// Incompressible payloads are stored uncompressed
+			data, err = compressTransaction(codec, incompressible)
+			require.NoError(t, err)
+			assert.Equal(t, byte(CompressionNone), data[0], "header must record no compression")
+			assert.Len(t, data, len(incompressible)+1)
+
+			txData, err = decompressTransaction(data)
+			require.NoError(t, err)
+			assert.Equal(t, incompressible, txData)
+		})
+	}
+
+	// Without codec, transactions are stored without header
+	data, err := compressTransaction(CompressionNone, compressible)
+	require.NoError(t, err)
+	assert.Equal(t, compressible, data)
+
+	// Transactions stored without header are returned verbatim
+	stx, err := makeTransaction(t, ed25519.GenPrivKey(), []byte(testComplexValue))
+	require.NoError(t, err)
+
+	txData, err := decompressTransaction(stx.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, stx.Bytes(), txData)
+
+	// Corrupt payloads and unknown codecs are errors
+	_, err = decompressTransaction([]byte{byte(CompressionGzip), 0x01, 0x02})
+	assert.Error(t, err)
+	_, err = decompressTransaction([]byte{0x07, 0x01, 0x02})
+	assert.Error(t, err)
+	_, err = compressTransaction(Compression(0x07), compressible)
+	assert.Error(t, err)
+
+	for _, name := range []string{"none", "gzip", "ZSTD"} {
+		codec, err := ParseCompression(name)
+		require.NoError(t, err)
+		assert.Equal(t, strings.ToLower(name), codec.String())
+	}
+
+	_, err = ParseCompression("lz4")
+	assert.Error(t, err)
+}
+
+func TestVStoreCompression(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-compression", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	db := cmtdb.NewMemDB()
+	vstore := NewVStoreApplication(db, filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	secret, err := vstore.priv.Identity().Secret()
+	require.NoError(t, err)
+	key, err := DeriveSignerKey(secret, ed25519.PrivKey(ownerPrivs[0]).PubKey().Bytes())
+	require.NoError(t, err)
+
+	random, err := readRandom(4096)
+	require.NoError(t, err)
+
+	// A transaction committed before compression is enabled
+	legacy, err := makeTransaction(t, ownerPrivs[0], []byte(testComplexValue))
+	require.NoError(t, err)
+
+	compressible, err := makeTransaction(t, ownerPrivs[0], []byte(strings.Repeat(testComplexValue, 64)))
+	require.NoError(t, err)
+	incompressible, err := makeTransaction(t, ownerPrivs[0], random)
+	require.NoError(t, err)
+
+	expectedHeaders := []int{-1, int(CompressionZstd), int(CompressionNone)}
+	for i, stx := range []*SignedTransaction{legacy, compressible, incompressible} {
+		if i == 0 {
+			vstore.SetCompression(CompressionNone)
+		} else {
+			vstore.SetCompression(CompressionZstd)
+		}
+
+		resFinalize := testVStoreCommitTx(ctx, t, vstore, stx.Bytes())
+		require.Equal(t, CodeTypeOK, resFinalize.TxResults[0].Code)
+		txHash := resFinalize.TxResults[0].Data
+
+		// The stored transaction starts with the compression header, if any
+		stored, err := db.Get(TransactionKey(txHash))
+		require.NoError(t, err)
+
+		data, err := Decrypt(key, stored[txKeyHeaderLen:])
+		require.NoError(t, err)
+		if expectedHeaders[i] < 0 {
+			assert.Equal(t, stx.Bytes(), data, "transaction must be stored without header")
+		} else {
+			assert.Equal(t, byte(expectedHeaders[i]), data[0])
+		}
+
+		// Queries return the decompressed transaction
+		resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: txHash})
+		require.NoError(t, err)
+		assert.Equal(t, stx.Bytes(), resQuery.Value)
+	}
+
+	compressed, err := db.Get(TransactionKey(ComputeHash(compressible)))
+	require.NoError(t, err)
+	assert.Less(t, len(compressed), len(compressible.Bytes()))
+
+	// Transactions are read regardless of the codec of the application
+	vstore.SetCompression(CompressionGzip)
+	assert.NoError(t, vstore.VerifyIntegrity())
+	require.NoError(t, vstore.Reindex())
+	assert.NoError(t, vstore.VerifyIntegrity())
+}
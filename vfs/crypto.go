@@ -4,13 +4,14 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
-	"crypto/rand"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sync"
 
 	"github.com/cometbft/cometbft/crypto"
 	"github.com/cometbft/cometbft/crypto/ed25519"
@@ -20,8 +21,8 @@ import (
 // SecretProvider describes a provider that returns an AES-256 secret which
 // is used to encrypt an ed25519 identity (private key).
 type SecretProvider interface {
-	// Bytes returns the raw bytes of a secret provider which include the random
-	// salt (8 bytes) used for encryption and the private key (64 bytes)
+	// Bytes returns the raw bytes of a secret provider which include the header,
+	// the random salt used for encryption and the private key (64 bytes)
 	// Note: This function does not decrypt the AES encrypted private key.
 	Bytes() ([]byte, error)
 
@@ -53,12 +54,28 @@ type IdentitySecretProvider interface {
 
 // identityFile is a private structure that describes a password-protected
 // identity file. The identity file is expected to contain a base64-encoded
-// AES-256 ciphertext prepended by an 8-bytes salt. The private key can be
-// accessed only using the Identity() method and SecretIdentity interface.
-// The file must be accessible.
+// AES-256 ciphertext prepended by a header and a random salt. The header
+// records the key derivation function and the salt size, files without a
+// header use SHA-256 and an 8-bytes salt.
+// The private key can be accessed only using the Identity() method and
+// SecretIdentity interface. The file must be accessible.
 type identityFile struct {
 	Path string
 	pw   []byte
+
+	// secrets keeps the derived secret such that the (slow) key derivation
+	// function does not run every time the identity is opened.
+	secrets *secretCache
+}
+
+// secretCache keeps the last secret derived from the password of an identity
+// file, with the key derivation function and salt used to derive it. It holds
+// a single secret which is zeroized when it is replaced and when the identity
+// file is closed, callers receive copies.
+type secretCache struct {
+	mtx    sync.Mutex
+	key    []byte
+	secret []byte
 }
 
 // ed25519Identity is a byte slice that describes a ed25519 private key.
@@ -68,23 +85,39 @@ type ed25519Identity []byte
 // Type assertion to ensure the struct can be used to decrypt a ed25519 private key.
 var _ SecretProvider = (*identityFile)(nil)
 
+// Type assertion to ensure the cached secret of the struct can be zeroized.
+var _ io.Closer = (*identityFile)(nil)
+
 // Type assertion to ensure the struct can be used to create a secret from private key.
 var _ IdentitySecretProvider = (*ed25519Identity)(nil)
 
 // NewIdentity creates a new identityFile instance
+// This function will panic if the password is empty or the file is missing.
 func NewIdentity(file string, pw []byte) *identityFile {
+	id, err := NewIdentityE(file, pw)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return id
+}
+
+// NewIdentityE creates a new identityFile instance or returns an error if the
+// password is empty or the file is missing.
+func NewIdentityE(file string, pw []byte) (*identityFile, error) {
 	if len(pw) == 0 {
-		panic("password must not be empty")
+		return nil, errors.New("password must not be empty")
 	}
 
 	if _, err := os.Stat(file); err != nil {
-		panic(fmt.Sprintf("could not open id file: %v", err))
+		return nil, fmt.Errorf("could not open id file: %v", err)
 	}
 
 	return &identityFile{
-		Path: file,
-		pw:   pw,
-	}
+		Path:    file,
+		pw:      pw,
+		secrets: &secretCache{},
+	}, nil
 }
 
 // --------------------------------------------------------------------------
@@ -116,28 +149,12 @@ func (id identityFile) Bytes() ([]byte, error) {
 
 // Open reads an AES encrypted file (base64-encoded) and decrypts
 // its content using a salted password hash. This function expects
-// the random salt to be prepended to the ciphertext (8 bytes).
+// the random salt to be prepended to the ciphertext, and reads the
+// key derivation function and salt size from the header, if any.
 // Open implements SecretProvider
 func (id identityFile) Open() ([]byte, error) {
-	if len(id.pw) == 0 {
-		return []byte{}, errors.New("password must not be empty")
-	}
-
-	// Read the AES ciphertext bytes from file
-	// Note: the first 8-bytes contain the random salt
-	ctbz, err := id.Bytes()
-	if err != nil {
-		return []byte{}, err
-	}
-
-	// Extract salt 8-bytes before ciphertext
-	salt, ctbz := ctbz[:8], ctbz[8:]
-
-	// Generate secret from password
-	secret, _ := MustGenerateSecret(id.pw, salt)
-
-	// Decrypt the ciphertext (private key bytes)
-	pbz, err := Decrypt(secret, ctbz)
+	secret, pbz, err := id.unseal()
+	zeroize(secret)
 	if err != nil {
 		return []byte{}, err
 	}
@@ -145,22 +162,13 @@ func (id identityFile) Open() ([]byte, error) {
 	return pbz, nil
 }
 
-// Secret returns the 32-bytes secret generated as a SHA-256 hash using
-// a salt (8 bytes) and a password. A salt is expected to be available as
-// the first 8 bytes before the ciphertext returned with Bytes.
+// Secret returns the 32-bytes secret derived from a salt and a password,
+// using the key derivation function and salt size of the identity file
+// header. Files without a header use a SHA-256 hash of an 8-bytes salt and
+// the password.
 // Secret implement SecretProvider
 func (id identityFile) Secret() ([]byte, error) {
-	// Read content and base64-decode
-	ctbz, err := id.Bytes()
-	if err != nil {
-		return []byte{}, err
-	}
-
-	// Salt consists of 8 bytes prepended to ciphertext
-	salt := ctbz[:8]
-
-	// Generate the AES-compatible 32-bytes secret from password and salt
-	secret, _, err := GenerateSecret(id.pw, salt)
+	secret, _, err := id.unseal()
 	if err != nil {
 		return []byte{}, err
 	}
@@ -168,6 +176,15 @@ func (id identityFile) Secret() ([]byte, error) {
 	return secret, nil
 }
 
+// KDF returns the key derivation function that derives the secret of the
+// identity file from the password. Files without a header use KDFSHA256.
+func (id identityFile) KDF() (KDF, error) {
+	secret, pbz, kdf, err := id.unsealWithKDF()
+	zeroize(secret)
+	zeroize(pbz)
+	return kdf, err
+}
+
 // Identity returns a ed25519Identity by opening the identity file and using
 // the secret to decrypt the ed25519 private key.
 // Identity implements SecretProvider
@@ -180,6 +197,95 @@ func (id identityFile) Identity() IdentitySecretProvider {
 	return ed25519Identity(bz)
 }
 
+// unseal derives the secret from the password and decrypts the identity file.
+// It returns the secret and the private key bytes.
+func (id identityFile) unseal() ([]byte, []byte, error) {
+	secret, pbz, _, err := id.unsealWithKDF()
+	return secret, pbz, err
+}
+
+// unsealWithKDF decrypts the identity file like unseal and also returns the
+// key derivation function that derived the secret.
+func (id identityFile) unsealWithKDF() ([]byte, []byte, KDF, error) {
+	if len(id.pw) == 0 {
+		return []byte{}, []byte{}, 0, errors.New("password must not be empty")
+	}
+
+	// Read the header, salt and AES ciphertext bytes from file
+	ctbz, err := id.Bytes()
+	if err != nil {
+		return []byte{}, []byte{}, 0, err
+	}
+
+	candidates, err := parseIdentityBytes(ctbz)
+	if err != nil {
+		return []byte{}, []byte{}, 0, err
+	}
+
+	for _, c := range candidates {
+		var secret []byte
+		secret, err = id.deriveSecret(c.kdf, c.salt)
+		if err != nil {
+			continue
+		}
+
+		// Decrypt the ciphertext (private key bytes)
+		var pbz []byte
+		pbz, err = Decrypt(secret, c.ct)
+		if err != nil {
+			zeroize(secret)
+			continue
+		}
+
+		return secret, pbz, c.kdf, nil
+	}
+
+	return []byte{}, []byte{}, 0, err
+}
+
+// Close zeroizes the secret that is kept in memory, the secret is derived
+// again from the password when the identity is opened after Close.
+// Close implements io.Closer
+func (id identityFile) Close() error {
+	if id.secrets == nil {
+		return nil
+	}
+
+	id.secrets.mtx.Lock()
+	defer id.secrets.mtx.Unlock()
+
+	zeroize(id.secrets.secret)
+	id.secrets.key, id.secrets.secret = nil, nil
+	return nil
+}
+
+// deriveSecret derives the secret from the password with kdf and salt. The
+// last derived secret is kept when the identity was created with NewIdentity,
+// a copy is returned such that callers can zeroize it after use.
+func (id identityFile) deriveSecret(kdf KDF, salt []byte) ([]byte, error) {
+	if id.secrets == nil {
+		return DeriveSecret(kdf, id.pw, salt)
+	}
+
+	key := append([]byte{byte(kdf)}, salt...)
+
+	id.secrets.mtx.Lock()
+	defer id.secrets.mtx.Unlock()
+
+	if bytes.Equal(id.secrets.key, key) {
+		return append([]byte{}, id.secrets.secret...), nil
+	}
+
+	secret, err := DeriveSecret(kdf, id.pw, salt)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	zeroize(id.secrets.secret)
+	id.secrets.key, id.secrets.secret = key, append([]byte{}, secret...)
+	return secret, nil
+}
+
 // --------------------------------------------------------------------------
 // ed25519Identity implements IdentitySecretProvider
 
@@ -221,8 +327,8 @@ func (id ed25519Identity) PubKey() (crypto.PubKey, error) {
 
 // GenerateSecret generates a 32-bytes secret by creating a SHA-256
 // hash of a salted password using a random salt of 8-bytes. If a non-empty
-// salt is provided, it is expected to be of 8-bytes length.
-// It returns the 32-bytes secret and an 8-bytes salt.
+// salt is provided, it is expected to be of 8, 16 or 32 bytes length.
+// It returns the 32-bytes secret and the salt.
 func GenerateSecret(pw, salt []byte) ([]byte, []byte, error) {
 	if len(pw) == 0 {
 		return []byte{}, []byte{}, errors.New("password must not be empty")
@@ -230,20 +336,21 @@ func GenerateSecret(pw, salt []byte) ([]byte, []byte, error) {
 
 	if len(salt) == 0 {
 		// Generate random 8-bytes salt
-		salt = make([]byte, 8)
-		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		var err error
+		if salt, err = readRandom(8); err != nil {
 			return []byte{}, []byte{}, err
 		}
-	} else if len(salt) != 8 {
-		return []byte{}, []byte{}, fmt.Errorf("invalid salt size, want: %d, got: %d", 8, len(salt))
+	} else if !validSaltSize(len(salt)) {
+		return []byte{}, []byte{}, fmt.Errorf("invalid salt size, want: 8, 16 or 32, got: %d", len(salt))
 	}
 
 	// Secret is: SHA256(salt || password)
 	var sbuf bytes.Buffer
-	sbuf.Grow(8 + len(pw))
-	sbuf.Write(salt) // 8-bytes salt
+	sbuf.Grow(len(salt) + len(pw))
+	sbuf.Write(salt) // salt
 	sbuf.Write(pw)   // password
 	secret := tmhash.Sum(sbuf.Bytes())
+	zeroize(sbuf.Bytes())
 
 	return secret, salt, nil
 }
@@ -261,9 +368,9 @@ func Encrypt(secret []byte, data []byte) ([]byte, error) {
 		return []byte{}, err
 	}
 
-	// Generate random salt
-	salt := make([]byte, gcm.NonceSize())
-	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+	// Generate random salt (nonce)
+	salt, err := readRandom(gcm.NonceSize())
+	if err != nil {
 		return []byte{}, err
 	}
 
@@ -286,6 +393,10 @@ func Decrypt(secret []byte, ciphertext []byte) ([]byte, error) {
 	}
 
 	saltSize := gcm.NonceSize()
+	if len(ciphertext) < saltSize {
+		return []byte{}, errors.New("ciphertext too short")
+	}
+
 	salt, ct := ciphertext[:saltSize], ciphertext[saltSize:]
 
 	bz, err := gcm.Open(nil, salt, ct, nil)
@@ -297,12 +408,23 @@ func Decrypt(secret []byte, ciphertext []byte) ([]byte, error) {
 }
 
 // MustGenerateIdentity generates a new ed25519 private key and saves it to
-// the provided idFile file. A password pw is used to encrypt the private key.
-// 8 bytes are added in front of the ciphertext which consist of a random salt.
-// The created identity file contains a base64-encoded AES ciphertext prefixed
-// with a random salt of 8 bytes.
+// the provided idFile file. A password pw is used to encrypt the private key
+// with a secret derived using DefaultKDF.
 // This function will panic if any errors occur.
 func MustGenerateIdentity(idFile string, pw []byte) (string, string) {
+	return MustGenerateIdentityWithKDF(idFile, pw, DefaultKDF)
+}
+
+// MustGenerateIdentityWithKDF generates a new ed25519 private key and saves it
+// to the provided idFile file. A password pw is used to encrypt the private key
+// with a secret derived using kdf. A header and DefaultSaltSize bytes are added
+// in front of the ciphertext which consist of the key derivation function, the
+// salt size and a random salt.
+// The created identity file contains a base64-encoded AES ciphertext prefixed
+// with a header and a random salt of DefaultSaltSize bytes. The identity file
+// and the .pub file are written atomically, i.e. never half-written.
+// This function will panic if any errors occur.
+func MustGenerateIdentityWithKDF(idFile string, pw []byte, kdf KDF) (string, string) {
 	if len(pw) == 0 {
 		panic("password must not be empty")
 	}
@@ -314,23 +436,16 @@ func MustGenerateIdentity(idFile string, pw []byte) (string, string) {
 
 	// Generate ed25519 private key
 	priv := ed25519.GenPrivKey()
-
-	// Generate random salt and 32-bytes secret for AES
-	secret, salt := MustGenerateSecret(pw, []byte{}) // random salt
+	defer zeroize(priv)
 
 	// Encrypt the private key using AES
-	ctbz, err := Encrypt(secret, priv.Bytes())
+	b64, err := sealIdentity(priv.Bytes(), pw, kdf)
 	if err != nil {
 		panic(err.Error())
 	}
 
-	// Salt is added in front of ciphertext (starting 8-bytes)
-	// The salt must be in plaintext to decrypt with the password.
-	ctbz = append(salt, ctbz...)
-
-	// Write base64-encoded ciphertext to file
-	b64 := base64.StdEncoding.EncodeToString(ctbz)
-	err = os.WriteFile(idFile, []byte(b64), 0600)
+	// Write base64-encoded ciphertext to file (atomically)
+	err = writeFileAtomic(idFile, b64, 0600)
 	if err != nil {
 		panic(err.Error())
 	}
@@ -338,7 +453,7 @@ func MustGenerateIdentity(idFile string, pw []byte) (string, string) {
 	// Also *always* create a (cleartext) co-located .pub file
 	pubFile := idFile + ".pub"
 	b64_pub := base64.StdEncoding.EncodeToString(priv.PubKey().Bytes())
-	err = os.WriteFile(pubFile, []byte(b64_pub), 0644)
+	err = writeFileAtomic(pubFile, []byte(b64_pub), 0644)
 	if err != nil {
 		panic(err.Error())
 	}
@@ -347,6 +462,35 @@ func MustGenerateIdentity(idFile string, pw []byte) (string, string) {
 	return idFile, pubFile
 }
 
+// sealIdentity encrypts the private key bytes pbz with a secret derived from
+// the password pw using kdf and a random salt of DefaultSaltSize bytes. It
+// returns the base64-encoded content of an identity file.
+func sealIdentity(pbz []byte, pw []byte, kdf KDF) ([]byte, error) {
+	// Generate random salt and 32-bytes secret for AES
+	salt, err := readRandom(DefaultSaltSize)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	secret, err := DeriveSecret(kdf, pw, salt)
+	if err != nil {
+		return []byte{}, err
+	}
+	defer zeroize(secret)
+
+	// Encrypt the private key using AES
+	ctbz, err := Encrypt(secret, pbz)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	// Header and salt are added in front of ciphertext
+	// The salt must be in plaintext to decrypt with the password.
+	ctbz = append(append(identityHeader(kdf, len(salt)), salt...), ctbz...)
+
+	return []byte(base64.StdEncoding.EncodeToString(ctbz)), nil
+}
+
 // MustGenerateSecret generates a 32-bytes secret with salt or panics.
 // This function will panic if any errors occur.
 func MustGenerateSecret(pw, salt []byte) ([]byte, []byte) {
@@ -358,3 +502,13 @@ func MustGenerateSecret(pw, salt []byte) ([]byte, []byte) {
 
 	return secret, salt
 }
+
+// zeroize overwrites the backing array of b with zeros, such that secrets,
+// private keys and decrypted transactions do not linger in memory after use.
+// Reassigning a slice, e.g. with b = []byte{}, does not clear its bytes.
+func zeroize(b []byte) {
+	clear(b)
+
+	// The writes must not be eliminated as dead stores
+	runtime.KeepAlive(b)
+}
@@ -1,6 +1,9 @@
 package vfs
 
 import (
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -8,6 +11,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/cometbft/cometbft/crypto/ed25519"
 	"github.com/cometbft/cometbft/crypto/tmhash"
 )
 
@@ -141,3 +145,245 @@ func TestVStoreCryptoMustGenerateIdentity(t *testing.T) {
 	// ed25519 private key contains compressed pubkey bytes (32)
 	assert.Contains(t, string(pbz), string(pk.Bytes()))
 }
+
+func TestVStoreCryptoIdentityKDF(t *testing.T) {
+	// create a unique, concurrency-safe test directory under os.TempDir()
+	rootDir, _ := os.MkdirTemp("", "test-vstore-crypto-identity_kdf")
+	defer os.RemoveAll(rootDir)
+
+	pw := []byte("testpassword")
+	for _, kdf := range []KDF{KDFSHA256, KDFArgon2id} {
+		idFile, _ := MustGenerateIdentityWithKDF(filepath.Join(rootDir, kdf.String()), pw, kdf)
+
+		// check that the header records the key derivation function
+		bz, err := NewIdentity(idFile, pw).Bytes()
+		require.NoError(t, err)
+		assert.Equal(t, identityHeader(kdf, DefaultSaltSize), bz[:identityHeaderLen2])
+
+		// check round-trip decryption
+		id := NewIdentity(idFile, pw)
+		pbz, err := id.Open()
+		require.NoError(t, err, "should decrypt identity file with %s", kdf)
+		assert.Len(t, pbz, 64)
+
+		salt := bz[identityHeaderLen2 : identityHeaderLen2+DefaultSaltSize]
+		expected, err := DeriveSecret(kdf, pw, salt)
+		require.NoError(t, err)
+
+		secret, err := id.Secret()
+		require.NoError(t, err)
+		assert.Equal(t, expected, secret)
+
+		_, err = NewIdentity(idFile, []byte("wrongpassword")).Open()
+		assert.Error(t, err, "should not decrypt with a wrong password")
+	}
+
+	// Argon2id and SHA-256 derive different secrets
+	salt := []byte("12345678")
+	s1, _ := DeriveSecret(KDFSHA256, pw, salt)
+	s2, _ := DeriveSecret(KDFArgon2id, pw, salt)
+	assert.NotEqual(t, s1, s2)
+
+	_, err := ParseKDF("md5")
+	assert.Error(t, err, "should not accept unknown key derivation functions")
+}
+
+func TestVStoreCryptoIdentityLegacy(t *testing.T) {
+	// create a unique, concurrency-safe test directory under os.TempDir()
+	rootDir, _ := os.MkdirTemp("", "test-vstore-crypto-identity_legacy")
+	defer os.RemoveAll(rootDir)
+
+	pw := []byte("testpassword")
+	priv := ed25519.GenPrivKey()
+
+	// Legacy identity files contain: salt (8 bytes) || ciphertext
+	secret, salt := MustGenerateSecret(pw, []byte{})
+	ctbz, err := Encrypt(secret, priv.Bytes())
+	require.NoError(t, err)
+
+	idFile := filepath.Join(rootDir, "id")
+	b64 := base64.StdEncoding.EncodeToString(append(salt, ctbz...))
+	require.NoError(t, os.WriteFile(idFile, []byte(b64), 0600))
+
+	id := NewIdentity(idFile, pw)
+	pbz, err := id.Open()
+	require.NoError(t, err, "should decrypt legacy identity file")
+	assert.Equal(t, priv.Bytes(), pbz)
+
+	s, err := id.Secret()
+	require.NoError(t, err)
+	assert.Equal(t, secret, s)
+}
+
+func TestVStoreCryptoIdentitySaltSize(t *testing.T) {
+	// create a unique, concurrency-safe test directory under os.TempDir()
+	rootDir, _ := os.MkdirTemp("", "test-vstore-crypto-identity_salt_size")
+	defer os.RemoveAll(rootDir)
+
+	pw := []byte("testpassword")
+	priv := ed25519.GenPrivKey()
+
+	// writeIdentity writes an identity file with the header and salt
+	writeIdentity := func(name string, header, salt []byte) string {
+		secret, err := DeriveSecret(KDFSHA256, pw, salt)
+		require.NoError(t, err)
+
+		ctbz, err := Encrypt(secret, priv.Bytes())
+		require.NoError(t, err)
+
+		idFile := filepath.Join(rootDir, name)
+		bz := append(append(append([]byte{}, header...), salt...), ctbz...)
+		b64 := base64.StdEncoding.EncodeToString(bz)
+		require.NoError(t, os.WriteFile(idFile, []byte(b64), 0600))
+		return idFile
+	}
+
+	for _, size := range []int{8, 16, 32} {
+		salt := make([]byte, size)
+		copy(salt, "saltsaltsaltsaltsaltsaltsaltsalt")
+
+		idFile := writeIdentity(fmt.Sprintf("id-%d", size), identityHeader(KDFSHA256, size), salt)
+		pbz, err := NewIdentity(idFile, pw).Open()
+		require.NoError(t, err, "should decrypt identity file with a salt of %d bytes", size)
+		assert.Equal(t, priv.Bytes(), pbz)
+
+		_, s, err := GenerateSecret(pw, salt)
+		require.NoError(t, err)
+		assert.Len(t, s, size)
+	}
+
+	// Version 1 headers use a salt of 8 bytes
+	v1Header := append(append([]byte{}, identityMagic...), identityVersion1, byte(KDFSHA256))
+	idFile := writeIdentity("id-v1", v1Header, []byte("12345678"))
+	pbz, err := NewIdentity(idFile, pw).Open()
+	require.NoError(t, err, "should decrypt identity file with a version 1 header")
+	assert.Equal(t, priv.Bytes(), pbz)
+
+	// New identity files use the longer salt by default
+	idFile, _ = MustGenerateIdentityWithKDF(filepath.Join(rootDir, "id-new"), pw, KDFSHA256)
+	bz, err := NewIdentity(idFile, pw).Bytes()
+	require.NoError(t, err)
+	assert.Equal(t, byte(DefaultSaltSize), bz[identityHeaderLen2-1])
+
+	_, err = DeriveSecret(KDFSHA256, pw, make([]byte, 12))
+	assert.Error(t, err, "should not accept a salt of 12 bytes")
+}
+
+func TestVStoreCryptoIdentityAtomicWrite(t *testing.T) {
+	// create a unique, concurrency-safe test directory under os.TempDir()
+	rootDir, _ := os.MkdirTemp("", "test-vstore-crypto-identity_atomic_write")
+	defer os.RemoveAll(rootDir)
+
+	pw := []byte("testpassword")
+	idFile, pubFile := MustGenerateIdentity(filepath.Join(rootDir, "id"), pw)
+	idBytes, _ := os.ReadFile(idFile)
+	pubBytes, _ := os.ReadFile(pubFile)
+
+	// Simulate an interrupted write: half of the data is written
+	defer func(write func(*os.File, []byte) error) { writeTempFile = write }(writeTempFile)
+	writeTempFile = func(f *os.File, data []byte) error {
+		f.Write(data[:len(data)/2])
+		return errors.New("interrupted write")
+	}
+
+	err := RekeyIdentity(idFile, pw, []byte("newtestpassword"), DefaultKDF)
+	assert.Error(t, err, "interrupted write must fail")
+
+	// Original files are unchanged and no temporary file is left
+	after, _ := os.ReadFile(idFile)
+	assert.Equal(t, idBytes, after, "identity file must not be half-written")
+
+	_, err = NewIdentity(idFile, pw).Open()
+	assert.NoError(t, err, "identity must still open with the password")
+
+	assert.Panics(t, func() {
+		MustGenerateIdentity(filepath.Join(rootDir, "id2"), pw)
+	})
+	assert.NoFileExists(t, filepath.Join(rootDir, "id2"), "identity file must not be created")
+
+	after, _ = os.ReadFile(pubFile)
+	assert.Equal(t, pubBytes, after)
+
+	entries, err := os.ReadDir(rootDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2, "temporary files must be removed")
+}
+
+func TestVStoreCryptoNewIdentityE(t *testing.T) {
+	// create a unique, concurrency-safe test directory under os.TempDir()
+	rootDir, _ := os.MkdirTemp("", "test-vstore-crypto-new_identity_e")
+	defer os.RemoveAll(rootDir)
+
+	pw := []byte("testpassword")
+	idFile, _ := MustGenerateIdentity(filepath.Join(rootDir, "id"), pw)
+
+	id, err := NewIdentityE(idFile, pw)
+	require.NoError(t, err)
+	assert.Equal(t, idFile, id.Path)
+
+	_, err = NewIdentityE(filepath.Join(rootDir, "missing"), pw)
+	assert.Error(t, err, "should return an error for a missing file")
+
+	_, err = NewIdentityE(idFile, []byte{})
+	assert.Error(t, err, "should return an error for an empty password")
+
+	// Panicking wrapper is kept for convenience
+	assert.Panics(t, func() { NewIdentity(idFile, []byte{}) })
+}
+
+func TestVStoreCryptoZeroize(t *testing.T) {
+	secret, err := readRandom(32)
+	require.NoError(t, err)
+
+	// The backing array is overwritten, not only the slice header
+	alias := secret[:]
+	zeroize(secret)
+	assert.Equal(t, make([]byte, 32), alias)
+
+	// Sub-slices zeroize their range of the backing array only
+	buf := []byte("public|secret")
+	zeroize(buf[7:])
+	assert.Equal(t, append([]byte("public|"), make([]byte, 6)...), buf)
+
+	assert.NotPanics(t, func() { zeroize(nil) })
+	assert.NotPanics(t, func() { zeroize([]byte{}) })
+
+	// Zeroized secrets do not alter the identity
+	rootDir, _ := os.MkdirTemp("", "test-vstore-crypto-zeroize")
+	defer os.RemoveAll(rootDir)
+
+	idFile, _ := MustGenerateIdentity(filepath.Join(rootDir, "id"), []byte("testpassword"))
+	vstore := NewInMemoryVStoreApplication(idFile, []byte("testpassword"))
+	first, err := vstore.identitySecret()
+	require.NoError(t, err)
+	expected := append([]byte{}, first...)
+	zeroize(first)
+
+	second, err := vstore.identitySecret()
+	require.NoError(t, err)
+	assert.Equal(t, expected, second)
+
+	// Zeroized secrets of the identity file do not alter the cached secret
+	id := NewIdentity(idFile, []byte("testpassword"))
+	idSecret, err := id.Secret()
+	require.NoError(t, err)
+	expected = append([]byte{}, idSecret...)
+	zeroize(idSecret)
+
+	idSecret, err = id.Secret()
+	require.NoError(t, err)
+	assert.Equal(t, expected, idSecret)
+
+	_, err = id.Open()
+	assert.NoError(t, err, "should decrypt with the cached secret")
+
+	// Close zeroizes the cached secret, which is derived again
+	cached := id.secrets.secret
+	require.NoError(t, id.Close())
+	assert.Equal(t, make([]byte, len(cached)), cached)
+	assert.Nil(t, id.secrets.secret)
+
+	idSecret, err = id.Secret()
+	require.NoError(t, err)
+	assert.Equal(t, expected, idSecret)
+}
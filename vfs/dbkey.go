@@ -0,0 +1,69 @@
+package vfs
+
+import (
+	"strconv"
+	"time"
+)
+
+// The functions of this file document the storage layout of the database and
+// return the exact keys under which the application stores its data, e.g. for
+// external tooling that reads the database directly:
+//
+//   - "vfs:" || hash: the encrypted transaction ;
+//   - "vfs:height:block-" || height: the JSON array of transaction hashes of a block ;
+//   - "vfs:pubkey:" || pubkey [|| "/" || page]: the JSON array of transaction hashes of a signer ;
+//   - "vfs:pubkey:" || pubkey || "/frontier": the JSON merkle frontier of the transaction hashes of a signer ;
+//   - "vfs:chunks:" || pubkey || root: the JSON manifest of a chunked body ;
+//   - "vfs:blocktime:block-" || height: the RFC 3339 block time of a block ;
+//   - "vfs:apphash:block-" || height: the JSON app hash record of a block ;
+//   - "vfs:time:" || hour: the JSON array of transaction hashes and timestamps of an hour ; and
+//   - "vfsState": the JSON application state.
+//
+// Heights are base10 strings, hours are formatted "2006-01-02T15" in UTC,
+// hashes and public keys are raw bytes.
+
+// TransactionKey returns the database key of the transaction with hash.
+func TransactionKey(hash []byte) []byte {
+	return prefixKey(hash)
+}
+
+// HeightIndexKey returns the database key of the transaction hashes of the
+// block at height.
+func HeightIndexKey(height int64) []byte {
+	return prefixKeyWith([]byte(strconv.FormatInt(height, 10)), vfsPrefixKeyByHeight)
+}
+
+// SignerIndexKey returns the database key of a page of the transaction hashes
+// of the signer with the public key pubKey. The first page is page 0.
+func SignerIndexKey(pubKey []byte, page int) []byte {
+	return signerIndexPageKey(pubKey, page)
+}
+
+// ChunkManifestKey returns the database key of the manifest of the chunked
+// body with the chunk root hash root, as signed by the signer with the public
+// key pubKey. Signers of the same body have distinct manifests.
+func ChunkManifestKey(pubKey []byte, root []byte) []byte {
+	key := make([]byte, 0, len(pubKey)+len(root))
+	return prefixKeyWith(append(append(key, pubKey...), root...), vfsPrefixKeyByChunks)
+}
+
+// BlockTimeKey returns the database key of the block time of height.
+func BlockTimeKey(height int64) []byte {
+	return prefixKeyWith([]byte(strconv.FormatInt(height, 10)), vfsPrefixKeyByBlockTime)
+}
+
+// AppHashKey returns the database key of the app hash record of height.
+func AppHashKey(height int64) []byte {
+	return prefixKeyWith([]byte(strconv.FormatInt(height, 10)), vfsPrefixKeyByAppHash)
+}
+
+// TimeIndexKey returns the database key of the hour bucket of the time index
+// which contains the transactions with a timestamp in the same hour as ts.
+func TimeIndexKey(ts time.Time) []byte {
+	return prefixKeyWith([]byte(ts.UTC().Format(timeIndexBucketLayout)), vfsPrefixKeyByTime)
+}
+
+// StateKey returns the database key of the application state.
+func StateKey() []byte {
+	return append([]byte{}, stateKey...)
+}
@@ -0,0 +1,67 @@
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cmtdb "github.com/cometbft/cometbft-db"
+)
+
+func TestVStoreStorageKeys(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-storage_keys", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	db := cmtdb.NewMemDB()
+	vstore := NewVStoreApplication(db, filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	stx, err := makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue))
+	require.NoError(t, err)
+
+	respFinBlock, _ := makeBlockCommit(ctx, t, vstore, 1, [][]byte{stx.Bytes()})
+	hash := respFinBlock.TxResults[0].Data
+
+	// Layout is documented with the exact keys
+	assert.Equal(t, append([]byte("vfs:"), hash...), TransactionKey(hash))
+	assert.Equal(t, []byte("vfs:height:block-1"), HeightIndexKey(1))
+	assert.Equal(t, append([]byte("vfs:pubkey:"), stx.Signer.Bytes()...), SignerIndexKey(stx.Signer.Bytes(), 0))
+	assert.Equal(t, append(append([]byte("vfs:pubkey:"), stx.Signer.Bytes()...), "/2"...), SignerIndexKey(stx.Signer.Bytes(), 2))
+	assert.Equal(t, []byte("vfs:blocktime:block-1"), BlockTimeKey(1))
+	assert.Equal(t, []byte("vfs:apphash:block-1"), AppHashKey(1))
+	assert.Equal(t, []byte("vfs:time:2024-06-04T09"), TimeIndexKey(time.Date(2024, 6, 4, 11, 30, 0, 0, time.FixedZone("CEST", 2*3600))))
+	assert.Equal(t, []byte("vfsState"), StateKey())
+
+	// Keys match what Commit writes
+	for _, key := range [][]byte{
+		TransactionKey(hash),
+		HeightIndexKey(1),
+		SignerIndexKey(stx.Signer.Bytes(), 0),
+		BlockTimeKey(1),
+		AppHashKey(1),
+		TimeIndexKey(stx.Time),
+		StateKey(),
+	} {
+		exists, err := db.Has(key)
+		require.NoError(t, err)
+		assert.True(t, exists, "Commit should write key %q", key)
+	}
+
+	// The transaction key stores the encrypted transaction
+	ct, err := db.Get(TransactionKey(hash))
+	require.NoError(t, err)
+	secret, err := vstore.priv.Identity().Secret()
+	require.NoError(t, err)
+	pt, err := openTransaction(secret, ct)
+	require.NoError(t, err)
+
+	committed, err := FromBytes(pt)
+	require.NoError(t, err)
+	assert.Equal(t, hash, committed.Hash)
+}
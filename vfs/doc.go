@@ -6,7 +6,10 @@ protocol which is based on digital signatures with ed25519 elliptic-curve.
 
 # Structures
 
-  - [IdentitySecretProvider]: Creates AES-256 secrets used to encrypt the database.
+  - [Chunk]: Describes the position of a transaction body in a chunked body.
+  - [DirBlobStore]: Stores transaction bodies that are referenced by content hash.
+  - [EventSink]: Receives the events emitted for committed transactions, e.g. CloudEvents.
+  - [IdentitySecretProvider]: Creates AES-256 secrets from which the signer keys of the database are derived.
   - [SecretProvider]: Creates AES-256 secrets used to encrypt private keys.
   - [Signable]: Interfaces that describes data to be signed using an ed25519 private key.
   - [SignedTransaction]: Describes a signed data object that is timestamped.
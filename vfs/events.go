@@ -0,0 +1,181 @@
+package vfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// EventFormat selects the payload format of the events that are emitted for
+// committed transactions.
+type EventFormat string
+
+const (
+	// EventFormatJSON emits the plain JSON CommittedEvent. This is the default.
+	EventFormatJSON EventFormat = "json"
+
+	// EventFormatCloudEvents emits the CommittedEvent as the data of a
+	// CloudEvents v1.0 envelope (structured content mode).
+	EventFormatCloudEvents EventFormat = "cloudevents"
+)
+
+// CloudEvents attributes of the events emitted for committed transactions.
+const (
+	CloudEventsSpecVersion = "1.0"
+	EventTypeCommitted     = "labs.securesharelabs.vstore.committed"
+	EventSource            = "/vstore"
+)
+
+// ParseEventFormat returns the event format with the name s.
+func ParseEventFormat(s string) (EventFormat, error) {
+	switch EventFormat(strings.ToLower(s)) {
+	case EventFormatJSON:
+		return EventFormatJSON, nil
+	case EventFormatCloudEvents:
+		return EventFormatCloudEvents, nil
+	default:
+		break
+	}
+
+	return "", fmt.Errorf("unknown event format: %q", s)
+}
+
+// ContentType returns the media type of the event payloads.
+func (f EventFormat) ContentType() string {
+	if f == EventFormatCloudEvents {
+		return "application/cloudevents+json"
+	}
+
+	return "application/json"
+}
+
+// CommittedEvent describes the metadata of a committed transaction. The hash
+// and signer are uppercase hexadecimal.
+type CommittedEvent struct {
+	Hash      string    `json:"hash"`
+	Signer    string    `json:"signer"`
+	Height    int64     `json:"height"`
+	Size      int       `json:"size"`
+	Time      time.Time `json:"time"`
+	BlockTime time.Time `json:"block_time"`
+}
+
+// CloudEvent describes a CloudEvents v1.0 envelope in structured content mode.
+type CloudEvent struct {
+	SpecVersion     string         `json:"specversion"`
+	ID              string         `json:"id"`
+	Source          string         `json:"source"`
+	Type            string         `json:"type"`
+	Subject         string         `json:"subject,omitempty"`
+	Time            time.Time      `json:"time"`
+	DataContentType string         `json:"datacontenttype"`
+	Data            CommittedEvent `json:"data"`
+}
+
+// NewCommittedEvent creates the event of a transaction committed at height
+// with the block time blockTime.
+func NewCommittedEvent(tx SignedTransaction, height int64, blockTime time.Time) CommittedEvent {
+	return CommittedEvent{
+		Hash:      fmt.Sprintf("%X", tx.Hash),
+		Signer:    tx.PublicKey(),
+		Height:    height,
+		Size:      tx.Size,
+		Time:      tx.Time.UTC(),
+		BlockTime: blockTime.UTC(),
+	}
+}
+
+// EncodeEvent returns the payload of an event in the format f.
+func EncodeEvent(f EventFormat, ev CommittedEvent) ([]byte, error) {
+	if f != EventFormatCloudEvents {
+		return json.Marshal(ev)
+	}
+
+	// Transaction hashes are unique, the height is added for re-deliveries
+	return json.Marshal(CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              fmt.Sprintf("%d-%s", ev.Height, ev.Hash),
+		Source:          EventSource,
+		Type:            EventTypeCommitted,
+		Subject:         ev.Hash,
+		Time:            ev.BlockTime,
+		DataContentType: "application/json",
+		Data:            ev,
+	})
+}
+
+// EventSink describes a destination of the events that are emitted for
+// committed transactions, e.g. a webhook.
+type EventSink interface {
+	// Emit delivers an event payload with the media type contentType.
+	Emit(contentType string, payload []byte) error
+}
+
+// WebhookSink implements EventSink by sending the event payloads with HTTP
+// POST requests to a URL.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+var _ EventSink = (*WebhookSink)(nil)
+
+// NewWebhookSink creates an event sink that sends events to url.
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Emit implements EventSink
+func (s *WebhookSink) Emit(contentType string, payload []byte) error {
+	resp, err := s.client.Post(s.url, contentType, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status: %s", resp.Status)
+	}
+
+	return nil
+}
+
+// SetEventSink sets the destination of the events emitted for committed
+// transactions, and the payload format of the events.
+func (app *VStoreApplication) SetEventSink(sink EventSink, format EventFormat) {
+	app.events = sink
+	app.eventFormat = format
+}
+
+// emitCommittedEvents emits an event for every staged transaction. Events
+// are delivered in the background, in order of the transactions, such that
+// a slow event sink never delays the commit.
+func (app *VStoreApplication) emitCommittedEvents() {
+	if app.events == nil || len(app.stage) == 0 {
+		return
+	}
+
+	payloads := make([][]byte, 0, len(app.stage))
+	for _, payload := range app.stage {
+		ev := NewCommittedEvent(payload, app.state.Height, app.time)
+		bz, err := EncodeEvent(app.eventFormat, ev)
+		if err != nil {
+			app.logger.Error("could not encode event", "hash", ev.Hash, "err", err)
+			continue
+		}
+
+		payloads = append(payloads, bz)
+	}
+
+	sink, contentType := app.events, app.eventFormat.ContentType()
+	go func() {
+		for _, bz := range payloads {
+			if err := sink.Emit(contentType, bz); err != nil {
+				app.logger.Error("could not emit event", "err", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,119 @@
+package vfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+)
+
+// channelSink implements EventSink and forwards event payloads to a channel.
+type channelSink struct {
+	contentType chan string
+	payloads    chan []byte
+}
+
+func (s *channelSink) Emit(contentType string, payload []byte) error {
+	s.contentType <- contentType
+	s.payloads <- payload
+	return nil
+}
+
+func TestVStoreCommittedCloudEvent(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-committed_cloud_event", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	sink := &channelSink{contentType: make(chan string, 1), payloads: make(chan []byte, 1)}
+	vstore.SetEventSink(sink, EventFormatCloudEvents)
+
+	stx, err := makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue))
+	require.NoError(t, err, "should create a signed transaction")
+
+	blockTime := time.Date(2024, 6, 1, 12, 30, 15, 0, time.UTC)
+	resFinBlock, err := vstore.FinalizeBlock(ctx, &abci.RequestFinalizeBlock{
+		Height: 1,
+		Time:   blockTime,
+		Txs:    [][]byte{stx.Bytes()},
+	})
+	require.NoError(t, err)
+
+	_, err = vstore.Commit(ctx, &abci.RequestCommit{})
+	require.NoError(t, err)
+
+	var payload []byte
+	select {
+	case payload = <-sink.payloads:
+	case <-time.After(5 * time.Second):
+		t.Fatal("should emit an event upon commit")
+	}
+	assert.Equal(t, "application/cloudevents+json", <-sink.contentType)
+
+	// Validate the CloudEvents v1.0 envelope (structured content mode)
+	var envelope map[string]interface{}
+	require.NoError(t, json.Unmarshal(payload, &envelope))
+
+	for _, attr := range []string{"specversion", "id", "source", "type"} {
+		value, ok := envelope[attr].(string)
+		assert.True(t, ok && value != "", "required attribute %q must be a non-empty string", attr)
+	}
+
+	hash := fmt.Sprintf("%X", resFinBlock.TxResults[0].Data)
+	assert.Equal(t, CloudEventsSpecVersion, envelope["specversion"])
+	assert.Equal(t, EventTypeCommitted, envelope["type"])
+	assert.Equal(t, hash, envelope["subject"])
+	assert.Equal(t, "application/json", envelope["datacontenttype"])
+
+	ts, err := time.Parse(time.RFC3339, envelope["time"].(string))
+	require.NoError(t, err, "time attribute must be a RFC 3339 timestamp")
+	assert.True(t, blockTime.Equal(ts))
+
+	data, ok := envelope["data"].(map[string]interface{})
+	require.True(t, ok, "data must contain the transaction metadata")
+	assert.Equal(t, hash, data["hash"])
+	assert.Equal(t, stx.PublicKey(), data["signer"])
+	assert.Equal(t, float64(1), data["height"])
+}
+
+func TestVStoreWebhookSink(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bz, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- bz
+	}))
+	defer server.Close()
+
+	ev := CommittedEvent{Hash: "ABCD", Signer: "EF01", Height: 2}
+	payload, err := EncodeEvent(EventFormatJSON, ev)
+	require.NoError(t, err)
+
+	sink := NewWebhookSink(server.URL)
+	require.NoError(t, sink.Emit(EventFormatJSON.ContentType(), payload))
+
+	req := <-received
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+
+	var decoded CommittedEvent
+	require.NoError(t, json.Unmarshal(<-bodies, &decoded))
+	assert.Equal(t, ev, decoded)
+
+	_, err = ParseEventFormat("xml")
+	assert.Error(t, err, "should not accept unknown event formats")
+}
@@ -0,0 +1,135 @@
+package vfs
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	cmtcrypto "github.com/cometbft/cometbft/proto/tendermint/crypto"
+)
+
+// ExportBundleVersion is the version of the schema of export bundles.
+const ExportBundleVersion = 1
+
+// ExportBundle is a self-contained bundle of a committed transaction and of
+// its inclusion proof, e.g. to present a transaction to a third party or to
+// anchor it on another blockchain. Bundles are verified offline with Verify.
+//
+// The bundle proves that the transaction is part of the app hash of a block
+// height, the app hash is recorded in the block header of the next height.
+// Verifiers must compare the app hash with a trusted source, e.g. the block
+// header of a CometBFT light client or an anchored app hash.
+type ExportBundle struct {
+	// Version is the version of the bundle schema, i.e. ExportBundleVersion.
+	Version int `json:"version"`
+
+	// Transaction contains the bytes of the vfsp2p.Transaction protobuf.
+	Transaction []byte `json:"transaction"`
+
+	// Hash is the uppercase hexadecimal transaction hash.
+	Hash string `json:"hash"`
+
+	// Signer is the uppercase hexadecimal public key of the signer.
+	Signer string `json:"signer"`
+
+	// Height is the block height of the app hash.
+	Height int64 `json:"height"`
+
+	// AppHash is the uppercase hexadecimal app hash of the block height.
+	AppHash string `json:"app_hash"`
+
+	// Proof contains the proof operations from the transaction hash to the
+	// app hash, see VerifyTransactionProof.
+	Proof *cmtcrypto.ProofOps `json:"proof"`
+}
+
+// NewExportBundle creates the export bundle of the bytes of a transaction tx
+// with its inclusion proof for the app hash of a block height. The bundle is
+// verified before it is returned.
+func NewExportBundle(
+	tx []byte,
+	proof *cmtcrypto.ProofOps,
+	height int64,
+	appHash []byte,
+) (*ExportBundle, error) {
+	stx, err := FromBytes(tx)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode transaction: %w", err)
+	}
+
+	bundle := &ExportBundle{
+		Version:     ExportBundleVersion,
+		Transaction: tx,
+		Hash:        fmt.Sprintf("%X", ComputeHash(stx.Unresolved())),
+		Signer:      stx.PublicKey(),
+		Height:      height,
+		AppHash:     fmt.Sprintf("%X", appHash),
+		Proof:       proof,
+	}
+
+	if err := bundle.Verify(); err != nil {
+		return nil, err
+	}
+
+	return bundle, nil
+}
+
+// Verify verifies the export bundle offline: the transaction must match the
+// hash and the signer of the bundle, its signature must be valid and the
+// proof must verify the transaction hash against the app hash. The app hash
+// itself must be compared with a trusted source by the verifier.
+func (b ExportBundle) Verify() error {
+	if b.Version != ExportBundleVersion {
+		return fmt.Errorf("unsupported bundle version, want: %d, got: %d", ExportBundleVersion, b.Version)
+	}
+
+	stx, err := FromBytes(b.Transaction)
+	if err != nil {
+		return fmt.Errorf("could not decode transaction: %w", err)
+	}
+
+	// A body resolved from external storage must match its reference, the
+	// hash and the signature cover the reference only
+	if err := verifyResolvedBody(stx); err != nil {
+		return err
+	}
+
+	stx = stx.Unresolved()
+	hash := ComputeHash(stx)
+	if !strings.EqualFold(b.Hash, hex.EncodeToString(hash)) {
+		return errors.New("transaction hash mismatch")
+	}
+
+	if !strings.EqualFold(b.Signer, stx.PublicKey()) {
+		return errors.New("transaction signer mismatch")
+	}
+
+	// Legacy signatures do not cover the timestamp
+	if !stx.Verify() && !stx.VerifyLegacy() {
+		return errors.New("invalid transaction signature")
+	}
+
+	appHash, err := hex.DecodeString(b.AppHash)
+	if err != nil || len(appHash) == 0 {
+		return errors.New("invalid app hash")
+	}
+
+	if err := VerifyTransactionProof(b.Proof, appHash, b.Signer, hash); err != nil {
+		return fmt.Errorf("proof does not verify: %w", err)
+	}
+
+	return nil
+}
+
+// VerifyAppHash verifies the export bundle like Verify and also checks that the
+// app hash of the bundle is the trusted app hash, e.g. from a block header.
+func (b ExportBundle) VerifyAppHash(trusted []byte) error {
+	appHash, err := hex.DecodeString(b.AppHash)
+	if err != nil || !bytes.Equal(appHash, trusted) {
+		return errors.New("app hash does not match the trusted app hash")
+	}
+
+	return b.Verify()
+}
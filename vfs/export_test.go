@@ -0,0 +1,147 @@
+package vfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/crypto/tmhash"
+)
+
+func TestVStoreExportBundle(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-export_bundle", 2)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	// Commit 2 blocks with transactions of 2 signers
+	stxs := []*SignedTransaction{}
+	for height := 1; height <= 2; height++ {
+		txs := [][]byte{}
+		for _, priv := range ownerPrivs {
+			stx, err := makeTransaction(t, priv, []byte(testComplexValue+string(rune('a'+height))))
+			require.NoError(t, err, "should create a signed transaction")
+
+			stx.Hash = ComputeHash(stx)
+			stxs = append(stxs, stx)
+			txs = append(txs, stx.Bytes())
+		}
+
+		makeBlockCommit(ctx, t, vstore, height, txs)
+	}
+
+	// Export like vstore export: transaction, proof and app hash
+	stx := stxs[1]
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: stx.Hash, Prove: true})
+	require.NoError(t, err)
+	require.NotNil(t, resQuery.ProofOps)
+
+	height := []byte(strconv.FormatInt(resQuery.Height, 10))
+	resAppHash, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/apphash/height", Data: height})
+	require.NoError(t, err)
+	appHash := resAppHash.Value
+
+	bundle, err := NewExportBundle(resQuery.Value, resQuery.ProofOps, resQuery.Height, appHash)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%X", stx.Hash), bundle.Hash)
+	assert.Equal(t, stx.PublicKey(), bundle.Signer)
+	assert.EqualValues(t, 2, bundle.Height)
+
+	// Bundles are verified offline after a JSON round trip
+	bz, err := json.Marshal(bundle)
+	require.NoError(t, err)
+
+	decode := func() ExportBundle {
+		decoded := ExportBundle{}
+		require.NoError(t, json.Unmarshal(bz, &decoded))
+		return decoded
+	}
+
+	assert.NoError(t, decode().Verify())
+	assert.NoError(t, decode().VerifyAppHash(appHash))
+	assert.Error(t, decode().VerifyAppHash(tmhash.Sum(appHash)), "must not verify against another app hash")
+
+	// Tampered bundles do not verify
+	tampered := decode()
+	tampered.Hash = fmt.Sprintf("%X", stxs[0].Hash)
+	assert.Error(t, tampered.Verify(), "must not verify another hash")
+
+	tampered = decode()
+	tampered.Transaction = stxs[0].Bytes()
+	assert.Error(t, tampered.Verify(), "must not verify another transaction")
+
+	tampered = decode()
+	tampered.AppHash = fmt.Sprintf("%X", tmhash.Sum(appHash))
+	assert.Error(t, tampered.Verify(), "must not verify another app hash")
+
+	tampered = decode()
+	tampered.Proof = nil
+	assert.Error(t, tampered.Verify(), "must not verify without proof")
+
+	tampered = decode()
+	tampered.Version = ExportBundleVersion + 1
+	assert.Error(t, tampered.Verify(), "must not verify unknown versions")
+
+	// Bundles are not created for a proof of another transaction
+	_, err = NewExportBundle(stxs[0].Bytes(), resQuery.ProofOps, resQuery.Height, appHash)
+	assert.Error(t, err)
+}
+
+func TestVStoreExportBundleBodyRef(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-export_bundle_body_ref", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	blobs := NewDirBlobStore(filepath.Join(vfsDir, "blobs"))
+	ref, err := blobs.Put([]byte(testComplexValue))
+	require.NoError(t, err)
+	vstore.SetBlobStore(blobs)
+
+	stx := makeBodyRefTransaction(t, ownerPrivs[0], ref)
+	stx.Hash = ComputeHash(stx)
+	makeBlockCommit(ctx, t, vstore, 1, [][]byte{stx.Bytes()})
+
+	// Queries resolve the body, the hash is the committed hash
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: stx.Hash, Prove: true})
+	require.NoError(t, err)
+
+	resolved, err := FromBytes(resQuery.Value)
+	require.NoError(t, err)
+	assert.Equal(t, []byte(testComplexValue), []byte(resolved.Data))
+	assert.Equal(t, stx.Hash, resolved.Hash)
+	assert.Equal(t, stx.Hash, ComputeHash(resolved.Unresolved()))
+	assert.True(t, resolved.Unresolved().Verify())
+
+	stxs, err := vstore.TransactionsByPubKey(stx.Signer.Bytes())
+	require.NoError(t, err)
+	require.Len(t, stxs, 1)
+	assert.Equal(t, stx.Hash, ComputeHash(stxs[0].Unresolved()))
+
+	// Bundles of resolved transactions verify with the committed hash
+	resAppHash, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/apphash/height", Data: []byte("1")})
+	require.NoError(t, err)
+
+	bundle, err := NewExportBundle(resQuery.Value, resQuery.ProofOps, resQuery.Height, resAppHash.Value)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("%X", stx.Hash), bundle.Hash)
+
+	// A resolved body that does not match its reference does not verify
+	tampered := *resolved
+	tampered.Data = []byte("tampered")
+	bundle.Transaction = tampered.Bytes()
+	assert.Error(t, bundle.Verify())
+}
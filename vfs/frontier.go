@@ -0,0 +1,105 @@
+package vfs
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cometbft/cometbft/crypto/merkle"
+	"github.com/cometbft/cometbft/crypto/tmhash"
+)
+
+// Prefixes of the leaf and inner node hashes of the merkle trees of cometbft,
+// i.e. of RFC 6962.
+var (
+	merkleLeafPrefix  = []byte{0}
+	merkleInnerPrefix = []byte{1}
+)
+
+// merkleFrontier describes the merkle tree of the transaction hashes of a
+// signer with the roots of its perfect subtrees, such that hashes are appended
+// and the merkle root is computed without the previous hashes. The root is the
+// root of merkle.HashFromByteSlices with all hashes of the signer.
+type merkleFrontier struct {
+	// Size is the number of leaves of the tree.
+	Size uint64 `json:"size"`
+
+	// Peaks contains the roots of the perfect subtrees, from the largest to
+	// the smallest subtree, i.e. one root for every bit set in Size.
+	Peaks [][]byte `json:"peaks"`
+}
+
+// signerFrontierKey returns the database key of the merkle frontier of a
+// signer, i.e. "vfs:pubkey:X/frontier", which is written with the last page of
+// the signer index.
+func signerFrontierKey(signer []byte) []byte {
+	return append(prefixKeyWith(signer, vfsPrefixKeyByPubKey), "/frontier"...)
+}
+
+// append appends the leaves of hashes, the roots of the subtrees of equal size
+// are merged.
+func (f *merkleFrontier) append(hashes [][]byte) {
+	for _, hash := range hashes {
+		node := merkleLeafHash(hash)
+		for size := f.Size; size&1 == 1; size >>= 1 {
+			node = merkleInnerHash(f.Peaks[len(f.Peaks)-1], node)
+			f.Peaks = f.Peaks[:len(f.Peaks)-1]
+		}
+
+		f.Peaks = append(f.Peaks, node)
+		f.Size++
+	}
+}
+
+// root returns the merkle root of the tree, i.e. the peaks are folded from
+// the smallest to the largest subtree.
+func (f *merkleFrontier) root() []byte {
+	if len(f.Peaks) == 0 {
+		return merkle.HashFromByteSlices(nil)
+	}
+
+	root := f.Peaks[len(f.Peaks)-1]
+	for i := len(f.Peaks) - 2; i >= 0; i-- {
+		root = merkleInnerHash(f.Peaks[i], root)
+	}
+
+	return root
+}
+
+// readSignerFrontier reads the merkle frontier of a signer. Frontiers that are
+// missing, e.g. of indexes written before frontiers were introduced, are
+// computed once from the signer index.
+func (app *VStoreApplication) readSignerFrontier(signer []byte) (*merkleFrontier, error) {
+	bz, err := app.state.db.Get(signerFrontierKey(signer))
+	if err != nil {
+		return nil, err
+	}
+
+	frontier := &merkleFrontier{}
+	if len(bz) > 0 {
+		if err := json.Unmarshal(bz, frontier); err != nil {
+			return nil, fmt.Errorf("could not decode merkle frontier of signer %X: %w", signer, err)
+		}
+
+		return frontier, nil
+	}
+
+	hashes, err := app.readSignerHashes(signer)
+	if err != nil {
+		return nil, err
+	}
+
+	frontier.append(hashes)
+	return frontier, nil
+}
+
+// merkleLeafHash returns the hash of a leaf of a merkle tree.
+func merkleLeafHash(leaf []byte) []byte {
+	return tmhash.Sum(append(append([]byte{}, merkleLeafPrefix...), leaf...))
+}
+
+// merkleInnerHash returns the hash of an inner node of a merkle tree.
+func merkleInnerHash(left, right []byte) []byte {
+	data := make([]byte, 0, len(merkleInnerPrefix)+len(left)+len(right))
+	data = append(append(append(data, merkleInnerPrefix...), left...), right...)
+	return tmhash.Sum(data)
+}
@@ -0,0 +1,33 @@
+package vfs
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/cometbft/cometbft/crypto/merkle"
+	"github.com/cometbft/cometbft/crypto/tmhash"
+)
+
+func TestVStoreMerkleFrontier(t *testing.T) {
+	hashes := [][]byte{}
+	for i := 0; i < 70; i++ {
+		hashes = append(hashes, tmhash.Sum([]byte(strconv.Itoa(i))))
+	}
+
+	// Root is the root of the merkle tree of all hashes, appended one by one
+	// or in batches of any size
+	for _, batch := range []int{1, 2, 3, 7, 64} {
+		frontier := &merkleFrontier{}
+		assert.Equal(t, merkle.HashFromByteSlices(nil), frontier.root())
+
+		for n := 0; n < len(hashes); n += batch {
+			end := min(n+batch, len(hashes))
+			frontier.append(hashes[n:end])
+
+			assert.EqualValues(t, end, frontier.Size)
+			assert.Equal(t, merkle.HashFromByteSlices(hashes[:end]), frontier.root(), "size %d, batch %d", end, batch)
+		}
+	}
+}
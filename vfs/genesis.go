@@ -0,0 +1,106 @@
+package vfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+)
+
+// GenesisTransaction describes a signed transaction of the genesis app state.
+// The app state is a JSON array of genesis transactions, e.g. of the JSON
+// output of vstore factory, where tx contains the base64 encoded bytes of the
+// signed transaction. Other fields are ignored.
+type GenesisTransaction struct {
+	Tx []byte `json:"tx"`
+}
+
+// ParseGenesisState parses the app state of a genesis file and returns the
+// bytes of the genesis transactions, in order. An empty app state, null or an
+// empty object contains no transactions.
+func ParseGenesisState(appState []byte) ([][]byte, error) {
+	appState = bytes.TrimSpace(appState)
+	if len(appState) == 0 || bytes.Equal(appState, []byte("null")) || bytes.Equal(appState, []byte("{}")) {
+		return nil, nil
+	}
+
+	genesisTxs := []GenesisTransaction{}
+	if err := json.Unmarshal(appState, &genesisTxs); err != nil {
+		return nil, fmt.Errorf("could not parse genesis app state: %w", err)
+	}
+
+	txs := make([][]byte, len(genesisTxs))
+	for i, gtx := range genesisTxs {
+		if len(gtx.Tx) == 0 {
+			return nil, fmt.Errorf("genesis transaction %d is empty", i)
+		}
+
+		txs[i] = gtx.Tx
+	}
+
+	return txs, nil
+}
+
+// --------------------------------------------------------------------------
+// Private helpers
+
+// commitGenesisTransactions validates and commits the transactions of the
+// genesis app state at height 0, such that they are queryable before the first
+// block and are part of the app hash that InitChain returns. Transactions are
+// validated as in ProcessProposal, relative to the genesis time, and a single
+// invalid transaction aborts InitChain. Genesis transactions are committed
+// once: a state that contains transactions is returned as is, e.g. when
+// InitChain is called again after a restart before the first block.
+func (app *VStoreApplication) commitGenesisTransactions(
+	ctx context.Context,
+	chain *abci.RequestInitChain,
+) error {
+	txs, err := ParseGenesisState(chain.AppStateBytes)
+	if err != nil || len(txs) == 0 {
+		return err
+	}
+
+	if app.state.NumTransactions > 0 {
+		app.logger.Info("genesis transactions already committed", "txs", app.state.NumTransactions)
+		return nil
+	}
+
+	for i, tx := range txs {
+		stx, code := app.validateTxBasic(tx, chain.Time)
+		if code == CodeTypeOK {
+			code = app.validateSignature(stx)
+		}
+
+		if code != CodeTypeOK {
+			return fmt.Errorf("invalid genesis transaction %d: %s (code %d)", i, CodeToString(code), code)
+		}
+	}
+
+	// Genesis transactions are processed like the transactions of a block,
+	// the height stays 0 such that CometBFT still starts at InitialHeight
+	respTxs := app.processFinalizeBlock(ctx, &abci.RequestFinalizeBlock{
+		Height: 0,
+		Time:   chain.Time,
+		Txs:    txs,
+	})
+
+	for i, res := range respTxs {
+		if res.Code != CodeTypeOK {
+			return fmt.Errorf("invalid genesis transaction %d: %s (code %d): %s", i, CodeToString(res.Code), res.Code, res.Log)
+		}
+	}
+
+	if err := app.commitMerkleRoots(); err != nil {
+		return err
+	}
+
+	app.pendingBlock = true
+	if _, err := app.Commit(ctx, &abci.RequestCommit{}); err != nil {
+		return err
+	}
+
+	app.logger.Info("committed genesis transactions", "txs", len(txs), "app_hash", fmt.Sprintf("%X", app.state.Hash()))
+	return nil
+}
@@ -0,0 +1,229 @@
+package vfs
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cmtdb "github.com/cometbft/cometbft-db"
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+func TestVStoreGenesisTransactions(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-genesis_transactions", 2)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	genesisTime := time.Now().UTC()
+
+	// The app state is a JSON array of signed transactions
+	stxs := []*SignedTransaction{}
+	genesisTxs := []GenesisTransaction{}
+	for i, data := range []string{"genesis record 1", "genesis record 2", "genesis record 3"} {
+		stx, err := makeTransactionAt(t, ownerPrivs[i%2], []byte(data), genesisTime)
+		require.NoError(t, err)
+
+		stxs = append(stxs, stx)
+		genesisTxs = append(genesisTxs, GenesisTransaction{Tx: stx.Bytes()})
+	}
+
+	appState, err := json.Marshal(genesisTxs)
+	require.NoError(t, err)
+
+	db := cmtdb.NewMemDB()
+	vstore := NewVStoreApplication(db, filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	resInit, err := vstore.InitChain(ctx, &abci.RequestInitChain{
+		Time:          genesisTime,
+		InitialHeight: 1,
+		AppStateBytes: appState,
+	})
+	require.NoError(t, err)
+	assert.NotEqual(t, GenesisAppHash, hex.EncodeToString(resInit.AppHash))
+
+	// Genesis transactions are queryable before the first block
+	for _, stx := range stxs {
+		resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: ComputeHash(stx)})
+		require.NoError(t, err)
+		assert.Equal(t, CodeTypeOK, resQuery.Code)
+		assert.Equal(t, stx.Bytes(), resQuery.Value)
+	}
+
+	// CometBFT starts at InitialHeight with the genesis app hash
+	resInfo, err := vstore.Info(ctx, &abci.RequestInfo{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, resInfo.LastBlockHeight)
+	assert.Equal(t, resInit.AppHash, resInfo.LastBlockAppHash)
+	assert.EqualValues(t, 3, vstore.state.NumTransactions)
+	assert.Len(t, vstore.state.MerkleRoots, 2)
+
+	// InitChain is idempotent, e.g. after a restart before the first block
+	reloaded := NewVStoreApplication(db, filepath.Join(vfsDir, "id"), []byte("testpassword"))
+	resInit2, err := reloaded.InitChain(ctx, &abci.RequestInitChain{
+		Time:          genesisTime,
+		InitialHeight: 1,
+		AppStateBytes: appState,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, resInit.AppHash, resInit2.AppHash)
+	assert.EqualValues(t, 3, reloaded.state.NumTransactions)
+
+	// Blocks are committed on top of the genesis state
+	stx, err := makeTransaction(t, ownerPrivs[0], []byte("first block"))
+	require.NoError(t, err)
+
+	respFinBlock, _ := makeBlockCommit(ctx, t, reloaded, 1, [][]byte{stx.Bytes()})
+	assert.Equal(t, CodeTypeOK, respFinBlock.TxResults[0].Code)
+	assert.EqualValues(t, 4, reloaded.state.NumTransactions)
+}
+
+func TestVStoreGenesisTransactionsInvalid(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-genesis_transactions_invalid", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	genesisTime := time.Now().UTC()
+	stx, err := makeTransactionAt(t, ownerPrivs[0], []byte("genesis record"), genesisTime)
+	require.NoError(t, err)
+
+	forged, err := makeTransactionAt(t, ownerPrivs[0], []byte("forged record"), genesisTime)
+	require.NoError(t, err)
+	forged.Signature = stx.Signature
+
+	tooOld, err := makeTransactionAt(t, ownerPrivs[0], []byte("old record"), genesisTime.Add(-48*time.Hour))
+	require.NoError(t, err)
+
+	appState := func(stxs ...*SignedTransaction) []byte {
+		genesisTxs := []GenesisTransaction{}
+		for _, stx := range stxs {
+			genesisTxs = append(genesisTxs, GenesisTransaction{Tx: stx.Bytes()})
+		}
+
+		bz, err := json.Marshal(genesisTxs)
+		require.NoError(t, err)
+		return bz
+	}
+
+	for name, bz := range map[string][]byte{
+		"malformed JSON":     []byte(`[{"tx":`),
+		"empty transaction":  []byte(`[{"tx":""}]`),
+		"invalid signature":  appState(stx, forged),
+		"invalid timestamp":  appState(tooOld),
+		"duplicate hash":     appState(stx, stx),
+		"invalid format":     []byte(`[{"tx":"AAEC"}]`),
+		"unexpected object":  []byte(`{"txs":[]}`),
+		"unexpected strings": []byte(`["AAEC"]`),
+	} {
+		t.Run(name, func(t *testing.T) {
+			vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+			_, err := vstore.InitChain(ctx, &abci.RequestInitChain{Time: genesisTime, AppStateBytes: bz})
+			assert.Error(t, err)
+		})
+	}
+
+	// Genesis files without transactions start with an empty state
+	for _, bz := range [][]byte{nil, []byte("null"), []byte("{}"), []byte("[]")} {
+		vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+		resInit, err := vstore.InitChain(ctx, &abci.RequestInitChain{Time: genesisTime, AppStateBytes: bz})
+		require.NoError(t, err)
+		assert.Equal(t, GenesisAppHash, hex.EncodeToString(resInit.AppHash))
+	}
+}
+
+func TestVStoreChainID(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-chain_id", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	idFile := filepath.Join(vfsDir, "id")
+	dbA := cmtdb.NewMemDB()
+	chainA := NewVStoreApplication(dbA, idFile, []byte("testpassword"))
+	chainB := NewInMemoryVStoreApplication(idFile, []byte("testpassword"))
+
+	// The chain id is taken from the genesis
+	_, err := chainA.InitChain(ctx, &abci.RequestInitChain{ChainId: "chain-a"})
+	require.NoError(t, err)
+	_, err = chainB.InitChain(ctx, &abci.RequestInitChain{ChainId: "chain-b"})
+	require.NoError(t, err)
+
+	// A transaction signed for chain A
+	stx, err := makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue))
+	require.NoError(t, err)
+	stx.ChainID = "chain-a"
+	stx.Signature = SignData(ed25519.PrivKey(ownerPrivs[0]), stx)
+	stx.Hash = ComputeHash(stx)
+
+	// Transactions without a chain id
+	unbound, err := makeTransaction(t, ownerPrivs[0], []byte(testComplexValue))
+	require.NoError(t, err)
+	unbound.Hash = ComputeHash(unbound)
+
+	checkTx := func(app *VStoreApplication, tx []byte) uint32 {
+		resp, err := app.CheckTx(ctx, &abci.RequestCheckTx{Tx: tx})
+		require.NoError(t, err)
+		return resp.Code
+	}
+
+	processProposal := func(app *VStoreApplication, tx []byte) abci.ResponseProcessProposal_ProposalStatus {
+		resp, err := app.ProcessProposal(ctx, &abci.RequestProcessProposal{Txs: [][]byte{tx}, Time: time.Now()})
+		require.NoError(t, err)
+		return resp.Status
+	}
+
+	assert.Equal(t, CodeTypeOK, checkTx(chainA, stx.Bytes()))
+	assert.Equal(t, CodeTypeInvalidChainIDError, checkTx(chainB, stx.Bytes()))
+	assert.Equal(t, CodeTypeInvalidChainIDError, checkTx(chainA, unbound.Bytes()), "chain id must be required")
+	assert.Equal(t, CodeTypeInvalidChainIDError, checkTx(chainB, unbound.Bytes()), "chain id must be required")
+	assert.Equal(t, abci.ResponseProcessProposal_REJECT, processProposal(chainA, unbound.Bytes()))
+	assert.Equal(t, abci.ResponseProcessProposal_ACCEPT, processProposal(chainA, stx.Bytes()))
+	assert.Equal(t, abci.ResponseProcessProposal_REJECT, processProposal(chainB, stx.Bytes()))
+
+	// The chain id is signed and cannot be replaced, nor moved to another field
+	replayed := *stx
+	replayed.ChainID = "chain-b"
+	replayed.Hash = ComputeHash(&replayed)
+	assert.Equal(t, CodeTypeInvalidSignatureError, checkTx(chainB, replayed.Bytes()))
+
+	chained := makeChainedTransaction(t, ownerPrivs[0], []byte("chained"), stx.Hash)
+	moved := *chained
+	moved.PrevHash, moved.ChainID = nil, string(chained.PrevHash)
+	moved.Hash = ComputeHash(&moved)
+	assert.False(t, moved.Verify())
+	assert.NotEqual(t, CodeTypeOK, checkTx(chainA, moved.Bytes()))
+
+	// Blocks of chain B do not commit the transaction of chain A
+	respFinBlock, _ := makeBlockCommit(ctx, t, chainB, 1, [][]byte{stx.Bytes()})
+	assert.Equal(t, CodeTypeInvalidChainIDError, respFinBlock.TxResults[0].Code)
+	assert.Zero(t, chainB.state.NumTransactions)
+
+	respFinBlock, _ = makeBlockCommit(ctx, t, chainA, 1, [][]byte{stx.Bytes(), unbound.Bytes()})
+	assert.Equal(t, CodeTypeOK, respFinBlock.TxResults[0].Code)
+	assert.Equal(t, CodeTypeInvalidChainIDError, respFinBlock.TxResults[1].Code)
+
+	resQuery, err := chainA.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: stx.Hash})
+	require.NoError(t, err)
+	assert.Equal(t, stx.Bytes(), resQuery.Value)
+
+	// The chain id is persisted in the State, e.g. after a restart
+	restarted := NewVStoreApplication(dbA, idFile, []byte("testpassword"))
+	assert.Equal(t, "chain-a", restarted.state.ChainID)
+	assert.Equal(t, CodeTypeOK, checkTx(restarted, stx.Bytes()))
+
+	// Nodes without a genesis chain id only accept unbound transactions
+	unknown := NewInMemoryVStoreApplication(idFile, []byte("testpassword"))
+	assert.Equal(t, CodeTypeInvalidChainIDError, checkTx(unknown, stx.Bytes()))
+	assert.Equal(t, CodeTypeOK, checkTx(unknown, unbound.Bytes()))
+}
@@ -0,0 +1,52 @@
+package vfs
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+// ErrPaused is returned by Health when the application is healthy but does
+// not accept new transactions, i.e. it is degraded and only serves queries.
+var ErrPaused = errors.New("transactions paused")
+
+// Health checks that the application can serve requests, i.e. the database
+// is reachable and the identity secret can be unlocked to decrypt the stored
+// transactions, except in observer mode. It returns nil when the application is healthy, ErrPaused when
+// it is paused, or the reason of the failure otherwise.
+func (app *VStoreApplication) Health() error {
+	// Trivial read, the state key may not exist yet
+	if _, err := app.state.db.Has(StateKey()); err != nil {
+		return fmt.Errorf("database unreachable: %w", err)
+	}
+
+	// Observers have no identity to unlock
+	if !app.Observer() {
+		if err := app.checkIdentity(); err != nil {
+			return err
+		}
+	}
+
+	if app.Paused() {
+		return ErrPaused
+	}
+
+	return nil
+}
+
+// checkIdentity returns an error if the identity secret cannot be unlocked.
+func (app *VStoreApplication) checkIdentity() error {
+	// Open never panics, unlike Identity
+	pbz, err := app.priv.Open()
+	if err != nil {
+		return fmt.Errorf("identity locked: %w", err)
+	}
+	defer zeroize(pbz)
+
+	if len(pbz) != ed25519.PrivateKeySize {
+		return errors.New("identity locked: invalid private key size")
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package vfs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	cmtdb "github.com/cometbft/cometbft-db"
+)
+
+// unreachableDB is a database whose reads fail.
+type unreachableDB struct {
+	cmtdb.DB
+}
+
+func (db *unreachableDB) Has(key []byte) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func TestVStoreHealth(t *testing.T) {
+	_, cancel, _, vfsDir := ResetTestRoot(t, "test-vstore-health", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	idFile := filepath.Join(vfsDir, "id")
+	db := cmtdb.NewMemDB()
+	vstore := NewVStoreApplication(db, idFile, []byte("testpassword"))
+	assert.NoError(t, vstore.Health())
+
+	// Paused applications are degraded
+	vstore.Pause()
+	assert.ErrorIs(t, vstore.Health(), ErrPaused)
+	vstore.Resume()
+	assert.NoError(t, vstore.Health())
+
+	// Database failures
+	vstore.state.db = &unreachableDB{DB: db}
+	assert.ErrorContains(t, vstore.Health(), "database unreachable")
+	vstore.state.db = db
+
+	// Identity failures
+	vstore.priv = NewIdentity(idFile, []byte("wrongpassword"))
+	err := vstore.Health()
+	assert.ErrorContains(t, err, "identity locked")
+	assert.NotErrorIs(t, err, ErrPaused)
+}
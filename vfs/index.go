@@ -0,0 +1,257 @@
+package vfs
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+// signerIndexPageSize is the maximum number of transaction hashes in a page of
+// the signer index. Appending a hash rewrites the last page only, such that the
+// cost of an append does not grow with the number of transactions of a signer.
+var signerIndexPageSize = 1000
+
+// signerIndexTail describes the last page of the index of the signer that was
+// last appended to. Consecutive appends of a single signer, e.g. a sensor that
+// writes continuously, do not read the page from the database.
+type signerIndexTail struct {
+	signer string
+	page   int
+	hashes [][]byte
+}
+
+// signerIndexPageKey returns the database key of a page of the signer index.
+// The first page uses the key of the signer index "vfs:pubkey:X", such that
+// indexes written before pages were introduced are read as the first page.
+// Next pages are suffixed with the page number, e.g. "vfs:pubkey:X/1".
+func signerIndexPageKey(signer []byte, page int) []byte {
+	dbKey := prefixKeyWith(signer, vfsPrefixKeyByPubKey)
+	if page == 0 {
+		return dbKey
+	}
+
+	return append(append(dbKey, '/'), strconv.Itoa(page)...)
+}
+
+// readSignerHashes reads the transaction hashes of a signer from all pages of
+// the signer index, in order of commitment.
+func (app *VStoreApplication) readSignerHashes(signer []byte) ([][]byte, error) {
+	hashes := [][]byte{}
+	for page := 0; ; page++ {
+		pageHashes, err := app.readIndexHashes(signerIndexPageKey(signer, page))
+		if err != nil {
+			return nil, err
+		}
+
+		if len(pageHashes) == 0 {
+			return hashes, nil
+		}
+
+		hashes = append(hashes, pageHashes...)
+	}
+}
+
+// TransactionsByPubKey returns the transactions of the signer with the public
+// key pub, in order of commitment. The transactions are read from the signer
+// index and decrypted with the node's secret, bodies in external storage are
+// resolved with the blob store. This is the in-process counterpart of the
+// /pubkey query, without pagination. An empty slice is returned for unknown
+// signers, pruned transactions are skipped.
+func (app *VStoreApplication) TransactionsByPubKey(pub []byte) ([]*SignedTransaction, error) {
+	hashes, err := app.readSignerHashes(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	stxs := make([]*SignedTransaction, 0, len(hashes))
+	if len(hashes) == 0 {
+		return stxs, nil
+	}
+
+	// Unlock the decryption secret
+	secret, err := app.identitySecret()
+	if err != nil {
+		return nil, err
+	}
+	defer zeroize(secret)
+
+	for _, hash := range hashes {
+		data, err := app.state.db.Get(TransactionKey(hash))
+		if err != nil {
+			return nil, err
+		}
+
+		if len(data) == 0 {
+			return nil, fmt.Errorf("transaction %X of signer index not found", hash)
+		}
+
+		// Pruned transactions are committed but their body was removed
+		if isPrunedTransaction(data) {
+			continue
+		}
+
+		txData, err := openTransaction(secret, data)
+		if err != nil {
+			return nil, fmt.Errorf("could not decrypt transaction %X: %w", hash, err)
+		}
+
+		resolved, err := app.resolveBodyRef(txData)
+		if err != nil {
+			zeroize(txData)
+			return nil, err
+		}
+
+		stx, err := FromBytes(resolved)
+		zeroize(txData)
+		zeroize(resolved)
+		if err != nil {
+			return nil, err
+		}
+
+		stxs = append(stxs, stx)
+	}
+
+	return stxs, nil
+}
+
+// SignerCount returns the number of committed transactions of the signer with
+// the public key pub, or 0 for unknown signers. The count is read from the
+// State, such that the signer index is not decoded.
+func (app *VStoreApplication) SignerCount(pub []byte) int64 {
+	return app.state.SignerCount(pub)
+}
+
+// AllSigners returns the public keys of the signers of the signer index, i.e.
+// of all signers with committed transactions, sorted lexicographically.
+func (app *VStoreApplication) AllSigners() ([][]byte, error) {
+	signers := [][]byte{}
+	err := iteratePrefix(app.state.db, vfsPrefixKeyByPubKey, func(key, _ []byte) error {
+		// Keys are sorted, next pages follow the first page of a signer
+		if len(key) < ed25519.PubKeySize {
+			return nil
+		}
+
+		signer := key[:ed25519.PubKeySize]
+		if n := len(signers); n > 0 && bytes.Equal(signers[n-1], signer) {
+			return nil
+		}
+
+		signers = append(signers, append([]byte{}, signer...))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return signers, nil
+}
+
+// appendSignerHashes appends transaction hashes to the index of a signer.
+// Hashes are appended to the last page, a new page is started when the last
+// page is full. The pages and the merkle frontier of the signer are written
+// with w.
+func (app *VStoreApplication) appendSignerHashes(w dbWriter, signer []byte, hashes [][]byte) error {
+	tail, err := app.readSignerIndexTail(signer)
+	if err != nil {
+		return err
+	}
+
+	frontier, err := app.readSignerFrontier(signer)
+	if err != nil {
+		return err
+	}
+
+	frontier.append(hashes)
+	fbz, err := json.Marshal(frontier)
+	if err != nil {
+		return err
+	}
+
+	if err := w.Set(signerFrontierKey(signer), fbz); err != nil {
+		return err
+	}
+
+	for len(hashes) > 0 {
+		if len(tail.hashes) >= signerIndexPageSize {
+			tail.page, tail.hashes = tail.page+1, [][]byte{}
+		}
+
+		n := min(signerIndexPageSize-len(tail.hashes), len(hashes))
+		tail.hashes = append(tail.hashes, hashes[:n]...)
+		hashes = hashes[n:]
+
+		bz, err := json.Marshal(tail.hashes)
+		if err != nil {
+			return err
+		}
+
+		if err := w.Set(signerIndexPageKey(signer, tail.page), bz); err != nil {
+			app.tail = nil
+			return err
+		}
+	}
+
+	app.tail = tail
+	return nil
+}
+
+// readSignerIndexTail returns the last page of the signer index. The page is
+// kept in memory for the signer that was last appended to.
+func (app *VStoreApplication) readSignerIndexTail(signer []byte) (*signerIndexTail, error) {
+	if app.tail != nil && app.tail.signer == string(signer) {
+		return app.tail, nil
+	}
+
+	// Find the last page, a missing first page is an empty index
+	tail := &signerIndexTail{signer: string(signer), hashes: [][]byte{}}
+	for {
+		exists, err := app.state.db.Has(signerIndexPageKey(signer, tail.page+1))
+		if err != nil {
+			return nil, err
+		}
+
+		if !exists {
+			break
+		}
+
+		tail.page++
+	}
+
+	hashes, err := app.readIndexHashes(signerIndexPageKey(signer, tail.page))
+	if err != nil {
+		return nil, err
+	}
+
+	tail.hashes = hashes
+	return tail, nil
+}
+
+// backfillSignerCounts counts the transactions of every signer of the signer
+// index, e.g. for States that were saved without signer counts. The counts
+// are saved with the next commit.
+func (app *VStoreApplication) backfillSignerCounts() error {
+	signers, err := app.indexedSigners()
+	if err != nil {
+		return err
+	}
+
+	counts := make(map[string]int64, len(signers))
+	for _, signer := range signers {
+		hashes, err := app.readSignerHashes(signer)
+		if err != nil {
+			return err
+		}
+
+		if len(hashes) > 0 {
+			counts[strings.ToUpper(hex.EncodeToString(signer))] = int64(len(hashes))
+		}
+	}
+
+	app.state.SignerCounts = counts
+	return nil
+}
@@ -0,0 +1,270 @@
+package vfs
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cmtdb "github.com/cometbft/cometbft-db"
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/crypto/merkle"
+	"github.com/cosmos/gogoproto/proto"
+
+	vfsp2p "github.com/securesharelabs/vstore/api/vstore/v1"
+)
+
+func TestVStoreSignerIndexPages(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-signer_index_pages", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+		signerIndexPageSize = 1000
+	}()
+
+	// Sequential transactions of a single signer, 1 to 3 per block
+	stxs := []*SignedTransaction{}
+	blocks := [][][]byte{}
+	for height := 1; height <= 8; height++ {
+		block := []*SignedTransaction{}
+		for i := 0; i < 1+height%3; i++ {
+			stx, err := makeTransaction(t, ownerPrivs[0], []byte(testComplexValue+string(rune('a'+height))+string(rune('a'+i))))
+			require.NoError(t, err, "should create a signed transaction")
+
+			stx.Hash = ComputeHash(stx)
+			block = append(block, stx)
+		}
+
+		// Transactions of a block are committed in order of hash
+		sortTransactionsByHash(block)
+		stxs = append(stxs, block...)
+		blocks = append(blocks, transactionBytes(block))
+	}
+
+	// Commit the same blocks with the unpaged (single page) and paged index
+	commitBlocks := func(db cmtdb.DB) *VStoreApplication {
+		app := NewVStoreApplication(db, filepath.Join(vfsDir, "id"), []byte("testpassword"))
+		for i, txs := range blocks {
+			makeBlockCommit(ctx, t, app, i+1, txs)
+		}
+		return app
+	}
+
+	generic := commitBlocks(cmtdb.NewMemDB())
+
+	signerIndexPageSize = 3
+	pagedDB := cmtdb.NewMemDB()
+	paged := commitBlocks(pagedDB)
+
+	// Merkle root is the merkle tree of all transaction hashes
+	expected := [][]byte{}
+	for _, stx := range stxs {
+		expected = append(expected, stx.Hash)
+	}
+
+	root := merkle.HashFromByteSlices(expected)
+
+	pub := stxs[0].PublicKey()
+	assert.Equal(t, root, generic.state.MerkleRoots[pub])
+	assert.Equal(t, root, paged.state.MerkleRoots[pub])
+	assert.Equal(t, generic.state.Hash(), paged.state.Hash())
+
+	// Index is split in pages of 3 hashes
+	signer := stxs[0].Signer.Bytes()
+	for page := 0; page*3 < len(stxs); page++ {
+		bz, err := pagedDB.Get(signerIndexPageKey(signer, page))
+		require.NoError(t, err)
+
+		hashes := [][]byte{}
+		require.NoError(t, json.Unmarshal(bz, &hashes))
+		assert.Equal(t, expected[page*3:min(page*3+3, len(expected))], hashes)
+	}
+
+	for _, app := range []*VStoreApplication{generic, paged} {
+		hashes, err := app.readSignerHashes(signer)
+		require.NoError(t, err)
+		assert.Equal(t, expected, hashes)
+
+		resQuery, err := app.Query(ctx, &abci.RequestQuery{Path: "/pubkey?limit=500", Data: signer})
+		require.NoError(t, err)
+
+		list := new(vfsp2p.TransactionList)
+		require.NoError(t, proto.Unmarshal(resQuery.Value, list))
+		assert.EqualValues(t, len(stxs), list.Total)
+		assert.Len(t, list.Transactions, len(stxs))
+	}
+
+	// A restarted application finds the last page of the index, a missing
+	// merkle frontier is computed from the index
+	require.NoError(t, pagedDB.Delete(signerFrontierKey(signer)))
+	restarted := NewVStoreApplication(pagedDB, filepath.Join(vfsDir, "id"), []byte("testpassword"))
+	stx, err := makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue))
+	require.NoError(t, err)
+	stx.Hash = ComputeHash(stx)
+	makeBlockCommit(ctx, t, restarted, len(blocks)+1, [][]byte{stx.Bytes()})
+
+	hashes, err := restarted.readSignerHashes(signer)
+	require.NoError(t, err)
+	assert.Equal(t, append(expected, stx.Hash), hashes)
+	assert.Equal(t, merkle.HashFromByteSlices(hashes), restarted.state.MerkleRoots[pub])
+
+	has, err := pagedDB.Has(signerFrontierKey(signer))
+	require.NoError(t, err)
+	assert.True(t, has)
+}
+
+func BenchmarkSingleSignerIngestion(b *testing.B) {
+	// e.g.: go test ./vfs -run '^$' -bench SingleSignerIngestion -benchtime 100000x
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(b, "bench-vstore-single_signer_ingestion", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	app := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	txs := make([][]byte, b.N)
+	for i := range txs {
+		stx, err := makeTransaction(b, ownerPrivs[0], []byte(testComplexValue+strconv.Itoa(i)))
+		require.NoError(b, err)
+		txs[i] = stx.Bytes()
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	// One transaction per block, i.e. sequential writes of a sensor
+	for i := 0; i < b.N; i++ {
+		_, err := app.FinalizeBlock(ctx, &abci.RequestFinalizeBlock{
+			Height: int64(i + 1),
+			Txs:    txs[i : i+1],
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		if _, err := app.Commit(ctx, &abci.RequestCommit{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestVStoreTransactionsByPubKey(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-transactions_by_pubkey", 3)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	// Signers 0 and 1 interleave their transactions, signer 2 has none
+	bySigner := make(map[int][]*SignedTransaction)
+	for height := 1; height <= 3; height++ {
+		txs := [][]byte{}
+		for i := 0; i < 2; i++ {
+			stx, err := makeTransaction(t, ownerPrivs[i], []byte(testSimpleValue+strconv.Itoa(height)))
+			require.NoError(t, err)
+
+			stx.Hash = ComputeHash(stx)
+			bySigner[i] = append(bySigner[i], stx)
+			txs = append(txs, stx.Bytes())
+		}
+
+		makeBlockCommit(ctx, t, vstore, height, txs)
+	}
+
+	for i := 0; i < 2; i++ {
+		stxs, err := vstore.TransactionsByPubKey(bySigner[i][0].Signer.Bytes())
+		require.NoError(t, err)
+		require.Len(t, stxs, 3)
+
+		for j, stx := range stxs {
+			assert.Equal(t, bySigner[i][j].Hash, stx.Hash, "should be in order of commitment")
+			assert.Equal(t, bySigner[i][j].Data, stx.Data)
+			assert.True(t, stx.Signer.Equals(bySigner[i][0].Signer), "signers must be isolated")
+		}
+	}
+
+	unknown, err := makeTransaction(t, ownerPrivs[2], []byte(testSimpleValue))
+	require.NoError(t, err)
+	stxs, err := vstore.TransactionsByPubKey(unknown.Signer.Bytes())
+	require.NoError(t, err)
+	assert.Empty(t, stxs)
+}
+
+func TestVStoreQuerySigners(t *testing.T) {
+	numSigners := uint32(5)
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-query_signers", numSigners)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	// Signer i signs i+1 transactions over i+1 blocks
+	counts := make(map[string]int64, numSigners)
+	for height := 1; height <= int(numSigners); height++ {
+		txs := [][]byte{}
+		for i, priv := range ownerPrivs {
+			if i+1 < height {
+				continue
+			}
+
+			stx, err := makeTransaction(t, priv, []byte(testSimpleValue+string(rune('a'+height))))
+			require.NoError(t, err, "should create a signed transaction")
+			txs = append(txs, stx.Bytes())
+			counts[stx.PublicKey()]++
+		}
+
+		makeBlockCommit(ctx, t, vstore, height, txs)
+	}
+
+	querySigners := func(path string) SignerList {
+		resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: path})
+		require.NoError(t, err)
+		require.Equal(t, CodeTypeOK, resQuery.Code, resQuery.Log)
+
+		list := SignerList{}
+		require.NoError(t, json.Unmarshal(resQuery.Value, &list))
+		return list
+	}
+
+	// All signers appear exactly once, with their number of transactions
+	list := querySigners("/signers")
+	assert.Equal(t, int(numSigners), list.Total)
+	require.Len(t, list.Signers, int(numSigners))
+	assert.Empty(t, list.Next)
+	for _, signer := range list.Signers {
+		assert.Equal(t, counts[signer.PubKey], signer.Count, "count of signer %s", signer.PubKey)
+		delete(counts, signer.PubKey)
+	}
+
+	assert.Empty(t, counts, "every signer must be listed")
+
+	// Pages follow the cursor
+	seen := []SignerInfo{}
+	path := "/signers?limit=2"
+	for {
+		page := querySigners(path)
+		assert.LessOrEqual(t, len(page.Signers), 2)
+		seen = append(seen, page.Signers...)
+		if page.Next == "" {
+			break
+		}
+
+		path = "/signers?limit=2&after=" + page.Next
+	}
+
+	assert.Equal(t, list.Signers, seen)
+
+	// Unknown cursor
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/signers?after=ABCD"})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeInvalidFormatError, resQuery.Code)
+}
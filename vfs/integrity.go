@@ -0,0 +1,229 @@
+package vfs
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cometbft/cometbft/crypto/ed25519"
+	"github.com/cometbft/cometbft/crypto/merkle"
+)
+
+// IntegrityMismatch describes an inconsistency between the State and the
+// transactions that are stored in the database.
+type IntegrityMismatch struct {
+	// Signer is the uppercase hexadecimal public key of the signer, it is
+	// empty for mismatches of the whole state, e.g. the app hash.
+	Signer string `json:"signer,omitempty"`
+
+	// Hash is the hash of the transaction, if any.
+	Hash []byte `json:"hash,omitempty"`
+
+	// Reason describes the inconsistency.
+	Reason string `json:"reason"`
+
+	// Expected is the value that is recorded in the State, and Actual is the
+	// value that is recomputed from the database.
+	Expected []byte `json:"expected,omitempty"`
+	Actual   []byte `json:"actual,omitempty"`
+}
+
+// IntegrityError is returned by VerifyIntegrity when the State does not match
+// the transactions that are stored in the database.
+type IntegrityError struct {
+	Mismatches []IntegrityMismatch
+}
+
+// Error implements error
+func (e *IntegrityError) Error() string {
+	reasons := make([]string, 0, len(e.Mismatches))
+	for _, m := range e.Mismatches {
+		reasons = append(reasons, m.String())
+	}
+
+	return fmt.Sprintf("integrity check failed with %d mismatches: %s", len(e.Mismatches), strings.Join(reasons, "; "))
+}
+
+// String returns a human-readable description of the mismatch.
+func (m IntegrityMismatch) String() string {
+	s := m.Reason
+	if len(m.Signer) > 0 {
+		s = fmt.Sprintf("signer %s: %s", m.Signer, s)
+	}
+
+	if len(m.Hash) > 0 {
+		s = fmt.Sprintf("%s (tx %X)", s, m.Hash)
+	}
+
+	if len(m.Expected) > 0 || len(m.Actual) > 0 {
+		s = fmt.Sprintf("%s, expected: %X, actual: %X", s, m.Expected, m.Actual)
+	}
+
+	return s
+}
+
+// VerifyIntegrity recomputes the merkle root of every signer from the hashes
+// of the signer index and compares it with the merkle roots of the State. The
+// transactions are decrypted and their hashes are recomputed, the number of
+// transactions, the signer counts and the app hash must match too. An
+// *IntegrityError is returned which lists all mismatches, e.g. after database
+// tampering or corruption.
+func (app *VStoreApplication) VerifyIntegrity() error {
+	signers, err := app.indexedSigners()
+	if err != nil {
+		return err
+	}
+
+	secret, err := app.identitySecret()
+	if err != nil {
+		return err
+	}
+	defer zeroize(secret)
+
+	mismatches := []IntegrityMismatch{}
+	roots := make(map[string][]byte, len(signers))
+	numTxs := int64(0)
+	for _, signer := range signers {
+		pub := strings.ToUpper(hex.EncodeToString(signer))
+		hashes, err := app.readSignerHashes(signer)
+		if err != nil {
+			return err
+		}
+
+		numTxs += int64(len(hashes))
+		if expected := app.state.SignerCount(signer); expected != int64(len(hashes)) {
+			mismatches = append(mismatches, IntegrityMismatch{
+				Signer: pub,
+				Reason: fmt.Sprintf("number of signer transactions mismatch, expected: %d, actual: %d", expected, len(hashes)),
+			})
+		}
+
+		for _, hash := range hashes {
+			if reason := app.verifyStoredTransaction(secret, signer, hash); reason != "" {
+				mismatches = append(mismatches, IntegrityMismatch{Signer: pub, Hash: hash, Reason: reason})
+			}
+		}
+
+		// Merkle root is the merkle tree of all signer transaction hashes
+		var root []byte
+		if len(hashes) > 0 {
+			root = merkle.HashFromByteSlices(hashes)
+		}
+
+		roots[pub] = root
+		if expected := app.state.MerkleRoots[pub]; !bytes.Equal(expected, root) {
+			mismatches = append(mismatches, IntegrityMismatch{
+				Signer:   pub,
+				Reason:   "merkle root mismatch",
+				Expected: expected,
+				Actual:   root,
+			})
+		}
+	}
+
+	if numTxs != app.state.NumTransactions {
+		mismatches = append(mismatches, IntegrityMismatch{
+			Reason: fmt.Sprintf("number of transactions mismatch, expected: %d, actual: %d", app.state.NumTransactions, numTxs),
+		})
+	}
+
+	// App hash of the recomputed roots
+	recomputed := State{MerkleRoots: roots}
+	if expected := app.state.Hash(); !bytes.Equal(expected, recomputed.Hash()) {
+		mismatches = append(mismatches, IntegrityMismatch{
+			Reason:   "app hash mismatch",
+			Expected: expected,
+			Actual:   recomputed.Hash(),
+		})
+	}
+
+	if len(mismatches) > 0 {
+		for _, m := range mismatches {
+			app.logger.Error("integrity mismatch", "mismatch", m.String())
+		}
+
+		return &IntegrityError{Mismatches: mismatches}
+	}
+
+	app.logger.Info("integrity verified", "signers", len(signers), "txs", numTxs)
+	return nil
+}
+
+// --------------------------------------------------------------------------
+// Private helpers
+
+// indexedSigners returns the public keys of the signers of the State and of
+// the signer index, sorted lexicographically. Signers of the index that are
+// missing from the State are detected with their merkle root.
+func (app *VStoreApplication) indexedSigners() ([][]byte, error) {
+	unique := make(map[string]struct{}, len(app.state.MerkleRoots))
+	for pub := range app.state.MerkleRoots {
+		pbz, err := hex.DecodeString(pub)
+		if err != nil || len(pbz) != ed25519.PubKeySize {
+			return nil, fmt.Errorf("invalid signer public key in state: %s", pub)
+		}
+
+		unique[string(pbz)] = struct{}{}
+	}
+
+	indexed, err := app.AllSigners()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, signer := range indexed {
+		unique[string(signer)] = struct{}{}
+	}
+
+	signers := make([][]byte, 0, len(unique))
+	for signer := range unique {
+		signers = append(signers, []byte(signer))
+	}
+
+	sort.Slice(signers, func(i, j int) bool {
+		return bytes.Compare(signers[i], signers[j]) < 0
+	})
+
+	return signers, nil
+}
+
+// verifyStoredTransaction decrypts the stored transaction with hash and
+// returns the reason of an inconsistency, or an empty string.
+func (app *VStoreApplication) verifyStoredTransaction(secret, signer, hash []byte) string {
+	data, err := app.state.db.Get(TransactionKey(hash))
+	if err != nil || len(data) == 0 {
+		return "transaction not found"
+	}
+
+	// Pruned transactions keep their signer only
+	if isPrunedTransaction(data) {
+		if !bytes.Equal(prunedSigner(data), signer) {
+			return "pruned transaction signer mismatch"
+		}
+
+		return ""
+	}
+
+	txData, err := openTransaction(secret, data)
+	if err != nil {
+		return "transaction cannot be decrypted"
+	}
+	defer zeroize(txData)
+
+	stx, err := FromBytes(txData)
+	if err != nil {
+		return "transaction cannot be decoded"
+	}
+
+	if !bytes.Equal(ComputeHash(stx), hash) {
+		return "transaction hash mismatch"
+	}
+
+	if !bytes.Equal(stx.Signer.Bytes(), signer) {
+		return "transaction signer mismatch"
+	}
+
+	return ""
+}
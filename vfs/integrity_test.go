@@ -0,0 +1,81 @@
+package vfs
+
+import (
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cmtdb "github.com/cometbft/cometbft-db"
+)
+
+func TestVStoreVerifyIntegrity(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-verify_integrity", 2)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	db := cmtdb.NewMemDB()
+	vstore := NewVStoreApplication(db, filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	// Empty state is consistent
+	require.NoError(t, vstore.VerifyIntegrity())
+
+	stxs := make([]*SignedTransaction, 0, 4)
+	for height := 1; height <= 2; height++ {
+		txs := [][]byte{}
+		for i, priv := range ownerPrivs {
+			stx, err := makeTransaction(t, priv, []byte(testSimpleValue+string(rune('a'+height*2+i))))
+			require.NoError(t, err)
+			stx.Hash = ComputeHash(stx)
+			stxs = append(stxs, stx)
+			txs = append(txs, stx.Bytes())
+		}
+
+		makeBlockCommit(ctx, t, vstore, height, txs)
+	}
+
+	require.NoError(t, vstore.VerifyIntegrity())
+
+	// Corrupt one stored transaction
+	corrupted := stxs[2]
+	data, err := db.Get(TransactionKey(corrupted.Hash))
+	require.NoError(t, err)
+	data[len(data)-1] ^= 0xFF
+	require.NoError(t, db.Set(TransactionKey(corrupted.Hash), data))
+
+	err = vstore.VerifyIntegrity()
+	var integrityErr *IntegrityError
+	require.True(t, errors.As(err, &integrityErr), "should return an *IntegrityError")
+	require.Len(t, integrityErr.Mismatches, 1)
+
+	mismatch := integrityErr.Mismatches[0]
+	assert.Equal(t, strings.ToUpper(hex.EncodeToString(corrupted.Signer.Bytes())), mismatch.Signer)
+	assert.Equal(t, corrupted.Hash, mismatch.Hash)
+	assert.Equal(t, "transaction cannot be decrypted", mismatch.Reason)
+
+	// Tampered merkle root of the other signer
+	data[len(data)-1] ^= 0xFF
+	require.NoError(t, db.Set(TransactionKey(corrupted.Hash), data))
+
+	other := strings.ToUpper(hex.EncodeToString(stxs[1].Signer.Bytes()))
+	expected := vstore.state.MerkleRoots[other]
+	vstore.state.MerkleRoots[other] = make([]byte, 32)
+
+	err = vstore.VerifyIntegrity()
+	require.True(t, errors.As(err, &integrityErr), "should return an *IntegrityError")
+	require.Len(t, integrityErr.Mismatches, 2, "app hash must mismatch too")
+
+	mismatch = integrityErr.Mismatches[0]
+	assert.Equal(t, other, mismatch.Signer)
+	assert.Equal(t, "merkle root mismatch", mismatch.Reason)
+	assert.Equal(t, make([]byte, 32), mismatch.Expected)
+	assert.Equal(t, expected, mismatch.Actual)
+	assert.Equal(t, "app hash mismatch", integrityErr.Mismatches[1].Reason)
+}
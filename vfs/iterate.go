@@ -0,0 +1,48 @@
+package vfs
+
+import (
+	"errors"
+
+	cmtdb "github.com/cometbft/cometbft-db"
+)
+
+// errStopIteration is returned by the fn of iterateRange and iteratePrefix to
+// stop iterating early, callers do not return it.
+var errStopIteration = errors.New("stop iteration")
+
+// iterateRange calls fn with the key and the value of every database key in
+// the range [start, end), in ascending order. Iteration stops at the first
+// error of fn, which is returned. The iterator is always closed, such that fn
+// must not write to the database (some backends lock during iteration).
+func iterateRange(db cmtdb.DB, start, end []byte, fn func(key, value []byte) error) error {
+	it, err := db.Iterator(start, end)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	for ; it.Valid(); it.Next() {
+		if err := fn(it.Key(), it.Value()); err != nil {
+			return err
+		}
+	}
+
+	return it.Error()
+}
+
+// iteratePrefix calls fn with every database key with prefix, e.g. all keys of
+// the signer index with vfsPrefixKeyByPubKey. The key is passed without the
+// prefix. Keys and values must be copied if they are kept after fn returns.
+func iteratePrefix(db cmtdb.DB, prefix []byte, fn func(key, value []byte) error) error {
+	return iterateRange(db, prefix, prefixEnd(prefix), func(key, value []byte) error {
+		return fn(key[len(prefix):], value)
+	})
+}
+
+// prefixEnd returns the end of the key range of a prefix, i.e. the prefix
+// with its last byte incremented. The prefixes of vfs never end with 0xFF.
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte{}, prefix...)
+	end[len(end)-1]++
+	return end
+}
@@ -0,0 +1,114 @@
+package vfs
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cmtdb "github.com/cometbft/cometbft-db"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+func TestIteratePrefix(t *testing.T) {
+	db := cmtdb.NewMemDB()
+	for _, key := range []string{"vfs:height:block-1", "vfs:height:block-10", "vfs:height:block-2", "vfs:pubkey:A", "vfs:time:X", "vfsState"} {
+		require.NoError(t, db.Set([]byte(key), []byte("value of "+key)))
+	}
+
+	// Keys are passed in order and without prefix
+	keys := []string{}
+	err := iteratePrefix(db, vfsPrefixKeyByHeight, func(key, value []byte) error {
+		assert.Equal(t, "value of vfs:height:block-"+string(key), string(value))
+		keys = append(keys, string(key))
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "10", "2"}, keys)
+
+	// Prefixes do not match other indexes
+	count := 0
+	require.NoError(t, iteratePrefix(db, vfsPrefixKey, func(_, _ []byte) error {
+		count++
+		return nil
+	}))
+	assert.Equal(t, 5, count, "vfsState must not match the vfs: prefix")
+
+	// Ranges are half-open
+	keys = []string{}
+	require.NoError(t, iterateRange(db, []byte("vfs:height:block-10"), []byte("vfs:pubkey:A"), func(key, _ []byte) error {
+		keys = append(keys, string(key))
+		return nil
+	}))
+	assert.Equal(t, []string{"vfs:height:block-10", "vfs:height:block-2"}, keys)
+
+	// Iteration stops at the first error
+	errStop := errors.New("stop")
+	count = 0
+	err = iteratePrefix(db, vfsPrefixKeyByHeight, func(_, _ []byte) error {
+		count++
+		return errStop
+	})
+	assert.ErrorIs(t, err, errStop)
+	assert.Equal(t, 1, count)
+
+	// The iterator is closed, i.e. writes do not block
+	done := make(chan error)
+	go func() { done <- db.Set([]byte("vfs:height:block-3"), []byte("value")) }()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("iterator must be closed")
+	}
+}
+
+func TestVStoreAllSigners(t *testing.T) {
+	numSigners := uint32(4)
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-all_signers", numSigners)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+		signerIndexPageSize = 1000
+	}()
+
+	// Signer indexes span multiple pages
+	signerIndexPageSize = 2
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	signers, err := vstore.AllSigners()
+	require.NoError(t, err)
+	assert.Empty(t, signers)
+
+	// The last signer never signs
+	for height := 1; height <= 3; height++ {
+		txs := [][]byte{}
+		for _, priv := range ownerPrivs[:numSigners-1] {
+			stx, err := makeTransaction(t, priv, []byte(testSimpleValue+string(rune('a'+height))))
+			require.NoError(t, err, "should create a signed transaction")
+			txs = append(txs, stx.Bytes())
+		}
+
+		makeBlockCommit(ctx, t, vstore, height, txs)
+	}
+
+	expected := [][]byte{}
+	for _, priv := range ownerPrivs[:numSigners-1] {
+		expected = append(expected, ed25519.PrivKey(priv).PubKey().Bytes())
+	}
+
+	sort.Slice(expected, func(i, j int) bool {
+		return bytes.Compare(expected[i], expected[j]) < 0
+	})
+
+	signers, err = vstore.AllSigners()
+	require.NoError(t, err)
+	assert.Equal(t, expected, signers, "signers must be distinct and sorted")
+}
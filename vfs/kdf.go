@@ -0,0 +1,167 @@
+package vfs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// KDF describes the key derivation function that derives the AES-256 secret
+// of an identity file from its password.
+type KDF byte
+
+const (
+	// KDFSHA256 derives the secret as SHA256(salt || password). This is the
+	// key derivation function of identity files created without a header.
+	KDFSHA256 KDF = iota
+
+	// KDFArgon2id derives the secret with Argon2id (RFC 9106).
+	KDFArgon2id
+)
+
+// DefaultKDF is the key derivation function of new identity files.
+const DefaultKDF = KDFArgon2id
+
+// Argon2id parameters, as recommended in RFC 9106 (second recommended option).
+const (
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024 // KiB
+	argon2Threads = 4
+)
+
+// DefaultSaltSize is the size of the salt of new identity files. Salts of
+// 8, 16 or 32 bytes are accepted.
+const DefaultSaltSize = 32
+
+// identityMagic and the identity versions describe the header that is prepended
+// to identity files. The header is followed by the salt and the ciphertext.
+var identityMagic = []byte("VSID")
+
+const (
+	// magic (4) || version (1) || kdf (1), with a salt of 8 bytes
+	identityVersion1   byte = 1
+	identityHeaderLen1      = 6
+
+	// magic (4) || version (1) || kdf (1) || salt size (1)
+	identityVersion2   byte = 2
+	identityHeaderLen2      = 7
+)
+
+// String returns the name of the key derivation function as used in flags.
+func (k KDF) String() string {
+	switch k {
+	case KDFSHA256:
+		return "sha256"
+	case KDFArgon2id:
+		return "argon2id"
+	default:
+		break
+	}
+
+	return fmt.Sprintf("unknown(%d)", byte(k))
+}
+
+// ParseKDF returns the key derivation function with the name s.
+func ParseKDF(s string) (KDF, error) {
+	switch strings.ToLower(s) {
+	case "sha256":
+		return KDFSHA256, nil
+	case "argon2id":
+		return KDFArgon2id, nil
+	default:
+		break
+	}
+
+	return 0, fmt.Errorf("unknown key derivation function: %q", s)
+}
+
+// DeriveSecret derives the 32-bytes secret from a password pw and a salt of
+// 8, 16 or 32 bytes using the key derivation function kdf.
+func DeriveSecret(kdf KDF, pw, salt []byte) ([]byte, error) {
+	if len(pw) == 0 {
+		return []byte{}, errors.New("password must not be empty")
+	}
+
+	if !validSaltSize(len(salt)) {
+		return []byte{}, fmt.Errorf("invalid salt size, want: 8, 16 or 32, got: %d", len(salt))
+	}
+
+	switch kdf {
+	case KDFSHA256:
+		secret, _, err := GenerateSecret(pw, salt)
+		return secret, err
+	case KDFArgon2id:
+		return argon2.IDKey(pw, salt, argon2Time, argon2Memory, argon2Threads, 32), nil
+	default:
+		break
+	}
+
+	return []byte{}, fmt.Errorf("unknown key derivation function: %d", byte(kdf))
+}
+
+// validSaltSize returns true if n is an accepted salt size.
+func validSaltSize(n int) bool {
+	return n == 8 || n == 16 || n == 32
+}
+
+// identityHeader returns the header that is prepended to identity files
+// which are encrypted with a secret derived using kdf and a salt of saltSize.
+func identityHeader(kdf KDF, saltSize int) []byte {
+	return append(append([]byte{}, identityMagic...), identityVersion2, byte(kdf), byte(saltSize))
+}
+
+// sealedIdentity describes the content of an identity file.
+type sealedIdentity struct {
+	kdf  KDF
+	salt []byte
+	ct   []byte
+}
+
+// parseIdentityBytes returns the possible interpretations of the content of an
+// identity file, in order of preference. Files with a header are expected to
+// use the key derivation function and salt size of the header, files without
+// a header use KDFSHA256 and a salt of 8 bytes. A legacy file may start with
+// the magic bytes by chance (its salt is random), such that it is always
+// returned as the last interpretation.
+func parseIdentityBytes(bz []byte) ([]sealedIdentity, error) {
+	candidates := []sealedIdentity{}
+
+	if len(bz) > identityHeaderLen2 && bytes.HasPrefix(bz, identityMagic) {
+		kdf := KDF(bz[len(identityMagic)+1])
+
+		headerLen, saltSize := 0, 0
+		switch bz[len(identityMagic)] {
+		case identityVersion1:
+			headerLen, saltSize = identityHeaderLen1, 8
+		case identityVersion2:
+			headerLen, saltSize = identityHeaderLen2, int(bz[identityHeaderLen2-1])
+		default:
+			break
+		}
+
+		if headerLen > 0 && validSaltSize(saltSize) && len(bz) > headerLen+saltSize {
+			body := bz[headerLen:]
+			candidates = append(candidates, sealedIdentity{
+				kdf:  kdf,
+				salt: body[:saltSize],
+				ct:   body[saltSize:],
+			})
+		}
+	}
+
+	if len(bz) <= 8 {
+		return nil, errors.New("invalid identity file")
+	}
+
+	// Legacy files: salt (8 bytes) || ciphertext
+	candidates = append(candidates, sealedIdentity{
+		kdf:  KDFSHA256,
+		salt: bz[:8],
+		ct:   bz[8:],
+	})
+
+	return candidates, nil
+}
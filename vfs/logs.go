@@ -0,0 +1,204 @@
+package vfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+)
+
+// Log levels of structured log entries, in order of severity.
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelError = "error"
+)
+
+// DefaultLogBufferSize is the number of log entries kept by a BufferedLogger.
+const DefaultLogBufferSize = 1000
+
+// LogEntry describes a structured log entry.
+type LogEntry struct {
+	Time    time.Time         `json:"time"`
+	Level   string            `json:"level"`
+	Message string            `json:"msg"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// ParseLogLine decodes a structured log entry from a JSON line.
+func ParseLogLine(line []byte) (LogEntry, error) {
+	var entry LogEntry
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return LogEntry{}, err
+	}
+
+	if _, err := logLevelRank(entry.Level); err != nil {
+		return LogEntry{}, err
+	}
+
+	return entry, nil
+}
+
+// LogFilter describes a filter of structured log entries. Entries must have
+// at least the severity of Level and must not be older than Since. Empty
+// values do not filter entries.
+type LogFilter struct {
+	Level string
+	Since time.Duration
+}
+
+// Validate checks that the log level of the filter is known.
+func (f LogFilter) Validate() error {
+	if f.Level == "" {
+		return nil
+	}
+
+	_, err := logLevelRank(f.Level)
+	return err
+}
+
+// Match returns true if the log entry passes the filter at the time now.
+func (f LogFilter) Match(entry LogEntry, now time.Time) bool {
+	if f.Level != "" {
+		min, _ := logLevelRank(f.Level)
+		rank, err := logLevelRank(entry.Level)
+		if err != nil || rank < min {
+			return false
+		}
+	}
+
+	if f.Since > 0 && entry.Time.Before(now.Add(-f.Since)) {
+		return false
+	}
+
+	return true
+}
+
+// Apply returns the log entries that pass the filter at the time now.
+func (f LogFilter) Apply(entries []LogEntry, now time.Time) []LogEntry {
+	matches := make([]LogEntry, 0, len(entries))
+	for _, entry := range entries {
+		if f.Match(entry, now) {
+			matches = append(matches, entry)
+		}
+	}
+
+	return matches
+}
+
+// logLevelRank returns the severity of a log level.
+func logLevelRank(level string) (int, error) {
+	switch strings.ToLower(level) {
+	case LogLevelDebug:
+		return 0, nil
+	case LogLevelInfo:
+		return 1, nil
+	case LogLevelError:
+		return 2, nil
+	default:
+		break
+	}
+
+	return -1, fmt.Errorf("unknown log level: %q", level)
+}
+
+// --------------------------------------------------------------------------
+
+// logRing is a fixed-size buffer of the most recent log entries.
+type logRing struct {
+	mtx     sync.Mutex
+	size    int
+	entries []LogEntry
+}
+
+// BufferedLogger implements cmtlog.Logger and keeps the most recent log
+// entries in memory, such that they can be inspected on a running node.
+// Log entries are forwarded to the next logger.
+type BufferedLogger struct {
+	ring    *logRing
+	next    cmtlog.Logger
+	keyvals []interface{}
+}
+
+var _ cmtlog.Logger = (*BufferedLogger)(nil)
+
+// NewBufferedLogger creates a logger that keeps up to size log entries and
+// forwards log entries to next.
+func NewBufferedLogger(next cmtlog.Logger, size int) *BufferedLogger {
+	if size <= 0 {
+		size = DefaultLogBufferSize
+	}
+
+	return &BufferedLogger{
+		ring: &logRing{size: size, entries: make([]LogEntry, 0, size)},
+		next: next,
+	}
+}
+
+// Debug implements cmtlog.Logger
+func (l *BufferedLogger) Debug(msg string, keyvals ...interface{}) {
+	l.record(LogLevelDebug, msg, keyvals)
+	l.next.Debug(msg, keyvals...)
+}
+
+// Info implements cmtlog.Logger
+func (l *BufferedLogger) Info(msg string, keyvals ...interface{}) {
+	l.record(LogLevelInfo, msg, keyvals)
+	l.next.Info(msg, keyvals...)
+}
+
+// Error implements cmtlog.Logger
+func (l *BufferedLogger) Error(msg string, keyvals ...interface{}) {
+	l.record(LogLevelError, msg, keyvals)
+	l.next.Error(msg, keyvals...)
+}
+
+// With returns a logger which adds keyvals to all log entries, the log
+// entries are kept in the same buffer.
+// With implements cmtlog.Logger
+func (l *BufferedLogger) With(keyvals ...interface{}) cmtlog.Logger {
+	return &BufferedLogger{
+		ring:    l.ring,
+		next:    l.next.With(keyvals...),
+		keyvals: append(append([]interface{}{}, l.keyvals...), keyvals...),
+	}
+}
+
+// Entries returns a copy of the log entries in the buffer, oldest first.
+func (l *BufferedLogger) Entries() []LogEntry {
+	l.ring.mtx.Lock()
+	defer l.ring.mtx.Unlock()
+
+	return append([]LogEntry{}, l.ring.entries...)
+}
+
+// record appends a log entry to the buffer, the oldest entry is dropped
+// when the buffer is full.
+func (l *BufferedLogger) record(level, msg string, keyvals []interface{}) {
+	entry := LogEntry{Time: time.Now().UTC(), Level: level, Message: msg}
+
+	all := append(append([]interface{}{}, l.keyvals...), keyvals...)
+	if len(all) > 0 {
+		entry.Fields = make(map[string]string, len(all)/2)
+		for i := 0; i < len(all); i += 2 {
+			val := "(MISSING)"
+			if i+1 < len(all) {
+				val = fmt.Sprint(all[i+1])
+			}
+
+			entry.Fields[fmt.Sprint(all[i])] = val
+		}
+	}
+
+	l.ring.mtx.Lock()
+	defer l.ring.mtx.Unlock()
+
+	if len(l.ring.entries) == l.ring.size {
+		l.ring.entries = l.ring.entries[1:]
+	}
+
+	l.ring.entries = append(l.ring.entries, entry)
+}
@@ -0,0 +1,125 @@
+package vfs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+)
+
+func TestVStoreLogFilter(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	lines := []string{
+		`{"time":"2024-06-01T11:50:00Z","level":"error","msg":"old error"}`,
+		`{"time":"2024-06-01T11:58:00Z","level":"debug","msg":"recent debug"}`,
+		`{"time":"2024-06-01T11:58:30Z","level":"info","msg":"recent info","fields":{"height":"12"}}`,
+		`{"time":"2024-06-01T11:59:00Z","level":"error","msg":"recent error","fields":{"err":"boom"}}`,
+	}
+
+	entries := make([]LogEntry, len(lines))
+	for i, line := range lines {
+		entry, err := ParseLogLine([]byte(line))
+		require.NoError(t, err, "should parse structured log line")
+		entries[i] = entry
+	}
+
+	assert.Equal(t, "12", entries[2].Fields["height"])
+
+	testCases := []struct {
+		name     string
+		filter   LogFilter
+		expected []string
+	}{
+		{"no filter", LogFilter{}, []string{"old error", "recent debug", "recent info", "recent error"}},
+		{"level error", LogFilter{Level: "error"}, []string{"old error", "recent error"}},
+		{"level info", LogFilter{Level: "INFO"}, []string{"old error", "recent info", "recent error"}},
+		{"since 5m", LogFilter{Since: 5 * time.Minute}, []string{"recent debug", "recent info", "recent error"}},
+		{"level error since 5m", LogFilter{Level: "error", Since: 5 * time.Minute}, []string{"recent error"}},
+		{"since 30s", LogFilter{Since: 30 * time.Second}, []string{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, tc.filter.Validate())
+
+			messages := []string{}
+			for _, entry := range tc.filter.Apply(entries, now) {
+				messages = append(messages, entry.Message)
+			}
+
+			assert.Equal(t, tc.expected, messages)
+		})
+	}
+
+	// Unknown levels are rejected
+	assert.Error(t, LogFilter{Level: "fatal"}.Validate())
+	_, err := ParseLogLine([]byte(`{"level":"fatal","msg":"unknown"}`))
+	assert.Error(t, err)
+	_, err = ParseLogLine([]byte(`not json`))
+	assert.Error(t, err)
+}
+
+func TestVStoreBufferedLogger(t *testing.T) {
+	logger := NewBufferedLogger(cmtlog.NewNopLogger(), 3)
+
+	logger.Info("first")
+	logger.With("module", "abci").Error("second", "err", "boom")
+	logger.Debug("third")
+	logger.Info("fourth", "height", 12)
+
+	// Oldest entries are dropped
+	entries := logger.Entries()
+	require.Len(t, entries, 3)
+	assert.Equal(t, "second", entries[0].Message)
+	assert.Equal(t, LogLevelError, entries[0].Level)
+	assert.Equal(t, map[string]string{"module": "abci", "err": "boom"}, entries[0].Fields)
+	assert.Equal(t, "third", entries[1].Message)
+	assert.Equal(t, "12", entries[2].Fields["height"])
+}
+
+func TestVStoreWithLogger(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-with_logger", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	// Nop logger is the default
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+	assert.Equal(t, cmtlog.NewNopLogger(), vstore.logger)
+
+	vstore.SetLogger(nil)
+	assert.Equal(t, cmtlog.NewNopLogger(), vstore.logger, "nil logger must discard entries")
+
+	logger := NewBufferedLogger(cmtlog.NewNopLogger(), DefaultLogBufferSize)
+	vstore = NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"), WithLogger(logger))
+
+	stx, err := makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue))
+	require.NoError(t, err)
+	testVStoreCommitTx(ctx, t, vstore, stx.Bytes())
+
+	// Duplicate is rejected in the next block
+	makeBlockCommit(ctx, t, vstore, 2, [][]byte{stx.Bytes()})
+	require.NoError(t, vstore.VerifyIntegrity())
+
+	// First entry of every message
+	messages := make(map[string]LogEntry)
+	for _, entry := range logger.Entries() {
+		if _, ok := messages[entry.Message]; !ok {
+			messages[entry.Message] = entry
+		}
+	}
+
+	assert.Contains(t, messages, "using identity")
+	assert.Contains(t, messages, "finalized block")
+	assert.Contains(t, messages, "integrity verified")
+	assert.Equal(t, "1", messages["committed block"].Fields["txs"])
+	assert.Equal(t, LogLevelInfo, messages["duplicate transaction in block"].Level)
+	assert.Equal(t, fmt.Sprintf("%X", ComputeHash(stx)), messages["duplicate transaction in block"].Fields["hash"])
+}
@@ -0,0 +1,113 @@
+package vfs
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MetricsNamespace is the namespace of the Prometheus metrics of vfs.
+const MetricsNamespace = "vstore"
+
+// Metrics describes the Prometheus metrics of a vfs application. Metrics are
+// optional, a nil *Metrics records nothing.
+type Metrics struct {
+	// TxsCommitted counts the transactions that are persisted in Commit.
+	TxsCommitted prometheus.Counter
+
+	// BytesStored counts the bytes of the encrypted transactions that are
+	// persisted in Commit.
+	BytesStored prometheus.Counter
+
+	// DuplicateRejections counts the transactions that are rejected with a
+	// duplicate hash in CheckTx or in FinalizeBlock.
+	DuplicateRejections prometheus.Counter
+
+	// SignatureFailures counts the transactions that are rejected with an
+	// invalid signature in CheckTx.
+	SignatureFailures prometheus.Counter
+
+	// Height is the height of the last committed block.
+	Height prometheus.Gauge
+}
+
+// NewMetrics creates the metrics of a vfs application and registers them
+// with the registerer, e.g. a prometheus.NewRegistry().
+func NewMetrics(registerer prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		TxsCommitted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Name:      "txs_committed_total",
+			Help:      "Number of transactions committed.",
+		}),
+		BytesStored: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Name:      "bytes_stored_total",
+			Help:      "Number of bytes of encrypted transactions stored.",
+		}),
+		DuplicateRejections: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Name:      "duplicate_rejections_total",
+			Help:      "Number of transactions rejected with a duplicate hash.",
+		}),
+		SignatureFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: MetricsNamespace,
+			Name:      "signature_failures_total",
+			Help:      "Number of transactions rejected with an invalid signature.",
+		}),
+		Height: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: MetricsNamespace,
+			Name:      "height",
+			Help:      "Height of the last committed block.",
+		}),
+	}
+
+	collectors := []prometheus.Collector{
+		m.TxsCommitted,
+		m.BytesStored,
+		m.DuplicateRejections,
+		m.SignatureFailures,
+		m.Height,
+	}
+
+	for _, c := range collectors {
+		if err := registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// SetMetrics sets the metrics that are recorded by the application. Metrics
+// are not recorded by default.
+func (app *VStoreApplication) SetMetrics(m *Metrics) {
+	app.metrics = m
+}
+
+// --------------------------------------------------------------------------
+// Private helpers
+
+// observeTxCode records the rejection of a transaction with code, if any.
+func (m *Metrics) observeTxCode(code uint32) {
+	if m == nil {
+		return
+	}
+
+	switch code {
+	case CodeTypeDuplicateHashError:
+		m.DuplicateRejections.Inc()
+	case CodeTypeInvalidSignatureError:
+		m.SignatureFailures.Inc()
+	}
+}
+
+// observeCommit records a commit at height of txs transactions with a total
+// of size bytes stored.
+func (m *Metrics) observeCommit(height int64, txs, size int) {
+	if m == nil {
+		return
+	}
+
+	m.TxsCommitted.Add(float64(txs))
+	m.BytesStored.Add(float64(size))
+	m.Height.Set(float64(height))
+}
@@ -0,0 +1,158 @@
+package vfs
+
+import (
+	"fmt"
+
+	cmtdb "github.com/cometbft/cometbft-db"
+)
+
+// migrateBatchSize is the number of key-value pairs written per batch when
+// a database is migrated.
+var migrateBatchSize = 1000
+
+// MigrateOptions configures a database migration.
+type MigrateOptions struct {
+	// DryRun counts the key-value pairs that would be copied, the destination
+	// database is never written.
+	DryRun bool
+
+	// Resume continues an interrupted migration. Key-value pairs are copied
+	// in order of keys, such that the copy restarts from the last key of the
+	// destination database.
+	Resume bool
+}
+
+// MigrateResult describes the outcome of a database migration.
+type MigrateResult struct {
+	// Copied is the number of key-value pairs copied by this migration.
+	Copied int64
+
+	// Bytes is the size of the key-value pairs copied by this migration.
+	Bytes int64
+
+	// SourceKeys is the number of keys in the source database.
+	SourceKeys int64
+
+	// DestKeys is the number of keys in the destination database after the
+	// migration, it is always 0 in dry-run mode.
+	DestKeys int64
+}
+
+// MigrateDB copies all key-value pairs of src to dst verbatim, e.g. to change
+// the database backend. Values are not decrypted, such that no identity is
+// necessary. After the copy, the number of keys of both databases must match,
+// an error is returned otherwise.
+func MigrateDB(src, dst cmtdb.DB, opts MigrateOptions) (MigrateResult, error) {
+	result := MigrateResult{}
+
+	// Restart from the last copied key, it is copied again
+	var start []byte
+	if opts.Resume && !opts.DryRun {
+		last, err := lastKey(dst)
+		if err != nil {
+			return result, err
+		}
+
+		start = last
+	}
+
+	it, err := src.Iterator(start, nil)
+	if err != nil {
+		return result, err
+	}
+	defer it.Close()
+
+	batch := dst.NewBatch()
+	defer func() { batch.Close() }()
+
+	pending := 0
+	for ; it.Valid(); it.Next() {
+		result.Copied++
+		result.Bytes += int64(len(it.Key()) + len(it.Value()))
+
+		if opts.DryRun {
+			continue
+		}
+
+		if err := batch.Set(it.Key(), it.Value()); err != nil {
+			return result, err
+		}
+
+		pending++
+		if pending < migrateBatchSize {
+			continue
+		}
+
+		// Flush the batch and start a new one
+		if err := batch.WriteSync(); err != nil {
+			return result, err
+		}
+
+		batch.Close()
+		batch, pending = dst.NewBatch(), 0
+	}
+
+	if err := it.Error(); err != nil {
+		return result, err
+	}
+
+	if pending > 0 {
+		if err := batch.WriteSync(); err != nil {
+			return result, err
+		}
+	}
+
+	result.SourceKeys, err = countKeys(src)
+	if err != nil {
+		return result, err
+	}
+
+	if opts.DryRun {
+		return result, nil
+	}
+
+	result.DestKeys, err = countKeys(dst)
+	if err != nil {
+		return result, err
+	}
+
+	if result.SourceKeys != result.DestKeys {
+		return result, fmt.Errorf("key count mismatch, source: %d, destination: %d", result.SourceKeys, result.DestKeys)
+	}
+
+	return result, nil
+}
+
+// --------------------------------------------------------------------------
+// Private helpers
+
+// lastKey returns the last key of a database or nil if it is empty.
+func lastKey(db cmtdb.DB) ([]byte, error) {
+	it, err := db.ReverseIterator(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	if !it.Valid() {
+		return nil, it.Error()
+	}
+
+	return append([]byte{}, it.Key()...), nil
+}
+
+// countKeys returns the number of keys in a database.
+func countKeys(db cmtdb.DB) (int64, error) {
+	it, err := db.Iterator(nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer it.Close()
+
+	n := int64(0)
+	for ; it.Valid(); it.Next() {
+		n++
+	}
+
+	return n, it.Error()
+}
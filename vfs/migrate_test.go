@@ -0,0 +1,93 @@
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cmtdb "github.com/cometbft/cometbft-db"
+	abci "github.com/cometbft/cometbft/abci/types"
+)
+
+func TestVStoreMigrateDB(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-migrate_db", 2)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	idFile := filepath.Join(vfsDir, "id")
+	src := cmtdb.NewMemDB()
+	vstore := NewVStoreApplication(src, idFile, []byte("testpassword"))
+
+	hashes := [][]byte{}
+	for i, priv := range ownerPrivs {
+		stx, err := makeTransaction(t, priv, []byte(testSimpleValue))
+		require.NoError(t, err)
+
+		respFinBlock, _ := makeBlockCommit(ctx, t, vstore, i+1, [][]byte{stx.Bytes()})
+		hashes = append(hashes, respFinBlock.TxResults[0].Data)
+	}
+
+	dst, err := cmtdb.NewGoLevelDB("vfs", filepath.Join(vfsDir, "goleveldb"))
+	require.NoError(t, err)
+	defer dst.Close()
+
+	// Dry-run never writes the destination
+	result, err := MigrateDB(src, dst, MigrateOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.NotZero(t, result.SourceKeys)
+	assert.Equal(t, result.SourceKeys, result.Copied)
+	assert.Zero(t, result.DestKeys)
+
+	n, err := countKeys(dst)
+	require.NoError(t, err)
+	assert.Zero(t, n, "dry-run must not write the destination")
+
+	result, err = MigrateDB(src, dst, MigrateOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, result.SourceKeys, result.Copied)
+	assert.Equal(t, result.SourceKeys, result.DestKeys)
+
+	// Queries work with the migrated database
+	migrated := NewVStoreApplication(dst, idFile, []byte("testpassword"))
+	assert.Equal(t, vstore.state.Hash(), migrated.state.Hash())
+	assert.Equal(t, vstore.state.Height, migrated.state.Height)
+
+	for _, hash := range hashes {
+		resQuery, err := migrated.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: hash})
+		require.NoError(t, err)
+
+		stx, err := FromBytes(resQuery.Value)
+		require.NoError(t, err, "should decrypt the migrated transaction")
+		assert.Equal(t, []byte(testSimpleValue), []byte(stx.Data))
+	}
+}
+
+func TestVStoreMigrateDBResume(t *testing.T) {
+	src, dst := cmtdb.NewMemDB(), cmtdb.NewMemDB()
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		require.NoError(t, src.Set([]byte(key), []byte("value-"+key)))
+	}
+
+	// Interrupted migration copied the first keys
+	require.NoError(t, dst.Set([]byte("a"), []byte("value-a")))
+	require.NoError(t, dst.Set([]byte("b"), []byte("value-b")))
+
+	result, err := MigrateDB(src, dst, MigrateOptions{Resume: true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), result.Copied, "should restart from the last copied key")
+	assert.Equal(t, int64(5), result.DestKeys)
+
+	value, err := dst.Get([]byte("e"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value-e"), value)
+
+	// Key counts must match
+	require.NoError(t, dst.Set([]byte("f"), []byte("value-f")))
+	_, err = MigrateDB(src, dst, MigrateOptions{Resume: true})
+	assert.Error(t, err, "should detect a key count mismatch")
+}
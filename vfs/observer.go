@@ -0,0 +1,42 @@
+package vfs
+
+import (
+	"errors"
+
+	cmtdb "github.com/cometbft/cometbft-db"
+)
+
+// ErrEncryptionUnavailable is returned when an encrypted transaction is read
+// in observer mode, i.e. without the identity secret.
+var ErrEncryptionUnavailable = errors.New("encryption unavailable in observer mode")
+
+// NewObserverVStoreApplication creates a vfs application in observer mode, i.e.
+// without the identity, such that a replica or an auditor runs the node without
+// the identity password. Plaintext transactions are returned and their
+// signatures verified, whereas queries of encrypted transactions respond with
+// CodeTypeEncryptionUnavailableError. New transactions are always stored in
+// plaintext, see SetPlaintext.
+//
+// Note: Operations which decrypt all transactions, e.g. VerifyIntegrity or
+// Reindex, and state sync snapshots are not available to observers.
+func NewObserverVStoreApplication(db cmtdb.DB, opts ...Option) (*VStoreApplication, error) {
+	app, err := newVStoreApplication(db, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if app.requiredKDF != nil {
+		return nil, errors.New("observer mode does not use an identity file")
+	}
+
+	app.SetPlaintext(true)
+	app.logger.Info("observing without identity")
+	app.logger.Info("loaded state", "height", app.state.Height, "txs", app.state.NumTransactions)
+	return app, nil
+}
+
+// Observer returns true if the application runs without the identity, as
+// created with NewObserverVStoreApplication.
+func (app *VStoreApplication) Observer() bool {
+	return app.priv == nil
+}
@@ -0,0 +1,90 @@
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cmtdb "github.com/cometbft/cometbft-db"
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+func TestVStoreObserver(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-observer", 2)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	db := cmtdb.NewMemDB()
+	vstore := NewVStoreApplication(db, filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	// An encrypted and a plaintext transaction
+	encrypted, err := makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue))
+	require.NoError(t, err)
+	encrypted.Hash = ComputeHash(encrypted)
+	makeBlockCommit(ctx, t, vstore, 1, [][]byte{encrypted.Bytes()})
+
+	vstore.SetPlaintext(true)
+	plain, err := makeTransaction(t, ownerPrivs[1], []byte(testComplexValue))
+	require.NoError(t, err)
+	plain.Hash = ComputeHash(plain)
+	makeBlockCommit(ctx, t, vstore, 2, [][]byte{plain.Bytes()})
+
+	observer, err := NewObserverVStoreApplication(db)
+	require.NoError(t, err)
+	assert.True(t, observer.Observer())
+	assert.False(t, vstore.Observer())
+	assert.NoError(t, observer.Health())
+
+	// Plaintext transactions are returned and their signature verifies
+	resQuery, err := observer.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: plain.Hash})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeOK, resQuery.Code)
+	assert.Equal(t, plain.Bytes(), resQuery.Value)
+
+	stx, err := FromBytes(resQuery.Value)
+	require.NoError(t, err)
+	assert.True(t, stx.Verify())
+
+	// Encrypted transactions fail cleanly
+	for _, req := range []*abci.RequestQuery{
+		{Path: "/hash", Data: encrypted.Hash},
+		{Path: "/height", Data: []byte("1")},
+		{Path: "/pubkey", Data: ed25519.PrivKey(ownerPrivs[0]).PubKey().Bytes()},
+	} {
+		resQuery, err := observer.Query(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, CodeTypeEncryptionUnavailableError, resQuery.Code, req.Path)
+		assert.Equal(t, ErrEncryptionUnavailable.Error(), resQuery.Log)
+		assert.Empty(t, resQuery.Value)
+	}
+
+	// Transactions are committed in plaintext, also chained to encrypted ones
+	chained := makeChainedTransaction(t, ownerPrivs[0], []byte("chained"), encrypted.Hash)
+	forged := makeChainedTransaction(t, ownerPrivs[1], []byte("forged"), encrypted.Hash)
+	respFinBlock, _ := makeBlockCommit(ctx, t, observer, 3, [][]byte{chained.Bytes(), forged.Bytes()})
+	assert.Equal(t, CodeTypeOK, respFinBlock.TxResults[0].Code)
+	assert.Equal(t, CodeTypeInvalidPrevHashError, respFinBlock.TxResults[1].Code)
+
+	stored, err := db.Get(TransactionKey(chained.Hash))
+	require.NoError(t, err)
+	assert.True(t, isPlaintextTransaction(stored))
+
+	// Observers cannot encrypt new transactions
+	observer.SetPlaintext(false)
+	assert.True(t, observer.plaintext)
+
+	resQuery, err = observer.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: chained.Hash})
+	require.NoError(t, err)
+	assert.Equal(t, chained.Bytes(), resQuery.Value)
+
+	// Transactions committed by observers are readable with the identity
+	resQuery, err = vstore.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: chained.Hash})
+	require.NoError(t, err)
+	assert.Equal(t, chained.Bytes(), resQuery.Value)
+}
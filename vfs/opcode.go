@@ -2,8 +2,62 @@ package vfs
 
 // Return codes for vfs application
 const (
-	CodeTypeOK                    uint32 = 0
-	CodeTypeEmptyDataError        uint32 = 1
-	CodeTypeInvalidFormatError    uint32 = 2
-	CodeTypeInvalidSignatureError uint32 = 3
+	CodeTypeOK                         uint32 = 0
+	CodeTypeEmptyDataError             uint32 = 1
+	CodeTypeInvalidFormatError         uint32 = 2
+	CodeTypeInvalidSignatureError      uint32 = 3
+	CodeTypeInvalidChunkError          uint32 = 4
+	CodeTypeInvalidTimestampError      uint32 = 5
+	CodeTypeDuplicateHashError         uint32 = 6
+	CodeTypePausedError                uint32 = 7
+	CodeTypeInvalidPrevHashError       uint32 = 8
+	CodeTypeInvalidBodyError           uint32 = 9
+	CodeTypePrunedError                uint32 = 10
+	CodeTypeMissingBodyError           uint32 = 11
+	CodeTypeDecryptError               uint32 = 12
+	CodeTypeEncryptionUnavailableError uint32 = 13
+	CodeTypeInvalidChainIDError        uint32 = 14
+	CodeTypeInvalidSequenceError       uint32 = 15
 )
+
+// CodeToString returns a human-readable description of a return code.
+func CodeToString(code uint32) string {
+	switch code {
+	case CodeTypeOK:
+		return "ok"
+	case CodeTypeEmptyDataError:
+		return "empty data"
+	case CodeTypeInvalidFormatError:
+		return "invalid format"
+	case CodeTypeInvalidSignatureError:
+		return "invalid signature"
+	case CodeTypeInvalidChunkError:
+		return "invalid chunk"
+	case CodeTypeInvalidTimestampError:
+		return "invalid timestamp"
+	case CodeTypeDuplicateHashError:
+		return "duplicate hash"
+	case CodeTypePausedError:
+		return "transactions paused"
+	case CodeTypeInvalidPrevHashError:
+		return "invalid previous hash"
+	case CodeTypeInvalidBodyError:
+		return "invalid body"
+	case CodeTypePrunedError:
+		return "pruned transaction"
+	case CodeTypeMissingBodyError:
+		return "missing transaction body"
+	case CodeTypeDecryptError:
+		return "decryption error"
+	case CodeTypeEncryptionUnavailableError:
+		return "encryption unavailable"
+	case CodeTypeInvalidChainIDError:
+		return "invalid chain id"
+	case CodeTypeInvalidSequenceError:
+		return "invalid sequence"
+	default:
+		break
+	}
+
+	return "unknown error"
+}
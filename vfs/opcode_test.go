@@ -0,0 +1,37 @@
+package vfs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVStoreCodeToString(t *testing.T) {
+	codes := map[uint32]string{
+		CodeTypeOK:                         "ok",
+		CodeTypeEmptyDataError:             "empty data",
+		CodeTypeInvalidFormatError:         "invalid format",
+		CodeTypeInvalidSignatureError:      "invalid signature",
+		CodeTypeInvalidChunkError:          "invalid chunk",
+		CodeTypeInvalidTimestampError:      "invalid timestamp",
+		CodeTypeDuplicateHashError:         "duplicate hash",
+		CodeTypePausedError:                "transactions paused",
+		CodeTypeInvalidPrevHashError:       "invalid previous hash",
+		CodeTypeInvalidBodyError:           "invalid body",
+		CodeTypePrunedError:                "pruned transaction",
+		CodeTypeMissingBodyError:           "missing transaction body",
+		CodeTypeDecryptError:               "decryption error",
+		CodeTypeEncryptionUnavailableError: "encryption unavailable",
+		CodeTypeInvalidChainIDError:        "invalid chain id",
+		CodeTypeInvalidSequenceError:       "invalid sequence",
+	}
+
+	// Return codes must be distinct
+	assert.Len(t, codes, 16)
+
+	for code, str := range codes {
+		assert.Equal(t, str, CodeToString(code))
+	}
+
+	assert.Equal(t, "unknown error", CodeToString(999))
+}
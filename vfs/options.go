@@ -0,0 +1,143 @@
+package vfs
+
+import (
+	"time"
+
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+)
+
+// Option configures a vfs application upon creation with
+// NewVStoreApplication, NewVStoreApplicationE or NewInMemoryVStoreApplication.
+// Options are applied in order, after the defaults. Every setting has an
+// option, the Set methods of VStoreApplication change the same settings of an
+// existing application and must not be called while it serves requests.
+type Option func(*VStoreApplication)
+
+// WithLogger sets the structured logger of the application. Events are not
+// logged by default, i.e. the logger is a nop logger.
+func WithLogger(logger cmtlog.Logger) Option {
+	return func(app *VStoreApplication) {
+		app.SetLogger(logger)
+	}
+}
+
+// WithMaxBodySize sets the maximum size in bytes of the body that is carried
+// in a transaction, i.e. of each chunk of a chunked body. Larger bodies are
+// rejected in CheckTx with CodeTypeInvalidBodyError. The size is not limited
+// by default (0).
+func WithMaxBodySize(size int) Option {
+	return func(app *VStoreApplication) {
+		app.maxBodySize = size
+	}
+}
+
+// WithTimestampDrift sets the drift window of transaction timestamps, as with
+// SetTimestampWindow. Defaults to DefaultMaxPastDrift and DefaultMaxFutureDrift.
+func WithTimestampDrift(maxPast, maxFuture time.Duration) Option {
+	return func(app *VStoreApplication) {
+		app.SetTimestampWindow(maxPast, maxFuture)
+	}
+}
+
+// WithKDF sets the key derivation function that the identity file must use,
+// the application is not created if the identity file uses another function,
+// e.g. to refuse legacy KDFSHA256 identity files after a vstore rekey. Any
+// key derivation function is accepted by default.
+func WithKDF(kdf KDF) Option {
+	return func(app *VStoreApplication) {
+		app.requiredKDF = &kdf
+	}
+}
+
+// WithLegacySignatures sets whether signatures in the legacy format are
+// accepted, as with SetLegacySignatures. Legacy signatures are rejected by
+// default.
+func WithLegacySignatures(allow bool) Option {
+	return func(app *VStoreApplication) {
+		app.SetLegacySignatures(allow)
+	}
+}
+
+// WithCheckTxMode sets the checks that are performed in CheckTx, as with
+// SetCheckTxMode. Defaults to CheckTxStateless.
+func WithCheckTxMode(mode CheckTxMode) Option {
+	return func(app *VStoreApplication) {
+		app.SetCheckTxMode(mode)
+	}
+}
+
+// WithCompression sets the codec that compresses new transactions, as with
+// SetCompression. Transactions are not compressed by default.
+func WithCompression(codec Compression) Option {
+	return func(app *VStoreApplication) {
+		app.SetCompression(codec)
+	}
+}
+
+// WithPlaintext sets whether new transactions are stored unencrypted, as with
+// SetPlaintext. Transactions are encrypted by default.
+func WithPlaintext(plaintext bool) Option {
+	return func(app *VStoreApplication) {
+		app.SetPlaintext(plaintext)
+	}
+}
+
+// WithFinalizeWorkers sets the number of workers that parse transactions in
+// FinalizeBlock, as with SetFinalizeWorkers. Transactions are parsed
+// sequentially by default.
+func WithFinalizeWorkers(n int) Option {
+	return func(app *VStoreApplication) {
+		app.SetFinalizeWorkers(n)
+	}
+}
+
+// WithSnapshots enables the state sync snapshots, as with SetSnapshots.
+// Snapshots are disabled by default, they stall Commit while they are written.
+func WithSnapshots(dir string, interval int64, keepRecent int) Option {
+	return func(app *VStoreApplication) {
+		app.SetSnapshots(dir, interval, keepRecent)
+	}
+}
+
+// WithRetention sets the retention policy which prunes committed transactions
+// every interval heights, as with SetRetention. Transactions are never pruned
+// by default.
+func WithRetention(policy RetentionPolicy, interval int64) Option {
+	return func(app *VStoreApplication) {
+		app.SetRetention(policy, interval)
+	}
+}
+
+// WithBlobStore sets the external storage which resolves the body references
+// of transactions, as with SetBlobStore. Body references are not resolved by
+// default.
+func WithBlobStore(blobs BlobStore) Option {
+	return func(app *VStoreApplication) {
+		app.SetBlobStore(blobs)
+	}
+}
+
+// WithBodyValidator sets the validator of bodies in CheckTx, as with
+// SetBodyValidator. Bodies are not validated by default.
+func WithBodyValidator(validator BodyValidator) Option {
+	return func(app *VStoreApplication) {
+		app.SetBodyValidator(validator)
+	}
+}
+
+// WithEventSink sets the destination and the payload format of the events of
+// committed transactions, as with SetEventSink. Events are not emitted by
+// default.
+func WithEventSink(sink EventSink, format EventFormat) Option {
+	return func(app *VStoreApplication) {
+		app.SetEventSink(sink, format)
+	}
+}
+
+// WithMetrics sets the metrics that are recorded by the application, as with
+// SetMetrics. Metrics are not recorded by default.
+func WithMetrics(m *Metrics) Option {
+	return func(app *VStoreApplication) {
+		app.SetMetrics(m)
+	}
+}
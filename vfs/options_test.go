@@ -0,0 +1,137 @@
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cmtdb "github.com/cometbft/cometbft-db"
+	abci "github.com/cometbft/cometbft/abci/types"
+	cmtlog "github.com/cometbft/cometbft/libs/log"
+)
+
+func TestVStoreDefaultOptions(t *testing.T) {
+	_, cancel, _, vfsDir := ResetTestRoot(t, "test-vstore-default_options", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	assert.Equal(t, cmtlog.NewNopLogger(), vstore.logger)
+	assert.Zero(t, vstore.maxBodySize, "body size must not be limited")
+	assert.Equal(t, DefaultMaxPastDrift, vstore.maxPastDrift)
+	assert.Equal(t, DefaultMaxFutureDrift, vstore.maxFutureDrift)
+	assert.Nil(t, vstore.requiredKDF, "any key derivation function must be accepted")
+	assert.False(t, vstore.legacySignatures, "legacy signatures must be rejected")
+}
+
+func TestVStoreOptions(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-options", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	idFile := filepath.Join(vfsDir, "id")
+	logger := NewBufferedLogger(cmtlog.NewNopLogger(), DefaultLogBufferSize)
+	vstore := NewInMemoryVStoreApplication(idFile, []byte("testpassword"),
+		WithLogger(logger),
+		WithMaxBodySize(16),
+		WithTimestampDrift(time.Hour, time.Minute),
+		WithKDF(DefaultKDF),
+	)
+
+	assert.Equal(t, logger, vstore.logger)
+	assert.Equal(t, time.Hour, vstore.maxPastDrift)
+	assert.Equal(t, time.Minute, vstore.maxFutureDrift)
+
+	checkTx := func(body string) uint32 {
+		stx, err := makeTransaction(t, ownerPrivs[0], []byte(body))
+		require.NoError(t, err)
+
+		resp, err := vstore.CheckTx(ctx, &abci.RequestCheckTx{Tx: stx.Bytes()})
+		require.NoError(t, err)
+		return resp.Code
+	}
+
+	assert.Equal(t, CodeTypeOK, checkTx(strings.Repeat("x", 16)))
+	assert.Equal(t, CodeTypeInvalidBodyError, checkTx(strings.Repeat("x", 17)))
+
+	// Identity files with another key derivation function are refused
+	legacyFile := filepath.Join(vfsDir, "id-sha256")
+	MustGenerateIdentityWithKDF(legacyFile, []byte("testpassword"), KDFSHA256)
+
+	_, err := NewVStoreApplicationE(cmtdb.NewMemDB(), legacyFile, []byte("testpassword"), WithKDF(KDFArgon2id))
+	assert.ErrorContains(t, err, "expected argon2id")
+
+	_, err = NewVStoreApplicationE(cmtdb.NewMemDB(), legacyFile, []byte("testpassword"), WithKDF(KDFSHA256))
+	assert.NoError(t, err)
+}
+
+func TestVStoreOptionsSetters(t *testing.T) {
+	_, cancel, _, vfsDir := ResetTestRoot(t, "test-vstore-options_setters", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	idFile := filepath.Join(vfsDir, "id")
+	blobs := NewDirBlobStore(filepath.Join(vfsDir, "blobs"))
+	schema := &JSONSchema{}
+	sink := NewWebhookSink("http://localhost")
+	metrics := &Metrics{}
+	retention := RetentionPolicy{MaxAge: time.Hour}
+
+	// Options set the same settings as the setters
+	withOptions := NewInMemoryVStoreApplication(idFile, []byte("testpassword"),
+		WithLegacySignatures(false),
+		WithCheckTxMode(CheckTxStateful),
+		WithCompression(CompressionGzip),
+		WithPlaintext(true),
+		WithFinalizeWorkers(4),
+		WithSnapshots(vfsDir, 10, 2),
+		WithRetention(retention, 5),
+		WithBlobStore(blobs),
+		WithBodyValidator(schema),
+		WithEventSink(sink, EventFormatCloudEvents),
+		WithMetrics(metrics),
+	)
+
+	withSetters := NewInMemoryVStoreApplication(idFile, []byte("testpassword"))
+	withSetters.SetLegacySignatures(false)
+	withSetters.SetCheckTxMode(CheckTxStateful)
+	withSetters.SetCompression(CompressionGzip)
+	withSetters.SetPlaintext(true)
+	withSetters.SetFinalizeWorkers(4)
+	withSetters.SetSnapshots(vfsDir, 10, 2)
+	withSetters.SetRetention(retention, 5)
+	withSetters.SetBlobStore(blobs)
+	withSetters.SetBodyValidator(schema)
+	withSetters.SetEventSink(sink, EventFormatCloudEvents)
+	withSetters.SetMetrics(metrics)
+
+	for _, app := range []*VStoreApplication{withOptions, withSetters} {
+		assert.False(t, app.legacySignatures)
+		assert.Equal(t, CheckTxStateful, app.checkTxMode)
+		assert.Equal(t, CompressionGzip, app.compression)
+		assert.True(t, app.plaintext)
+		assert.Equal(t, 4, app.finalizeWorkers)
+		assert.Equal(t, vfsDir, app.snapshotDir)
+		assert.EqualValues(t, 10, app.snapshotInterval)
+		assert.Equal(t, 2, app.snapshotKeepRecent)
+		assert.Equal(t, retention, app.retention)
+		assert.EqualValues(t, 5, app.retentionInterval)
+		assert.Equal(t, blobs, app.blobs)
+		assert.Equal(t, schema, app.bodyValidator)
+		assert.Equal(t, sink, app.events)
+		assert.Equal(t, EventFormatCloudEvents, app.eventFormat)
+		assert.Equal(t, metrics, app.metrics)
+	}
+}
@@ -0,0 +1,58 @@
+package vfs
+
+import "sync"
+
+// parallelParseMinTxs is the minimum number of transactions of a block for
+// which transactions are parsed in parallel. Smaller blocks are parsed
+// sequentially, as the cost of the workers outweighs the gain.
+var parallelParseMinTxs = 64
+
+// SetFinalizeWorkers sets the number of workers that parse transactions and
+// compute transaction hashes in FinalizeBlock. With 0 or 1 worker, which is
+// the default, transactions are parsed sequentially. Transactions are always
+// staged in block order, such that the results do not depend on the workers.
+func (app *VStoreApplication) SetFinalizeWorkers(n int) {
+	app.finalizeWorkers = n
+}
+
+// parsedTx holds the result of parsing the transaction at an index of a block.
+type parsedTx struct {
+	stx *SignedTransaction
+	err error
+}
+
+// parseBlockTxs parses the transactions of a block and computes their hashes
+// with NewSignedTransactionFromBytes. Results are returned in block order.
+func (app *VStoreApplication) parseBlockTxs(txs [][]byte) []parsedTx {
+	results := make([]parsedTx, len(txs))
+
+	workers := min(app.finalizeWorkers, len(txs))
+	if workers <= 1 || len(txs) < parallelParseMinTxs {
+		for i, tx := range txs {
+			results[i].stx, results[i].err = NewSignedTransactionFromBytes(tx)
+		}
+
+		return results
+	}
+
+	// Workers write the result at the index of the transaction
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i].stx, results[i].err = NewSignedTransactionFromBytes(txs[i])
+			}
+		}()
+	}
+
+	for i := range txs {
+		indexes <- i
+	}
+
+	close(indexes)
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,97 @@
+package vfs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+)
+
+func TestVStoreFinalizeBlockParallel(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-finalize_block_parallel", 4)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	idFile := filepath.Join(vfsDir, "id")
+	sequential := NewInMemoryVStoreApplication(idFile, []byte("testpassword"))
+	parallel := NewInMemoryVStoreApplication(idFile, []byte("testpassword"))
+	parallel.SetFinalizeWorkers(8)
+
+	// Second block is small enough for the sequential path
+	for height, n := range []int{500, parallelParseMinTxs - 1} {
+		txs := makeMixedBlockTxs(t, ownerPrivs, height, n)
+		req := &abci.RequestFinalizeBlock{Height: int64(height + 1), Txs: txs}
+
+		want, err := sequential.FinalizeBlock(ctx, req)
+		require.NoError(t, err)
+		got, err := parallel.FinalizeBlock(ctx, req)
+		require.NoError(t, err)
+
+		assert.Equal(t, want.TxResults, got.TxResults, "results must be identical to the sequential path")
+		assert.Equal(t, want.AppHash, got.AppHash)
+		assert.Equal(t, sequential.stage, parallel.stage, "staging order must be deterministic")
+
+		_, err = sequential.Commit(ctx, &abci.RequestCommit{})
+		require.NoError(t, err)
+		_, err = parallel.Commit(ctx, &abci.RequestCommit{})
+		require.NoError(t, err)
+	}
+}
+
+// makeMixedBlockTxs returns n transactions of which some are malformed or
+// carry a forged hash, such that their results are errors.
+func makeMixedBlockTxs(t *testing.T, privs [][]byte, block, n int) [][]byte {
+	t.Helper()
+
+	txs := make([][]byte, 0, n)
+	for i := 0; i < n; i++ {
+		data := testSimpleValue + strconv.Itoa(block) + "-" + strconv.Itoa(i)
+		stx, err := makeTransaction(t, privs[i%len(privs)], []byte(data))
+		require.NoError(t, err)
+
+		switch i % 50 {
+		case 7:
+			txs = append(txs, []byte("not a transaction"))
+		case 13:
+			stx.Hash = make([]byte, 32) // forged hash
+			txs = append(txs, stx.Bytes())
+		default:
+			txs = append(txs, stx.Bytes())
+		}
+	}
+
+	return txs
+}
+
+func BenchmarkFinalizeBlockSequential(b *testing.B) {
+	benchmarkFinalizeBlock(b, 0)
+}
+
+func BenchmarkFinalizeBlockParallel(b *testing.B) {
+	benchmarkFinalizeBlock(b, runtime.NumCPU())
+}
+
+func benchmarkFinalizeBlock(b *testing.B, workers int) {
+	app, proposal, teardown := makeBenchmarkProposal(b, 1000)
+	defer teardown()
+
+	app.SetFinalizeWorkers(workers)
+	req := &abci.RequestFinalizeBlock{Height: 1, Txs: proposal.Txs, Time: proposal.Time}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		resp, err := app.FinalizeBlock(context.Background(), req)
+		if err != nil || resp.TxResults[0].Code != CodeTypeOK {
+			b.Fatal("transactions must be staged")
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package vfs
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// PasswordPolicy describes the requirements for passwords that are used to
+// encrypt identity files. The policy is enforced at identity generation.
+type PasswordPolicy struct {
+	// MinLength is the minimum number of characters of the password.
+	MinLength int
+
+	// RequireUpper requires at least one uppercase letter.
+	RequireUpper bool
+
+	// RequireLower requires at least one lowercase letter.
+	RequireLower bool
+
+	// RequireDigit requires at least one digit.
+	RequireDigit bool
+
+	// RequireSymbol requires at least one symbol or punctuation character.
+	RequireSymbol bool
+
+	// MinUnique is the minimum number of distinct characters, such that
+	// repeated characters are rejected, e.g. "aaaaaaaa".
+	MinUnique int
+}
+
+// DefaultPasswordPolicy requires passwords of at least 12 characters which
+// contain uppercase and lowercase letters, digits and symbols.
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:     12,
+	RequireUpper:  true,
+	RequireLower:  true,
+	RequireDigit:  true,
+	RequireSymbol: true,
+}
+
+// MinimumPasswordPolicy requires passwords of at least 8 characters of which
+// at least 5 are distinct. It rejects empty and trivial passwords when the
+// DefaultPasswordPolicy is not enforced.
+var MinimumPasswordPolicy = PasswordPolicy{
+	MinLength: 8,
+	MinUnique: 5,
+}
+
+// Validate checks a password against the policy and returns an error which
+// lists all the unmet requirements, or nil if the password is accepted.
+func (p PasswordPolicy) Validate(pw []byte) error {
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+
+	runes := []rune(string(pw))
+	unique := make(map[rune]struct{})
+	for _, r := range runes {
+		unique[r] = struct{}{}
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+
+	unmet := []string{}
+	if len(runes) < p.MinLength {
+		unmet = append(unmet, fmt.Sprintf("at least %d characters", p.MinLength))
+	}
+
+	if p.RequireUpper && !hasUpper {
+		unmet = append(unmet, "an uppercase letter")
+	}
+
+	if p.RequireLower && !hasLower {
+		unmet = append(unmet, "a lowercase letter")
+	}
+
+	if p.RequireDigit && !hasDigit {
+		unmet = append(unmet, "a digit")
+	}
+
+	if p.RequireSymbol && !hasSymbol {
+		unmet = append(unmet, "a symbol")
+	}
+
+	if len(unique) < p.MinUnique {
+		unmet = append(unmet, fmt.Sprintf("at least %d distinct characters", p.MinUnique))
+	}
+
+	if len(unmet) > 0 {
+		return fmt.Errorf("password must contain: %s", strings.Join(unmet, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package vfs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVStorePasswordPolicyValidate(t *testing.T) {
+	// ----------------------------------------------
+	// Success cases
+	pws := [][]byte{
+		[]byte("^{&,fqG6[]<Pe(i7,ADptqeZt{?^DB%="),
+		[]byte("Correct-Horse-9"),
+		[]byte("Äbcdefghijk1$"),
+	}
+
+	for _, pw := range pws {
+		assert.NoError(t, DefaultPasswordPolicy.Validate(pw), "should accept password %s", pw)
+	}
+
+	// ----------------------------------------------
+	// Error cases
+	failPws := map[string]string{
+		"1":                                "at least 12 characters, an uppercase letter, a lowercase letter, a symbol",
+		"testpassword":                     "an uppercase letter, a digit, a symbol",
+		"ZhmSBMGoQK9FktNf4SZXE5US6MHUadoe": "a symbol",
+		"SHORT-1a":                         "at least 12 characters",
+	}
+
+	for pw, unmet := range failPws {
+		err := DefaultPasswordPolicy.Validate([]byte(pw))
+		assert.EqualError(t, err, "password must contain: "+unmet)
+	}
+
+	// Empty policy accepts any password
+	assert.NoError(t, PasswordPolicy{}.Validate([]byte("1")))
+}
+
+func TestVStorePasswordPolicyMinimum(t *testing.T) {
+	for _, pw := range []string{"testpassword", "12345678", "correct horse"} {
+		assert.NoError(t, MinimumPasswordPolicy.Validate([]byte(pw)), "should accept password %s", pw)
+	}
+
+	failPws := map[string]string{
+		"":          "at least 8 characters, at least 5 distinct characters",
+		"secret":    "at least 8 characters",
+		"aaaaaaaaa": "at least 5 distinct characters",
+		"abababab":  "at least 5 distinct characters",
+	}
+
+	for pw, unmet := range failPws {
+		err := MinimumPasswordPolicy.Validate([]byte(pw))
+		assert.EqualError(t, err, "password must contain: "+unmet)
+	}
+}
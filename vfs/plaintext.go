@@ -0,0 +1,49 @@
+package vfs
+
+import "bytes"
+
+// plaintextMagic and plaintextVersion1 describe the storage header that is
+// prepended to the transactions that are stored unencrypted:
+// magic (4) || version (1) || compression header (0-1) || transaction
+//
+// The magic is the record type of plaintext transactions, other stored
+// transactions start with the header of their record type too, see txKeyMagic
+// and prunedMagic, such that records are never identified by their content.
+var plaintextMagic = []byte("VSPT")
+
+const (
+	plaintextVersion1  byte = 1
+	plaintextHeaderLen      = 5
+)
+
+// SetPlaintext sets whether new transactions are stored unencrypted, e.g. for
+// public records which are openly readable. Plaintext transactions are read
+// without the decryption secret and coexist with encrypted transactions, i.e.
+// the storage mode can be changed at any time. Transactions are encrypted by
+// default. Observers always store new transactions unencrypted.
+//
+// Note: Plaintext transactions are readable by anyone with access to the
+// database, e.g. to its backups, without the node identity.
+func (app *VStoreApplication) SetPlaintext(plaintext bool) {
+	app.plaintext = plaintext || app.Observer()
+}
+
+// --------------------------------------------------------------------------
+// Private helpers
+
+// isPlaintextTransaction returns true if a stored transaction starts with the
+// storage header of plaintext transactions.
+func isPlaintextTransaction(data []byte) bool {
+	return len(data) > plaintextHeaderLen &&
+		bytes.HasPrefix(data, plaintextMagic) &&
+		data[len(plaintextMagic)] == plaintextVersion1
+}
+
+// storePlaintext prepends the storage header of plaintext transactions to the
+// transaction data, which may be compressed.
+func storePlaintext(data []byte) []byte {
+	stored := make([]byte, 0, plaintextHeaderLen+len(data))
+	stored = append(stored, plaintextMagic...)
+	stored = append(stored, plaintextVersion1)
+	return append(stored, data...)
+}
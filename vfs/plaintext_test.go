@@ -0,0 +1,99 @@
+package vfs
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	vfsp2p "github.com/securesharelabs/vstore/api/vstore/v1"
+
+	cmtdb "github.com/cometbft/cometbft-db"
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+	"github.com/cosmos/gogoproto/proto"
+)
+
+func TestVStorePlaintext(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-plaintext", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	db := cmtdb.NewMemDB()
+	vstore := NewVStoreApplication(db, filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	// Encrypted, plaintext and compressed plaintext transactions coexist
+	modes := []struct {
+		plaintext   bool
+		compression Compression
+		data        []byte
+	}{
+		{false, CompressionNone, []byte(testComplexValue + "encrypted")},
+		{true, CompressionNone, []byte(testComplexValue + "plaintext")},
+		{true, CompressionZstd, bytes.Repeat([]byte(testComplexValue), 32)},
+	}
+
+	stxs := []*SignedTransaction{}
+	for i, mode := range modes {
+		vstore.SetPlaintext(mode.plaintext)
+		vstore.SetCompression(mode.compression)
+
+		stx, err := makeTransaction(t, ownerPrivs[0], mode.data)
+		require.NoError(t, err)
+		stx.Hash = ComputeHash(stx)
+		stxs = append(stxs, stx)
+
+		makeBlockCommit(ctx, t, vstore, i+1, [][]byte{stx.Bytes()})
+
+		stored, err := db.Get(TransactionKey(stx.Hash))
+		require.NoError(t, err)
+		assert.Equal(t, mode.plaintext, isPlaintextTransaction(stored))
+
+		// Uncompressed plaintext transactions are readable in the database
+		readable := bytes.Contains(stored, mode.data)
+		assert.Equal(t, mode.plaintext && mode.compression == CompressionNone, readable)
+	}
+
+	// Queries by hash return all transactions
+	for _, stx := range stxs {
+		resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: stx.Hash})
+		require.NoError(t, err)
+		assert.Equal(t, stx.Bytes(), resQuery.Value)
+	}
+
+	// Queries by signer return all transactions
+	pub := ed25519.PrivKey(ownerPrivs[0]).PubKey().Bytes()
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/pubkey", Data: pub})
+	require.NoError(t, err)
+
+	list := new(vfsp2p.TransactionList)
+	require.NoError(t, proto.Unmarshal(resQuery.Value, list))
+	require.Len(t, list.Transactions, len(stxs))
+	for i, stx := range stxs {
+		assert.Equal(t, []byte(stx.Data), list.Transactions[i].Body)
+	}
+
+	// Plaintext transactions do not need the node secret
+	txData, err := openTransaction([]byte{}, mustGet(t, db, TransactionKey(stxs[1].Hash)))
+	require.NoError(t, err)
+	assert.Equal(t, stxs[1].Bytes(), txData)
+
+	_, err = openTransaction([]byte{}, mustGet(t, db, TransactionKey(stxs[0].Hash)))
+	assert.Error(t, err)
+
+	assert.NoError(t, vstore.VerifyIntegrity())
+}
+
+func mustGet(t *testing.T, db cmtdb.DB, key []byte) []byte {
+	t.Helper()
+
+	value, err := db.Get(key)
+	require.NoError(t, err)
+	require.NotEmpty(t, value)
+	return value
+}
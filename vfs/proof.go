@@ -0,0 +1,309 @@
+package vfs
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cometbft/cometbft/crypto/merkle"
+	cmtcrypto "github.com/cometbft/cometbft/proto/tendermint/crypto"
+)
+
+// ProofOpMerkle is the type of proof operations produced by vfs. Each proof
+// operation contains a merkle proof which computes an intermediate root from
+// a leaf, e.g. the merkle root of a signer from a transaction hash. The last
+// operation is of type ProofOpSignerRoot and computes the application hash
+// (State.Hash()).
+const ProofOpMerkle = "vfs:merkle"
+
+// MerkleProofOp describes a proof operation which proves that a leaf is part
+// of a merkle tree. Running the operation returns the merkle root.
+type MerkleProofOp struct {
+	// Key is optional and consumed from the key path when verifying.
+	Key []byte
+
+	// Proof is the merkle proof of the leaf.
+	Proof *merkle.Proof
+}
+
+var _ merkle.ProofOperator = MerkleProofOp{}
+
+// Run verifies that the leaf is part of the merkle tree and returns the
+// merkle root computed from the proof.
+// Run implements merkle.ProofOperator
+func (op MerkleProofOp) Run(args [][]byte) ([][]byte, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("expected 1 arg, got: %d", len(args))
+	}
+
+	root := op.Proof.ComputeRootHash()
+	if err := op.Proof.Verify(root, args[0]); err != nil {
+		return nil, err
+	}
+
+	return [][]byte{root}, nil
+}
+
+// GetKey implements merkle.ProofOperator
+func (op MerkleProofOp) GetKey() []byte {
+	return op.Key
+}
+
+// ProofOp encodes the merkle proof in a generic proof operation.
+// ProofOp implements merkle.ProofOperator
+func (op MerkleProofOp) ProofOp() cmtcrypto.ProofOp {
+	bz, err := op.Proof.ToProto().Marshal()
+	if err != nil {
+		panic(err)
+	}
+
+	return cmtcrypto.ProofOp{
+		Type: ProofOpMerkle,
+		Key:  op.Key,
+		Data: bz,
+	}
+}
+
+// MerkleProofOpDecoder decodes a generic proof operation of type ProofOpMerkle.
+func MerkleProofOpDecoder(pop cmtcrypto.ProofOp) (merkle.ProofOperator, error) {
+	if pop.Type != ProofOpMerkle {
+		return nil, fmt.Errorf("unexpected proof op type, want: %s, got: %s", ProofOpMerkle, pop.Type)
+	}
+
+	pb := new(cmtcrypto.Proof)
+	if err := pb.Unmarshal(pop.Data); err != nil {
+		return nil, err
+	}
+
+	proof, err := merkle.ProofFromProto(pb)
+	if err != nil {
+		return nil, err
+	}
+
+	return MerkleProofOp{Key: pop.Key, Proof: proof}, nil
+}
+
+// ProofOpSignerRoot is the type of proof operations which prove that the
+// merkle root of a signer is part of the application hash. The leaf of the
+// merkle proof is the public key of the signer followed by its merkle root,
+// such that the proof is valid only for the signer of its key.
+const ProofOpSignerRoot = "vfs:signer-root"
+
+// SignerRootProofOp describes a proof operation which proves that the merkle
+// root of a signer is part of the application hash. Running the operation
+// returns the application hash.
+type SignerRootProofOp struct {
+	// Key is the uppercase hexadecimal public key of the signer, it is
+	// consumed from the key path when verifying.
+	Key []byte
+
+	// Proof is the merkle proof of the signer leaf, see signerRootLeaf.
+	Proof *merkle.Proof
+}
+
+var _ merkle.ProofOperator = SignerRootProofOp{}
+
+// Run verifies that the signer public key followed by the merkle root is part
+// of the merkle tree and returns the merkle root computed from the proof.
+// Run implements merkle.ProofOperator
+func (op SignerRootProofOp) Run(args [][]byte) ([][]byte, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("expected 1 arg, got: %d", len(args))
+	}
+
+	signer, err := hex.DecodeString(string(op.Key))
+	if err != nil {
+		return nil, fmt.Errorf("invalid signer public key: %w", err)
+	}
+
+	root := op.Proof.ComputeRootHash()
+	if err := op.Proof.Verify(root, signerRootLeaf(signer, args[0])); err != nil {
+		return nil, err
+	}
+
+	return [][]byte{root}, nil
+}
+
+// GetKey implements merkle.ProofOperator
+func (op SignerRootProofOp) GetKey() []byte {
+	return op.Key
+}
+
+// ProofOp encodes the merkle proof in a generic proof operation.
+// ProofOp implements merkle.ProofOperator
+func (op SignerRootProofOp) ProofOp() cmtcrypto.ProofOp {
+	bz, err := op.Proof.ToProto().Marshal()
+	if err != nil {
+		panic(err)
+	}
+
+	return cmtcrypto.ProofOp{
+		Type: ProofOpSignerRoot,
+		Key:  op.Key,
+		Data: bz,
+	}
+}
+
+// SignerRootProofOpDecoder decodes a generic proof operation of type
+// ProofOpSignerRoot.
+func SignerRootProofOpDecoder(pop cmtcrypto.ProofOp) (merkle.ProofOperator, error) {
+	if pop.Type != ProofOpSignerRoot {
+		return nil, fmt.Errorf("unexpected proof op type, want: %s, got: %s", ProofOpSignerRoot, pop.Type)
+	}
+
+	pb := new(cmtcrypto.Proof)
+	if err := pb.Unmarshal(pop.Data); err != nil {
+		return nil, err
+	}
+
+	proof, err := merkle.ProofFromProto(pb)
+	if err != nil {
+		return nil, err
+	}
+
+	return SignerRootProofOp{Key: pop.Key, Proof: proof}, nil
+}
+
+// NewProofRuntime returns a proof runtime that decodes vfs proof operations.
+func NewProofRuntime() *merkle.ProofRuntime {
+	prt := merkle.NewProofRuntime()
+	prt.RegisterOpDecoder(ProofOpMerkle, MerkleProofOpDecoder)
+	prt.RegisterOpDecoder(ProofOpSignerRoot, SignerRootProofOpDecoder)
+	return prt
+}
+
+// VerifyTransactionProof verifies that a transaction hash is committed under
+// the merkle root of its signer and that this merkle root is part of the
+// application hash. The signer is the uppercase hexadecimal public key.
+func VerifyTransactionProof(
+	proof *cmtcrypto.ProofOps,
+	appHash []byte,
+	signer string,
+	txHash []byte,
+) error {
+	if proof == nil {
+		return errors.New("nil proof")
+	}
+
+	keyPath := "/" + strings.ToUpper(signer)
+	return NewProofRuntime().VerifyValue(proof, appHash, keyPath, txHash)
+}
+
+// VerifySignerRootProof verifies that the merkle root of a signer is part of
+// the application hash. The signer is the uppercase hexadecimal public key.
+func VerifySignerRootProof(
+	proof *cmtcrypto.ProofOps,
+	appHash []byte,
+	signer string,
+	root []byte,
+) error {
+	if proof == nil {
+		return errors.New("nil proof")
+	}
+
+	keyPath := "/" + strings.ToUpper(signer)
+	return NewProofRuntime().VerifyValue(proof, appHash, keyPath, root)
+}
+
+// --------------------------------------------------------------------------
+
+// proveTransaction creates the proof operations for a transaction hash. The
+// merkle root of a signer is the merkle tree of all its transaction hashes,
+// such that the proof contains a first operation that proves the transaction
+// hash is a leaf of the signer merkle tree and a last operation that proves
+// the signer merkle root is part of the application hash.
+// If height is set, the proof is created for the app hash of this height and
+// the signer merkle tree contains the transactions committed until then.
+// The position of the transaction for this signer is returned as well.
+func (app *VStoreApplication) proveTransaction(
+	signer []byte,
+	txHash []byte,
+	height int64,
+) (*cmtcrypto.ProofOps, int64, error) {
+	pub := strings.ToUpper(hex.EncodeToString(signer))
+
+	signerRoots := app.state.SortedSignerRoots()
+	signerCounts := app.state.SignerCounts
+	if height > 0 && height != app.state.Height {
+		record, err := app.readAppHashRecord(height)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if record == nil {
+			return nil, 0, fmt.Errorf("no app hash at height %d", height)
+		}
+
+		signerRoots = record.MerkleRoots
+		signerCounts = record.SignerCounts
+	}
+
+	position := -1
+	for i, sr := range signerRoots {
+		if sr.PubKey == pub {
+			position = i
+			break
+		}
+	}
+
+	if position < 0 {
+		return nil, 0, errors.New("signer has no merkle root at this height")
+	}
+
+	// Read the signer transaction hashes (in order of commitment)
+	hashes, err := app.readSignerHashes(signer)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	index := -1
+	for i, hash := range hashes {
+		if bytes.Equal(hash, txHash) {
+			index = i
+			break
+		}
+	}
+
+	if index < 0 {
+		return nil, 0, errors.New("transaction hash not found in signer index")
+	}
+
+	// Leaves are the transactions of the signer until this height
+	count, ok := signerCounts[pub]
+	if !ok || count > int64(len(hashes)) {
+		return nil, 0, errors.New("signer has no transaction count at this height")
+	}
+
+	if int64(index) >= count {
+		return nil, 0, errors.New("transaction was committed after this height")
+	}
+
+	leaves := hashes[:count]
+	if !bytes.Equal(merkle.HashFromByteSlices(leaves), signerRoots[position].Root) {
+		return nil, 0, errors.New("signer index does not produce the merkle root")
+	}
+
+	_, proofs := merkle.ProofsFromByteSlices(leaves)
+	ops := []merkle.ProofOperator{
+		MerkleProofOp{Proof: proofs[index]},
+
+		// Prove that the signer merkle root is part of the app hash
+		proveSignerRoot(signerRoots, position),
+	}
+
+	proofOps := &cmtcrypto.ProofOps{Ops: make([]cmtcrypto.ProofOp, len(ops))}
+	for i, op := range ops {
+		proofOps.Ops[i] = op.ProofOp()
+	}
+
+	return proofOps, int64(index), nil
+}
+
+// proveSignerRoot creates the proof operation which proves that the merkle
+// root of the signer at position is part of the app hash of signerRoots.
+func proveSignerRoot(signerRoots []SignerRoot, position int) SignerRootProofOp {
+	_, proofs := merkle.ProofsFromByteSlices(signerRootLeaves(signerRoots))
+	return SignerRootProofOp{Key: []byte(signerRoots[position].PubKey), Proof: proofs[position]}
+}
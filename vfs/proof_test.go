@@ -0,0 +1,364 @@
+package vfs
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cmtdb "github.com/cometbft/cometbft-db"
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+	"github.com/cometbft/cometbft/crypto/merkle"
+	"github.com/cometbft/cometbft/crypto/tmhash"
+	cmtcrypto "github.com/cometbft/cometbft/proto/tendermint/crypto"
+)
+
+func TestVStoreQueryProof(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-query_proof", 3)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	// Commit 3 blocks with transactions of 3 signers
+	stxs := []*SignedTransaction{}
+	for height := 1; height <= 3; height++ {
+		txs := [][]byte{}
+		for _, priv := range ownerPrivs {
+			stx, err := makeTransaction(t, priv, []byte(testComplexValue+string(rune('a'+height))))
+			require.NoError(t, err, "should create a signed transaction")
+
+			stx.Hash = ComputeHash(stx)
+			stxs = append(stxs, stx)
+			txs = append(txs, stx.Bytes())
+		}
+
+		makeBlockCommit(ctx, t, vstore, height, txs)
+	}
+
+	info, err := vstore.Info(ctx, &abci.RequestInfo{})
+	require.NoError(t, err)
+	appHash := info.LastBlockAppHash
+	require.NotEmpty(t, appHash)
+
+	for i, stx := range stxs {
+		resQuery, err := vstore.Query(ctx, &abci.RequestQuery{
+			Path:  "/hash",
+			Data:  stx.Hash,
+			Prove: true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, CodeTypeOK, resQuery.Code)
+		require.NotNil(t, resQuery.ProofOps, "proof must be returned")
+		assert.EqualValues(t, i/len(ownerPrivs), resQuery.Index, "index must be the position for the signer")
+
+		// Proof verifies against the app hash
+		err = VerifyTransactionProof(resQuery.ProofOps, appHash, stx.PublicKey(), stx.Hash)
+		assert.NoError(t, err, "proof must verify against the app hash")
+
+		// Proof does not verify for another transaction hash
+		err = VerifyTransactionProof(resQuery.ProofOps, appHash, stx.PublicKey(), tmhash.Sum([]byte("forged")))
+		assert.Error(t, err, "proof must not verify a forged hash")
+
+		// Proof does not verify against another app hash
+		err = VerifyTransactionProof(resQuery.ProofOps, tmhash.Sum(appHash), stx.PublicKey(), stx.Hash)
+		assert.Error(t, err, "proof must not verify against another app hash")
+
+		// Proof does not verify for another signer, the public key is committed
+		other := stxs[(i+1)%len(stxs)].PublicKey()
+		err = VerifyTransactionProof(swapProofSigner(resQuery.ProofOps, other), appHash, other, stx.Hash)
+		assert.Error(t, err, "proof must not verify for another signer")
+	}
+
+	// Proof is only computed on request
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: stxs[0].Hash})
+	require.NoError(t, err)
+	assert.Nil(t, resQuery.ProofOps)
+}
+
+func TestVStoreQueryProofAtHeight(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-query_proof_at_height", 2)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	// Commit 3 blocks with transactions of 2 signers, keep the app hashes
+	stxs := []*SignedTransaction{}
+	appHashes := [][]byte{}
+	for height := 1; height <= 3; height++ {
+		txs := [][]byte{}
+		for _, priv := range ownerPrivs {
+			stx, err := makeTransaction(t, priv, []byte(testComplexValue+string(rune('a'+height))))
+			require.NoError(t, err, "should create a signed transaction")
+
+			stx.Hash = ComputeHash(stx)
+			stxs = append(stxs, stx)
+			txs = append(txs, stx.Bytes())
+		}
+
+		resFinBlock, _ := makeBlockCommit(ctx, t, vstore, height, txs)
+		appHashes = append(appHashes, resFinBlock.AppHash)
+	}
+
+	// App hashes are persisted per height
+	for i, appHash := range appHashes {
+		resQuery, err := vstore.Query(ctx, &abci.RequestQuery{
+			Path: "/apphash/height",
+			Data: []byte(string(rune('1' + i))),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, appHash, resQuery.Value, "app hash must be stored for height %d", i+1)
+	}
+
+	// Transaction of height 1 verifies against the historical app hash
+	stx := stxs[0]
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{
+		Path:   "/hash",
+		Data:   stx.Hash,
+		Height: 1,
+		Prove:  true,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, resQuery.ProofOps, "proof must be returned")
+	assert.EqualValues(t, 1, resQuery.Height)
+
+	err = VerifyTransactionProof(resQuery.ProofOps, appHashes[0], stx.PublicKey(), stx.Hash)
+	assert.NoError(t, err, "proof must verify against the app hash of height 1")
+
+	err = VerifyTransactionProof(resQuery.ProofOps, appHashes[2], stx.PublicKey(), stx.Hash)
+	assert.Error(t, err, "proof must not verify against a wrong app hash")
+
+	// Transaction of height 2 verifies against the app hash of height 2 and 3
+	stx = stxs[len(ownerPrivs)]
+	for _, height := range []int64{2, 3} {
+		resQuery, err = vstore.Query(ctx, &abci.RequestQuery{
+			Path:   "/hash",
+			Data:   stx.Hash,
+			Height: height,
+			Prove:  true,
+		})
+		require.NoError(t, err)
+
+		err = VerifyTransactionProof(resQuery.ProofOps, appHashes[height-1], stx.PublicKey(), stx.Hash)
+		assert.NoError(t, err, "proof must verify against the app hash of height %d", height)
+	}
+
+	// Transaction of height 2 is not part of the app hash of height 1
+	_, err = vstore.Query(ctx, &abci.RequestQuery{
+		Path:   "/hash",
+		Data:   stx.Hash,
+		Height: 1,
+		Prove:  true,
+	})
+	assert.Error(t, err, "transaction must not be proven before its height")
+}
+
+func TestVStoreQuerySignerRoot(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-query_signer_root", 3)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	// Commit 2 blocks with transactions of 3 signers
+	hashes := make([][][]byte, len(ownerPrivs))
+	for height := 1; height <= 2; height++ {
+		txs := [][]byte{}
+		for i, priv := range ownerPrivs {
+			stx, err := makeTransaction(t, priv, []byte(testComplexValue+string(rune('a'+height))))
+			require.NoError(t, err, "should create a signed transaction")
+
+			hashes[i] = append(hashes[i], ComputeHash(stx))
+			txs = append(txs, stx.Bytes())
+		}
+
+		makeBlockCommit(ctx, t, vstore, height, txs)
+	}
+
+	appHash := vstore.state.Hash()
+	for i, priv := range ownerPrivs {
+		pub := ed25519.PrivKey(priv).PubKey().Bytes()
+		signer := strings.ToUpper(hex.EncodeToString(pub))
+
+		resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/root", Data: pub})
+		require.NoError(t, err)
+		assert.Equal(t, CodeTypeOK, resQuery.Code)
+		assert.Equal(t, int64(2), resQuery.Height)
+
+		// Root is the one of commitMerkleRoots, i.e. the merkle tree of hashes
+		root := merkle.HashFromByteSlices(hashes[i])
+		assert.Equal(t, vstore.state.MerkleRoots[signer], resQuery.Value)
+		assert.Equal(t, root, resQuery.Value)
+
+		// Proof verifies against the app hash
+		require.NotNil(t, resQuery.ProofOps, "proof must be returned")
+		assert.NoError(t, VerifySignerRootProof(resQuery.ProofOps, appHash, signer, resQuery.Value))
+		assert.Error(t, VerifySignerRootProof(resQuery.ProofOps, appHash, signer, tmhash.Sum([]byte("forged"))))
+		assert.Error(t, VerifySignerRootProof(resQuery.ProofOps, tmhash.Sum(appHash), signer, resQuery.Value))
+
+		// Proof does not verify the merkle root for another signer
+		other := strings.ToUpper(hex.EncodeToString(ed25519.PrivKey(ownerPrivs[(i+1)%len(ownerPrivs)]).PubKey().Bytes()))
+		assert.Error(t, VerifySignerRootProof(swapProofSigner(resQuery.ProofOps, other), appHash, other, resQuery.Value))
+	}
+
+	// Unknown signer has no merkle root
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/root", Data: ed25519.GenPrivKey().PubKey().Bytes()})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeOK, resQuery.Code)
+	assert.Empty(t, resQuery.Value)
+	assert.Nil(t, resQuery.ProofOps)
+
+	// Invalid public key
+	resQuery, err = vstore.Query(ctx, &abci.RequestQuery{Path: "/root", Data: []byte("abc")})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeInvalidFormatError, resQuery.Code)
+
+	// Without public key, the app hash is returned
+	resQuery, err = vstore.Query(ctx, &abci.RequestQuery{Path: "/root"})
+	require.NoError(t, err)
+	assert.Equal(t, appHash, resQuery.Value)
+}
+
+func TestVStoreMerkleRootOrdering(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-merkle_root_ordering", 2)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	// Transactions of 2 signers, several per signer
+	stxs := []*SignedTransaction{}
+	for i := 0; i < 6; i++ {
+		stx, err := makeTransaction(t, ownerPrivs[i%2], []byte(testComplexValue+string(rune('a'+i))))
+		require.NoError(t, err, "should create a signed transaction")
+
+		stx.Hash = ComputeHash(stx)
+		stxs = append(stxs, stx)
+	}
+
+	// Same transactions of a block in different orders
+	orders := [][]int{{0, 1, 2, 3, 4, 5}, {5, 4, 3, 2, 1, 0}, {2, 0, 5, 1, 3, 4}, {1, 3, 5, 0, 2, 4}}
+	var appHash []byte
+	var heightIndex []byte
+	for _, order := range orders {
+		db := cmtdb.NewMemDB()
+		vstore := NewVStoreApplication(db, filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+		block := make([]*SignedTransaction, len(order))
+		for i, j := range order {
+			block[i] = stxs[j]
+		}
+
+		respFinBlock, _ := makeBlockCommit(ctx, t, vstore, 1, transactionBytes(block))
+
+		// Results are in block order
+		for i, res := range respFinBlock.TxResults {
+			assert.Equal(t, CodeTypeOK, res.Code)
+			assert.Equal(t, block[i].Hash, res.Data)
+		}
+
+		index, err := db.Get(HeightIndexKey(1))
+		require.NoError(t, err)
+
+		if appHash == nil {
+			appHash, heightIndex = respFinBlock.AppHash, index
+		}
+
+		assert.Equal(t, appHash, respFinBlock.AppHash, "app hash must not depend on order %v", order)
+		assert.Equal(t, heightIndex, index, "height index must not depend on order %v", order)
+
+		// Any transaction can be proven against the app hash
+		for _, stx := range stxs {
+			resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: stx.Hash, Prove: true})
+			require.NoError(t, err)
+			assert.NoError(t, VerifyTransactionProof(resQuery.ProofOps, appHash, stx.PublicKey(), stx.Hash))
+		}
+	}
+}
+
+func TestVStoreMerkleRootBatching(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-merkle_root_batching", 2)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	// Transactions of 2 signers, interleaved
+	stxs := []*SignedTransaction{}
+	txs := [][]byte{}
+	for i := 0; i < 6; i++ {
+		stx, err := makeTransaction(t, ownerPrivs[i%2], []byte(testComplexValue+string(rune('a'+i))))
+		require.NoError(t, err, "should create a signed transaction")
+
+		stx.Hash = ComputeHash(stx)
+		stxs = append(stxs, stx)
+	}
+
+	// Transactions of a block are committed in order of hash, such that the
+	// batching of transactions in hash order does not matter
+	sortTransactionsByHash(stxs)
+	for _, stx := range stxs {
+		txs = append(txs, stx.Bytes())
+	}
+
+	// Same transactions in blocks of different sizes
+	batchings := [][]int{{6}, {1, 5}, {3, 3}, {1, 1, 1, 1, 1, 1}, {4, 2}}
+	var appHash []byte
+	for _, sizes := range batchings {
+		vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+		offset := 0
+		for i, size := range sizes {
+			makeBlockCommit(ctx, t, vstore, i+1, txs[offset:offset+size])
+			offset += size
+		}
+
+		// Merkle root of a signer is the merkle tree of all its hashes
+		for s := range ownerPrivs {
+			pub := ed25519.PrivKey(ownerPrivs[s]).PubKey().Bytes()
+			hashes := [][]byte{}
+			for _, stx := range stxs {
+				if bytes.Equal(stx.Signer.Bytes(), pub) {
+					hashes = append(hashes, stx.Hash)
+				}
+			}
+
+			assert.Equal(t, merkle.HashFromByteSlices(hashes), vstore.state.MerkleRoots[fmt.Sprintf("%X", pub)], "batching %v", sizes)
+		}
+
+		if appHash == nil {
+			appHash = vstore.state.Hash()
+		}
+
+		assert.Equal(t, appHash, vstore.state.Hash(), "app hash must not depend on batching %v", sizes)
+
+		// Any transaction can be proven against the latest app hash
+		for _, stx := range stxs {
+			resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: stx.Hash, Prove: true})
+			require.NoError(t, err)
+			assert.NoError(t, VerifyTransactionProof(resQuery.ProofOps, appHash, stx.PublicKey(), stx.Hash))
+		}
+	}
+}
+
+// swapProofSigner returns a copy of proof whose last operation, i.e. the proof
+// of the signer merkle root, is keyed with another signer.
+func swapProofSigner(proof *cmtcrypto.ProofOps, signer string) *cmtcrypto.ProofOps {
+	swapped := &cmtcrypto.ProofOps{Ops: append([]cmtcrypto.ProofOp{}, proof.Ops...)}
+	swapped.Ops[len(swapped.Ops)-1].Key = []byte(signer)
+	return swapped
+}
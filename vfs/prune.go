@@ -0,0 +1,235 @@
+package vfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+// ErrTransactionPruned is returned when a committed transaction was pruned,
+// i.e. its hash is still committed in the merkle roots but its body was
+// removed with a retention policy.
+var ErrTransactionPruned = errors.New("transaction pruned")
+
+// prunedMagic and prunedVersion1 describe the entries that replace the pruned
+// transactions: magic (4) || version (1) || signer public key (32)
+//
+// The signer of a pruned transaction is kept, such that its inclusion is
+// still proven with the signer merkle root and that chained transactions
+// still reference it.
+var prunedMagic = []byte("VSPR")
+
+const (
+	prunedVersion1  byte = 1
+	prunedHeaderLen      = 5
+)
+
+// RetentionPolicy describes which committed transactions are pruned. The
+// oldest transactions are pruned first, by timestamp. A zero value keeps all
+// transactions.
+type RetentionPolicy struct {
+	// MaxAge prunes the transactions with a timestamp older than MaxAge, if
+	// it is positive.
+	MaxAge time.Duration `json:"max_age,omitempty"`
+
+	// MaxBytes prunes the oldest transactions until the stored transactions
+	// use at most MaxBytes bytes, if it is positive.
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+}
+
+// PruneResult describes the outcome of Prune.
+type PruneResult struct {
+	// Pruned is the number of transactions pruned by this call, and Bytes is
+	// the size of their stored entries.
+	Pruned int64 `json:"pruned"`
+	Bytes  int64 `json:"bytes"`
+
+	// Retained is the number of stored transactions which were not pruned,
+	// and RetainedBytes is the size of their stored entries.
+	Retained      int64 `json:"retained"`
+	RetainedBytes int64 `json:"retained_bytes"`
+}
+
+// SetRetention sets the retention policy which prunes committed transactions
+// every interval heights, in Commit. Transactions are never pruned with a zero
+// policy or interval (default), see Prune.
+func (app *VStoreApplication) SetRetention(policy RetentionPolicy, interval int64) {
+	app.retention = policy
+	app.retentionInterval = interval
+}
+
+// Prune removes the stored bodies of the oldest committed transactions which
+// fall outside the retention policy, relative to now, e.g. on nodes which
+// provide redundant availability of recent transactions only. The hashes,
+// indexes and merkle roots are kept, such that the app hash is unchanged and
+// that inclusion proofs still hold. Queries for pruned transactions return
+// CodeTypePrunedError. Blocks are not processed while transactions are pruned.
+//
+// Note: Bodies in external storage (see SetBlobStore) are not removed.
+func (app *VStoreApplication) Prune(policy RetentionPolicy, now time.Time) (PruneResult, error) {
+	app.lifecycle.Lock()
+	defer app.lifecycle.Unlock()
+
+	if app.closing {
+		return PruneResult{}, ErrShuttingDown
+	}
+
+	// Block calls register under the lifecycle lock
+	app.active.Wait()
+	return app.prune(policy, now)
+}
+
+// --------------------------------------------------------------------------
+// Private helpers
+
+// prune implements Prune, the caller must ensure that no block is committed.
+func (app *VStoreApplication) prune(policy RetentionPolicy, now time.Time) (PruneResult, error) {
+	result := PruneResult{}
+
+	// Transactions are pruned in order of timestamps, oldest first
+	entries := []timeIndexEntry{}
+	err := iteratePrefix(app.state.db, vfsPrefixKeyByTime, func(_, value []byte) error {
+		bucket := []timeIndexEntry{}
+		if err := json.Unmarshal(value, &bucket); err != nil {
+			return err
+		}
+
+		entries = append(entries, bucket...)
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Time != entries[j].Time {
+			return entries[i].Time < entries[j].Time
+		}
+
+		return bytes.Compare(entries[i].Hash, entries[j].Hash) < 0
+	})
+
+	// Size of the stored transactions which are not pruned yet
+	sizes := make([]int64, len(entries))
+	for i, entry := range entries {
+		data, err := app.state.db.Get(TransactionKey(entry.Hash))
+		if err != nil {
+			return result, err
+		}
+
+		if len(data) == 0 || isPrunedTransaction(data) {
+			sizes[i] = -1
+			continue
+		}
+
+		sizes[i] = int64(len(data))
+		result.Retained++
+		result.RetainedBytes += sizes[i]
+	}
+
+	secret := []byte{}
+	defer func() { zeroize(secret) }()
+
+	batch := app.state.db.NewBatch()
+	defer batch.Close()
+
+	for i, entry := range entries {
+		if sizes[i] < 0 {
+			continue
+		}
+
+		expired := policy.MaxAge > 0 && time.Unix(0, entry.Time).Before(now.Add(-policy.MaxAge))
+		exceeded := policy.MaxBytes > 0 && result.RetainedBytes > policy.MaxBytes
+		if !expired && !exceeded {
+			continue
+		}
+
+		data, err := app.state.db.Get(TransactionKey(entry.Hash))
+		if err != nil {
+			return result, err
+		}
+
+		// The signer is kept for inclusion proofs
+		if len(secret) == 0 && !isPlaintextTransaction(data) {
+			secret, err = app.identitySecret()
+			if err != nil {
+				return result, err
+			}
+		}
+
+		txData, err := openTransaction(secret, data)
+		if err != nil {
+			return result, fmt.Errorf("could not decrypt transaction %X: %w", entry.Hash, err)
+		}
+
+		stx, err := FromBytes(txData)
+		zeroize(txData)
+		if err != nil {
+			return result, err
+		}
+
+		if err := batch.Set(TransactionKey(entry.Hash), prunedTransaction(stx.Signer.Bytes())); err != nil {
+			return result, err
+		}
+
+		result.Pruned++
+		result.Bytes += sizes[i]
+		result.Retained--
+		result.RetainedBytes -= sizes[i]
+	}
+
+	if result.Pruned == 0 {
+		return result, nil
+	}
+
+	if err := batch.WriteSync(); err != nil {
+		return result, err
+	}
+
+	app.logger.Info("pruned transactions", "txs", result.Pruned, "bytes", result.Bytes, "retained", result.Retained)
+	return result, nil
+}
+
+// pruneCommitted prunes transactions with the retention policy every
+// retention interval heights, relative to the block time. Errors are logged,
+// they never halt the chain.
+func (app *VStoreApplication) pruneCommitted() {
+	height := app.state.Height
+	if app.retentionInterval <= 0 || height <= 0 || height%app.retentionInterval != 0 {
+		return
+	}
+
+	if app.retention.MaxAge <= 0 && app.retention.MaxBytes <= 0 {
+		return
+	}
+
+	if _, err := app.prune(app.retention, app.time); err != nil {
+		app.logger.Error("could not prune transactions", "height", height, "err", err)
+	}
+}
+
+// isPrunedTransaction returns true if a stored transaction was pruned.
+func isPrunedTransaction(data []byte) bool {
+	return len(data) == prunedHeaderLen+ed25519.PubKeySize &&
+		bytes.HasPrefix(data, prunedMagic) &&
+		data[len(prunedMagic)] == prunedVersion1
+}
+
+// prunedTransaction returns the entry that replaces a pruned transaction of
+// the signer with public key pub.
+func prunedTransaction(pub []byte) []byte {
+	data := make([]byte, 0, prunedHeaderLen+len(pub))
+	data = append(data, prunedMagic...)
+	data = append(data, prunedVersion1)
+	return append(data, pub...)
+}
+
+// prunedSigner returns the public key of the signer of a pruned transaction.
+func prunedSigner(data []byte) []byte {
+	return data[prunedHeaderLen:]
+}
@@ -0,0 +1,142 @@
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cosmos/gogoproto/proto"
+
+	vfsp2p "github.com/securesharelabs/vstore/api/vstore/v1"
+)
+
+func TestVStorePrune(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-prune", 2)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	// Blocks of transactions which are 3, 2 and 1 hour old
+	now := time.Now()
+	stxs := []*SignedTransaction{}
+	for height := 1; height <= 3; height++ {
+		txs := [][]byte{}
+		for _, priv := range ownerPrivs {
+			ts := now.Add(-time.Duration(4-height) * time.Hour)
+			stx, err := makeTransactionAt(t, priv, []byte(testComplexValue), ts)
+			require.NoError(t, err, "should create a signed transaction")
+
+			stx.Hash = ComputeHash(stx)
+			stxs = append(stxs, stx)
+			txs = append(txs, stx.Bytes())
+		}
+
+		makeBlockCommit(ctx, t, vstore, height, txs)
+	}
+
+	appHash := vstore.state.Hash()
+
+	// Transactions older than 150 minutes are pruned
+	result, err := vstore.Prune(RetentionPolicy{MaxAge: 150 * time.Minute}, now)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, result.Pruned)
+	assert.EqualValues(t, 4, result.Retained)
+	assert.Positive(t, result.Bytes)
+
+	// The app hash is unchanged and integrity still holds
+	resInfo, err := vstore.Info(ctx, &abci.RequestInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, appHash, resInfo.LastBlockAppHash)
+	assert.NoError(t, vstore.VerifyIntegrity())
+
+	// Pruned transactions are committed with a distinct status
+	pruned := stxs[0]
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: pruned.Hash, Prove: true})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypePrunedError, resQuery.Code)
+	assert.Empty(t, resQuery.Value)
+	require.NotNil(t, resQuery.ProofOps, "pruned transactions are still proven")
+	assert.NoError(t, VerifyTransactionProof(resQuery.ProofOps, appHash, pruned.PublicKey(), pruned.Hash))
+
+	resQuery, err = vstore.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: stxs[2].Hash})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeOK, resQuery.Code)
+	assert.Equal(t, stxs[2].Bytes(), resQuery.Value)
+
+	// Index queries skip the pruned transactions
+	resQuery, err = vstore.Query(ctx, &abci.RequestQuery{Path: "/pubkey", Data: pruned.Signer.Bytes()})
+	require.NoError(t, err)
+	list := new(vfsp2p.TransactionList)
+	require.NoError(t, proto.Unmarshal(resQuery.Value, list))
+	assert.EqualValues(t, 3, list.Total)
+	assert.Len(t, list.Transactions, 2)
+
+	signerTxs, err := vstore.TransactionsByPubKey(pruned.Signer.Bytes())
+	require.NoError(t, err)
+	assert.Len(t, signerTxs, 2)
+
+	// Pruning is idempotent
+	result, err = vstore.Prune(RetentionPolicy{MaxAge: 150 * time.Minute}, now)
+	require.NoError(t, err)
+	assert.Zero(t, result.Pruned)
+
+	// The oldest transactions are pruned to fit the maximum size
+	result, err = vstore.Prune(RetentionPolicy{MaxBytes: result.RetainedBytes - 1}, now)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, result.Pruned)
+	assert.EqualValues(t, 3, result.Retained)
+
+	// Chained transactions still reference pruned transactions of their signer
+	chained, err := makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue))
+	require.NoError(t, err)
+	chained.PrevHash = pruned.Hash
+	assert.NoError(t, vstore.validatePrevHash(chained, nil))
+
+	other, err := makeTransaction(t, ownerPrivs[1], []byte(testSimpleValue))
+	require.NoError(t, err)
+	other.PrevHash = pruned.Hash
+	assert.ErrorIs(t, vstore.validatePrevHash(other, nil), errPrevHashSigner)
+
+	// Pruned transactions cannot be reindexed
+	assert.ErrorIs(t, vstore.Reindex(), ErrTransactionPruned)
+}
+
+func TestVStorePruneRetention(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-prune_retention", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+	vstore.SetRetention(RetentionPolicy{MaxBytes: 1}, 2)
+
+	first, err := makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue))
+	require.NoError(t, err)
+	makeBlockCommit(ctx, t, vstore, 1, [][]byte{first.Bytes()})
+
+	// Transactions are pruned every 2 heights, in Commit
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: ComputeHash(first)})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeOK, resQuery.Code)
+
+	second, err := makeTransaction(t, ownerPrivs[0], []byte(testComplexValue))
+	require.NoError(t, err)
+	respFinBlock, _ := makeBlockCommit(ctx, t, vstore, 2, [][]byte{second.Bytes()})
+	assert.Equal(t, respFinBlock.AppHash, vstore.state.Hash())
+
+	hashes := [][]byte{ComputeHash(first), ComputeHash(second)}
+	for _, hash := range hashes {
+		resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: hash})
+		require.NoError(t, err)
+		assert.Equal(t, CodeTypePrunedError, resQuery.Code)
+	}
+}
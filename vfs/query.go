@@ -0,0 +1,530 @@
+package vfs
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+	"github.com/cometbft/cometbft/crypto/tmhash"
+	cmtcrypto "github.com/cometbft/cometbft/proto/tendermint/crypto"
+)
+
+// pagination describes a page of an index query. Index queries accept the
+// optional "offset" and "limit" parameters in the request path, e.g.:
+// "/pubkey?offset=100&limit=50". The optional "after" parameter is a cursor,
+// i.e. the hexadecimal hash of the last transaction of the previous page, e.g.:
+// "/pubkey?after=HEX&limit=50". The offset is ignored when a cursor is set.
+type pagination struct {
+	Offset int
+	Limit  int
+	After  string
+}
+
+// SignerInfo describes a signer of the signer index and its number of
+// committed transactions. The public key is uppercase hexadecimal.
+type SignerInfo struct {
+	PubKey string `json:"pubkey"`
+	Count  int64  `json:"count"`
+}
+
+// SignerList describes a page of the signers returned by a /signers query.
+// Next is the cursor of the next page, i.e. the public key of the last signer
+// of the page, and is empty on the last page.
+type SignerList struct {
+	Signers []SignerInfo `json:"signers"`
+	Total   int          `json:"total"`
+	Next    string       `json:"next,omitempty"`
+}
+
+// errCursorNotFound is returned when the cursor of an index query is not a
+// transaction hash of the index.
+var errCursorNotFound = errors.New("cursor not found in index")
+
+// newPagination parses the offset, limit and after parameters of an index
+// query. Invalid or negative values are ignored, the limit defaults to
+// DefaultQueryLimit and is clamped to MaxQueryLimit.
+func newPagination(params url.Values) pagination {
+	page := pagination{Offset: 0, Limit: DefaultQueryLimit}
+
+	if offset, err := strconv.Atoi(params.Get("offset")); err == nil && offset > 0 {
+		page.Offset = offset
+	}
+
+	if limit, err := strconv.Atoi(params.Get("limit")); err == nil && limit > 0 {
+		page.Limit = min(limit, MaxQueryLimit)
+	}
+
+	page.After = params.Get("after")
+	return page
+}
+
+// Bounds returns the start and end indexes of the items that are part of the
+// page. With a cursor, the page starts after the item with the cursor hash and
+// errCursorNotFound is returned if no item matches.
+func (p pagination) Bounds(items [][]byte) (int, int, error) {
+	start := p.Offset
+	if len(p.After) > 0 {
+		found := false
+		for i, item := range items {
+			if strings.EqualFold(hex.EncodeToString(item), p.After) {
+				start, found = i+1, true
+				break
+			}
+		}
+
+		if !found {
+			return 0, 0, fmt.Errorf("%w: %s", errCursorNotFound, p.After)
+		}
+	}
+
+	if start >= len(items) {
+		return len(items), len(items), nil
+	}
+
+	end := len(items)
+	if p.Limit > 0 {
+		end = min(start+p.Limit, len(items))
+	}
+
+	return start, end, nil
+}
+
+// splitQueryPath splits a request path in the query path and the parameters
+// that follow the "?" character, e.g.: "/height?limit=10".
+func splitQueryPath(path string) (string, url.Values) {
+	queryPath, rawParams, _ := strings.Cut(path, "?")
+	params, err := url.ParseQuery(rawParams)
+	if err != nil {
+		return queryPath, url.Values{}
+	}
+
+	return queryPath, params
+}
+
+// queryChunkedBody reassembles a chunked body from its chunk transactions.
+// Expects the signer public key (32 bytes) followed by the chunk root hash
+// (32 bytes) in the request's Data field.
+func (app *VStoreApplication) queryChunkedBody(
+	req *abci.RequestQuery,
+	response *abci.ResponseQuery,
+) (*abci.ResponseQuery, error) {
+	if len(req.Data) != ed25519.PubKeySize+tmhash.Size {
+		response.Code = CodeTypeInvalidFormatError
+		response.Log = fmt.Sprintf("invalid chunks query size, want: %d, got: %d", ed25519.PubKeySize+tmhash.Size, len(req.Data))
+		return response, nil
+	}
+
+	signer, root := req.Data[:ed25519.PubKeySize], req.Data[ed25519.PubKeySize:]
+	plainData, err := app.readChunkedBodyFromDB(signer, root)
+	if err == errIncompleteChunks {
+		response.Code = CodeTypeInvalidChunkError
+		response.Log = err.Error()
+		return response, nil
+	} else if errors.Is(err, ErrTransactionPruned) {
+		response.Code = CodeTypePrunedError
+		response.Log = err.Error()
+		return response, nil
+	} else if errors.Is(err, ErrDecryptTransaction) {
+		response.Code = CodeTypeDecryptError
+		response.Log = err.Error()
+		return response, nil
+	} else if errors.Is(err, ErrEncryptionUnavailable) {
+		response.Code = CodeTypeEncryptionUnavailableError
+		response.Log = err.Error()
+		return response, nil
+	} else if err != nil {
+		return response, err
+	}
+
+	response.Value = plainData
+	response.Log = "exists"
+	return response, nil
+}
+
+// queryPrunedTransaction responds to a query by hash for a pruned transaction
+// with CodeTypePrunedError. The transaction hash is still committed, such that
+// the merkle proof of its inclusion is returned when Prove is set.
+func (app *VStoreApplication) queryPrunedTransaction(
+	req *abci.RequestQuery,
+	response *abci.ResponseQuery,
+) (*abci.ResponseQuery, error) {
+	response.Code = CodeTypePrunedError
+	response.Log = ErrTransactionPruned.Error()
+	if !req.Prove {
+		return response, nil
+	}
+
+	data, err := app.state.db.Get(TransactionKey(req.Data))
+	if err != nil {
+		return response, err
+	}
+
+	proofOps, index, err := app.proveTransaction(prunedSigner(data), req.Data, req.Height)
+	if err != nil {
+		return response, err
+	}
+
+	response.ProofOps = proofOps
+	response.Index = index
+	if req.Height > 0 {
+		response.Height = req.Height
+	}
+
+	return response, nil
+}
+
+// ErrTransactionMissing is returned when a transaction hash is committed but
+// its body is absent from the database, e.g. after a partial restore.
+var ErrTransactionMissing = errors.New("transaction body missing")
+
+// ErrDecryptTransaction is returned when a stored transaction cannot be
+// decrypted, i.e. its ciphertext is corrupted or the identity secret differs.
+var ErrDecryptTransaction = errors.New("could not decrypt transaction")
+
+// queryMissingTransaction responds to a query by hash which returned no
+// transaction. The height index tells a transaction that was committed but
+// whose body is absent from the database, with CodeTypeMissingBodyError, from
+// a hash that was never committed, with an empty value.
+func (app *VStoreApplication) queryMissingTransaction(
+	req *abci.RequestQuery,
+	response *abci.ResponseQuery,
+) (*abci.ResponseQuery, error) {
+	height, err := app.committedHeight(req.Data)
+	if err != nil {
+		return response, err
+	}
+
+	if height == 0 {
+		response.Log = "not found"
+		return response, nil
+	}
+
+	response.Code = CodeTypeMissingBodyError
+	response.Log = fmt.Sprintf("%s: committed at height %d", ErrTransactionMissing, height)
+	return response, nil
+}
+
+// committedHeight returns the block height at which a transaction hash was
+// committed, or 0 if the hash is not part of the height index. The index is
+// iterated, such that it is only used when the transaction body is absent.
+func (app *VStoreApplication) committedHeight(hash []byte) (int64, error) {
+	if len(hash) == 0 {
+		return 0, nil
+	}
+
+	height := int64(0)
+	err := iteratePrefix(app.state.db, vfsPrefixKeyByHeight, func(key, value []byte) error {
+		hashes := [][]byte{}
+		if err := json.Unmarshal(value, &hashes); err != nil {
+			return err
+		}
+
+		for _, h := range hashes {
+			if bytes.Equal(h, hash) {
+				height, _ = strconv.ParseInt(string(key), 10, 64)
+				return errStopIteration
+			}
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStopIteration) {
+		return 0, err
+	}
+
+	return height, nil
+}
+
+// querySigners returns a page of the distinct signers of the signer index,
+// sorted by public key, with their number of transactions as a JSON encoded
+// SignerList. The cursor of the page is a signer public key.
+func (app *VStoreApplication) querySigners(
+	response *abci.ResponseQuery,
+	page pagination,
+) (*abci.ResponseQuery, error) {
+	signers, err := app.AllSigners()
+	if err != nil {
+		return response, err
+	}
+
+	start, end, err := page.Bounds(signers)
+	if errors.Is(err, errCursorNotFound) {
+		response.Code = CodeTypeInvalidFormatError
+		response.Log = err.Error()
+		return response, nil
+	}
+
+	list := SignerList{Signers: make([]SignerInfo, 0, end-start), Total: len(signers)}
+	for _, signer := range signers[start:end] {
+		list.Signers = append(list.Signers, SignerInfo{
+			PubKey: fmt.Sprintf("%X", signer),
+			Count:  app.SignerCount(signer),
+		})
+	}
+
+	// Last signer of the page is the cursor of the next page
+	if end < len(signers) && end > start {
+		list.Next = fmt.Sprintf("%X", signers[end-1])
+	}
+
+	bz, err := json.Marshal(list)
+	if err != nil {
+		return response, err
+	}
+
+	response.Value = bz
+	return response, nil
+}
+
+// querySignerRoot returns the current merkle root of a signer and the proof
+// operations which prove that this merkle root is part of the app hash of the
+// response height, see VerifySignerRootProof. The response value is empty for
+// unknown signers. Expects the signer public key (32 bytes) in the request's
+// Data field.
+func (app *VStoreApplication) querySignerRoot(
+	req *abci.RequestQuery,
+	response *abci.ResponseQuery,
+) (*abci.ResponseQuery, error) {
+	if len(req.Data) != ed25519.PubKeySize {
+		response.Code = CodeTypeInvalidFormatError
+		response.Log = fmt.Sprintf("invalid public key size, want: %d, got: %d", ed25519.PubKeySize, len(req.Data))
+		return response, nil
+	}
+
+	pub := strings.ToUpper(hex.EncodeToString(req.Data))
+	signerRoots := app.state.SortedSignerRoots()
+	for i, sr := range signerRoots {
+		if sr.PubKey != pub {
+			continue
+		}
+
+		op := proveSignerRoot(signerRoots, i)
+		response.Value = sr.Root
+		response.ProofOps = &cmtcrypto.ProofOps{Ops: []cmtcrypto.ProofOp{op.ProofOp()}}
+		response.Index = int64(i)
+		response.Log = "exists"
+		break
+	}
+
+	return response, nil
+}
+
+// queryVerifyRoot compares an expected merkle root with the merkle root that
+// is persisted for a signer public key. Expects the signer public key (32 bytes)
+// followed by the expected merkle root (32 bytes) in the request's Data field.
+// The response value contains a single byte: 1 if the roots match, 0 otherwise.
+func (app *VStoreApplication) queryVerifyRoot(
+	req *abci.RequestQuery,
+	response *abci.ResponseQuery,
+) (*abci.ResponseQuery, error) {
+	if len(req.Data) != ed25519.PubKeySize+tmhash.Size {
+		response.Code = CodeTypeInvalidFormatError
+		response.Log = fmt.Sprintf("invalid data size, want: %d, got: %d", ed25519.PubKeySize+tmhash.Size, len(req.Data))
+		return response, nil
+	}
+
+	pub, expected := req.Data[:ed25519.PubKeySize], req.Data[ed25519.PubKeySize:]
+	root, ok := app.state.MerkleRoots[strings.ToUpper(hex.EncodeToString(pub))]
+
+	if ok && bytes.Equal(root, expected) {
+		response.Value = []byte{1}
+		response.Log = "match"
+		return response, nil
+	}
+
+	response.Value = []byte{0}
+	response.Log = "mismatch"
+	return response, nil
+}
+
+// queryBlockTime returns the block time of a height, formatted with RFC3339
+// in UTC. Expects the block height (base10) in the request's Data field.
+// The response value is empty if no block was committed at this height.
+func (app *VStoreApplication) queryBlockTime(
+	req *abci.RequestQuery,
+	response *abci.ResponseQuery,
+) (*abci.ResponseQuery, error) {
+	dbKey := prefixKeyWith(req.Data, vfsPrefixKeyByBlockTime)
+	blockTime, err := app.state.db.Get(dbKey)
+	if err != nil {
+		return response, err
+	}
+
+	if len(blockTime) > 0 {
+		response.Value = blockTime
+		response.Log = "exists"
+	}
+
+	return response, nil
+}
+
+// queryAppHash returns the app hash of a committed block height, or an empty
+// value for heights committed before app hashes were persisted.
+// Expects a block height (base10) in the request's Data field.
+func (app *VStoreApplication) queryAppHash(
+	req *abci.RequestQuery,
+	response *abci.ResponseQuery,
+) (*abci.ResponseQuery, error) {
+	height, err := strconv.ParseInt(string(req.Data), 10, 64)
+	if err != nil || height <= 0 {
+		response.Code = CodeTypeInvalidFormatError
+		response.Log = "expected block height"
+		return response, nil
+	}
+
+	record, err := app.readAppHashRecord(height)
+	if err != nil {
+		return response, err
+	}
+
+	if record == nil {
+		return response, nil
+	}
+
+	response.Value = record.AppHash
+	response.Log = "exists"
+	return response, nil
+}
+
+// queryHeightCount returns the number of transactions of a block height as
+// a base10 string, the transactions are not fetched.
+// Expects a block height (base10) in the request's Data field.
+func (app *VStoreApplication) queryHeightCount(
+	req *abci.RequestQuery,
+	response *abci.ResponseQuery,
+) (*abci.ResponseQuery, error) {
+	if _, err := strconv.ParseInt(string(req.Data), 10, 64); err != nil {
+		response.Code = CodeTypeInvalidFormatError
+		response.Log = fmt.Sprintf("invalid block height: %v", err)
+		return response, nil
+	}
+
+	hashes, err := app.readIndexHashes(prefixKeyWith(req.Data, vfsPrefixKeyByHeight))
+	if err != nil {
+		return response, err
+	}
+
+	response.Value = []byte(strconv.Itoa(len(hashes)))
+	return response, nil
+}
+
+// queryPubKeyCount returns the number of transactions of a signer as a base10
+// string, the transactions are not fetched. Unknown signers have 0 transactions.
+// Expects the signer public key (32 bytes) in the request's Data field.
+func (app *VStoreApplication) queryPubKeyCount(
+	req *abci.RequestQuery,
+	response *abci.ResponseQuery,
+) (*abci.ResponseQuery, error) {
+	if len(req.Data) != ed25519.PubKeySize {
+		response.Code = CodeTypeInvalidFormatError
+		response.Log = fmt.Sprintf("invalid public key size, want: %d, got: %d", ed25519.PubKeySize, len(req.Data))
+		return response, nil
+	}
+
+	hashes, err := app.readSignerHashes(req.Data)
+	if err != nil {
+		return response, err
+	}
+
+	response.Value = []byte(strconv.Itoa(len(hashes)))
+	return response, nil
+}
+
+// querySignerAtHeight returns the transactions of a signer in a block. The
+// height and pubkey indexes are intersected, the order of the block is kept.
+// Expects the signer public key (32 bytes) followed by the block height
+// (base10) in the request's Data field.
+func (app *VStoreApplication) querySignerAtHeight(
+	req *abci.RequestQuery,
+	response *abci.ResponseQuery,
+	page pagination,
+) (*abci.ResponseQuery, error) {
+	if len(req.Data) <= ed25519.PubKeySize {
+		response.Code = CodeTypeInvalidFormatError
+		response.Log = "expected signer public key followed by block height"
+		return response, nil
+	}
+
+	pub, height := req.Data[:ed25519.PubKeySize], req.Data[ed25519.PubKeySize:]
+	if _, err := strconv.ParseInt(string(height), 10, 64); err != nil {
+		response.Code = CodeTypeInvalidFormatError
+		response.Log = fmt.Sprintf("invalid block height: %v", err)
+		return response, nil
+	}
+
+	byHeight, err := app.readIndexHashes(prefixKeyWith(height, vfsPrefixKeyByHeight))
+	if err != nil {
+		return response, err
+	}
+
+	byPubKey, err := app.readSignerHashes(pub)
+	if err != nil {
+		return response, err
+	}
+
+	if len(byHeight) == 0 || len(byPubKey) == 0 {
+		return response, nil
+	}
+
+	// Blocks are usually smaller than the signer index, the block hashes
+	// are used to look up the hashes of the signer
+	inBlock := make(map[string]struct{}, len(byHeight))
+	for _, hash := range byHeight {
+		inBlock[string(hash)] = struct{}{}
+	}
+
+	matches := make(map[string]struct{}, len(byHeight))
+	for _, hash := range byPubKey {
+		if _, ok := inBlock[string(hash)]; ok {
+			matches[string(hash)] = struct{}{}
+		}
+	}
+
+	hashes := make([][]byte, 0, len(matches))
+	for _, hash := range byHeight {
+		if _, ok := matches[string(hash)]; ok {
+			hashes = append(hashes, hash)
+		}
+	}
+
+	if len(hashes) == 0 {
+		return response, nil
+	}
+
+	plainData, err := app.readTransactionList(hashes, page)
+	if errors.Is(err, errCursorNotFound) {
+		response.Code = CodeTypeInvalidFormatError
+		response.Log = err.Error()
+		return response, nil
+	} else if err != nil {
+		return response, err
+	}
+
+	response.Value = plainData
+	response.Log = "exists"
+	return response, nil
+}
+
+// readIndexHashes decodes the JSON array of transaction hashes that is stored
+// in an index. An empty slice is returned if the index does not exist.
+func (app *VStoreApplication) readIndexHashes(dbKey []byte) ([][]byte, error) {
+	hashes := [][]byte{}
+
+	data, err := app.state.db.Get(dbKey)
+	if err != nil || len(data) == 0 {
+		return hashes, err
+	}
+
+	if err := json.Unmarshal(data, &hashes); err != nil {
+		return nil, err
+	}
+
+	return hashes, nil
+}
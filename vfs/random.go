@@ -0,0 +1,74 @@
+package vfs
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"sync"
+)
+
+// randomHistorySize is the number of recently generated random values that
+// are kept to detect repeated values.
+const randomHistorySize = 1024
+
+// randReader is the source of random nonces and salts. It is a variable such
+// that tests can inject a broken entropy source.
+var randReader io.Reader = rand.Reader
+
+var (
+	errRandomZero     = errors.New("random source returned all-zero bytes")
+	errRandomRepeated = errors.New("random source returned a repeated value")
+)
+
+// randomHistory keeps the recently generated random values in a ring buffer.
+type randomHistory struct {
+	mtx    sync.Mutex
+	seen   map[string]struct{}
+	values []string
+	next   int
+}
+
+var recentRandom = &randomHistory{seen: make(map[string]struct{}, randomHistorySize)}
+
+// readRandom returns n random bytes to be used as a nonce or salt. As defense
+// in depth against a broken entropy source, it fails if the bytes are all
+// zero or if the value was recently generated in this process.
+func readRandom(n int) ([]byte, error) {
+	bz := make([]byte, n)
+	if _, err := io.ReadFull(randReader, bz); err != nil {
+		return []byte{}, err
+	}
+
+	if bytes.Equal(bz, make([]byte, n)) {
+		return []byte{}, errRandomZero
+	}
+
+	if !recentRandom.add(string(bz)) {
+		return []byte{}, errRandomRepeated
+	}
+
+	return bz, nil
+}
+
+// add records a random value, it returns false if the value was recently seen.
+func (h *randomHistory) add(value string) bool {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	if _, ok := h.seen[value]; ok {
+		return false
+	}
+
+	// Forget the oldest value when the history is full
+	if len(h.values) < randomHistorySize {
+		h.values = append(h.values, value)
+	} else {
+		delete(h.seen, h.values[h.next])
+		h.values[h.next] = value
+		h.next = (h.next + 1) % randomHistorySize
+	}
+
+	h.seen[value] = struct{}{}
+	return true
+}
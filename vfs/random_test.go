@@ -0,0 +1,54 @@
+package vfs
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// zeroReader is a broken entropy source which returns all-zero bytes.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestVStoreRandomGuard(t *testing.T) {
+	defer func(r io.Reader) { randReader = r }(randReader)
+	secret := bytes.Repeat([]byte{1}, 32)
+
+	// All-zero nonces and salts are rejected
+	randReader = zeroReader{}
+
+	_, err := Encrypt(secret, []byte("Hello, World!"))
+	assert.ErrorIs(t, err, errRandomZero)
+
+	_, _, err = GenerateSecret([]byte("testpassword"), []byte{})
+	assert.ErrorIs(t, err, errRandomZero)
+
+	// Repeated nonces are rejected
+	value := make([]byte, 12)
+	_, err = io.ReadFull(rand.Reader, value)
+	require.NoError(t, err)
+
+	randReader = bytes.NewReader(append(append([]byte{}, value...), value...))
+
+	_, err = Encrypt(secret, []byte("Hello, World!"))
+	require.NoError(t, err, "first nonce must be accepted")
+
+	_, err = Encrypt(secret, []byte("Hello, World!"))
+	assert.ErrorIs(t, err, errRandomRepeated)
+
+	// Working entropy source
+	randReader = rand.Reader
+	for i := 0; i < 2*randomHistorySize; i++ {
+		_, err := readRandom(12)
+		require.NoError(t, err)
+	}
+	assert.Len(t, recentRandom.values, randomHistorySize)
+}
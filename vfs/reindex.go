@@ -0,0 +1,282 @@
+package vfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/cometbft/cometbft/crypto/merkle"
+	"github.com/cometbft/cometbft/crypto/tmhash"
+)
+
+// reindexBlock describes the transactions of a block height, in order of
+// commitment, which are indexed again by Reindex.
+type reindexBlock struct {
+	Height int64
+	Txs    []SignedTransaction
+}
+
+// Reindex rebuilds the height, signer, time and chunk indexes, the signer
+// counts and sequences and the merkle roots of the State from the stored
+// transactions, e.g. after a crash between the database writes of Commit. The
+// transactions are decrypted with the node's secret. The application must not
+// be running.
+//
+// The height index is the only record of the block of a transaction, such
+// that transactions are indexed again at the height and in the order of the
+// height index. Transactions which are missing from the height index are
+// indexed at the last height, ordered by timestamp and hash. Running Reindex
+// again produces the same indexes. Databases with pruned transactions cannot
+// be reindexed, ErrTransactionPruned is returned.
+func (app *VStoreApplication) Reindex() error {
+	stxs, err := app.readStoredTransactions()
+	if err != nil {
+		return err
+	}
+
+	blocks, err := app.recoverBlocks(stxs)
+	if err != nil {
+		return err
+	}
+
+	// Secondary indexes are rebuilt from scratch
+	prefixes := [][]byte{vfsPrefixKeyByHeight, vfsPrefixKeyByPubKey, vfsPrefixKeyByTime, vfsPrefixKeyByChunks}
+	for _, prefix := range prefixes {
+		if err := app.deleteKeysWithPrefix(prefix); err != nil {
+			return err
+		}
+	}
+
+	app.tail = nil
+	app.chunks = make(map[string]*chunkManifest)
+	app.state.SignerCounts = make(map[string]int64)
+	app.state.SignerSequences = nil
+
+	height := app.state.Height
+	for _, block := range blocks {
+		app.state.Height = block.Height
+		app.stage = block.Txs
+
+		for _, stx := range block.Txs {
+			if stx.Chunk == nil {
+				continue
+			}
+
+			if err := app.stageChunk(stx); err != nil {
+				app.logger.Error("skipping chunk in reindex", "hash", fmt.Sprintf("%X", stx.Hash), "err", err)
+			}
+		}
+
+		if err := app.commitTransactionHashes(app.state.db); err != nil {
+			return err
+		}
+
+		if err := app.commitChunkManifests(app.state.db); err != nil {
+			return err
+		}
+	}
+
+	app.state.Height = height
+	app.stage = make([]SignedTransaction, 0)
+
+	// Merkle roots are recomputed from the rebuilt signer index
+	roots := make(map[string][]byte, len(app.state.SignerCounts))
+	for _, signer := range signersOf(stxs) {
+		hashes, err := app.readSignerHashes(signer)
+		if err != nil {
+			return err
+		}
+
+		pub := fmt.Sprintf("%X", signer)
+		roots[pub] = merkle.HashFromByteSlices(hashes)
+		if expected, ok := app.state.MerkleRoots[pub]; ok && !bytes.Equal(expected, roots[pub]) {
+			app.logger.Error("merkle root changed in reindex", "signer", pub,
+				"expected", fmt.Sprintf("%X", expected), "actual", fmt.Sprintf("%X", roots[pub]))
+		}
+	}
+
+	app.state.MerkleRoots = roots
+	app.state.NumTransactions = int64(len(stxs))
+	saveState(app.state.db, app.state)
+
+	app.logger.Info("reindexed transactions", "txs", len(stxs), "blocks", len(blocks), "signers", len(roots))
+	return nil
+}
+
+// --------------------------------------------------------------------------
+// Private helpers
+
+// readStoredTransactions reads and decrypts all transactions of the database,
+// by hash. Keys of the secondary indexes share the "vfs:" prefix and are
+// skipped.
+func (app *VStoreApplication) readStoredTransactions() (map[string]SignedTransaction, error) {
+	secret, err := app.identitySecret()
+	if err != nil {
+		return nil, err
+	}
+	defer zeroize(secret)
+
+	stxs := make(map[string]SignedTransaction)
+	err = iteratePrefix(app.state.db, vfsPrefixKey, func(hash, value []byte) error {
+		if len(hash) != tmhash.Size || isIndexKey(prefixKey(hash)) {
+			return nil
+		}
+
+		// Pruned transactions cannot be indexed again without their body
+		if isPrunedTransaction(value) {
+			return fmt.Errorf("could not reindex transaction %X: %w", hash, ErrTransactionPruned)
+		}
+
+		txData, err := openTransaction(secret, value)
+		if err != nil {
+			return fmt.Errorf("could not decrypt transaction %X: %w", hash, err)
+		}
+
+		stx, err := FromBytes(txData)
+		zeroize(txData)
+		if err != nil {
+			return fmt.Errorf("could not decode transaction %X: %w", hash, err)
+		}
+
+		stx.Hash = append([]byte{}, hash...)
+		stxs[string(stx.Hash)] = *stx
+		return nil
+	})
+
+	return stxs, err
+}
+
+// recoverBlocks returns the blocks of the stored transactions sorted by height,
+// in order of the height index. Transactions which are missing from the height
+// index are appended to the last height.
+func (app *VStoreApplication) recoverBlocks(stxs map[string]SignedTransaction) ([]reindexBlock, error) {
+	placed := make(map[string]struct{}, len(stxs))
+	blocks := []reindexBlock{}
+	err := iteratePrefix(app.state.db, vfsPrefixKeyByHeight, func(key, value []byte) error {
+		height, err := strconv.ParseInt(string(key), 10, 64)
+		if err != nil {
+			return nil
+		}
+
+		hashes := [][]byte{}
+		if err := json.Unmarshal(value, &hashes); err != nil {
+			return fmt.Errorf("could not decode height index at height %d: %w", height, err)
+		}
+
+		block := reindexBlock{Height: height}
+		for _, hash := range hashes {
+			stx, ok := stxs[string(hash)]
+			if _, done := placed[string(hash)]; !ok || done {
+				continue
+			}
+
+			placed[string(hash)] = struct{}{}
+			block.Txs = append(block.Txs, stx)
+		}
+
+		if len(block.Txs) > 0 {
+			blocks = append(blocks, block)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Keys are sorted lexicographically, i.e. "10" before "9"
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].Height < blocks[j].Height
+	})
+
+	orphans := []SignedTransaction{}
+	for hash, stx := range stxs {
+		if _, ok := placed[hash]; !ok {
+			orphans = append(orphans, stx)
+		}
+	}
+
+	if len(orphans) == 0 {
+		return blocks, nil
+	}
+
+	sort.Slice(orphans, func(i, j int) bool {
+		if !orphans[i].Time.Equal(orphans[j].Time) {
+			return orphans[i].Time.Before(orphans[j].Time)
+		}
+
+		return bytes.Compare(orphans[i].Hash, orphans[j].Hash) < 0
+	})
+
+	app.logger.Error("transactions missing from height index", "txs", len(orphans), "height", app.state.Height)
+	if n := len(blocks); n > 0 && blocks[n-1].Height == app.state.Height {
+		blocks[n-1].Txs = append(blocks[n-1].Txs, orphans...)
+		return blocks, nil
+	}
+
+	return append(blocks, reindexBlock{Height: app.state.Height, Txs: orphans}), nil
+}
+
+// signersOf returns the public keys of the signers of transactions, sorted
+// lexicographically.
+func signersOf(stxs map[string]SignedTransaction) [][]byte {
+	unique := make(map[string]struct{})
+	for _, stx := range stxs {
+		unique[string(stx.Signer.Bytes())] = struct{}{}
+	}
+
+	signers := make([][]byte, 0, len(unique))
+	for signer := range unique {
+		signers = append(signers, []byte(signer))
+	}
+
+	sort.Slice(signers, func(i, j int) bool {
+		return bytes.Compare(signers[i], signers[j]) < 0
+	})
+
+	return signers
+}
+
+// deleteKeysWithPrefix deletes all database keys with prefix. Keys are read
+// before they are deleted, such that the iterator is not invalidated.
+func (app *VStoreApplication) deleteKeysWithPrefix(prefix []byte) error {
+	keys := [][]byte{}
+	err := iteratePrefix(app.state.db, prefix, func(key, _ []byte) error {
+		keys = append(keys, prefixKeyWith(key, prefix))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := app.state.db.Delete(key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isIndexKey returns true if a database key belongs to a secondary index or
+// to the records of a block height.
+func isIndexKey(key []byte) bool {
+	prefixes := [][]byte{
+		vfsPrefixKeyByHeight,
+		vfsPrefixKeyByPubKey,
+		vfsPrefixKeyByChunks,
+		vfsPrefixKeyByBlockTime,
+		vfsPrefixKeyByAppHash,
+		vfsPrefixKeyByTime,
+	}
+
+	for _, prefix := range prefixes {
+		if bytes.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
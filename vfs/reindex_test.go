@@ -0,0 +1,102 @@
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cmtdb "github.com/cometbft/cometbft-db"
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+func TestVStoreReindex(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-reindex", 2)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	db := cmtdb.NewMemDB()
+	vstore := NewVStoreApplication(db, filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	// Commit 3 blocks with transactions of 2 signers
+	ts := time.Now().Truncate(time.Second)
+	stxs := []*SignedTransaction{}
+	for height := 1; height <= 3; height++ {
+		txs := [][]byte{}
+		for _, priv := range ownerPrivs {
+			stx, err := makeTransactionAt(t, priv, []byte(testComplexValue+string(rune('a'+height))), ts)
+			require.NoError(t, err, "should create a signed transaction")
+
+			stx.Hash = ComputeHash(stx)
+			stxs = append(stxs, stx)
+			txs = append(txs, stx.Bytes())
+		}
+
+		makeBlockCommit(ctx, t, vstore, height, txs)
+	}
+
+	appHash := vstore.state.Hash()
+	pub := ed25519.PrivKey(ownerPrivs[0]).PubKey().Bytes()
+
+	queryValue := func(path string, data []byte) string {
+		resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: path, Data: data})
+		require.NoError(t, err)
+		return string(resQuery.Value)
+	}
+
+	require.Equal(t, "3", queryValue("/pubkey/count", pub))
+	require.Equal(t, "2", queryValue("/height/count", []byte("3")))
+
+	// Indexes are lost, e.g. after a crash during Commit
+	require.NoError(t, db.Delete(SignerIndexKey(pub, 0)))
+	require.NoError(t, db.Delete(HeightIndexKey(3)))
+	require.NoError(t, db.Delete(TimeIndexKey(ts)))
+
+	assert.Equal(t, "0", queryValue("/pubkey/count", pub))
+	assert.Equal(t, "0", queryValue("/height/count", []byte("3")))
+
+	// Reindexing restores the indexes and the State
+	require.NoError(t, vstore.Reindex())
+	assert.Equal(t, "3", queryValue("/pubkey/count", pub))
+	assert.Equal(t, "2", queryValue("/height/count", []byte("3")))
+	assert.NotEmpty(t, queryValue("/signer/height", append(append([]byte{}, pub...), []byte("3")...)))
+	assert.Equal(t, appHash, vstore.state.Hash())
+	assert.EqualValues(t, len(stxs), vstore.state.NumTransactions)
+	assert.EqualValues(t, 3, vstore.SignerCount(pub))
+	assert.NoError(t, vstore.VerifyIntegrity())
+
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/pubkey", Data: pub})
+	require.NoError(t, err)
+	assert.NotEmpty(t, resQuery.Value, "transactions must be found by signer")
+
+	entries, err := vstore.readTimeIndexBucket(TimeIndexKey(ts))
+	require.NoError(t, err)
+	assert.Len(t, entries, len(stxs))
+
+	// Reindexing is idempotent
+	keys := [][]byte{SignerIndexKey(pub, 0), HeightIndexKey(1), HeightIndexKey(3), TimeIndexKey(ts), StateKey()}
+	before := make([][]byte, len(keys))
+	for i, key := range keys {
+		before[i], err = db.Get(key)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, vstore.Reindex())
+	for i, key := range keys {
+		after, err := db.Get(key)
+		require.NoError(t, err)
+		assert.Equal(t, before[i], after, "key %q must not change", key)
+	}
+
+	// The reindexed State survives a restart
+	restarted := NewVStoreApplication(db, filepath.Join(vfsDir, "id"), []byte("testpassword"))
+	info, err := restarted.Info(ctx, &abci.RequestInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, appHash, info.LastBlockAppHash)
+}
@@ -0,0 +1,39 @@
+package vfs
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+// RekeyIdentity re-encrypts an identity file with a new password. The private
+// key is decrypted with the old password oldPw and encrypted with a secret that
+// is derived from the new password newPw using kdf and a new random salt.
+// The private key, and thereby the public key and .pub file, are unchanged.
+// The identity file is replaced atomically.
+func RekeyIdentity(idFile string, oldPw, newPw []byte, kdf KDF) error {
+	if len(oldPw) == 0 || len(newPw) == 0 {
+		return errors.New("password must not be empty")
+	}
+
+	id := &identityFile{Path: idFile, pw: oldPw}
+	pbz, err := id.Open()
+	if err != nil {
+		return fmt.Errorf("could not decrypt identity file: %v", err)
+	}
+	defer zeroize(pbz)
+
+	if len(pbz) != ed25519.PrivateKeySize {
+		return errors.New("invalid private key in identity file")
+	}
+
+	b64, err := sealIdentity(pbz, newPw, kdf)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file and rename, such that a crash never leaves
+	// a partially written identity file
+	return writeFileAtomic(idFile, b64, 0600)
+}
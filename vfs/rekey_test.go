@@ -0,0 +1,49 @@
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVStoreRekeyIdentity(t *testing.T) {
+	// create a unique, concurrency-safe test directory under os.TempDir()
+	rootDir, _ := os.MkdirTemp("", "test-vstore-rekey_identity")
+	defer os.RemoveAll(rootDir)
+
+	oldPw, newPw := []byte("testpassword"), []byte("newtestpassword")
+	idFile, pubFile := MustGenerateIdentity(filepath.Join(rootDir, "id"), oldPw)
+
+	expected, err := NewIdentity(idFile, oldPw).Identity().PubKey()
+	require.NoError(t, err)
+
+	pubBytes, _ := os.ReadFile(pubFile)
+	oldBytes, _ := os.ReadFile(idFile)
+
+	err = RekeyIdentity(idFile, []byte("wrongpassword"), newPw, DefaultKDF)
+	assert.Error(t, err, "should not rekey with a wrong password")
+
+	require.NoError(t, RekeyIdentity(idFile, oldPw, newPw, KDFSHA256))
+
+	newBytes, _ := os.ReadFile(idFile)
+	assert.NotEqual(t, oldBytes, newBytes, "identity file must be re-encrypted")
+
+	fi, err := os.Stat(idFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	// The old password does not open the identity anymore
+	_, err = NewIdentity(idFile, oldPw).Open()
+	assert.Error(t, err, "should not decrypt with the old password")
+
+	// The public key is unchanged
+	pk, err := NewIdentity(idFile, newPw).Identity().PubKey()
+	require.NoError(t, err, "should decrypt with the new password")
+	assert.Equal(t, expected.Bytes(), pk.Bytes())
+
+	pubAfter, _ := os.ReadFile(pubFile)
+	assert.Equal(t, pubBytes, pubAfter, ".pub file must be unchanged")
+}
@@ -0,0 +1,327 @@
+package vfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// BodyValidator describes a validator of transaction bodies. Validators are
+// applied in CheckTx to the bodies that declare a content type which the
+// validator accepts, such that malformed payloads are rejected at ingestion.
+type BodyValidator interface {
+	// Accepts returns true if bodies of the content type are validated.
+	Accepts(contentType string) bool
+
+	// ValidateBody returns an error if a body does not conform.
+	ValidateBody(body []byte) error
+}
+
+// JSONSchema implements BodyValidator for bodies with a JSON content type,
+// e.g. "application/json" or "application/ld+json". It supports a subset of
+// JSON Schema: type, enum, const, required, properties, additionalProperties,
+// items, minItems, maxItems, minLength, maxLength, minimum and maximum.
+type JSONSchema struct {
+	Type                 schemaTypes            `json:"type,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	Const                interface{}            `json:"const,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	MinItems             *int                   `json:"minItems,omitempty"`
+	MaxItems             *int                   `json:"maxItems,omitempty"`
+	MinLength            *int                   `json:"minLength,omitempty"`
+	MaxLength            *int                   `json:"maxLength,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+}
+
+var _ BodyValidator = (*JSONSchema)(nil)
+
+// ParseJSONSchema parses a JSON Schema document.
+func ParseJSONSchema(bz []byte) (*JSONSchema, error) {
+	schema := new(JSONSchema)
+	if err := json.Unmarshal(bz, schema); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %v", err)
+	}
+
+	return schema, nil
+}
+
+// LoadJSONSchema reads and parses a JSON Schema file.
+func LoadJSONSchema(file string) (*JSONSchema, error) {
+	bz, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseJSONSchema(bz)
+}
+
+// IsJSONContentType returns true if a media type denotes JSON, i.e. if it is
+// "application/json" or uses the "+json" structured syntax suffix.
+func IsJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// Accepts returns true for JSON content types.
+// Accepts implements BodyValidator
+func (s *JSONSchema) Accepts(contentType string) bool {
+	return IsJSONContentType(contentType)
+}
+
+// ValidateBody returns an error if a body is not valid JSON or does not
+// conform to the schema.
+// ValidateBody implements BodyValidator
+func (s *JSONSchema) ValidateBody(body []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidJSON, err)
+	}
+
+	if dec.More() {
+		return fmt.Errorf("%w: trailing data", errInvalidJSON)
+	}
+
+	return s.validate("$", value)
+}
+
+// SetBodyValidator sets the validator that is applied in CheckTx to bodies
+// that declare a content type accepted by the validator. Bodies are not
+// validated by default.
+func (app *VStoreApplication) SetBodyValidator(validator BodyValidator) {
+	app.bodyValidator = validator
+}
+
+// --------------------------------------------------------------------------
+// Private helpers
+
+// errInvalidJSON is returned when a body with a JSON content type cannot
+// be decoded.
+var errInvalidJSON = errors.New("invalid JSON body")
+
+// validateBody validates the body of a transaction with the body validator,
+// if any. Bodies in external storage and chunks are not validated as the
+// complete body is not part of the transaction.
+func (app *VStoreApplication) validateBody(stx *SignedTransaction) uint32 {
+	if app.bodyValidator == nil || stx.Chunk != nil || len(stx.BodyRef) > 0 {
+		return CodeTypeOK
+	}
+
+	if !app.bodyValidator.Accepts(stx.ContentType) {
+		return CodeTypeOK
+	}
+
+	if err := app.bodyValidator.ValidateBody(stx.Data); err != nil {
+		app.logger.Debug("invalid body", "hash", fmt.Sprintf("%X", stx.Hash), "err", err)
+		return CodeTypeInvalidBodyError
+	}
+
+	return CodeTypeOK
+}
+
+// schemaTypes holds the types of a schema, which can be a string or an
+// array of strings.
+type schemaTypes []string
+
+// UnmarshalJSON implements json.Unmarshaler
+func (t *schemaTypes) UnmarshalJSON(bz []byte) error {
+	var single string
+	if err := json.Unmarshal(bz, &single); err == nil {
+		*t = schemaTypes{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(bz, &multiple); err != nil {
+		return errors.New("type must be a string or an array of strings")
+	}
+
+	*t = multiple
+	return nil
+}
+
+// validate validates a decoded JSON value at path against the schema.
+func (s *JSONSchema) validate(path string, value interface{}) error {
+	if s == nil {
+		return nil
+	}
+
+	if len(s.Type) > 0 && !s.matchesType(value) {
+		return fmt.Errorf("%s: expected type %s", path, strings.Join(s.Type, " or "))
+	}
+
+	if s.Const != nil && !jsonEqual(s.Const, value) {
+		return fmt.Errorf("%s: value does not match const", path)
+	}
+
+	if len(s.Enum) > 0 && !s.inEnum(value) {
+		return fmt.Errorf("%s: value is not one of enum", path)
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return s.validateObject(path, v)
+	case []interface{}:
+		return s.validateArray(path, v)
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			return fmt.Errorf("%s: string shorter than %d", path, *s.MinLength)
+		}
+
+		if s.MaxLength != nil && n > *s.MaxLength {
+			return fmt.Errorf("%s: string longer than %d", path, *s.MaxLength)
+		}
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return fmt.Errorf("%s: invalid number", path)
+		}
+
+		if s.Minimum != nil && f < *s.Minimum {
+			return fmt.Errorf("%s: number less than %v", path, *s.Minimum)
+		}
+
+		if s.Maximum != nil && f > *s.Maximum {
+			return fmt.Errorf("%s: number greater than %v", path, *s.Maximum)
+		}
+	}
+
+	return nil
+}
+
+// validateObject validates the required and known properties of an object.
+func (s *JSONSchema) validateObject(path string, obj map[string]interface{}) error {
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			return fmt.Errorf("%s: missing required property %q", path, name)
+		}
+	}
+
+	// Properties are validated in order of names, for deterministic errors
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prop, known := s.Properties[name]
+		if !known {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				return fmt.Errorf("%s: unexpected property %q", path, name)
+			}
+
+			continue
+		}
+
+		if err := prop.validate(path+"."+name, obj[name]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateArray validates the size and the items of an array.
+func (s *JSONSchema) validateArray(path string, arr []interface{}) error {
+	if s.MinItems != nil && len(arr) < *s.MinItems {
+		return fmt.Errorf("%s: fewer than %d items", path, *s.MinItems)
+	}
+
+	if s.MaxItems != nil && len(arr) > *s.MaxItems {
+		return fmt.Errorf("%s: more than %d items", path, *s.MaxItems)
+	}
+
+	for i, item := range arr {
+		if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// matchesType returns true if a value matches one of the schema types.
+func (s *JSONSchema) matchesType(value interface{}) bool {
+	for _, typ := range s.Type {
+		switch v := value.(type) {
+		case nil:
+			if typ == "null" {
+				return true
+			}
+		case bool:
+			if typ == "boolean" {
+				return true
+			}
+		case string:
+			if typ == "string" {
+				return true
+			}
+		case []interface{}:
+			if typ == "array" {
+				return true
+			}
+		case map[string]interface{}:
+			if typ == "object" {
+				return true
+			}
+		case json.Number:
+			if typ == "number" {
+				return true
+			}
+
+			if _, err := v.Int64(); typ == "integer" && err == nil {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// inEnum returns true if a value equals one of the enum values.
+func (s *JSONSchema) inEnum(value interface{}) bool {
+	for _, candidate := range s.Enum {
+		if jsonEqual(candidate, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// jsonEqual compares JSON values by their canonical encoding, such that
+// numbers compare equal regardless of their decoded representation.
+func jsonEqual(a, b interface{}) bool {
+	abz, aerr := json.Marshal(a)
+	bbz, berr := json.Marshal(b)
+	if aerr != nil || berr != nil {
+		return false
+	}
+
+	var an, bn interface{}
+	if json.Unmarshal(abz, &an) != nil || json.Unmarshal(bbz, &bn) != nil {
+		return false
+	}
+
+	acz, _ := json.Marshal(an)
+	bcz, _ := json.Marshal(bn)
+	return bytes.Equal(acz, bcz)
+}
@@ -0,0 +1,129 @@
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cometbft/cometbft/crypto/ed25519"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+)
+
+const testBodySchema = `{
+  "type": "object",
+  "required": ["id", "tags"],
+  "additionalProperties": false,
+  "properties": {
+    "id": {"type": "integer", "minimum": 1},
+    "kind": {"enum": ["invoice", "receipt"]},
+    "tags": {"type": "array", "maxItems": 2, "items": {"type": "string", "minLength": 1}}
+  }
+}`
+
+func TestVStoreBodySchema(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-body_schema", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	schemaFile := filepath.Join(vfsDir, "schema.json")
+	require.NoError(t, os.WriteFile(schemaFile, []byte(testBodySchema), 0600))
+
+	schema, err := LoadJSONSchema(schemaFile)
+	require.NoError(t, err)
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	checkTx := func(contentType, body string) uint32 {
+		stx := makeTypedTransaction(t, ownerPrivs[0], contentType, []byte(body))
+		resp, err := vstore.CheckTx(ctx, &abci.RequestCheckTx{Tx: stx.Bytes()})
+		require.NoError(t, err)
+		return resp.Code
+	}
+
+	// Bodies are not validated unless a schema is configured
+	assert.Equal(t, CodeTypeOK, checkTx("application/json", `{"id": 0}`))
+
+	vstore.SetBodyValidator(schema)
+
+	conforming := []string{
+		`{"id": 1, "tags": []}`,
+		`{"id": 42, "kind": "receipt", "tags": ["a", "b"]}`,
+	}
+
+	for _, body := range conforming {
+		assert.Equal(t, CodeTypeOK, checkTx("application/json", body), body)
+		assert.Equal(t, CodeTypeOK, checkTx("application/ld+json; charset=utf-8", body), body)
+	}
+
+	nonConforming := []string{
+		`{"id": 1`,                               // malformed
+		`{"id": 1, "tags": []} {}`,               // trailing data
+		`[]`,                                     // not an object
+		`{"tags": []}`,                           // missing id
+		`{"id": 1.5, "tags": []}`,                // not an integer
+		`{"id": 0, "tags": []}`,                  // below minimum
+		`{"id": 1, "kind": "quote", "tags": []}`, // not in enum
+		`{"id": 1, "tags": ["a", "b", "c"]}`,     // too many items
+		`{"id": 1, "tags": [""]}`,                // empty item
+		`{"id": 1, "tags": [], "extra": true}`,   // unexpected property
+	}
+
+	for _, body := range nonConforming {
+		assert.Equal(t, CodeTypeInvalidBodyError, checkTx("application/json", body), body)
+	}
+
+	// Bodies without JSON content type are not validated
+	assert.Equal(t, CodeTypeOK, checkTx("", `{"id": 1`))
+	assert.Equal(t, CodeTypeOK, checkTx("text/plain", `{"id": 1`))
+}
+
+func TestVStoreContentTypeSigned(t *testing.T) {
+	_, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-content_type_signed", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	stx := makeTypedTransaction(t, ownerPrivs[0], "application/json", []byte(`{}`))
+	assert.True(t, stx.Verify())
+
+	decoded, err := NewSignedTransactionFromBytes(stx.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", decoded.ContentType)
+
+	// Content type cannot be changed without the signer
+	decoded.ContentType = "text/plain"
+	decoded.Hash = nil
+	assert.False(t, decoded.Verify(), "content type must be signed")
+	assert.NotEqual(t, stx.Hash, ComputeHash(decoded), "content type must be hashed")
+}
+
+func TestVStoreParseJSONSchema(t *testing.T) {
+	_, err := ParseJSONSchema([]byte(`{"type": ["string", "null"]}`))
+	assert.NoError(t, err)
+
+	_, err = ParseJSONSchema([]byte(`{"type": 1}`))
+	assert.Error(t, err)
+
+	_, err = ParseJSONSchema([]byte(`not json`))
+	assert.Error(t, err)
+}
+
+func makeTypedTransaction(t testing.TB, privKey []byte, contentType string, data []byte) *SignedTransaction {
+	t.Helper()
+
+	stx, err := makeTransaction(t, privKey, data)
+	require.NoError(t, err)
+
+	// Signature covers the content type
+	stx.ContentType = contentType
+	stx.Signature = SignData(ed25519.PrivKey(privKey), stx)
+	stx.Hash = ComputeHash(stx)
+	return stx
+}
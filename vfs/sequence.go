@@ -0,0 +1,83 @@
+package vfs
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+var (
+	errSequenceReused = errors.New("sequence already used")
+	errSequenceOrder  = errors.New("sequence out of order")
+)
+
+// nextSequence returns the sequence that the next sequenced transaction of the
+// signer with the public key pub must use, i.e. the last sequence of the
+// signer in staged, or else in the State, plus one.
+func (app *VStoreApplication) nextSequence(pub []byte, staged []SignedTransaction) uint64 {
+	last := app.state.SignerSequence(pub)
+	for _, prev := range staged {
+		if prev.Sequence > last && prev.Signer.Equals(ed25519.PubKey(pub)) {
+			last = prev.Sequence
+		}
+	}
+
+	return last + 1
+}
+
+// validateSequence validates the sequence of a sequenced transaction, i.e. the
+// sequence must be the next sequence of the signer, such that a transaction
+// is never committed twice, even with another timestamp. Sequences of staged
+// transactions are used, such that a signer can commit several sequences in
+// the same block. Transactions without a sequence are always valid.
+func (app *VStoreApplication) validateSequence(stx *SignedTransaction, staged []SignedTransaction) error {
+	if stx.Sequence == 0 {
+		return nil
+	}
+
+	next := app.nextSequence(stx.Signer.Bytes(), staged)
+	if stx.Sequence < next {
+		return fmt.Errorf("%w: %d, next sequence is %d", errSequenceReused, stx.Sequence, next)
+	} else if stx.Sequence > next {
+		return fmt.Errorf("%w: %d, next sequence is %d", errSequenceOrder, stx.Sequence, next)
+	}
+
+	return nil
+}
+
+// commitSequences updates the last sequence of the signers of the staged
+// transactions in the State.
+func (app *VStoreApplication) commitSequences() {
+	for _, payload := range app.stage {
+		if payload.Sequence == 0 {
+			continue
+		}
+
+		if app.state.SignerSequences == nil {
+			app.state.SignerSequences = make(map[string]uint64)
+		}
+
+		pub := payload.PublicKey()
+		app.state.SignerSequences[pub] = max(app.state.SignerSequences[pub], payload.Sequence)
+	}
+}
+
+// querySequence returns the next sequence of a signer (base10), i.e. the
+// sequence of its next sequenced transaction, which is 1 for new signers.
+// Expects the signer public key (32 bytes) in the request's Data field.
+func (app *VStoreApplication) querySequence(
+	req *abci.RequestQuery,
+	response *abci.ResponseQuery,
+) (*abci.ResponseQuery, error) {
+	if len(req.Data) != ed25519.PubKeySize {
+		response.Code = CodeTypeInvalidFormatError
+		response.Log = fmt.Sprintf("invalid public key size, want: %d, got: %d", ed25519.PubKeySize, len(req.Data))
+		return response, nil
+	}
+
+	response.Value = []byte(strconv.FormatUint(app.nextSequence(req.Data, nil), 10))
+	return response, nil
+}
@@ -0,0 +1,120 @@
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+func TestVStoreSequence(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-sequence", 2)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+	pub := ed25519.PrivKey(ownerPrivs[0]).PubKey().Bytes()
+
+	nextSequence := func(pub []byte) string {
+		resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/pubkey/sequence", Data: pub})
+		require.NoError(t, err)
+		require.Equal(t, CodeTypeOK, resQuery.Code)
+		return string(resQuery.Value)
+	}
+
+	checkTx := func(stx *SignedTransaction) uint32 {
+		resp, err := vstore.CheckTx(ctx, &abci.RequestCheckTx{Tx: stx.Bytes()})
+		require.NoError(t, err)
+		return resp.Code
+	}
+
+	assert.Equal(t, "1", nextSequence(pub))
+
+	// Sequences 1 and 2 are committed in the same block
+	first := makeSequencedTransaction(t, ownerPrivs[0], []byte(testSimpleValue), 1, time.Now())
+	second := makeSequencedTransaction(t, ownerPrivs[0], []byte(testComplexValue), 2, time.Now())
+	respFinBlock, _ := makeBlockCommit(ctx, t, vstore, 1, [][]byte{first.Bytes(), second.Bytes()})
+	assert.Equal(t, CodeTypeOK, respFinBlock.TxResults[0].Code)
+	assert.Equal(t, CodeTypeOK, respFinBlock.TxResults[1].Code)
+	assert.Equal(t, uint64(2), vstore.state.SignerSequence(pub))
+	assert.Equal(t, "3", nextSequence(pub))
+
+	// Sequences of a signer do not change the sequences of other signers
+	assert.Equal(t, "1", nextSequence(ed25519.PrivKey(ownerPrivs[1]).PubKey().Bytes()))
+
+	// A reused sequence is rejected, also with another timestamp, i.e. with
+	// another transaction hash
+	replayed := makeSequencedTransaction(t, ownerPrivs[0], []byte(testSimpleValue), 1, time.Now().Add(time.Second))
+	assert.NotEqual(t, first.Hash, replayed.Hash)
+	assert.Equal(t, CodeTypeInvalidSequenceError, checkTx(replayed))
+
+	respFinBlock, _ = makeBlockCommit(ctx, t, vstore, 2, [][]byte{replayed.Bytes()})
+	assert.Equal(t, CodeTypeInvalidSequenceError, respFinBlock.TxResults[0].Code)
+	assert.Contains(t, respFinBlock.TxResults[0].Log, errSequenceReused.Error())
+
+	// Out of order sequences wait in the mempool but are not committed
+	gap := makeSequencedTransaction(t, ownerPrivs[0], []byte("gap"), 4, time.Now())
+	assert.Equal(t, CodeTypeOK, checkTx(gap))
+
+	respFinBlock, _ = makeBlockCommit(ctx, t, vstore, 3, [][]byte{gap.Bytes()})
+	assert.Equal(t, CodeTypeInvalidSequenceError, respFinBlock.TxResults[0].Code)
+	assert.Contains(t, respFinBlock.TxResults[0].Log, errSequenceOrder.Error())
+
+	// The same sequence twice in a block is committed once
+	third := makeSequencedTransaction(t, ownerPrivs[0], []byte("third"), 3, time.Now())
+	again := makeSequencedTransaction(t, ownerPrivs[0], []byte("again"), 3, time.Now())
+	respFinBlock, _ = makeBlockCommit(ctx, t, vstore, 4, [][]byte{third.Bytes(), again.Bytes(), gap.Bytes()})
+	assert.Equal(t, CodeTypeOK, respFinBlock.TxResults[0].Code)
+	assert.Equal(t, CodeTypeInvalidSequenceError, respFinBlock.TxResults[1].Code)
+	assert.Equal(t, CodeTypeOK, respFinBlock.TxResults[2].Code)
+	assert.Equal(t, "5", nextSequence(pub))
+
+	// Transactions without a sequence are always valid
+	unsequenced, err := makeTransaction(t, ownerPrivs[0], []byte("unsequenced"))
+	require.NoError(t, err)
+	unsequenced.Hash = ComputeHash(unsequenced)
+	respFinBlock, _ = makeBlockCommit(ctx, t, vstore, 5, [][]byte{unsequenced.Bytes()})
+	assert.Equal(t, CodeTypeOK, respFinBlock.TxResults[0].Code)
+	assert.Equal(t, "5", nextSequence(pub))
+
+	// The sequence is signed and cannot be replaced
+	forged := *replayed
+	forged.Sequence = 5
+	forged.Hash = ComputeHash(&forged)
+	assert.Equal(t, CodeTypeInvalidSignatureError, checkTx(&forged))
+
+	// Sequences are persisted in the State and rebuilt by Reindex
+	state, err := readState(vstore.state.db)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), state.SignerSequence(pub))
+
+	vstore.state.SignerSequences = nil
+	require.NoError(t, vstore.Reindex())
+	assert.Equal(t, uint64(4), vstore.state.SignerSequence(pub))
+
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/pubkey/sequence", Data: pub[:16]})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeInvalidFormatError, resQuery.Code)
+}
+
+// makeSequencedTransaction creates a transaction with sequence at ts and
+// signs it with privKey.
+func makeSequencedTransaction(t testing.TB, privKey, data []byte, sequence uint64, ts time.Time) *SignedTransaction {
+	t.Helper()
+
+	stx, err := makeTransactionAt(t, privKey, data, ts)
+	require.NoError(t, err)
+
+	stx.Sequence = sequence
+	stx.Signature = SignData(ed25519.PrivKey(privKey), stx)
+	stx.Hash = ComputeHash(stx)
+	return stx
+}
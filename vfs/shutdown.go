@@ -0,0 +1,78 @@
+package vfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrShuttingDown is returned by FinalizeBlock and Commit once Shutdown has
+// been called.
+var ErrShuttingDown = errors.New("application is shutting down")
+
+// Shutdown stops the processing of blocks, waits for an active FinalizeBlock
+// or Commit to complete and saves the state with a synced write, such that
+// the database can be closed safely. When ctx is done before the active calls
+// complete, ctx.Err() is returned and the state is not saved. The secret
+// that the identity keeps in memory is zeroized.
+//
+// A block that was finalized but not committed is never persisted, CometBFT
+// replays it upon restart as the committed height is not updated.
+func (app *VStoreApplication) Shutdown(ctx context.Context) error {
+	app.lifecycle.Lock()
+	app.closing = true
+	app.lifecycle.Unlock()
+
+	app.logger.Info("shutdown: waiting for active FinalizeBlock and Commit")
+
+	done := make(chan struct{})
+	go func() {
+		app.active.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return fmt.Errorf("shutdown: active block processing did not complete: %w", ctx.Err())
+	}
+
+	// Secrets kept in memory by the identity are zeroized
+	if closer, ok := app.priv.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	if app.pendingBlock {
+		app.logger.Info("shutdown: discarding uncommitted block", "height", app.state.Height)
+		return nil
+	}
+
+	app.logger.Info("shutdown: saving state", "height", app.state.Height)
+
+	// The State is saved as in Commit, with a synced write
+	batch := app.state.db.NewBatch()
+	defer batch.Close()
+
+	saveState(batch, app.state)
+	return batch.WriteSync()
+}
+
+// beginBlockCall registers an active FinalizeBlock or Commit call. An error
+// is returned after Shutdown, the call must not be processed.
+func (app *VStoreApplication) beginBlockCall() error {
+	app.lifecycle.Lock()
+	defer app.lifecycle.Unlock()
+
+	if app.closing {
+		return ErrShuttingDown
+	}
+
+	app.active.Add(1)
+	return nil
+}
+
+// endBlockCall marks the end of an active FinalizeBlock or Commit call.
+func (app *VStoreApplication) endBlockCall() {
+	app.active.Done()
+}
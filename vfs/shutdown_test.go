@@ -0,0 +1,79 @@
+package vfs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cmtdb "github.com/cometbft/cometbft-db"
+	abci "github.com/cometbft/cometbft/abci/types"
+)
+
+func TestVStoreShutdown(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-shutdown", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	idFile := filepath.Join(vfsDir, "id")
+	db := cmtdb.NewMemDB()
+	vstore := NewVStoreApplication(db, idFile, []byte("testpassword"))
+
+	stx, err := makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue))
+	require.NoError(t, err)
+	makeBlockCommit(ctx, t, vstore, 1, [][]byte{stx.Bytes()})
+
+	// Active block processing delays the shutdown
+	require.NoError(t, vstore.beginBlockCall())
+	timeout, cancelTimeout := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancelTimeout()
+	assert.ErrorIs(t, vstore.Shutdown(timeout), context.DeadlineExceeded)
+
+	// New blocks are rejected during the shutdown
+	_, err = vstore.FinalizeBlock(ctx, &abci.RequestFinalizeBlock{Height: 2})
+	assert.ErrorIs(t, err, ErrShuttingDown)
+	_, err = vstore.Commit(ctx, &abci.RequestCommit{})
+	assert.ErrorIs(t, err, ErrShuttingDown)
+
+	vstore.endBlockCall()
+	require.NoError(t, vstore.Shutdown(ctx))
+
+	// Secret of the identity is not kept in memory
+	assert.Nil(t, vstore.priv.(*identityFile).secrets.secret)
+
+	// Committed state is persisted
+	state, err := readState(db)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), state.Height)
+	assert.Equal(t, vstore.state.Hash(), state.Hash())
+}
+
+func TestVStoreShutdownUncommittedBlock(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-shutdown_uncommitted", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	idFile := filepath.Join(vfsDir, "id")
+	db := cmtdb.NewMemDB()
+	vstore := NewVStoreApplication(db, idFile, []byte("testpassword"))
+
+	stx, err := makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue))
+	require.NoError(t, err)
+
+	// Finalized but not committed
+	_, err = vstore.FinalizeBlock(ctx, &abci.RequestFinalizeBlock{Height: 1, Txs: [][]byte{stx.Bytes()}})
+	require.NoError(t, err)
+	require.NoError(t, vstore.Shutdown(ctx))
+
+	state, err := readState(db)
+	require.NoError(t, err)
+	assert.Zero(t, state.Height, "uncommitted blocks must not be persisted")
+}
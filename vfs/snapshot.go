@@ -0,0 +1,308 @@
+package vfs
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	cmtdb "github.com/cometbft/cometbft-db"
+)
+
+// snapshotMagic and snapshotVersion1 describe the header of snapshot archives:
+// magic (4) || version (1) || gzip stream
+//
+// The gzip stream contains a JSON header with the height and the app hash of
+// the State, followed by all key-value pairs of the database in order of keys
+// and by an empty key, then the number of key-value pairs. Keys and values are
+// prefixed with their uvarint encoded length.
+var snapshotMagic = []byte("VSSN")
+
+const (
+	snapshotVersion1 byte = 1
+
+	// maxSnapshotEntrySize limits the size of the keys, values and header of
+	// snapshot archives, such that a corrupted length is never allocated.
+	maxSnapshotEntrySize = 256 << 20
+)
+
+// SnapshotInfo describes a snapshot archive of the database.
+type SnapshotInfo struct {
+	// AppVersion is the version of the application that saved the State.
+	AppVersion uint64 `json:"app_version"`
+
+	// Height is the committed height of the State.
+	Height int64 `json:"height"`
+
+	// AppHash is the app hash of the State.
+	AppHash []byte `json:"app_hash"`
+
+	// Keys is the number of key-value pairs of the archive.
+	Keys int64 `json:"keys"`
+
+	// Bytes is the size of the key-value pairs of the archive.
+	Bytes int64 `json:"bytes"`
+}
+
+// Snapshot writes a snapshot archive of the database of a running application
+// to w, see SnapshotDB. Blocks are not processed while the snapshot is taken:
+// an active FinalizeBlock or Commit completes first and the next calls wait
+// for the snapshot, such that the archive contains a committed height.
+func (app *VStoreApplication) Snapshot(w io.Writer) (SnapshotInfo, error) {
+	app.lifecycle.Lock()
+	defer app.lifecycle.Unlock()
+
+	if app.closing {
+		return SnapshotInfo{}, ErrShuttingDown
+	}
+
+	// Block calls register under the lifecycle lock
+	app.active.Wait()
+	return SnapshotDB(app.state.db, w)
+}
+
+// SnapshotDB writes a snapshot archive of all key-value pairs of db to w, i.e.
+// the transactions, which are still encrypted, the indexes and the State. The
+// archive is portable across database backends and is loaded with RestoreDB.
+// The database must not be written during the snapshot, e.g. the node must be
+// stopped, see Snapshot for running applications.
+func SnapshotDB(db cmtdb.DB, w io.Writer) (SnapshotInfo, error) {
+	state, err := readState(db)
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+
+	info := SnapshotInfo{
+		AppVersion: state.Version,
+		Height:     state.Height,
+		AppHash:    state.Hash(),
+	}
+
+	header, err := json.Marshal(info)
+	if err != nil {
+		return info, err
+	}
+
+	if _, err := w.Write(append(append([]byte{}, snapshotMagic...), snapshotVersion1)); err != nil {
+		return info, err
+	}
+
+	zw := gzip.NewWriter(w)
+	bw := bufio.NewWriter(zw)
+	if err := writeSnapshotEntry(bw, header); err != nil {
+		return info, err
+	}
+
+	it, err := db.Iterator(nil, nil)
+	if err != nil {
+		return info, err
+	}
+	defer it.Close()
+
+	for ; it.Valid(); it.Next() {
+		if err := writeSnapshotEntry(bw, it.Key()); err != nil {
+			return info, err
+		}
+
+		if err := writeSnapshotEntry(bw, it.Value()); err != nil {
+			return info, err
+		}
+
+		info.Keys++
+		info.Bytes += int64(len(it.Key()) + len(it.Value()))
+	}
+
+	if err := it.Error(); err != nil {
+		return info, err
+	}
+
+	// An empty key ends the key-value pairs
+	if err := writeSnapshotEntry(bw, nil); err != nil {
+		return info, err
+	}
+
+	if _, err := bw.Write(binary.AppendUvarint(nil, uint64(info.Keys))); err != nil {
+		return info, err
+	}
+
+	if err := bw.Flush(); err != nil {
+		return info, err
+	}
+
+	return info, zw.Close()
+}
+
+// RestoreDB loads a snapshot archive that was written with SnapshotDB from r
+// into db, which must be empty, e.g. a fresh database of another backend. The
+// number of key-value pairs and the app hash of the restored State are
+// compared with the archive, an error is returned otherwise. The key-value
+// pairs are written in batches, such that db is cleared on any error and the
+// restore can be retried with the same database.
+func RestoreDB(r io.Reader, db cmtdb.DB) (info SnapshotInfo, err error) {
+	last, err := lastKey(db)
+	if err != nil {
+		return info, err
+	}
+
+	if last != nil {
+		return info, errors.New("database must be empty")
+	}
+
+	// Corrupt archives never leave a partly restored database
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		if clearErr := clearDB(db); clearErr != nil {
+			err = fmt.Errorf("%w (could not clear database: %v)", err, clearErr)
+		}
+	}()
+
+	magic := make([]byte, len(snapshotMagic)+1)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return info, fmt.Errorf("could not read snapshot header: %w", err)
+	}
+
+	if !bytes.HasPrefix(magic, snapshotMagic) || magic[len(snapshotMagic)] != snapshotVersion1 {
+		return info, errors.New("not a vstore snapshot archive")
+	}
+
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return info, fmt.Errorf("could not read snapshot archive: %w", err)
+	}
+	defer zr.Close()
+
+	br := bufio.NewReader(zr)
+	header, err := readSnapshotEntry(br)
+	if err != nil {
+		return info, err
+	}
+
+	expected := SnapshotInfo{}
+	if err := json.Unmarshal(header, &expected); err != nil {
+		return info, fmt.Errorf("could not parse snapshot header: %w", err)
+	}
+
+	if expected.AppVersion > AppVersion {
+		return info, fmt.Errorf("%w: snapshot of app version %d", ErrUnsupportedStateVersion, expected.AppVersion)
+	} else if expected.AppVersion < AppVersion {
+		return info, fmt.Errorf("%w: snapshot of app version %d", ErrOutdatedStateVersion, expected.AppVersion)
+	}
+
+	info.AppVersion = expected.AppVersion
+
+	batch := db.NewBatch()
+	defer func() { batch.Close() }()
+
+	pending := 0
+	for {
+		key, err := readSnapshotEntry(br)
+		if err != nil {
+			return info, err
+		}
+
+		// An empty key ends the key-value pairs
+		if len(key) == 0 {
+			break
+		}
+
+		value, err := readSnapshotEntry(br)
+		if err != nil {
+			return info, err
+		}
+
+		if err := batch.Set(key, value); err != nil {
+			return info, err
+		}
+
+		info.Keys++
+		info.Bytes += int64(len(key) + len(value))
+
+		pending++
+		if pending < migrateBatchSize {
+			continue
+		}
+
+		// Flush the batch and start a new one
+		if err := batch.WriteSync(); err != nil {
+			return info, err
+		}
+
+		batch.Close()
+		batch, pending = db.NewBatch(), 0
+	}
+
+	numKeys, err := binary.ReadUvarint(br)
+	if err != nil {
+		return info, fmt.Errorf("could not read snapshot archive: %w", err)
+	}
+
+	// The gzip checksum is verified at the end of the stream
+	if _, err := br.ReadByte(); err == nil {
+		return info, errors.New("unexpected data after snapshot archive")
+	} else if err != io.EOF {
+		return info, fmt.Errorf("could not read snapshot archive: %w", err)
+	}
+
+	if int64(numKeys) != info.Keys {
+		return info, fmt.Errorf("key count mismatch, archive: %d, restored: %d", numKeys, info.Keys)
+	}
+
+	if pending > 0 {
+		if err := batch.WriteSync(); err != nil {
+			return info, err
+		}
+	}
+
+	state, err := readState(db)
+	if err != nil {
+		return info, err
+	}
+
+	info.Height = state.Height
+	info.AppHash = state.Hash()
+	if info.Height != expected.Height || !bytes.Equal(info.AppHash, expected.AppHash) {
+		return info, fmt.Errorf("app hash mismatch, archive: %X (height %d), restored: %X (height %d)",
+			expected.AppHash, expected.Height, info.AppHash, info.Height)
+	}
+
+	return info, nil
+}
+
+// --------------------------------------------------------------------------
+// Private helpers
+
+// writeSnapshotEntry writes the uvarint encoded length of bz followed by bz.
+func writeSnapshotEntry(w io.Writer, bz []byte) error {
+	if _, err := w.Write(binary.AppendUvarint(nil, uint64(len(bz)))); err != nil {
+		return err
+	}
+
+	_, err := w.Write(bz)
+	return err
+}
+
+// readSnapshotEntry reads an entry that was written with writeSnapshotEntry.
+func readSnapshotEntry(r *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, fmt.Errorf("could not read snapshot archive: %w", err)
+	}
+
+	if size > maxSnapshotEntrySize {
+		return nil, fmt.Errorf("snapshot entry too large: %d bytes", size)
+	}
+
+	bz := make([]byte, size)
+	if _, err := io.ReadFull(r, bz); err != nil {
+		return nil, fmt.Errorf("could not read snapshot archive: %w", err)
+	}
+
+	return bz, nil
+}
@@ -0,0 +1,121 @@
+package vfs
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cmtdb "github.com/cometbft/cometbft-db"
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+func TestVStoreSnapshotRestore(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-snapshot_restore", 2)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	// Restores are written in several batches
+	defer func(size int) { migrateBatchSize = size }(migrateBatchSize)
+	migrateBatchSize = 7
+
+	db := cmtdb.NewMemDB()
+	vstore := NewVStoreApplication(db, filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	hashes := [][]byte{}
+	for height := 1; height <= 3; height++ {
+		txs := [][]byte{}
+		for _, priv := range ownerPrivs {
+			stx, err := makeTransaction(t, priv, []byte(testSimpleValue+strconv.Itoa(height)))
+			require.NoError(t, err, "should create a signed transaction")
+
+			hashes = append(hashes, ComputeHash(stx))
+			txs = append(txs, stx.Bytes())
+		}
+
+		makeBlockCommit(ctx, t, vstore, height, txs)
+	}
+
+	archive := new(bytes.Buffer)
+	info, err := vstore.Snapshot(archive)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, info.Height)
+	assert.Equal(t, vstore.state.Hash(), info.AppHash)
+	assert.Equal(t, AppVersion, info.AppVersion)
+
+	keys, err := countKeys(db)
+	require.NoError(t, err)
+	assert.Equal(t, keys, info.Keys)
+
+	// Restore into a fresh database
+	restoredDB := cmtdb.NewMemDB()
+	restoredInfo, err := RestoreDB(bytes.NewReader(archive.Bytes()), restoredDB)
+	require.NoError(t, err)
+	assert.Equal(t, info, restoredInfo)
+
+	// Query results are preserved
+	restored := NewVStoreApplication(restoredDB, filepath.Join(vfsDir, "id"), []byte("testpassword"))
+	queries := []*abci.RequestQuery{
+		{Path: "/height", Data: []byte("2")},
+		{Path: "/state"},
+		{Path: "/signers"},
+	}
+
+	for _, hash := range hashes {
+		queries = append(queries, &abci.RequestQuery{Path: "/hash", Data: hash})
+	}
+
+	for _, priv := range ownerPrivs {
+		queries = append(queries, &abci.RequestQuery{Path: "/pubkey", Data: ed25519.PrivKey(priv).PubKey().Bytes()})
+	}
+
+	for _, req := range queries {
+		expected, err := vstore.Query(ctx, req)
+		require.NoError(t, err)
+		require.Equal(t, CodeTypeOK, expected.Code, req.Path)
+
+		actual, err := restored.Query(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, expected.Value, actual.Value, req.Path)
+	}
+
+	resInfo, err := restored.Info(ctx, &abci.RequestInfo{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, resInfo.LastBlockHeight)
+	assert.Equal(t, vstore.state.Hash(), resInfo.LastBlockAppHash)
+	assert.NoError(t, restored.VerifyIntegrity())
+
+	// Databases are only restored when empty
+	_, err = RestoreDB(bytes.NewReader(archive.Bytes()), restoredDB)
+	assert.Error(t, err)
+
+	// Truncated or corrupted archives are not restored
+	truncated := archive.Bytes()[:archive.Len()-10]
+	retryDB := cmtdb.NewMemDB()
+	_, err = RestoreDB(bytes.NewReader(truncated), retryDB)
+	assert.Error(t, err)
+
+	// Corrupt archives leave the database empty, the restore can be retried
+	last, err := lastKey(retryDB)
+	require.NoError(t, err)
+	assert.Nil(t, last, "database must be empty after a failed restore")
+
+	restoredInfo, err = RestoreDB(bytes.NewReader(archive.Bytes()), retryDB)
+	require.NoError(t, err)
+	assert.Equal(t, info, restoredInfo)
+
+	_, err = RestoreDB(bytes.NewReader([]byte("VSTX\x01")), cmtdb.NewMemDB())
+	assert.Error(t, err)
+
+	// Snapshots are refused after Shutdown
+	require.NoError(t, vstore.Shutdown(ctx))
+	_, err = vstore.Snapshot(new(bytes.Buffer))
+	assert.ErrorIs(t, err, ErrShuttingDown)
+}
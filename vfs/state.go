@@ -1,18 +1,37 @@
 package vfs
 
 import (
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"sort"
+	"strings"
 
 	cmtdb "github.com/cometbft/cometbft-db"
 	"github.com/cometbft/cometbft/crypto/merkle"
 )
 
+// ErrUnsupportedStateVersion is returned when the State was saved by a newer
+// version of the application than the running one.
+var ErrUnsupportedStateVersion = errors.New("unsupported state version")
+
+// ErrOutdatedStateVersion is returned when the State was saved by an older
+// version of the application than the running one. Versions commit different
+// app hashes, an upgrade must be coordinated with all nodes of the network,
+// e.g. with a new genesis.
+var ErrOutdatedStateVersion = errors.New("outdated state version")
+
 var (
 	stateKey             = []byte("vfsState")
 	vfsPrefixKey         = []byte("vfs:")
 	vfsPrefixKeyByHeight = []byte("vfs:height:block-")
 	vfsPrefixKeyByPubKey = []byte("vfs:pubkey:")
+	vfsPrefixKeyByChunks = []byte("vfs:chunks:")
+
+	vfsPrefixKeyByBlockTime = []byte("vfs:blocktime:block-")
+	vfsPrefixKeyByAppHash   = []byte("vfs:apphash:block-")
+	vfsPrefixKeyByTime      = []byte("vfs:time:")
 )
 
 // State describes the vstore application state which consists of a latest
@@ -21,46 +40,140 @@ var (
 type State struct {
 	db cmtdb.DB
 
+	// Version is the AppVersion of the application that saved the State. The
+	// State is refused if it was saved by another version, i.e. by a newer,
+	// unsupported version or by an older version. It is empty for States
+	// saved before versions were introduced, which are refused too.
+	Version uint64 `json:"version,omitempty"`
+
 	// NumTransactions is essentially the total number of transactions processed.
 	// This is used for the appHash in combination with the merkle roots
 	NumTransactions int64 `json:"num_transactions"`
 	Height          int64 `json:"height"`
 
+	// ChainID is the chain id of the genesis, as set in InitChain. Transactions
+	// that are signed for another chain id are rejected, such that they cannot
+	// be replayed across chains.
+	ChainID string `json:"chain_id,omitempty"`
+
 	// MerkleRoots contains the cryptographic commitments for transactions that
-	// have previously been processed.
+	// have previously been processed. The merkle root of a signer is the root
+	// of the merkle tree of all its transaction hashes, in order of commitment,
+	// i.e. by block and by hash within a block, such that the order of the
+	// transactions in a block does not matter.
 	// This is used for the appHash.
 	MerkleRoots map[string][]byte `json:"merkle_roots"`
+
+	// SignerCounts contains the number of committed transactions of every
+	// signer, by uppercase hexadecimal public key as in MerkleRoots.
+	// This is *not* used for the appHash: the counts are derived from the
+	// signer index which every node maintains locally, such that adding them
+	// does not change the consensus rules.
+	SignerCounts map[string]int64 `json:"signer_counts,omitempty"`
+
+	// SignerSequences contains the last committed sequence of every signer of
+	// sequenced transactions, by uppercase hexadecimal public key as in
+	// MerkleRoots. This is *not* used for the appHash, the sequences are
+	// derived from the committed transactions.
+	SignerSequences map[string]uint64 `json:"signer_sequences,omitempty"`
 }
 
-// MerkleRoots returns a slice of merkle roots that is *deterministic* due to
-// keys always being sorted lexicographically.
-func (s State) SortedMerkleRoots() [][]byte {
-	// Sort keys first (deterministic)
-	max := len(s.MerkleRoots)
-	keys := make([]string, max)
-	roots := make([][]byte, max)
+// SignerRoot describes the merkle root of a signer. The public key is the
+// uppercase hexadecimal representation of the signer public key.
+type SignerRoot struct {
+	PubKey string `json:"pubkey"`
+	Root   []byte `json:"root"`
+}
 
-	i := 0
-	for k := range s.MerkleRoots {
-		keys[i] = k
-		i++
+// AppHashRecord describes the application hash of a committed block height and
+// the signer merkle roots that produce it. Records are persisted for every
+// height, such that transactions can be proven against historical app hashes.
+// The number of transactions of every signer at this height is the number of
+// leaves of the signer merkle tree, it is empty for older records.
+type AppHashRecord struct {
+	AppHash      []byte           `json:"app_hash"`
+	MerkleRoots  []SignerRoot     `json:"merkle_roots"`
+	SignerCounts map[string]int64 `json:"signer_counts,omitempty"`
+}
+
+// SortedMerkleRoots returns a slice of merkle roots that is *deterministic* due
+// to keys always being sorted lexicographically.
+func (s State) SortedMerkleRoots() [][]byte {
+	signerRoots := s.SortedSignerRoots()
+	roots := make([][]byte, len(signerRoots))
+	for j, sr := range signerRoots {
+		roots[j] = sr.Root
 	}
 
-	// Sort keys lexicographically
-	sort.Strings(keys)
+	return roots
+}
+
+// SortedSignerRoots returns the merkle roots with their signer public key,
+// sorted lexicographically by public key as in SortedMerkleRoots.
+func (s State) SortedSignerRoots() []SignerRoot {
+	keys := s.sortedSigners()
+	roots := make([]SignerRoot, len(keys))
 
 	// Iterate over *keys* for determinism
 	for j, k := range keys {
-		v := s.MerkleRoots[k]
-		roots[j] = v
+		roots[j] = SignerRoot{PubKey: k, Root: s.MerkleRoots[k]}
 	}
 
 	return roots
 }
 
+// SignerCount returns the number of committed transactions of the signer with
+// the public key pub, or 0 for unknown signers.
+func (s State) SignerCount(pub []byte) int64 {
+	return s.SignerCounts[strings.ToUpper(hex.EncodeToString(pub))]
+}
+
+// SignerSequence returns the last committed sequence of the signer with the
+// public key pub, or 0 if the signer never committed a sequenced transaction.
+func (s State) SignerSequence(pub []byte) uint64 {
+	return s.SignerSequences[strings.ToUpper(hex.EncodeToString(pub))]
+}
+
+// CanonicalBytes returns a deterministic JSON serialization of the State which
+// can be compared byte-for-byte across nodes. Merkle roots are serialized as a
+// list of signer public keys and roots sorted lexicographically by public key.
+func (s State) CanonicalBytes() []byte {
+	// Struct fields are always serialized in order of declaration
+	bz, err := json.Marshal(struct {
+		NumTransactions int64        `json:"num_transactions"`
+		Height          int64        `json:"height"`
+		MerkleRoots     []SignerRoot `json:"merkle_roots"`
+		AppHash         []byte       `json:"app_hash"`
+	}{
+		s.NumTransactions,
+		s.Height,
+		s.SortedSignerRoots(),
+		s.Hash(),
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return bz
+}
+
+// sortedSigners returns the signer public keys of the merkle roots sorted
+// lexicographically.
+func (s State) sortedSigners() []string {
+	keys := make([]string, 0, len(s.MerkleRoots))
+	for k := range s.MerkleRoots {
+		keys = append(keys, k)
+	}
+
+	// Sort keys lexicographically
+	sort.Strings(keys)
+	return keys
+}
+
 // Hash returns the hash of the application state. This is computed as the merkle
 // root of all the committed transaction hashes using a deterministic merkle root
-// slices as produced with MerkleRoots().
+// slices as produced with SortedSignerRoots(). Each leaf is the public key of
+// a signer followed by its merkle root, see signerRootLeaf.
 // The produced hash can be used to verify the integrity of the State.
 // This function is used as the "AppHash"
 func (s State) Hash() []byte {
@@ -69,50 +182,102 @@ func (s State) Hash() []byte {
 	}
 
 	// Compute merkle root of all committed transactions
-	return merkle.HashFromByteSlices(s.SortedMerkleRoots())
+	return merkle.HashFromByteSlices(signerRootLeaves(s.SortedSignerRoots()))
 }
 
-// TODO: add implementation for Verify()
-// Verify should check the integrity using merkle roots
+// signerRootLeaves returns the leaves of the app hash merkle tree for the
+// sorted signerRoots.
+func signerRootLeaves(signerRoots []SignerRoot) [][]byte {
+	leaves := make([][]byte, len(signerRoots))
+	for i, sr := range signerRoots {
+		signer, err := hex.DecodeString(sr.PubKey)
+		if err != nil {
+			panic(fmt.Errorf("invalid signer public key %q: %w", sr.PubKey, err))
+		}
+
+		leaves[i] = signerRootLeaf(signer, sr.Root)
+	}
+
+	return leaves
+}
+
+// signerRootLeaf returns the leaf of the app hash merkle tree for the merkle
+// root of a signer, i.e. the public key followed by the merkle root, such that
+// the app hash commits to the signer of every merkle root.
+func signerRootLeaf(signer []byte, root []byte) []byte {
+	leaf := make([]byte, 0, len(signer)+len(root))
+	return append(append(leaf, signer...), root...)
+}
 
 // --------------------------------------------------------------------------
 
 // prefixKey adds the "vfs:" database key prefix
 func prefixKey(key []byte) []byte {
-	return append(vfsPrefixKey, key...)
+	return prefixKeyWith(key, vfsPrefixKey)
 }
 
-// prefixKeyWith adds a custom database key prefix
+// prefixKeyWith adds a custom database key prefix. The key is built in a new
+// slice, such that the shared prefixes are never written by append.
 func prefixKeyWith(key []byte, keyPrefix []byte) []byte {
-	return append(keyPrefix, key...)
+	dbKey := make([]byte, 0, len(keyPrefix)+len(key))
+	return append(append(dbKey, keyPrefix...), key...)
 }
 
 // loadState reads the state key from the database and tries to unmarshal
-// a State instance or panics in case it doesn't work.
+// a State instance or panics in case it doesn't work, e.g. if the State was
+// saved by a newer version of the application.
 func loadState(db cmtdb.DB) State {
+	state, err := readState(db)
+	if err != nil {
+		panic(err)
+	}
+	return state
+}
+
+// readState reads the state key from the database and tries to unmarshal
+// a State instance or returns an error in case it doesn't work. An error that
+// wraps ErrUnsupportedStateVersion is returned if the State was saved by a
+// newer version of the application, such that its format is never misread.
+func readState(db cmtdb.DB) (State, error) {
 	var state State
 	state.db = db
 	stateBytes, err := db.Get(stateKey)
 	if err != nil {
-		panic(err)
+		return state, err
 	}
 	if len(stateBytes) == 0 {
-		return state
+		return state, nil
 	}
 	err = json.Unmarshal(stateBytes, &state)
 	if err != nil {
-		panic(err)
+		return state, err
 	}
-	return state
+	if state.Version > AppVersion {
+		return state, fmt.Errorf("%w: state was saved by app version %d, this binary supports app version %d, please upgrade vStore",
+			ErrUnsupportedStateVersion, state.Version, AppVersion)
+	}
+	if state.Version < AppVersion {
+		return state, fmt.Errorf("%w: state was saved by app version %d, this binary requires app version %d, the upgrade must be coordinated with all nodes",
+			ErrOutdatedStateVersion, state.Version, AppVersion)
+	}
+	return state, nil
+}
+
+// dbWriter is implemented by cmtdb.DB and cmtdb.Batch. The writes of Commit
+// are grouped in a batch, such that all writes of a block land atomically.
+type dbWriter interface {
+	Set(key, value []byte) error
 }
 
-// saveState saves the application state in the database using the state key.
-func saveState(state State) {
+// saveState saves the application state with w using the state key. The
+// State is stamped with the AppVersion of the application.
+func saveState(w dbWriter, state State) {
+	state.Version = AppVersion
 	stateBytes, err := json.Marshal(state)
 	if err != nil {
 		panic(err)
 	}
-	err = state.db.Set(stateKey, stateBytes)
+	err = w.Set(stateKey, stateBytes)
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,216 @@
+package vfs
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cmtdb "github.com/cometbft/cometbft-db"
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+	"github.com/cometbft/cometbft/crypto/tmhash"
+)
+
+func TestVStoreStateCanonicalBytes(t *testing.T) {
+	signers := []string{"C0FFEE", "0A0B0C", "FFFFFF", "123456", "ABCDEF"}
+
+	// Two equal states with merkle roots inserted in different orders
+	s1 := State{db: cmtdb.NewMemDB(), NumTransactions: 5, Height: 3, MerkleRoots: map[string][]byte{}}
+	s2 := State{db: cmtdb.NewMemDB(), NumTransactions: 5, Height: 3, MerkleRoots: map[string][]byte{}}
+	for i := range signers {
+		s1.MerkleRoots[signers[i]] = tmhash.Sum([]byte(signers[i]))
+		j := len(signers) - 1 - i
+		s2.MerkleRoots[signers[j]] = tmhash.Sum([]byte(signers[j]))
+	}
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, s1.CanonicalBytes(), s2.CanonicalBytes(), "equal states must produce identical bytes")
+	}
+
+	// Different states produce different bytes
+	s2.Height = 4
+	assert.NotEqual(t, s1.CanonicalBytes(), s2.CanonicalBytes())
+
+	// Canonical bytes are available with the /state query
+	app := &VStoreApplication{state: s1}
+	resQuery, err := app.Query(context.Background(), &abci.RequestQuery{Path: "/state"})
+	require.NoError(t, err)
+	assert.Equal(t, s1.CanonicalBytes(), resQuery.Value)
+}
+
+func TestVStoreStateGenesisAppHash(t *testing.T) {
+	s := State{db: cmtdb.NewMemDB()}
+	assert.Equal(t, GenesisAppHash, hex.EncodeToString(s.Hash()), "genesis app hash must not change")
+
+	// InitChain returns the pinned genesis app hash
+	app := &VStoreApplication{state: s}
+	resInit, err := app.InitChain(context.Background(), &abci.RequestInitChain{})
+	require.NoError(t, err)
+	assert.Equal(t, GenesisAppHash, hex.EncodeToString(resInit.AppHash))
+}
+
+func TestVStoreStateSortedSignerRoots(t *testing.T) {
+	signers := []string{"C0FFEE", "0A0B0C", "FFFFFF", "123456", "ABCDEF"}
+
+	s := State{MerkleRoots: map[string][]byte{}}
+	for _, signer := range signers {
+		s.MerkleRoots[signer] = tmhash.Sum([]byte(signer))
+	}
+
+	sorted := []string{"0A0B0C", "123456", "ABCDEF", "C0FFEE", "FFFFFF"}
+	signerRoots := s.SortedSignerRoots()
+	roots := s.SortedMerkleRoots()
+	require.Len(t, signerRoots, len(sorted))
+	require.Len(t, roots, len(sorted))
+
+	// Each root is correlated with its signer, in lexicographic order
+	for i, signer := range sorted {
+		assert.Equal(t, signer, signerRoots[i].PubKey)
+		assert.Equal(t, tmhash.Sum([]byte(signer)), signerRoots[i].Root)
+		assert.Equal(t, signerRoots[i].Root, roots[i])
+	}
+}
+
+func TestVStoreStateReload(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-state_reload", 3)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	db := cmtdb.NewMemDB()
+	vstore := NewVStoreApplication(db, filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	for height := 1; height <= 2; height++ {
+		txs := [][]byte{}
+		for _, priv := range ownerPrivs {
+			stx, err := makeTransaction(t, priv, []byte(testSimpleValue+strconv.Itoa(height)))
+			require.NoError(t, err, "should create a signed transaction")
+			txs = append(txs, stx.Bytes())
+		}
+
+		makeBlockCommit(ctx, t, vstore, height, txs)
+	}
+
+	require.Len(t, vstore.state.MerkleRoots, len(ownerPrivs))
+
+	// Merkle roots survive persistence
+	reloaded := loadState(db)
+	assert.Equal(t, vstore.state.MerkleRoots, reloaded.MerkleRoots)
+	assert.Equal(t, vstore.state.NumTransactions, reloaded.NumTransactions)
+	assert.Equal(t, vstore.state.Height, reloaded.Height)
+	assert.Equal(t, vstore.state.Hash(), reloaded.Hash())
+
+	// Merkle roots survive a restart
+	restarted := NewVStoreApplication(db, filepath.Join(vfsDir, "id"), []byte("testpassword"))
+	info, err := restarted.Info(ctx, &abci.RequestInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, vstore.state.Hash(), info.LastBlockAppHash)
+	assert.Contains(t, info.Data, "merkle_roots")
+}
+
+func TestVStoreStateVersion(t *testing.T) {
+	_, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-state_version", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	db := cmtdb.NewMemDB()
+	vstore := NewVStoreApplication(db, filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	stx, err := makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue))
+	require.NoError(t, err)
+	makeBlockCommit(context.Background(), t, vstore, 1, [][]byte{stx.Bytes()})
+
+	// The State is stamped with the app version on save
+	reloaded, err := readState(db)
+	require.NoError(t, err)
+	assert.Equal(t, AppVersion, reloaded.Version)
+
+	// States of older versions, or without a version, are refused
+	for _, version := range []uint64{0, AppVersion - 1} {
+		older := reloaded
+		older.Version = version
+		bz, err := json.Marshal(older)
+		require.NoError(t, err)
+		require.NoError(t, db.Set(stateKey, bz))
+
+		_, err = readState(db)
+		assert.ErrorIs(t, err, ErrOutdatedStateVersion)
+		assert.NotErrorIs(t, err, ErrUnsupportedStateVersion)
+	}
+
+	// States saved by a newer version are refused
+	newer := reloaded
+	newer.Version = AppVersion + 1
+	bz, err := json.Marshal(newer)
+	require.NoError(t, err)
+	require.NoError(t, db.Set(stateKey, bz))
+
+	_, err = readState(db)
+	assert.ErrorIs(t, err, ErrUnsupportedStateVersion)
+
+	_, err = NewVStoreApplicationE(db, filepath.Join(vfsDir, "id"), []byte("testpassword"))
+	assert.ErrorIs(t, err, ErrUnsupportedStateVersion)
+	assert.Panics(t, func() { loadState(db) })
+}
+
+func TestVStoreStateSignerCounts(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-state_signer_counts", 2)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	db := cmtdb.NewMemDB()
+	vstore := NewVStoreApplication(db, filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	// First signer signs 2 transactions per block, second signer 1
+	expected := []int64{0, 0}
+	for height := 1; height <= 3; height++ {
+		txs := [][]byte{}
+		for i, priv := range ownerPrivs {
+			for j := 0; j < len(ownerPrivs)-i; j++ {
+				stx, err := makeTransaction(t, priv, []byte(testSimpleValue+strconv.Itoa(height)+strconv.Itoa(j)))
+				require.NoError(t, err, "should create a signed transaction")
+				txs = append(txs, stx.Bytes())
+				expected[i]++
+			}
+		}
+
+		makeBlockCommit(ctx, t, vstore, height, txs)
+
+		for i, priv := range ownerPrivs {
+			pub := ed25519.PrivKey(priv).PubKey().Bytes()
+			assert.Equal(t, expected[i], vstore.SignerCount(pub), "count of signer %d at height %d", i, height)
+		}
+	}
+
+	assert.Equal(t, int64(0), vstore.SignerCount(ed25519.GenPrivKey().PubKey().Bytes()))
+
+	// Signer counts are not used for the app hash
+	appHash := vstore.state.Hash()
+	vstore.state.SignerCounts = nil
+	assert.Equal(t, appHash, vstore.state.Hash())
+
+	// States saved without signer counts are counted from the signer index
+	saveState(db, vstore.state)
+	restarted := NewVStoreApplication(db, filepath.Join(vfsDir, "id"), []byte("testpassword"))
+	for i, priv := range ownerPrivs {
+		pub := ed25519.PrivKey(priv).PubKey().Bytes()
+		assert.Equal(t, expected[i], restarted.SignerCount(pub))
+	}
+
+	info, err := restarted.Info(ctx, &abci.RequestInfo{})
+	require.NoError(t, err)
+	assert.Contains(t, info.Data, "signer_counts")
+	assert.NoError(t, restarted.VerifyIntegrity())
+}
@@ -0,0 +1,533 @@
+package vfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	cmtdb "github.com/cometbft/cometbft-db"
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/crypto/tmhash"
+)
+
+// SnapshotFormat is the format of the state sync snapshots, i.e. snapshot
+// archives (see SnapshotDB) which are split in chunks.
+const SnapshotFormat uint32 = 1
+
+// snapshotChunkSize is the size of the chunks of state sync snapshots, the
+// last chunk may be smaller. CometBFT limits chunks to 16 MB.
+var snapshotChunkSize = 4 << 20
+
+// snapshotMetadata describes the metadata of state sync snapshots. The hash of
+// a snapshot is the hash of its chunk hashes, such that every chunk is verified
+// upon ApplySnapshotChunk. Transactions can only be read with the identity
+// that encrypted them, snapshots of other identities are rejected.
+type snapshotMetadata struct {
+	ChunkHashes [][]byte `json:"chunk_hashes"`
+	Identity    string   `json:"identity"`
+}
+
+// snapshotRestore describes a state sync snapshot that is being restored.
+// Chunks are streamed to RestoreDB in order, such that a snapshot is never
+// held in memory.
+type snapshotRestore struct {
+	snapshot *abci.Snapshot
+	appHash  []byte
+	hashes   [][]byte
+	next     uint32
+	writer   *io.PipeWriter
+	done     chan snapshotRestoreResult
+	result   *snapshotRestoreResult
+}
+
+// wait waits for RestoreDB to return and returns its outcome.
+func (r *snapshotRestore) wait() snapshotRestoreResult {
+	if r.result == nil {
+		result := <-r.done
+		r.result = &result
+	}
+
+	return *r.result
+}
+
+// snapshotRestoreResult describes the outcome of RestoreDB for a snapshot.
+type snapshotRestoreResult struct {
+	info SnapshotInfo
+	err  error
+}
+
+// SetSnapshots enables the state sync snapshots, such that new nodes with the
+// same identity fetch the encrypted store rather than replaying all blocks. A
+// snapshot is written to dir every interval heights, in Commit, and the
+// keepRecent most recent snapshots are kept. Snapshots are disabled with an
+// interval of 0 (default), nodes always restore offered snapshots.
+//
+// Note: The snapshot is written synchronously in Commit, from the database
+// that Commit writes to, such that the commit of every interval height lasts
+// as long as writing the whole database (see SnapshotDB) and the chain stalls
+// meanwhile. Large stores should use a large interval.
+func (app *VStoreApplication) SetSnapshots(dir string, interval int64, keepRecent int) {
+	app.snapshotDir = dir
+	app.snapshotInterval = interval
+	app.snapshotKeepRecent = keepRecent
+}
+
+// ListSnapshots returns the state sync snapshots in dir, most recent first.
+// ListSnapshots implements abci.Application
+func (app *VStoreApplication) ListSnapshots(
+	_ context.Context,
+	_ *abci.RequestListSnapshots,
+) (*abci.ResponseListSnapshots, error) {
+	snapshots, err := app.listSnapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	return &abci.ResponseListSnapshots{Snapshots: snapshots}, nil
+}
+
+// LoadSnapshotChunk returns a chunk of a state sync snapshot, or an empty
+// chunk if the snapshot does not exist, e.g. after it was pruned.
+// LoadSnapshotChunk implements abci.Application
+func (app *VStoreApplication) LoadSnapshotChunk(
+	_ context.Context,
+	req *abci.RequestLoadSnapshotChunk,
+) (*abci.ResponseLoadSnapshotChunk, error) {
+	if app.snapshotDir == "" || req.Format != SnapshotFormat {
+		return &abci.ResponseLoadSnapshotChunk{}, nil
+	}
+
+	chunk, err := os.ReadFile(app.snapshotChunkFile(int64(req.Height), req.Chunk))
+	if err != nil {
+		app.logger.Debug("could not load snapshot chunk", "height", req.Height, "chunk", req.Chunk, "err", err)
+		return &abci.ResponseLoadSnapshotChunk{}, nil
+	}
+
+	return &abci.ResponseLoadSnapshotChunk{Chunk: chunk}, nil
+}
+
+// OfferSnapshot accepts a state sync snapshot of the supported format whose
+// metadata matches the snapshot hash and the identity of the node. Snapshots
+// are only restored into an empty database, a snapshot that is offered again
+// restarts the restore.
+// OfferSnapshot implements abci.Application
+func (app *VStoreApplication) OfferSnapshot(
+	_ context.Context,
+	req *abci.RequestOfferSnapshot,
+) (*abci.ResponseOfferSnapshot, error) {
+	// A previous restore is discarded
+	if err := app.abortRestore(); err != nil {
+		return nil, err
+	}
+
+	snapshot := req.Snapshot
+	if snapshot == nil {
+		return &abci.ResponseOfferSnapshot{Result: abci.ResponseOfferSnapshot_REJECT}, nil
+	}
+
+	if snapshot.Format != SnapshotFormat {
+		return &abci.ResponseOfferSnapshot{Result: abci.ResponseOfferSnapshot_REJECT_FORMAT}, nil
+	}
+
+	metadata := snapshotMetadata{}
+	if err := json.Unmarshal(snapshot.Metadata, &metadata); err != nil ||
+		snapshot.Chunks == 0 ||
+		len(metadata.ChunkHashes) != int(snapshot.Chunks) ||
+		!bytes.Equal(snapshot.Hash, hashSnapshotChunks(metadata.ChunkHashes)) {
+		app.logger.Info("rejected snapshot with invalid metadata", "height", snapshot.Height)
+		return &abci.ResponseOfferSnapshot{Result: abci.ResponseOfferSnapshot_REJECT}, nil
+	}
+
+	identity, err := app.identityPubKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if metadata.Identity != identity {
+		app.logger.Info("rejected snapshot of another identity", "height", snapshot.Height, "identity", metadata.Identity)
+		return &abci.ResponseOfferSnapshot{Result: abci.ResponseOfferSnapshot_REJECT}, nil
+	}
+
+	last, err := lastKey(app.state.db)
+	if err != nil {
+		return nil, err
+	}
+
+	if last != nil {
+		app.logger.Error("could not restore snapshot: database must be empty", "height", snapshot.Height)
+		return &abci.ResponseOfferSnapshot{Result: abci.ResponseOfferSnapshot_ABORT}, nil
+	}
+
+	// Chunks are streamed to RestoreDB in a separate goroutine
+	reader, writer := io.Pipe()
+	restore := &snapshotRestore{
+		snapshot: snapshot,
+		appHash:  req.AppHash,
+		hashes:   metadata.ChunkHashes,
+		writer:   writer,
+		done:     make(chan snapshotRestoreResult, 1),
+	}
+
+	go func() {
+		info, err := RestoreDB(reader, app.state.db)
+		reader.CloseWithError(err)
+		restore.done <- snapshotRestoreResult{info, err}
+	}()
+
+	app.restore = restore
+	app.logger.Info("restoring snapshot", "height", snapshot.Height, "chunks", snapshot.Chunks)
+	return &abci.ResponseOfferSnapshot{Result: abci.ResponseOfferSnapshot_ACCEPT}, nil
+}
+
+// ApplySnapshotChunk verifies a chunk against the chunk hashes of the offered
+// snapshot and restores it. Invalid chunks are fetched again from another
+// sender. After the last chunk, the restored State must match the height and
+// the trusted app hash of the snapshot, the snapshot is rejected otherwise.
+// ApplySnapshotChunk implements abci.Application
+func (app *VStoreApplication) ApplySnapshotChunk(
+	_ context.Context,
+	req *abci.RequestApplySnapshotChunk,
+) (*abci.ResponseApplySnapshotChunk, error) {
+	restore := app.restore
+	if restore == nil {
+		return &abci.ResponseApplySnapshotChunk{Result: abci.ResponseApplySnapshotChunk_RETRY_SNAPSHOT}, nil
+	}
+
+	// Chunks are applied in order
+	if req.Index != restore.next {
+		return &abci.ResponseApplySnapshotChunk{
+			Result:        abci.ResponseApplySnapshotChunk_RETRY,
+			RefetchChunks: []uint32{restore.next},
+		}, nil
+	}
+
+	if !bytes.Equal(tmhash.Sum(req.Chunk), restore.hashes[req.Index]) {
+		app.logger.Info("invalid snapshot chunk", "height", restore.snapshot.Height, "chunk", req.Index, "sender", req.Sender)
+		return &abci.ResponseApplySnapshotChunk{
+			Result:        abci.ResponseApplySnapshotChunk_RETRY,
+			RefetchChunks: []uint32{req.Index},
+			RejectSenders: []string{req.Sender},
+		}, nil
+	}
+
+	if _, err := restore.writer.Write(req.Chunk); err != nil {
+		return app.rejectRestore(err)
+	}
+
+	restore.next++
+	if restore.next < uint32(len(restore.hashes)) {
+		return &abci.ResponseApplySnapshotChunk{Result: abci.ResponseApplySnapshotChunk_ACCEPT}, nil
+	}
+
+	// All chunks are applied
+	restore.writer.Close()
+	result := restore.wait()
+	if result.err != nil {
+		return app.rejectRestore(result.err)
+	}
+
+	if result.info.Height != int64(restore.snapshot.Height) || !bytes.Equal(result.info.AppHash, restore.appHash) {
+		return app.rejectRestore(fmt.Errorf("app hash mismatch, trusted: %X (height %d), restored: %X (height %d)",
+			restore.appHash, restore.snapshot.Height, result.info.AppHash, result.info.Height))
+	}
+
+	state, err := readState(app.state.db)
+	if err != nil {
+		return nil, err
+	}
+
+	app.state = state
+	app.tail = nil
+	app.restore = nil
+	app.logger.Info("restored snapshot", "height", state.Height, "txs", state.NumTransactions, "app_hash", fmt.Sprintf("%X", result.info.AppHash))
+	return &abci.ResponseApplySnapshotChunk{Result: abci.ResponseApplySnapshotChunk_ACCEPT}, nil
+}
+
+// --------------------------------------------------------------------------
+// Private helpers
+
+// takeSnapshot writes a state sync snapshot of the committed height, if
+// snapshots are enabled and the height is a multiple of the interval, and
+// prunes older snapshots. Errors are logged, they never halt the chain. The
+// snapshot is written before Commit returns, such that it contains exactly
+// the committed height, see SetSnapshots.
+func (app *VStoreApplication) takeSnapshot() {
+	height := app.state.Height
+	if app.snapshotInterval <= 0 || app.snapshotDir == "" || height <= 0 || height%app.snapshotInterval != 0 {
+		return
+	}
+
+	if err := app.writeSnapshot(height); err != nil {
+		app.logger.Error("could not write snapshot", "height", height, "err", err)
+		return
+	}
+
+	if err := app.pruneSnapshots(); err != nil {
+		app.logger.Error("could not prune snapshots", "err", err)
+	}
+}
+
+// writeSnapshot writes the snapshot archive of the database in chunks to a
+// temporary directory, which is renamed once the snapshot is complete.
+func (app *VStoreApplication) writeSnapshot(height int64) error {
+	identity, err := app.identityPubKey()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(app.snapshotDir, 0700); err != nil {
+		return err
+	}
+
+	tmpDir, err := os.MkdirTemp(app.snapshotDir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	chunks := &snapshotChunkWriter{dir: tmpDir}
+	if _, err := SnapshotDB(app.state.db, chunks); err != nil {
+		return err
+	}
+
+	if err := chunks.Close(); err != nil {
+		return err
+	}
+
+	metadata, err := json.Marshal(snapshotMetadata{
+		ChunkHashes: chunks.hashes,
+		Identity:    identity,
+	})
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := json.Marshal(&abci.Snapshot{
+		Height:   uint64(height),
+		Format:   SnapshotFormat,
+		Chunks:   uint32(len(chunks.hashes)),
+		Hash:     hashSnapshotChunks(chunks.hashes),
+		Metadata: metadata,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "snapshot.json"), snapshot, 0600); err != nil {
+		return err
+	}
+
+	snapshotDir := filepath.Join(app.snapshotDir, strconv.FormatInt(height, 10))
+	if err := os.RemoveAll(snapshotDir); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpDir, snapshotDir); err != nil {
+		return err
+	}
+
+	app.logger.Info("wrote snapshot", "height", height, "chunks", len(chunks.hashes))
+	return nil
+}
+
+// listSnapshots reads the state sync snapshots of the snapshot directory,
+// most recent first.
+func (app *VStoreApplication) listSnapshots() ([]*abci.Snapshot, error) {
+	if app.snapshotDir == "" {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(app.snapshotDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	snapshots := []*abci.Snapshot{}
+	for _, entry := range entries {
+		if _, err := strconv.ParseUint(entry.Name(), 10, 64); err != nil || !entry.IsDir() {
+			continue
+		}
+
+		bz, err := os.ReadFile(filepath.Join(app.snapshotDir, entry.Name(), "snapshot.json"))
+		if err != nil {
+			return nil, err
+		}
+
+		snapshot := &abci.Snapshot{}
+		if err := json.Unmarshal(bz, snapshot); err != nil {
+			return nil, err
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Height > snapshots[j].Height
+	})
+
+	return snapshots, nil
+}
+
+// pruneSnapshots removes the snapshots that are older than the keepRecent
+// most recent snapshots.
+func (app *VStoreApplication) pruneSnapshots() error {
+	if app.snapshotKeepRecent <= 0 {
+		return nil
+	}
+
+	snapshots, err := app.listSnapshots()
+	if err != nil || len(snapshots) <= app.snapshotKeepRecent {
+		return err
+	}
+
+	for _, snapshot := range snapshots[app.snapshotKeepRecent:] {
+		dir := filepath.Join(app.snapshotDir, strconv.FormatUint(snapshot.Height, 10))
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// snapshotChunkFile returns the path of a chunk of a state sync snapshot.
+func (app *VStoreApplication) snapshotChunkFile(height int64, chunk uint32) string {
+	return filepath.Join(app.snapshotDir, strconv.FormatInt(height, 10), strconv.FormatUint(uint64(chunk), 10))
+}
+
+// abortRestore discards the snapshot that is being restored, if any, and
+// removes the key-value pairs that were restored.
+func (app *VStoreApplication) abortRestore() error {
+	restore := app.restore
+	if restore == nil {
+		return nil
+	}
+
+	app.restore = nil
+	restore.writer.CloseWithError(errors.New("snapshot restore aborted"))
+	restore.wait()
+
+	return clearDB(app.state.db)
+}
+
+// rejectRestore discards the snapshot that is being restored after an error,
+// such that CometBFT offers another snapshot.
+func (app *VStoreApplication) rejectRestore(reason error) (*abci.ResponseApplySnapshotChunk, error) {
+	app.logger.Error("rejected snapshot", "err", reason)
+	if err := app.abortRestore(); err != nil {
+		return nil, err
+	}
+
+	return &abci.ResponseApplySnapshotChunk{Result: abci.ResponseApplySnapshotChunk_REJECT_SNAPSHOT}, nil
+}
+
+// identityPubKey returns the uppercase hexadecimal public key of the identity.
+func (app *VStoreApplication) identityPubKey() (string, error) {
+	if app.Observer() {
+		return "", ErrEncryptionUnavailable
+	}
+
+	id := app.priv.Identity()
+	if priv, ok := id.(ed25519Identity); ok {
+		defer zeroize(priv)
+	}
+
+	pub, err := id.PubKey()
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%X", pub.Bytes()), nil
+}
+
+// hashSnapshotChunks returns the hash of a snapshot, i.e. the hash of the
+// concatenated chunk hashes.
+func hashSnapshotChunks(hashes [][]byte) []byte {
+	return tmhash.Sum(bytes.Join(hashes, nil))
+}
+
+// clearDB deletes all key-value pairs of a database, in batches.
+func clearDB(db cmtdb.DB) error {
+	for {
+		it, err := db.Iterator(nil, nil)
+		if err != nil {
+			return err
+		}
+
+		keys := [][]byte{}
+		for ; it.Valid() && len(keys) < migrateBatchSize; it.Next() {
+			keys = append(keys, append([]byte{}, it.Key()...))
+		}
+
+		err = it.Error()
+		it.Close()
+		if err != nil || len(keys) == 0 {
+			return err
+		}
+
+		batch := db.NewBatch()
+		for _, key := range keys {
+			if err := batch.Delete(key); err != nil {
+				batch.Close()
+				return err
+			}
+		}
+
+		err = batch.WriteSync()
+		batch.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// snapshotChunkWriter implements io.Writer and writes the chunks of a state
+// sync snapshot to files in dir, named by chunk index.
+type snapshotChunkWriter struct {
+	dir    string
+	buf    []byte
+	hashes [][]byte
+}
+
+// Write buffers p and writes the chunks that are complete.
+func (w *snapshotChunkWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for len(w.buf) >= snapshotChunkSize {
+		if err := w.writeChunk(w.buf[:snapshotChunkSize]); err != nil {
+			return 0, err
+		}
+
+		w.buf = append([]byte{}, w.buf[snapshotChunkSize:]...)
+	}
+
+	return len(p), nil
+}
+
+// Close writes the last chunk.
+func (w *snapshotChunkWriter) Close() error {
+	if len(w.buf) == 0 && len(w.hashes) > 0 {
+		return nil
+	}
+
+	return w.writeChunk(w.buf)
+}
+
+// writeChunk writes the next chunk and records its hash.
+func (w *snapshotChunkWriter) writeChunk(chunk []byte) error {
+	file := filepath.Join(w.dir, strconv.Itoa(len(w.hashes)))
+	if err := os.WriteFile(file, chunk, 0600); err != nil {
+		return err
+	}
+
+	w.hashes = append(w.hashes, tmhash.Sum(chunk))
+	return nil
+}
@@ -0,0 +1,154 @@
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+func TestVStoreStateSync(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-state_sync", 2)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	// Snapshots consist of several chunks
+	defer func(size int) { snapshotChunkSize = size }(snapshotChunkSize)
+	snapshotChunkSize = 512
+
+	idFile := filepath.Join(vfsDir, "id")
+	source := NewInMemoryVStoreApplication(idFile, []byte("testpassword"))
+	source.SetSnapshots(filepath.Join(vfsDir, "snapshots"), 2, 2)
+
+	hashes := [][]byte{}
+	for height := 1; height <= 6; height++ {
+		txs := [][]byte{}
+		for _, priv := range ownerPrivs {
+			stx, err := makeTransaction(t, priv, []byte(testComplexValue+strconv.Itoa(height)))
+			require.NoError(t, err, "should create a signed transaction")
+
+			hashes = append(hashes, ComputeHash(stx))
+			txs = append(txs, stx.Bytes())
+		}
+
+		makeBlockCommit(ctx, t, source, height, txs)
+	}
+
+	// Snapshots are written every 2 heights, the 2 most recent are kept
+	resList, err := source.ListSnapshots(ctx, &abci.RequestListSnapshots{})
+	require.NoError(t, err)
+	require.Len(t, resList.Snapshots, 2)
+	assert.EqualValues(t, 6, resList.Snapshots[0].Height)
+	assert.EqualValues(t, 4, resList.Snapshots[1].Height)
+
+	snapshot := resList.Snapshots[0]
+	require.Greater(t, snapshot.Chunks, uint32(1))
+	appHash := source.state.Hash()
+
+	// restoreSnapshot drives the state sync handshake of CometBFT
+	restoreSnapshot := func(target *VStoreApplication, trusted []byte) abci.ResponseApplySnapshotChunk_Result {
+		resOffer, err := target.OfferSnapshot(ctx, &abci.RequestOfferSnapshot{Snapshot: snapshot, AppHash: trusted})
+		require.NoError(t, err)
+		require.Equal(t, abci.ResponseOfferSnapshot_ACCEPT, resOffer.Result)
+
+		for i := uint32(0); i < snapshot.Chunks; i++ {
+			resChunk, err := source.LoadSnapshotChunk(ctx, &abci.RequestLoadSnapshotChunk{
+				Height: snapshot.Height,
+				Format: snapshot.Format,
+				Chunk:  i,
+			})
+			require.NoError(t, err)
+			require.NotEmpty(t, resChunk.Chunk)
+
+			// Corrupted chunks are fetched again from another sender
+			corrupted := append([]byte{}, resChunk.Chunk...)
+			corrupted[0] ^= 0xFF
+			resApply, err := target.ApplySnapshotChunk(ctx, &abci.RequestApplySnapshotChunk{Index: i, Chunk: corrupted, Sender: "faulty"})
+			require.NoError(t, err)
+			require.Equal(t, abci.ResponseApplySnapshotChunk_RETRY, resApply.Result)
+			assert.Equal(t, []uint32{i}, resApply.RefetchChunks)
+			assert.Equal(t, []string{"faulty"}, resApply.RejectSenders)
+
+			resApply, err = target.ApplySnapshotChunk(ctx, &abci.RequestApplySnapshotChunk{Index: i, Chunk: resChunk.Chunk, Sender: "peer"})
+			require.NoError(t, err)
+			if resApply.Result != abci.ResponseApplySnapshotChunk_ACCEPT {
+				return resApply.Result
+			}
+		}
+
+		return abci.ResponseApplySnapshotChunk_ACCEPT
+	}
+
+	// A new node with the same identity restores the snapshot
+	target := NewInMemoryVStoreApplication(idFile, []byte("testpassword"))
+
+	// Snapshots that do not match the trusted app hash are rejected
+	wrongHash := append([]byte{}, appHash...)
+	wrongHash[0] ^= 0xFF
+	assert.Equal(t, abci.ResponseApplySnapshotChunk_REJECT_SNAPSHOT, restoreSnapshot(target, wrongHash))
+
+	keys, err := countKeys(target.state.db)
+	require.NoError(t, err)
+	assert.Zero(t, keys, "rejected snapshots must be removed")
+
+	assert.Equal(t, abci.ResponseApplySnapshotChunk_ACCEPT, restoreSnapshot(target, appHash))
+
+	resInfo, err := target.Info(ctx, &abci.RequestInfo{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 6, resInfo.LastBlockHeight)
+	assert.Equal(t, appHash, resInfo.LastBlockAppHash)
+
+	for _, hash := range hashes {
+		resQuery, err := target.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: hash})
+		require.NoError(t, err)
+		assert.Equal(t, CodeTypeOK, resQuery.Code)
+		assert.NotEmpty(t, resQuery.Value)
+	}
+
+	assert.NoError(t, target.VerifyIntegrity())
+
+	// The restored node commits the next blocks
+	stx, err := makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue))
+	require.NoError(t, err)
+	respFinBlock, _ := makeBlockCommit(ctx, t, target, 7, [][]byte{stx.Bytes()})
+	assert.Equal(t, CodeTypeOK, respFinBlock.TxResults[0].Code)
+
+	// Databases are only restored when empty
+	resOffer, err := target.OfferSnapshot(ctx, &abci.RequestOfferSnapshot{Snapshot: snapshot, AppHash: appHash})
+	require.NoError(t, err)
+	assert.Equal(t, abci.ResponseOfferSnapshot_ABORT, resOffer.Result)
+
+	// Snapshots of another identity, format or hash are rejected
+	otherIdFile := filepath.Join(vfsDir, "other-id")
+	MustGenerateIdentity(otherIdFile, []byte("testpassword"))
+	other := NewInMemoryVStoreApplication(otherIdFile, []byte("testpassword"))
+
+	resOffer, err = other.OfferSnapshot(ctx, &abci.RequestOfferSnapshot{Snapshot: snapshot, AppHash: appHash})
+	require.NoError(t, err)
+	assert.Equal(t, abci.ResponseOfferSnapshot_REJECT, resOffer.Result)
+
+	invalid := *snapshot
+	invalid.Format = SnapshotFormat + 1
+	resOffer, err = target.OfferSnapshot(ctx, &abci.RequestOfferSnapshot{Snapshot: &invalid, AppHash: appHash})
+	require.NoError(t, err)
+	assert.Equal(t, abci.ResponseOfferSnapshot_REJECT_FORMAT, resOffer.Result)
+
+	invalid = *snapshot
+	invalid.Hash = ed25519.GenPrivKey().PubKey().Bytes()
+	resOffer, err = target.OfferSnapshot(ctx, &abci.RequestOfferSnapshot{Snapshot: &invalid, AppHash: appHash})
+	require.NoError(t, err)
+	assert.Equal(t, abci.ResponseOfferSnapshot_REJECT, resOffer.Result)
+
+	// Chunks of pruned snapshots are empty
+	resChunk, err := source.LoadSnapshotChunk(ctx, &abci.RequestLoadSnapshotChunk{Height: 2, Format: SnapshotFormat})
+	require.NoError(t, err)
+	assert.Empty(t, resChunk.Chunk)
+}
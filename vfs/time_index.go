@@ -0,0 +1,206 @@
+package vfs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+)
+
+// timeIndexBucketLayout formats the hour bucket of the time index, in UTC.
+// Buckets are sorted lexicographically in order of time.
+const timeIndexBucketLayout = "2006-01-02T15"
+
+// timeIndexEntry describes a transaction of a bucket of the time index. The
+// time is the transaction timestamp in nanoseconds since the Unix epoch, such
+// that the transactions at the boundaries of a time range are filtered
+// without decrypting them.
+type timeIndexEntry struct {
+	Hash []byte `json:"hash"`
+	Time int64  `json:"time"`
+}
+
+// timeRange describes the inclusive range of a /time query. A zero From or To
+// is an unbounded range.
+type timeRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// errInvalidTimeRange is returned when the range of a /time query cannot be
+// parsed or when it ends before it starts.
+var errInvalidTimeRange = errors.New("invalid time range")
+
+// newTimeRange parses the "from" and "to" RFC3339 parameters of a /time query,
+// e.g.: "/time?from=2024-06-04T00:00:00Z&to=2024-06-04T23:59:59Z".
+func newTimeRange(params url.Values) (timeRange, error) {
+	r := timeRange{}
+	bounds := []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &r.From}, {"to", &r.To}}
+
+	for _, b := range bounds {
+		value := params.Get(b.name)
+		if value == "" {
+			continue
+		}
+
+		ts, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return r, fmt.Errorf("%w: %s: %v", errInvalidTimeRange, b.name, err)
+		}
+
+		*b.dst = ts
+	}
+
+	if !r.From.IsZero() && !r.To.IsZero() && r.To.Before(r.From) {
+		return r, fmt.Errorf("%w: to is before from", errInvalidTimeRange)
+	}
+
+	return r, nil
+}
+
+// Contains returns true if ts is in the range, boundaries are inclusive.
+func (r timeRange) Contains(ts time.Time) bool {
+	return (r.From.IsZero() || !ts.Before(r.From)) && (r.To.IsZero() || !ts.After(r.To))
+}
+
+// addTransactionsByTime appends the staged transaction hashes to the hour
+// buckets of their timestamps in the time index, written with w.
+func (app *VStoreApplication) addTransactionsByTime(w dbWriter) error {
+	buckets := []string{}
+	byBucket := make(map[string][]timeIndexEntry)
+	for _, payload := range app.stage {
+		bucket := string(TimeIndexKey(payload.Time))
+		if _, ok := byBucket[bucket]; !ok {
+			buckets = append(buckets, bucket)
+		}
+
+		byBucket[bucket] = append(byBucket[bucket], timeIndexEntry{
+			Hash: payload.Hash,
+			Time: payload.Time.UnixNano(),
+		})
+	}
+
+	for _, bucket := range buckets {
+		entries, err := app.readTimeIndexBucket([]byte(bucket))
+		if err != nil {
+			return err
+		}
+
+		bz, err := json.Marshal(append(entries, byBucket[bucket]...))
+		if err != nil {
+			return err
+		}
+
+		if err := w.Set([]byte(bucket), bz); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readTimeRange returns the hashes of the transactions with a timestamp in
+// the range, in order of timestamps. Transactions with equal timestamps are
+// kept in order of commitment.
+func (app *VStoreApplication) readTimeRange(r timeRange) ([][]byte, error) {
+	start, end := vfsPrefixKeyByTime, prefixEnd(vfsPrefixKeyByTime)
+	if !r.From.IsZero() {
+		start = TimeIndexKey(r.From)
+	}
+
+	if !r.To.IsZero() {
+		end = prefixEnd(TimeIndexKey(r.To))
+	}
+
+	entries := []timeIndexEntry{}
+	err := iterateRange(app.state.db, start, end, func(_, value []byte) error {
+		bucket := []timeIndexEntry{}
+		if err := json.Unmarshal(value, &bucket); err != nil {
+			return err
+		}
+
+		for _, entry := range bucket {
+			if r.Contains(time.Unix(0, entry.Time)) {
+				entries = append(entries, entry)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time < entries[j].Time
+	})
+
+	hashes := make([][]byte, len(entries))
+	for i, entry := range entries {
+		hashes[i] = entry.Hash
+	}
+
+	return hashes, nil
+}
+
+// readTimeIndexBucket decodes the entries of a bucket of the time index. An
+// empty slice is returned if the bucket does not exist.
+func (app *VStoreApplication) readTimeIndexBucket(dbKey []byte) ([]timeIndexEntry, error) {
+	entries := []timeIndexEntry{}
+
+	data, err := app.state.db.Get(dbKey)
+	if err != nil || len(data) == 0 {
+		return entries, err
+	}
+
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// queryTimeRange returns the transactions with a timestamp in the range of
+// the "from" and "to" parameters of the request path, both inclusive and
+// optional, e.g.: "/time?from=2024-06-04T00:00:00Z&to=2024-06-05T00:00:00Z".
+func (app *VStoreApplication) queryTimeRange(
+	response *abci.ResponseQuery,
+	params url.Values,
+	page pagination,
+) (*abci.ResponseQuery, error) {
+	r, err := newTimeRange(params)
+	if err != nil {
+		response.Code = CodeTypeInvalidFormatError
+		response.Log = err.Error()
+		return response, nil
+	}
+
+	hashes, err := app.readTimeRange(r)
+	if err != nil {
+		return response, err
+	}
+
+	if len(hashes) == 0 {
+		return response, nil
+	}
+
+	plainData, err := app.readTransactionList(hashes, page)
+	if errors.Is(err, errCursorNotFound) {
+		response.Code = CodeTypeInvalidFormatError
+		response.Log = err.Error()
+		return response, nil
+	} else if err != nil {
+		return response, err
+	}
+
+	response.Value = plainData
+	response.Log = "exists"
+	return response, nil
+}
@@ -0,0 +1,102 @@
+package vfs
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	vfsp2p "github.com/securesharelabs/vstore/api/vstore/v1"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+)
+
+func TestVStoreQueryTimeRange(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-query_time_range", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	from := time.Date(2024, 6, 4, 10, 0, 0, 0, time.UTC)
+	to := from.Add(26 * time.Hour)
+	times := []time.Time{
+		from.Add(-time.Second),     // excluded, timestamps are in seconds
+		to,                         // included (upper boundary)
+		from.Add(30 * time.Minute), // included
+		from,                       // included (lower boundary)
+		from.Add(2 * time.Hour),    // included, empty buckets in between
+		to.Add(time.Second),        // excluded
+	}
+
+	hashes := make([][]byte, len(times))
+	for height, ts := range times {
+		stx, err := makeTransactionAt(t, ownerPrivs[0], []byte(testSimpleValue+ts.String()), ts)
+		require.NoError(t, err)
+
+		respFinBlock, _ := makeBlockCommit(ctx, t, vstore, height+1, [][]byte{stx.Bytes()})
+		hashes[height] = respFinBlock.TxResults[0].Data
+	}
+
+	queryTime := func(params url.Values) *abci.ResponseQuery {
+		resp, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/time?" + params.Encode()})
+		require.NoError(t, err)
+		return resp
+	}
+
+	listHashes := func(resp *abci.ResponseQuery) [][]byte {
+		require.Equal(t, CodeTypeOK, resp.Code, resp.Log)
+
+		list := new(vfsp2p.TransactionList)
+		require.NoError(t, list.Unmarshal(resp.Value))
+
+		res := [][]byte{}
+		for _, tx := range list.Transactions {
+			stx, err := FromProto(&tx)
+			require.NoError(t, err)
+			res = append(res, ComputeHash(stx))
+		}
+
+		return res
+	}
+
+	params := url.Values{}
+	params.Set("from", from.Format(time.RFC3339Nano))
+	params.Set("to", to.Format(time.RFC3339Nano))
+
+	// Boundaries are inclusive, in order of timestamps
+	assert.Equal(t, [][]byte{hashes[3], hashes[2], hashes[4], hashes[1]}, listHashes(queryTime(params)))
+
+	// Timezones designate the same instants
+	params.Set("from", from.In(time.FixedZone("EST", -5*3600)).Format(time.RFC3339Nano))
+	assert.Len(t, listHashes(queryTime(params)), 4)
+
+	// Pagination applies to the range
+	params.Set("limit", "2")
+	assert.Equal(t, [][]byte{hashes[3], hashes[2]}, listHashes(queryTime(params)))
+
+	// Ranges are optional on both ends
+	assert.Len(t, listHashes(queryTime(url.Values{"to": {to.Format(time.RFC3339)}})), 5)
+	assert.Len(t, listHashes(queryTime(url.Values{"from": {from.Format(time.RFC3339)}})), 5)
+	assert.Len(t, listHashes(queryTime(url.Values{})), 6)
+
+	// Empty ranges return no value
+	resp := queryTime(url.Values{"from": {"2020-01-01T00:00:00Z"}, "to": {"2020-01-02T00:00:00Z"}})
+	assert.Equal(t, CodeTypeOK, resp.Code)
+	assert.Empty(t, resp.Value)
+
+	// Invalid ranges
+	for _, invalid := range []url.Values{
+		{"from": {"yesterday"}},
+		{"to": {"2024-06-04"}},
+		{"from": {to.Format(time.RFC3339)}, "to": {from.Format(time.RFC3339)}},
+	} {
+		assert.Equal(t, CodeTypeInvalidFormatError, queryTime(invalid).Code, invalid.Encode())
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 
@@ -18,6 +19,23 @@ import (
 	"github.com/cometbft/cometbft/crypto/tmhash"
 )
 
+// txEncodingDomain prefixes the canonical encoding of transactions, such
+// that sign bytes are never valid for another kind of signed message.
+var txEncodingDomain = []byte("vstore.v1.Transaction\x00")
+
+// Tags of the fields of the canonical encoding of transactions
+const (
+	txFieldSigner byte = iota
+	txFieldData
+	txFieldBodyRef
+	txFieldTime
+	txFieldChunk
+	txFieldPrevHash
+	txFieldContentType
+	txFieldChainID
+	txFieldSequence
+)
+
 const (
 	// timestamp uint64 (UTC always)
 	timestampSize = 8
@@ -33,12 +51,18 @@ type SignedTransaction struct {
 	Size      int
 	Time      time.Time
 	Data      TransactionBody
+	Chunk     *Chunk
+	BodyRef   string
+	PrevHash  []byte
+
+	ContentType string
+	ChainID     string
+	Sequence    uint64
 }
 
 // NewSignedTransaction expects a signed data payload which contains
 // an owner public key (32 bytes), a signature (64 bytes), a timestamp
 // and at least 1 byte of arbitrary data.
-// TODO: TBI verification of timestamp (too far in future, etc.)
 // TODO: TBI when to verify signatures (careful with CheckTx)
 func NewSignedTransactionFromBytes(tx []byte) (*SignedTransaction, error) {
 	// Create the transaction from bytes
@@ -47,17 +71,91 @@ func NewSignedTransactionFromBytes(tx []byte) (*SignedTransaction, error) {
 		return nil, err
 	}
 
-	// Compute SHA256 transaction hash
-	if len(stx.Hash) == 0 {
-		stx.Hash = ComputeHash(stx)
+	// Body must be inlined or referenced, not both
+	if len(stx.Data) > 0 && len(stx.BodyRef) > 0 {
+		return nil, errBodyAndReference
+	}
+
+	// Compute SHA256 transaction hash, a provided hash must match
+	hash := ComputeHash(stx)
+	if len(stx.Hash) > 0 && !bytes.Equal(stx.Hash, hash) {
+		return nil, errInvalidHash
 	}
 
+	stx.Hash = hash
+
 	return stx, nil
 }
 
+var _ Signable = (*SignedTransaction)(nil)
+
 // Verify returns a boolean that determines the validity of a signature.
 func (p SignedTransaction) Verify() bool {
-	return p.Signer.VerifySignature(p.Data, p.Signature)
+	return p.Signer.VerifySignature(p.SignBytes(), p.Signature)
+}
+
+// VerifyLegacy returns a boolean that determines the validity of a signature
+// in the legacy format, i.e. a signature that does not cover the timestamp.
+// Legacy signatures are recognized during the transition to signed timestamps.
+// A legacy signature covers the body (or body reference) and the chunk only,
+// it is never valid for a transaction with a chain id, a sequence, a previous
+// hash or a content type, such that these fields cannot be set by anyone else.
+func (p SignedTransaction) VerifyLegacy() bool {
+	if p.ChainID != "" || p.Sequence != 0 || len(p.PrevHash) > 0 || p.ContentType != "" {
+		return false
+	}
+
+	// Canonical sign bytes are never accepted as a legacy body
+	sbz := p.LegacySignBytes()
+	if bytes.HasPrefix(sbz, txEncodingDomain) {
+		return false
+	}
+
+	return p.Signer.VerifySignature(sbz, p.Signature)
+}
+
+// Sign creates a digital signature of the sign bytes, i.e. of the canonical
+// encoding of the transaction, using the private key.
+// Sign implements Signable
+func (p SignedTransaction) Sign(priv ed25519.PrivKey) ([]byte, error) {
+	return priv.Sign(p.SignBytes())
+}
+
+// ValidateTime checks that the transaction timestamp is neither older than
+// maxPast nor newer than maxFuture, relative to now.
+func (p SignedTransaction) ValidateTime(now time.Time, maxPast, maxFuture time.Duration) error {
+	if p.Time.After(now.Add(maxFuture)) {
+		return fmt.Errorf("timestamp too far in the future: %s", p.Time.UTC())
+	}
+
+	if p.Time.Before(now.Add(-maxPast)) {
+		return fmt.Errorf("timestamp too far in the past: %s", p.Time.UTC())
+	}
+
+	return nil
+}
+
+// SignBytes returns the bytes that are signed by the signer, i.e. the
+// canonical encoding of the transaction without the signer, see
+// encodeTransaction. Every field is tagged and length-prefixed, such that
+// the value of a field can never be moved to another field.
+func (p SignedTransaction) SignBytes() []byte {
+	return encodeTransaction(&p, false)
+}
+
+// LegacySignBytes returns the bytes that were signed by the signer before
+// timestamps were signed, i.e. the sign bytes without the timestamp bytes.
+func (p SignedTransaction) LegacySignBytes() []byte {
+	data := []byte(p.Data)
+	if len(p.BodyRef) > 0 {
+		data = []byte(p.BodyRef)
+	}
+
+	if p.Chunk == nil {
+		return data
+	}
+
+	return append(append([]byte{}, data...), p.Chunk.Bytes()...)
 }
 
 // PublicKey returns the uppercase hexadecimal representation
@@ -66,6 +164,20 @@ func (p SignedTransaction) PublicKey() string {
 	return strings.ToUpper(hex.EncodeToString(p.Signer))
 }
 
+// Unresolved returns the transaction as it was committed, i.e. without the
+// body that queries resolve from external storage for a body reference. The
+// hash and the signature of a transaction with a body reference cover the
+// reference only, they are computed and verified with the unresolved
+// transaction. Other transactions are returned unchanged.
+func (p SignedTransaction) Unresolved() *SignedTransaction {
+	if len(p.BodyRef) > 0 {
+		p.Data = nil
+		p.Size = 0
+	}
+
+	return &p
+}
+
 // Bytes returns a byte slice built from the size-prefixed
 // data and the signature.
 func (p SignedTransaction) Bytes() []byte {
@@ -100,6 +212,15 @@ func (p SignedTransaction) ToProto() *vfsp2p.Transaction {
 	tx.Len = uint32(len(p.Data))
 	tx.Body = p.Data
 
+	if p.Chunk != nil {
+		tx.Chunk = p.Chunk.ToProto()
+	}
+
+	tx.BodyRef = p.BodyRef
+	tx.PrevHash = p.PrevHash
+	tx.ContentType = p.ContentType
+	tx.ChainId = p.ChainID
+	tx.Sequence = p.Sequence
 	return tx
 }
 
@@ -107,23 +228,64 @@ func (p SignedTransaction) ToProto() *vfsp2p.Transaction {
 // Helpers
 
 // ComputeHash computes the SHA256 hash of a signed transaction
-// The transaction hash consists of a SHA256 of the signer public key,
-// followed by the data and the attached timestamp bytes.
+// The transaction hash is the SHA256 of the canonical encoding of the
+// transaction with the signer public key, see encodeTransaction, i.e. of
+// every field except the signature and the hash.
 func ComputeHash(p *SignedTransaction) []byte {
-	psize := ed25519.PubKeySize
+	// Tx hash is: sha256(domain || signer || data || ... || sequence)
+	return tmhash.Sum(encodeTransaction(p, true))
+}
 
-	// Timestamp bytes attached to hashed message
-	tzb := make([]byte, 8)
-	binary.BigEndian.PutUint64(tzb, uint64(p.Time.Unix()))
+// TimestampBytes returns the 8 bytes (big endian) of a timestamp in seconds.
+func TimestampBytes(t time.Time) []byte {
+	tzb := make([]byte, timestampSize)
+	binary.BigEndian.PutUint64(tzb, uint64(t.Unix()))
+	return tzb
+}
 
-	// Tx hash is: sha256(owner || data || sigtime)
-	var hbuf bytes.Buffer
-	hbuf.Grow(psize + p.Size + timestampSize)
-	hbuf.Write(p.Signer) // adding pubkey
-	hbuf.Write(p.Data)   // adding data
-	hbuf.Write(tzb)      // adding timestamp
+// SequenceBytes returns the 8 bytes (big endian) of a sequence number.
+func SequenceBytes(sequence uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, sequence)
+}
+
+// encodeTransaction returns the canonical encoding of the transaction p which
+// is signed and hashed. The encoding starts with txEncodingDomain, followed by
+// the signer public key (only if withSigner is true), the data, the body
+// reference, the timestamp bytes, the chunk information, the previous hash,
+// the content type, the chain id and the sequence bytes. Every field is
+// written as its tag (1 byte), its length (uvarint) and its value, also when
+// the value is empty.
+func encodeTransaction(p *SignedTransaction, withSigner bool) []byte {
+	var chunk []byte
+	if p.Chunk != nil {
+		chunk = p.Chunk.Bytes()
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(len(txEncodingDomain) + ed25519.PubKeySize + len(p.Data) + len(p.BodyRef) + 128)
+	buf.Write(txEncodingDomain)
+
+	if withSigner {
+		writeTxField(&buf, txFieldSigner, p.Signer)
+	}
 
-	return tmhash.Sum(hbuf.Bytes())
+	writeTxField(&buf, txFieldData, p.Data)
+	writeTxField(&buf, txFieldBodyRef, []byte(p.BodyRef))
+	writeTxField(&buf, txFieldTime, TimestampBytes(p.Time))
+	writeTxField(&buf, txFieldChunk, chunk)
+	writeTxField(&buf, txFieldPrevHash, p.PrevHash)
+	writeTxField(&buf, txFieldContentType, []byte(p.ContentType))
+	writeTxField(&buf, txFieldChainID, []byte(p.ChainID))
+	writeTxField(&buf, txFieldSequence, SequenceBytes(p.Sequence))
+	return buf.Bytes()
+}
+
+// writeTxField writes a field of the canonical encoding of transactions, i.e.
+// the tag, the length of the value (uvarint) and the value.
+func writeTxField(buf *bytes.Buffer, tag byte, value []byte) {
+	buf.WriteByte(tag)
+	buf.Write(binary.AppendUvarint(nil, uint64(len(value))))
+	buf.Write(value)
 }
 
 // FromProto takes a transaction proto message and returns the SignedTransaction.
@@ -140,11 +302,24 @@ func FromProto(pb *vfsp2p.Transaction) (*SignedTransaction, error) {
 	tx.Size = int(pb.Len)
 	tx.Time = pb.Time
 	tx.Data = pb.Body
+	tx.BodyRef = pb.BodyRef
+	tx.PrevHash = pb.PrevHash
+	tx.ContentType = pb.ContentType
+	tx.ChainID = pb.ChainId
+	tx.Sequence = pb.Sequence
 
 	if len(pb.Hash) != 0 {
 		tx.Hash = pb.Hash
 	}
 
+	if pb.Chunk != nil {
+		tx.Chunk = &Chunk{
+			Root:  pb.Chunk.Root,
+			Index: pb.Chunk.Index,
+			Total: pb.Chunk.Total,
+		}
+	}
+
 	return tx, nil
 }
 
@@ -159,6 +334,14 @@ func FromBytes(bz []byte) (*SignedTransaction, error) {
 	return FromProto(tx)
 }
 
+// errBodyAndReference is returned when a transaction sets both an inline
+// body and a reference to external storage.
+var errBodyAndReference = errors.New("transaction must not contain both body and body reference")
+
+// errInvalidHash is returned when the transaction hash does not match the
+// hash that is computed from the transaction.
+var errInvalidHash = errors.New("transaction hash does not match computed hash")
+
 func PubKeyToProto(pubKey crypto.PubKey) cmtp2p.PublicKey {
 	return cmtp2p.PublicKey{
 		Sum: &cmtp2p.PublicKey_Ed25519{
@@ -1,6 +1,8 @@
 package vfs
 
 import (
+	"time"
+
 	"github.com/cometbft/cometbft/crypto/ed25519"
 )
 
@@ -28,6 +30,8 @@ var _ Signable = (*TransactionBody)(nil)
 // Sign creates a digital signature of the bytes using the private
 // key implementation for ed25519. Only ed25519 compatibility is added
 // for now because of being able to batch verify ed25519 signatures.
+// Note: This signature does not cover a timestamp (legacy format), use
+// SignAt to sign transaction bodies.
 // Sign implements Signable
 func (p TransactionBody) Sign(priv ed25519.PrivKey) ([]byte, error) {
 	// Sign data using the private key
@@ -39,6 +43,19 @@ func (p TransactionBody) Sign(priv ed25519.PrivKey) ([]byte, error) {
 	return sig, nil
 }
 
+// SignAt creates a digital signature of a transaction with the bytes as its
+// body at the timestamp, such that the timestamp of a transaction can not be
+// altered, see SignedTransaction.SignBytes.
+func (p TransactionBody) SignAt(priv ed25519.PrivKey, t time.Time) ([]byte, error) {
+	// Sign the canonical encoding of data and timestamp using the private key
+	sig, err := priv.Sign(SignedTransaction{Data: p, Time: t}.SignBytes())
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return sig, nil
+}
+
 // Bytes returns a byte representation of unsigned data.
 // Bytes implements Signable
 func (p TransactionBody) Bytes() []byte {
@@ -2,6 +2,7 @@ package vfs
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 
 	vfsp2p "github.com/securesharelabs/vstore/api/vstore/v1"
 
+	abci "github.com/cometbft/cometbft/abci/types"
 	"github.com/cometbft/cometbft/crypto/ed25519"
 )
 
@@ -67,20 +69,93 @@ func TestVStoreTxFromBytes(t *testing.T) {
 	assert.Equal(t, pb.Signature, tx.Signature)
 }
 
+func TestVStoreTxSignedTimestamp(t *testing.T) {
+	_, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "vstore-tx-signed_timestamp", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	stx, err := makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue))
+	require.NoError(t, err)
+	assert.True(t, stx.Verify(), "signature must be valid")
+
+	// Body signature with SignAt is equivalent
+	sig, err := TransactionBody(testSimpleValue).SignAt(ed25519.PrivKey(ownerPrivs[0]), stx.Time)
+	require.NoError(t, err)
+	assert.Equal(t, stx.Signature, sig)
+
+	// Tampering with the timestamp invalidates the signature
+	stx.Time = stx.Time.Add(time.Hour)
+	assert.False(t, stx.Verify(), "signature must not be valid after tampering timestamp")
+	assert.False(t, stx.VerifyLegacy(), "signature must not be valid in legacy format")
+
+	// Legacy signatures do not cover the timestamp
+	legacy, err := makeLegacyTransaction(t, ownerPrivs[0], []byte(testSimpleValue))
+	require.NoError(t, err)
+	assert.False(t, legacy.Verify())
+	assert.True(t, legacy.VerifyLegacy())
+}
+
+func TestVStoreTxCanonicalEncoding(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "vstore-tx-canonical_encoding", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+	stx := makeSequencedTransaction(t, ownerPrivs[0], []byte(testSimpleValue), 1, time.Now())
+	require.True(t, stx.Verify())
+
+	// Values of a field cannot be moved to another field
+	for name, reencode := range map[string]func(tx *SignedTransaction){
+		"sequence as chain id": func(tx *SignedTransaction) {
+			tx.Sequence, tx.ChainID = 0, string(SequenceBytes(tx.Sequence))
+		},
+		"data as body reference": func(tx *SignedTransaction) {
+			tx.Data, tx.BodyRef = nil, string(tx.Data)
+		},
+		"data as content type": func(tx *SignedTransaction) {
+			tx.Data, tx.ContentType = TransactionBody(testSimpleValue[:3]), testSimpleValue[3:]
+		},
+	} {
+		forged := *stx
+		reencode(&forged)
+		forged.Size = len(forged.Data)
+		forged.Hash = ComputeHash(&forged)
+
+		assert.False(t, forged.Verify(), name)
+		assert.False(t, forged.VerifyLegacy(), name)
+		assert.NotEqual(t, stx.Hash, forged.Hash, name)
+
+		resp, err := vstore.CheckTx(ctx, &abci.RequestCheckTx{Tx: forged.Bytes()})
+		require.NoError(t, err)
+		assert.NotEqual(t, CodeTypeOK, resp.Code, name)
+	}
+
+	// Sign bytes are not valid as a legacy body
+	legacy := *stx
+	legacy.Data = TransactionBody(stx.SignBytes())
+	legacy.Size = len(legacy.Data)
+	legacy.Sequence = 0
+	assert.False(t, legacy.VerifyLegacy())
+}
+
 // --------------------------------------------------------------------------
 
-func makeSignature(t *testing.T, privKey, data []byte) ([]byte, error) {
+func makeSignature(t testing.TB, privKey, data []byte) ([]byte, error) {
 	t.Helper()
 
+	// No data means no signature (of the data)
 	priv := ed25519.PrivKey(privKey)
-	sig, err := priv.Sign([]byte(testSimpleValue))
-	if err != nil {
-		return []byte{}, err
+	if len(data) == 0 {
+		return priv.Sign([]byte(testSimpleValue))
 	}
 
-	// No data means no signature
-	if len(data) == 0 {
-		return sig, nil
+	sig, err := priv.Sign(data)
+	if err != nil {
+		return []byte{}, err
 	}
 
 	verifiable := priv.PubKey().VerifySignature(data, sig)
@@ -89,7 +164,34 @@ func makeSignature(t *testing.T, privKey, data []byte) ([]byte, error) {
 	return sig, nil
 }
 
-func makeTransaction(t *testing.T, privKey, data []byte) (*SignedTransaction, error) {
+func makeTransaction(t testing.TB, privKey, data []byte) (*SignedTransaction, error) {
+	t.Helper()
+
+	return makeTransactionAt(t, privKey, data, time.Now())
+}
+
+func makeTransactionAt(t testing.TB, privKey, data []byte, ts time.Time) (*SignedTransaction, error) {
+	t.Helper()
+
+	priv := ed25519.PrivKey(privKey)
+
+	tx := new(vfsp2p.Transaction)
+	tx.Signer = PubKeyToProto(priv.PubKey())
+	tx.Time = ts
+	tx.Len = uint32(len(data))
+	tx.Body = data
+
+	stx, err := FromProto(tx)
+	require.NoError(t, err, "should create transaction from protobuf schema")
+
+	// Signature covers data and timestamp
+	stx.Signature = SignData(priv, stx)
+	require.Len(t, stx.Signature, ed25519.SignatureSize)
+
+	return stx, err
+}
+
+func makeLegacyTransaction(t testing.TB, privKey, data []byte) (*SignedTransaction, error) {
 	t.Helper()
 
 	priv := ed25519.PrivKey(privKey)
@@ -0,0 +1,187 @@
+package vfs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+
+	"github.com/cometbft/cometbft/crypto/ed25519"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// Transactions are encrypted with a key that is derived from the node secret
+// and from the public key of the signer, i.e. every signer has its own key.
+//
+// Threat model: the node must decrypt transactions to serve queries, such that
+// the node identity (and its password) can still derive the keys of all
+// signers, and a compromise of the node identity exposes all stored data.
+// Signer keys separate the data of signers otherwise: a signer key that is
+// disclosed, e.g. handed to a signer to decrypt its transactions off-node,
+// decrypts the transactions of that signer only, and does not reveal the node
+// secret or the keys of other signers (HKDF, RFC 5869).
+//
+// The public key of the signer is stored in clear in front of the ciphertext,
+// it is not a secret as the signer index records it as well.
+//
+// Every stored transaction starts with a header whose magic is the type of the
+// record, i.e. "VSTX" for transactions encrypted with a signer key, "VSPT" for
+// plaintext transactions and "VSPR" for pruned transactions. Transactions that
+// were encrypted with the node secret without header, before signer keys were
+// introduced, are not read: their random nonce could start with the header of
+// another record type, and States of these versions are refused.
+
+// txKeyMagic and txKeyVersion1 describe the header that is prepended to the
+// transactions that are encrypted with a signer key:
+// magic (4) || version (1) || signer public key (32) || nonce || ciphertext
+var txKeyMagic = []byte("VSTX")
+
+const (
+	txKeyVersion1  byte = 1
+	txKeyHeaderLen      = 5 + ed25519.PubKeySize
+)
+
+// errUnknownRecord is returned when a stored transaction does not start with
+// the header of a known record type.
+var errUnknownRecord = errors.New("stored transaction has no known record header")
+
+// txKeyInfo is the HKDF context of signer keys.
+var txKeyInfo = []byte("vstore/transaction-key/v1")
+
+// DeriveSignerKey derives the AES-256 key that encrypts the transactions of the
+// signer with public key pub from the node secret. The key can decrypt the
+// transactions of this signer only, see DecryptWithSignerKey.
+func DeriveSignerKey(secret, pub []byte) ([]byte, error) {
+	if len(secret) == 0 {
+		return []byte{}, errors.New("secret must not be empty")
+	}
+
+	if len(pub) != ed25519.PubKeySize {
+		return []byte{}, errors.New("invalid signer public key size")
+	}
+
+	info := append(append([]byte{}, txKeyInfo...), pub...)
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, nil, info), key); err != nil {
+		return []byte{}, err
+	}
+
+	return key, nil
+}
+
+// DecryptWithSignerKey decrypts a stored transaction with the signer key of
+// its signer, as derived with DeriveSignerKey, and decompresses it.
+func DecryptWithSignerKey(key, ciphertext []byte) ([]byte, error) {
+	if !hasTxKeyHeader(ciphertext) {
+		return []byte{}, errors.New("transaction is not encrypted with a signer key")
+	}
+
+	data, err := Decrypt(key, ciphertext[txKeyHeaderLen:])
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return decompressTransaction(data)
+}
+
+// --------------------------------------------------------------------------
+// Private helpers
+
+// sealTransaction compresses the transaction txData with the application's
+// codec, if any, and encrypts it with the key of the signer with public key
+// pub which is derived from the node secret. In plaintext mode, the transaction
+// is stored with the plaintext storage header instead, see SetPlaintext.
+func (app *VStoreApplication) sealTransaction(secret, pub, txData []byte) ([]byte, error) {
+	data, err := compressTransaction(app.compression, txData)
+	if err != nil {
+		return nil, err
+	}
+
+	if app.compression != CompressionNone {
+		defer zeroize(data)
+	}
+
+	if app.plaintext {
+		return storePlaintext(data), nil
+	}
+
+	key, err := DeriveSignerKey(secret, pub)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroize(key)
+
+	ct, err := Encrypt(key, data)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, txKeyHeaderLen+len(ct))
+	header = append(header, txKeyMagic...)
+	header = append(header, txKeyVersion1)
+	header = append(header, pub...)
+	return append(header, ct...), nil
+}
+
+// openTransaction decrypts a stored transaction with the key of its signer
+// which is derived from the node secret, and decompresses it. Plaintext
+// transactions are not decrypted. The type of the record is read from its
+// header, errUnknownRecord is returned for records without a known header.
+func openTransaction(secret, ciphertext []byte) ([]byte, error) {
+	// Plaintext transactions are copied, such that callers may zeroize them
+	if isPlaintextTransaction(ciphertext) {
+		return decompressTransaction(append([]byte{}, ciphertext[plaintextHeaderLen:]...))
+	}
+
+	if !hasTxKeyHeader(ciphertext) {
+		return nil, errUnknownRecord
+	}
+
+	key, err := DeriveSignerKey(secret, ciphertext[5:txKeyHeaderLen])
+	if err != nil {
+		return nil, err
+	}
+	defer zeroize(key)
+
+	data, err := Decrypt(key, ciphertext[txKeyHeaderLen:])
+	if err != nil {
+		return nil, err
+	}
+
+	return decompressTransaction(data)
+}
+
+// recordSigner returns the public key of the signer of a stored transaction
+// without the node secret: encrypted and pruned records store the signer in
+// their header, plaintext records are decoded.
+func recordSigner(data []byte) ([]byte, error) {
+	switch {
+	case hasTxKeyHeader(data):
+		return data[5:txKeyHeaderLen], nil
+	case isPrunedTransaction(data):
+		return prunedSigner(data), nil
+	case isPlaintextTransaction(data):
+		txData, err := openTransaction(nil, data)
+		if err != nil {
+			return nil, err
+		}
+
+		stx, err := FromBytes(txData)
+		if err != nil {
+			return nil, err
+		}
+
+		return stx.Signer.Bytes(), nil
+	default:
+		return nil, errUnknownRecord
+	}
+}
+
+// hasTxKeyHeader returns true if ciphertext starts with the header of the
+// transactions that are encrypted with a signer key.
+func hasTxKeyHeader(ciphertext []byte) bool {
+	return len(ciphertext) > txKeyHeaderLen &&
+		bytes.HasPrefix(ciphertext, txKeyMagic) &&
+		ciphertext[len(txKeyMagic)] == txKeyVersion1
+}
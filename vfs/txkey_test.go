@@ -0,0 +1,129 @@
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cmtdb "github.com/cometbft/cometbft-db"
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+)
+
+func TestDeriveSignerKey(t *testing.T) {
+	secret, err := readRandom(32)
+	require.NoError(t, err)
+
+	pub1 := ed25519.GenPrivKey().PubKey().Bytes()
+	pub2 := ed25519.GenPrivKey().PubKey().Bytes()
+
+	key1, err := DeriveSignerKey(secret, pub1)
+	require.NoError(t, err)
+	assert.Len(t, key1, 32)
+
+	again, err := DeriveSignerKey(secret, pub1)
+	require.NoError(t, err)
+	assert.Equal(t, key1, again, "signer keys must be deterministic")
+
+	key2, err := DeriveSignerKey(secret, pub2)
+	require.NoError(t, err)
+	assert.NotEqual(t, key1, key2, "signers must have distinct keys")
+	assert.NotEqual(t, secret, key1)
+
+	otherSecret, err := readRandom(32)
+	require.NoError(t, err)
+	other, err := DeriveSignerKey(otherSecret, pub1)
+	require.NoError(t, err)
+	assert.NotEqual(t, key1, other, "nodes must have distinct keys")
+
+	_, err = DeriveSignerKey([]byte{}, pub1)
+	assert.Error(t, err)
+	_, err = DeriveSignerKey(secret, pub1[:16])
+	assert.Error(t, err)
+}
+
+func TestVStoreSignerKeys(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-signer_keys", 2)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	db := cmtdb.NewMemDB()
+	vstore := NewVStoreApplication(db, filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	secret, err := vstore.priv.Identity().Secret()
+	require.NoError(t, err)
+
+	// Commit one transaction of each signer
+	stxs := []*SignedTransaction{}
+	txs := [][]byte{}
+	for _, priv := range ownerPrivs {
+		stx, err := makeTransaction(t, priv, []byte(testComplexValue))
+		require.NoError(t, err)
+
+		stx.Hash = ComputeHash(stx)
+		stxs = append(stxs, stx)
+		txs = append(txs, stx.Bytes())
+	}
+
+	makeBlockCommit(ctx, t, vstore, 1, txs)
+
+	keys := make([][]byte, len(stxs))
+	for i, stx := range stxs {
+		pub := stx.Signer.Bytes()
+		keys[i], err = DeriveSignerKey(secret, pub)
+		require.NoError(t, err)
+
+		stored, err := db.Get(TransactionKey(stx.Hash))
+		require.NoError(t, err)
+		require.True(t, hasTxKeyHeader(stored))
+		assert.Equal(t, pub, stored[5:txKeyHeaderLen], "header must contain the signer")
+
+		// The node secret does not decrypt the transaction
+		_, err = Decrypt(secret, stored[txKeyHeaderLen:])
+		assert.Error(t, err)
+
+		// The node decrypts transactions of all signers
+		resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: stx.Hash})
+		require.NoError(t, err)
+		assert.Equal(t, stx.Bytes(), resQuery.Value)
+	}
+
+	// A signer key decrypts the transactions of its signer only
+	for i, key := range keys {
+		for j, stx := range stxs {
+			stored, err := db.Get(TransactionKey(stx.Hash))
+			require.NoError(t, err)
+
+			txData, err := DecryptWithSignerKey(key, stored)
+			if i != j {
+				assert.Error(t, err, "signer %d must not decrypt transactions of signer %d", i, j)
+				continue
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, stx.Bytes(), txData)
+		}
+	}
+
+	assert.NoError(t, vstore.VerifyIntegrity())
+
+	// Records without header, e.g. encrypted with the node secret by older
+	// versions, are not read
+	legacy, err := makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue))
+	require.NoError(t, err)
+	legacy.Hash = ComputeHash(legacy)
+
+	ct, err := Encrypt(secret, legacy.Bytes())
+	require.NoError(t, err)
+
+	_, err = openTransaction(secret, ct)
+	assert.ErrorIs(t, err, errUnknownRecord)
+
+	_, err = DecryptWithSignerKey(keys[0], ct)
+	assert.Error(t, err)
+}
@@ -1,25 +1,74 @@
 package vfs
 
 import (
+	"bytes"
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
-	"log"
-	"strconv"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	vfsp2p "github.com/securesharelabs/vstore/api/vstore/v1"
 
 	cmtdb "github.com/cometbft/cometbft-db"
 
 	abci "github.com/cometbft/cometbft/abci/types"
-	"github.com/cometbft/cometbft/crypto/merkle"
 	cmtlog "github.com/cometbft/cometbft/libs/log"
+	cmttypes "github.com/cometbft/cometbft/types"
 	"github.com/cometbft/cometbft/version"
 )
 
 const (
-	AppVersion        uint64 = 1
+	// AppVersion 2 commits the signer merkle roots as merkle trees of all
+	// signer transaction hashes, the app hashes of version 1 differ.
+	// AppVersion 3 commits the transactions of a block in order of hash, the
+	// app hashes of blocks with several transactions of a signer may differ.
+	// AppVersion 4 signs and hashes the canonical encoding of transactions,
+	// the signatures and the hashes of version 3 differ. It also commits the
+	// public key of every signer with its merkle root in the app hash.
+	// States of other versions are refused, upgrades must be coordinated
+	// with all nodes, see ErrOutdatedStateVersion.
+	AppVersion        uint64 = 4
 	QueryType_Default string = "hash"
 	QueryType_Height  string = "height"
 	QueryType_PubKey  string = "pubkey"
+	QueryType_Chunks  string = "chunks"
+
+	QueryType_Root        string = "root"
+	QueryType_RootVerify  string = "root/verify"
+	QueryType_State       string = "state"
+	QueryType_BlockTime   string = "blocktime/height"
+	QueryType_SignerAt    string = "signer/height"
+	QueryType_AppHash     string = "apphash/height"
+	QueryType_HeightCount string = "height/count"
+	QueryType_PubKeyCount string = "pubkey/count"
+	QueryType_Sequence    string = "pubkey/sequence"
+	QueryType_Time        string = "time"
+	QueryType_Signers     string = "signers"
+
+	// DefaultQueryLimit is the number of transactions returned by index
+	// queries when no limit is provided.
+	DefaultQueryLimit int = 50
+
+	// MaxQueryLimit is the maximum number of transactions returned by index
+	// queries, greater limits are clamped to this value.
+	MaxQueryLimit int = 500
+
+	// GenesisAppHash is the hexadecimal app hash of an empty state, as returned
+	// in InitChain. It is pinned to detect determinism breaks across versions.
+	GenesisAppHash string = "0000000000000000000000000000000000000000000000000000000000000000"
+
+	// DefaultMaxPastDrift is the maximum age of a transaction timestamp.
+	DefaultMaxPastDrift = 24 * time.Hour
+
+	// DefaultMaxFutureDrift is the maximum time that a transaction timestamp
+	// can be ahead of the node's clock (or of the block time).
+	DefaultMaxFutureDrift = 10 * time.Minute
 )
 
 var _ abci.Application = (*VStoreApplication)(nil)
@@ -30,36 +79,182 @@ type VStoreApplication struct {
 
 	state  State
 	stage  []SignedTransaction
+	time   time.Time
+	chunks map[string]*chunkManifest
+	blobs  BlobStore
 	logger cmtlog.Logger
 
+	maxPastDrift   time.Duration
+	maxFutureDrift time.Duration
+
+	paused atomic.Bool
+
+	// legacySignatures accepts signatures that do not cover the timestamp
+	legacySignatures bool
+
+	// checkTxMode selects stateless or stateful checks in CheckTx
+	checkTxMode CheckTxMode
+
+	// finalizeWorkers is the number of workers that parse transactions in
+	// FinalizeBlock, transactions are parsed sequentially with 0 or 1
+	finalizeWorkers int
+
+	// bodyValidator validates bodies in CheckTx, e.g. with a JSON schema
+	bodyValidator BodyValidator
+
+	// maxBodySize limits the size of bodies in CheckTx, 0 means no limit
+	maxBodySize int
+
+	// compression is the codec of new transactions, as set with SetCompression
+	compression Compression
+
+	// plaintext stores new transactions unencrypted, as set with SetPlaintext
+	plaintext bool
+
+	// requiredKDF is the key derivation function of the identity file, if
+	// any, as set with WithKDF
+	requiredKDF *KDF
+
+	// lifecycle guards closing, active counts the FinalizeBlock and Commit
+	// calls in progress and pendingBlock is set between FinalizeBlock and
+	// Commit, such that Shutdown never saves an uncommitted state
+	lifecycle    sync.Mutex
+	closing      bool
+	active       sync.WaitGroup
+	pendingBlock bool
+
+	// tail is the last page of the signer index that was last appended to
+	tail *signerIndexTail
+
+	// events receives an event for every committed transaction
+	events      EventSink
+	eventFormat EventFormat
+
+	// metrics are recorded in CheckTx, FinalizeBlock and Commit, if any
+	metrics *Metrics
+
+	// snapshotDir contains the state sync snapshots which are written every
+	// snapshotInterval heights, as set with SetSnapshots, and restore is the
+	// snapshot that is being restored
+	snapshotDir        string
+	snapshotInterval   int64
+	snapshotKeepRecent int
+	restore            *snapshotRestore
+
+	// retention prunes committed transactions every retentionInterval
+	// heights, as set with SetRetention
+	retention         RetentionPolicy
+	retentionInterval int64
+
 	priv SecretProvider
 }
 
 // NewVStoreApplication creates a vfs application using a DB to load the State
-// and an ed25519 identity to encrypt/decrypt database entities.
+// and an ed25519 identity to encrypt/decrypt database entities. Options, e.g.
+// WithLogger, are applied after the defaults.
+// This function will panic if any errors occur.
 func NewVStoreApplication(
 	db cmtdb.DB,
 	id_file string,
 	password []byte,
+	opts ...Option,
 ) *VStoreApplication {
+	app, err := NewVStoreApplicationE(db, id_file, password, opts...)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return app
+}
+
+// NewVStoreApplicationE creates a vfs application like NewVStoreApplication
+// or returns an error if the identity cannot be opened or the State cannot be
+// loaded.
+func NewVStoreApplicationE(
+	db cmtdb.DB,
+	id_file string,
+	password []byte,
+	opts ...Option,
+) (*VStoreApplication, error) {
 
 	// Opens the identity file to read the public key.
 	// This also makes sure that the provided identity is valid.
-	provider := NewIdentity(id_file, password)
-	pubkey, err := provider.Identity().PubKey()
+	provider, err := NewIdentityE(id_file, password)
 	if err != nil {
-		panic(err.Error())
+		return nil, err
+	}
+
+	pbz, err := provider.Open()
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt id file: %v", err)
+	}
+	defer zeroize(pbz)
+
+	pubkey, err := ed25519Identity(pbz).PubKey()
+	if err != nil {
+		return nil, err
+	}
+
+	app, err := newVStoreApplication(db, provider, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if app.requiredKDF != nil {
+		kdf, err := provider.KDF()
+		if err != nil {
+			return nil, err
+		}
+
+		if kdf != *app.requiredKDF {
+			return nil, fmt.Errorf("identity file uses key derivation function %s, expected %s", kdf, *app.requiredKDF)
+		}
 	}
 
-	log.Printf("using identity: %x", pubkey.Bytes())
+	app.logger.Info("using identity", "pubkey", fmt.Sprintf("%x", pubkey.Bytes()))
+	app.logger.Info("loaded state", "height", app.state.Height, "txs", app.state.NumTransactions)
+	return app, nil
+}
 
-	// TODO: verify integrity upon loadState
+// newVStoreApplication loads the State from db and creates a vfs application
+// with the defaults, then applies the options. The provider is nil in observer
+// mode, see NewObserverVStoreApplication.
+func newVStoreApplication(
+	db cmtdb.DB,
+	provider SecretProvider,
+	opts ...Option,
+) (*VStoreApplication, error) {
+	// Integrity is verified on demand with VerifyIntegrity, which reads
+	// and decrypts all transactions (see vstore verify --integrity)
+	state, err := readState(db)
+	if err != nil {
+		return nil, err
+	}
 
-	return &VStoreApplication{
+	app := &VStoreApplication{
 		logger: cmtlog.NewNopLogger(),
-		state:  loadState(db),
+		state:  state,
+		chunks: make(map[string]*chunkManifest),
 		priv:   provider,
+
+		maxPastDrift:   DefaultMaxPastDrift,
+		maxFutureDrift: DefaultMaxFutureDrift,
+
+		eventFormat: EventFormatJSON,
+	}
+
+	for _, opt := range opts {
+		opt(app)
+	}
+
+	// States saved before signer counts were introduced count the signer index
+	if app.state.SignerCounts == nil && len(app.state.MerkleRoots) > 0 {
+		if err := app.backfillSignerCounts(); err != nil {
+			return nil, err
+		}
 	}
+
+	return app, nil
 }
 
 // NewInMemoryApplication creates a new application from an in memory database.
@@ -67,25 +262,124 @@ func NewVStoreApplication(
 func NewInMemoryVStoreApplication(
 	id_file string,
 	password []byte,
+	opts ...Option,
 ) *VStoreApplication {
-	return NewVStoreApplication(cmtdb.NewMemDB(), id_file, password)
+	return NewVStoreApplication(cmtdb.NewMemDB(), id_file, password, opts...)
+}
+
+// SetLogger sets the logger of the application. A nil logger discards all
+// log entries.
+func (app *VStoreApplication) SetLogger(logger cmtlog.Logger) {
+	if logger == nil {
+		logger = cmtlog.NewNopLogger()
+	}
+
+	app.logger = logger
+}
+
+// SetBlobStore sets the external storage which is used to resolve the body
+// references of transactions upon queries.
+func (app *VStoreApplication) SetBlobStore(blobs BlobStore) {
+	app.blobs = blobs
+}
+
+// SetTimestampWindow sets the drift window of transaction timestamps, i.e.
+// transactions older than maxPast or newer than maxFuture are rejected.
+func (app *VStoreApplication) SetTimestampWindow(maxPast, maxFuture time.Duration) {
+	app.maxPastDrift = maxPast
+	app.maxFutureDrift = maxFuture
+}
+
+// SetLegacySignatures sets whether signatures in the legacy format, i.e. that
+// do not cover the timestamp, are accepted. Legacy signatures are rejected by
+// default, e.g. for older clients of a network that still sign the body only.
+func (app *VStoreApplication) SetLegacySignatures(allow bool) {
+	app.legacySignatures = allow
+}
+
+// Pause stops the acceptance of new transactions, queries are still served.
+func (app *VStoreApplication) Pause() {
+	app.paused.Store(true)
+}
+
+// Resume restarts the acceptance of new transactions after Pause.
+func (app *VStoreApplication) Resume() {
+	app.paused.Store(false)
+}
+
+// Paused returns true if the acceptance of new transactions is paused.
+func (app *VStoreApplication) Paused() bool {
+	return app.paused.Load()
 }
 
 // validateTx validates that the bytes slice is not empty, and that the data
 // contains at least the 32 bytes of the owner pubkey, 64 bytes of the signature
-// and 1 byte of arbitrary data.
-func (app *VStoreApplication) validateTx(tx []byte) uint32 {
+// and 1 byte of arbitrary data. The timestamp must be in the drift window
+// relative to now.
+func (app *VStoreApplication) validateTx(tx []byte, now time.Time) uint32 {
+	stx, code := app.validateTxBasic(tx, now)
+	if code != CodeTypeOK {
+		return code
+	}
+
+	return app.validateSignature(stx)
+}
+
+// validateTxBasic performs the checks of validateTx except the signature check
+// and returns the parsed transaction, such that signatures can be verified in
+// a batch.
+func (app *VStoreApplication) validateTxBasic(tx []byte, now time.Time) (*SignedTransaction, uint32) {
 	// Expects valid marshalled format for vfsp2p.Transaction
 	stx, err := FromBytes(tx)
 	if err != nil {
-		return CodeTypeInvalidFormatError
+		return nil, CodeTypeInvalidFormatError
+	}
+
+	// Body must be inlined or referenced, not both
+	if len(stx.Data) > 0 && len(stx.BodyRef) > 0 {
+		return nil, CodeTypeInvalidFormatError
+	}
+
+	if len(stx.BodyRef) == 0 && (stx.Size == 0 || len(stx.Data) == 0) {
+		return nil, CodeTypeEmptyDataError
+	}
+
+	// Hash is used as the database key and must not be forged
+	if len(stx.Hash) > 0 && !bytes.Equal(stx.Hash, ComputeHash(stx)) {
+		return nil, CodeTypeInvalidFormatError
 	}
 
-	if stx.Size == 0 || len(stx.Data) == 0 {
-		return CodeTypeEmptyDataError
+	if stx.Chunk != nil && stx.Chunk.ValidateBasic() != nil {
+		return nil, CodeTypeInvalidChunkError
 	}
 
-	if !stx.Verify() {
+	if len(stx.PrevHash) > 0 && len(stx.PrevHash) != 32 {
+		return nil, CodeTypeInvalidPrevHashError
+	}
+
+	if !app.validChainID(stx) {
+		return nil, CodeTypeInvalidChainIDError
+	}
+
+	if stx.ValidateTime(now, app.maxPastDrift, app.maxFutureDrift) != nil {
+		return nil, CodeTypeInvalidTimestampError
+	}
+
+	return stx, CodeTypeOK
+}
+
+// validChainID returns true if the transaction is signed for the chain id of
+// the genesis, as recorded in the State. Transactions without chain id are
+// valid only on nodes without genesis chain id.
+func (app *VStoreApplication) validChainID(stx *SignedTransaction) bool {
+	return stx.ChainID == app.state.ChainID
+}
+
+// validateSignature checks the signature of a transaction.
+func (app *VStoreApplication) validateSignature(stx *SignedTransaction) uint32 {
+	// Legacy signatures do not cover the timestamp, nor the sequence, see
+	// SignedTransaction.VerifyLegacy
+	if !stx.Verify() && !(app.legacySignatures && stx.VerifyLegacy()) {
 		return CodeTypeInvalidSignatureError
 	}
 
@@ -104,15 +398,32 @@ func (app *VStoreApplication) processFinalizeBlock(
 
 	// Reset stages
 	app.stage = make([]SignedTransaction, 0)
+	app.chunks = make(map[string]*chunkManifest)
+
+	// Extract pubkey (32b), signature (64b), timestamp (8b) and data
+	parsed := app.parseBlockTxs(req.Txs)
+	staged := make(map[string]struct{}, len(req.Txs))
 
-	// Stage the block data
-	for i, tx := range req.Txs {
-		// Extract pubkey (32b), signature (64b), timestamp (8b) and data
-		payload, err := NewSignedTransactionFromBytes(tx)
+	// Stage the block data, in order
+	for i := range req.Txs {
+		payload, err := parsed[i].stx, parsed[i].err
 		if err != nil {
 			respTxs[i] = &abci.ExecTxResult{
 				Code:   CodeTypeInvalidFormatError,
+				Log:    err.Error(),
+				Events: []abci.Event{},
+			}
+
+			// This transaction won't be staged!
+			continue
+		}
+
+		// Transactions signed for another chain must not be replayed
+		if !app.validChainID(payload) {
+			respTxs[i] = &abci.ExecTxResult{
+				Code:   CodeTypeInvalidChainIDError,
 				Data:   payload.Hash,
+				Log:    fmt.Sprintf("transaction chain id %q does not match %q", payload.ChainID, app.state.ChainID),
 				Events: []abci.Event{},
 			}
 
@@ -120,8 +431,65 @@ func (app *VStoreApplication) processFinalizeBlock(
 			continue
 		}
 
+		// Transaction hash must not be committed or staged, such that the
+		// merkle roots never include a transaction twice
+		if app.isDuplicateHash(payload.Hash, staged) {
+			app.metrics.observeTxCode(CodeTypeDuplicateHashError)
+			respTxs[i] = &abci.ExecTxResult{
+				Code:   CodeTypeDuplicateHashError,
+				Data:   payload.Hash,
+				Log:    "transaction hash already exists",
+				Events: []abci.Event{},
+			}
+
+			// This transaction won't be staged!
+			continue
+		}
+
+		// Chained transactions reference a committed or staged transaction
+		if err := app.validatePrevHash(payload, app.stage); err != nil {
+			respTxs[i] = &abci.ExecTxResult{
+				Code:   CodeTypeInvalidPrevHashError,
+				Data:   payload.Hash,
+				Log:    err.Error(),
+				Events: []abci.Event{},
+			}
+
+			// This transaction won't be staged!
+			continue
+		}
+
+		// Sequenced transactions use the next sequence of the signer
+		if err := app.validateSequence(payload, app.stage); err != nil {
+			respTxs[i] = &abci.ExecTxResult{
+				Code:   CodeTypeInvalidSequenceError,
+				Data:   payload.Hash,
+				Log:    err.Error(),
+				Events: []abci.Event{},
+			}
+
+			// This transaction won't be staged!
+			continue
+		}
+
+		// Chunks must be received in order and produce the root hash
+		if payload.Chunk != nil {
+			if err := app.stageChunk(*payload); err != nil {
+				respTxs[i] = &abci.ExecTxResult{
+					Code:   CodeTypeInvalidChunkError,
+					Data:   payload.Hash,
+					Log:    err.Error(),
+					Events: []abci.Event{},
+				}
+
+				// This transaction won't be staged!
+				continue
+			}
+		}
+
 		// Stage this transaction
 		app.stage = append(app.stage, *payload)
+		staged[string(payload.Hash)] = struct{}{}
 
 		respTxs[i] = &abci.ExecTxResult{
 			Code:   CodeTypeOK,
@@ -132,94 +500,199 @@ func (app *VStoreApplication) processFinalizeBlock(
 		app.state.NumTransactions++
 	}
 
+	// Rejected transactions are not part of the block state
+	for _, res := range respTxs {
+		if res.Code == CodeTypeOK {
+			continue
+		}
+
+		fields := []interface{}{"height", req.Height, "code", res.Code, "reason", CodeToString(res.Code), "hash", fmt.Sprintf("%X", res.Data), "err", res.Log}
+		if res.Code == CodeTypeDuplicateHashError {
+			app.logger.Info("duplicate transaction in block", fields...)
+			continue
+		}
+
+		app.logger.Debug("rejected transaction in block", fields...)
+	}
+
+	// Staged transactions are committed in order of hash, such that the app
+	// hash does not depend on the order of the transactions in the block
+	sortStagedTransactions(app.stage)
+
 	app.state.Height = req.Height
+	app.time = req.Time
 	return respTxs
 }
 
-// commitMerkleRoots computes merkle roots per owner public key
-// and stores them in the merkleRoots property.
-func (app *VStoreApplication) commitMerkleRoots() {
+// commitMerkleRoots computes the merkle roots of the signers of the staged
+// transactions. The merkle root of a signer is the merkle tree of all of its
+// transaction hashes in order of commitment, i.e. the hashes of the signer
+// index followed by the staged hashes, such that any leaf can be proven. The
+// staged hashes are appended to the merkle frontier of the signer, such that
+// the previous hashes are not read.
+func (app *VStoreApplication) commitMerkleRoots() error {
 	if len(app.state.MerkleRoots) == 0 {
 		app.state.MerkleRoots = make(map[string][]byte, 0)
 	}
 
+	signers := []string{}
+	bySigner := make(map[string][][]byte)
 	for _, payload := range app.stage {
-		pub := payload.PublicKey()
-		txs := [][]byte{payload.Hash} // merkle root computed with transaction hash
+		signer := string(payload.Signer.Bytes())
+		if _, ok := bySigner[signer]; !ok {
+			signers = append(signers, signer)
+		}
 
-		// Prepend merkle root if it exists
-		if mr, ok := app.state.MerkleRoots[pub]; ok {
-			txs = append([][]byte{mr}, txs...)
+		bySigner[signer] = append(bySigner[signer], payload.Hash)
+	}
+
+	for _, signer := range signers {
+		frontier, err := app.readSignerFrontier([]byte(signer))
+		if err != nil {
+			return err
 		}
 
 		// Compute merkle root by owner public key
-		merkleRoot := merkle.HashFromByteSlices(txs)
-		app.state.MerkleRoots[pub] = merkleRoot
+		frontier.append(bySigner[signer])
+		pub := strings.ToUpper(hex.EncodeToString([]byte(signer)))
+		app.state.MerkleRoots[pub] = frontier.root()
 	}
+
+	return nil
+}
+
+// sortStagedTransactions sorts the staged transactions by hash. Transactions
+// are validated in block order before, e.g. a chained transaction must follow
+// its previous transaction in the block, and the results of FinalizeBlock are
+// in block order.
+func sortStagedTransactions(stage []SignedTransaction) {
+	sort.Slice(stage, func(i, j int) bool {
+		return bytes.Compare(stage[i].Hash, stage[j].Hash) < 0
+	})
 }
 
-// commitStateTransactions saves the State to database and
-// resets the stage.
-func (app *VStoreApplication) commitStateTransitions() {
-	// TODO: verify integrity before saveState
+// txHash returns the uppercase hexadecimal hash of a transaction for log
+// entries, or an empty string if the transaction could not be parsed.
+func txHash(stx *SignedTransaction) string {
+	if stx == nil {
+		return ""
+	}
 
-	// Save State instance to database
-	saveState(app.state)
+	return fmt.Sprintf("%X", stx.Hash)
+}
 
-	// Reset data stage
-	app.stage = make([]SignedTransaction, 0)
+// isDuplicateHash returns true if a transaction hash is already staged in
+// the current block, or committed.
+func (app *VStoreApplication) isDuplicateHash(hash []byte, staged map[string]struct{}) bool {
+	if _, ok := staged[string(hash)]; ok {
+		return true
+	}
+
+	exists, err := app.state.db.Has(TransactionKey(hash))
+	return err != nil || exists
 }
 
 // commitTransactionHashes indexes transaction hashes by
-// block height and by signer public key.
-func (app *VStoreApplication) commitTransactionHashes() {
+// block height, by signer public key and by timestamp. The
+// hashes of a signer are appended to the signer index once
+// per block, and the transaction count and the last sequence
+// of the signer are updated in the State. The indexes are
+// written with w.
+func (app *VStoreApplication) commitTransactionHashes(w dbWriter) error {
+	if app.state.SignerCounts == nil {
+		app.state.SignerCounts = make(map[string]int64)
+	}
+
+	// Indexes transaction hashes by height
+	if err := app.addTransactionsByHeight(w); err != nil {
+		return err
+	}
+
+	signers := []string{}
+	bySigner := make(map[string][][]byte)
 	for _, payload := range app.stage {
-		// Indexes transaction hashes by height
-		app.addTransactionByHeight(payload)
+		signer := string(payload.Signer.Bytes())
+		if _, ok := bySigner[signer]; !ok {
+			signers = append(signers, signer)
+		}
+
+		bySigner[signer] = append(bySigner[signer], payload.Hash)
+		app.state.SignerCounts[payload.PublicKey()]++
+	}
+
+	// Last sequences of the signers of sequenced transactions
+	app.commitSequences()
 
-		// Indexes transaction hashes by pubkey
-		app.addTransactionByPubKey(payload)
+	// Indexes transaction hashes by pubkey
+	for _, signer := range signers {
+		if err := app.appendSignerHashes(w, []byte(signer), bySigner[signer]); err != nil {
+			return err
+		}
 	}
+
+	// Indexes transaction hashes by timestamp
+	return app.addTransactionsByTime(w)
 }
 
-// addTransactionByHeight appends the transaction hash to
-// the block height transaction index.
-func (app *VStoreApplication) addTransactionByHeight(tx SignedTransaction) error {
-	txes := [][]byte{}
+// commitBlockTime saves the block time of the current height, i.e. the
+// consensus time which may differ from the transaction timestamps.
+func (app *VStoreApplication) commitBlockTime(w dbWriter) error {
+	dbKey := BlockTimeKey(app.state.Height)
 
-	// Indexes hashes by height with prefix "vfs:height:block-X"
-	heightStr := strconv.FormatInt(app.state.Height, 10) // base10
-	dbKey_byHeight := prefixKeyWith([]byte(heightStr), vfsPrefixKeyByHeight)
+	return w.Set(dbKey, []byte(app.time.UTC().Format(time.RFC3339Nano)))
+}
 
-	// Do we have hashes indexed by this height already?
-	data, err := app.state.db.Get(dbKey_byHeight)
+// commitAppHash saves the app hash of the current height, the signer merkle
+// roots that produce it and the number of transactions of every signer.
+func (app *VStoreApplication) commitAppHash(w dbWriter) error {
+	dbKey := AppHashKey(app.state.Height)
+
+	bz, err := json.Marshal(AppHashRecord{
+		AppHash:      app.state.Hash(),
+		MerkleRoots:  app.state.SortedSignerRoots(),
+		SignerCounts: app.state.SignerCounts,
+	})
 	if err != nil {
 		return err
 	}
 
-	if len(data) > 0 {
-		json.Unmarshal([]byte(data), &txes)
+	return w.Set(dbKey, bz)
+}
+
+// readAppHashRecord reads the app hash record of a committed height. A nil
+// record is returned if no app hash was persisted for this height.
+func (app *VStoreApplication) readAppHashRecord(height int64) (*AppHashRecord, error) {
+	bz, err := app.state.db.Get(AppHashKey(height))
+	if err != nil {
+		return nil, err
 	}
 
-	// Adds transaction hash by height
-	txes = append(txes, tx.Hash)
-	byHeight, _ := json.Marshal(txes)
+	if len(bz) == 0 {
+		return nil, nil
+	}
 
-	// Stores transaction hash to index
-	err = app.state.db.Set(dbKey_byHeight, byHeight)
-	return err
+	record := &AppHashRecord{}
+	if err := json.Unmarshal(bz, record); err != nil {
+		return nil, err
+	}
+
+	return record, nil
 }
 
-// addTransactionByPubKey appends the transaction hash to
-// the signer pubkey transaction index.
-func (app *VStoreApplication) addTransactionByPubKey(tx SignedTransaction) error {
+// addTransactionsByHeight appends the staged transaction
+// hashes to the block height transaction index.
+func (app *VStoreApplication) addTransactionsByHeight(w dbWriter) error {
+	if len(app.stage) == 0 {
+		return nil
+	}
+
 	txes := [][]byte{}
 
-	// Indexes hashes by pubkey with prefix "vfs:pubkey:X"
-	dbKey_byPubKey := prefixKeyWith(tx.Signer.Bytes(), vfsPrefixKeyByPubKey)
+	// Indexes hashes by height with prefix "vfs:height:block-X"
+	dbKey_byHeight := HeightIndexKey(app.state.Height)
 
-	// Do we have hashes indexed by this pubkey already?
-	data, err := app.state.db.Get(dbKey_byPubKey)
+	// Do we have hashes indexed by this height already?
+	data, err := app.state.db.Get(dbKey_byHeight)
 	if err != nil {
 		return err
 	}
@@ -228,22 +701,30 @@ func (app *VStoreApplication) addTransactionByPubKey(tx SignedTransaction) error
 		json.Unmarshal([]byte(data), &txes)
 	}
 
-	// Adds transaction hash by pubkey
-	txes = append(txes, tx.Hash)
-	byPubKey, _ := json.Marshal(txes)
+	// Adds transaction hashes by height
+	for _, tx := range app.stage {
+		txes = append(txes, tx.Hash)
+	}
 
-	// Stores transaction hash to index
-	err = app.state.db.Set(dbKey_byPubKey, byPubKey)
+	byHeight, _ := json.Marshal(txes)
+
+	// Stores transaction hashes to index
+	err = w.Set(dbKey_byHeight, byHeight)
 	return err
 }
 
 // readTransactionFromDB fetches a transaction from the database.
 // Given a transaction hash, the transaction content will be decrypted,
-// otherwise the index is read to retrieve the hash and a second query
-// is executed to fetch the transaction content by hash.
+// otherwise the index is read to retrieve the hashes and more queries
+// are executed to fetch the transaction contents by hash. The page is
+// used to slice the list of hashes of an index, from an offset or after a
+// cursor. ErrDecryptTransaction is returned if a stored transaction cannot be
+// decrypted, e.g. after corruption or with another identity, and
+// ErrEncryptionUnavailable if it is encrypted and the application observes.
 func (app *VStoreApplication) readTransactionFromDB(
 	queryType string,
 	value []byte,
+	page pagination,
 ) ([]byte, error) {
 	var (
 		queryKey []byte = getQueryKey(queryType, value)
@@ -255,25 +736,107 @@ func (app *VStoreApplication) readTransactionFromDB(
 		return []byte{}, err
 	}
 
-	// TODO: Return array of transaction for height/pubkey indexes
+	// Signer index consists of pages of transaction hashes
+	if queryType == QueryType_PubKey {
+		hashes, err := app.readSignerHashes(value)
+		if err != nil {
+			return []byte{}, err
+		}
+
+		return app.readTransactionList(hashes, page)
+	}
+
+	// Height index contains a list of transaction hashes
 	if queryType != QueryType_Default {
-		return []byte{}, nil
+		return app.readTransactionsFromIndex(data, page)
 	}
 
-	// Unlock the decryption secret
-	secret, err := app.priv.Identity().Secret()
-	if err != nil {
-		return []byte{}, nil
+	// Pruned transactions are committed but their body was removed
+	if isPrunedTransaction(data) {
+		return []byte{}, ErrTransactionPruned
+	}
+
+	// Plaintext transactions are returned without the decryption secret
+	secret := []byte{}
+	if !isPlaintextTransaction(data) {
+		// Unlock the decryption secret
+		secret, err = app.identitySecret()
+		if errors.Is(err, ErrEncryptionUnavailable) {
+			return []byte{}, err
+		} else if err != nil {
+			return []byte{}, fmt.Errorf("%w: could not unlock secret: %v", ErrDecryptTransaction, err)
+		}
+		defer zeroize(secret)
 	}
-	defer func() { secret = []byte{} }()
 
 	// Decrypt the transaction data with the node's secret
-	txData, err := Decrypt(secret, data)
+	txData, err := openTransaction(secret, data)
 	if err != nil {
-		return []byte{}, nil
+		return []byte{}, fmt.Errorf("%w %X: %v", ErrDecryptTransaction, value, err)
 	}
 
-	return txData, nil
+	// Bodies in external storage are resolved with the blob store
+	return app.resolveBodyRef(txData)
+}
+
+// readTransactionsFromIndex decodes the JSON array of transaction hashes that
+// is stored in an index and fetches each transaction by hash for the page. The
+// decrypted transactions are returned as a marshalled vfsp2p.TransactionList
+// which also contains the total number of transactions in the index.
+func (app *VStoreApplication) readTransactionsFromIndex(
+	data []byte,
+	page pagination,
+) ([]byte, error) {
+	hashes := [][]byte{}
+	if err := json.Unmarshal(data, &hashes); err != nil {
+		return []byte{}, err
+	}
+
+	return app.readTransactionList(hashes, page)
+}
+
+// readTransactionList fetches the transactions of a list of hashes for the
+// page. The decrypted transactions are returned as a marshalled
+// vfsp2p.TransactionList which also contains the total number of hashes.
+func (app *VStoreApplication) readTransactionList(
+	hashes [][]byte,
+	page pagination,
+) ([]byte, error) {
+	list := new(vfsp2p.TransactionList)
+	list.Total = uint64(len(hashes))
+
+	start, end, err := page.Bounds(hashes)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	// Last hash of the page is the cursor of the next page
+	if end < len(hashes) && end > start {
+		list.Next = hashes[end-1]
+	}
+
+	hashes = hashes[start:end]
+	list.Transactions = make([]vfsp2p.Transaction, 0, len(hashes))
+	for _, hash := range hashes {
+		txData, err := app.readTransactionFromDB(QueryType_Default, hash, pagination{})
+		if err != nil && !errors.Is(err, ErrTransactionPruned) {
+			return []byte{}, err
+		}
+
+		// Transaction body not found or pruned, skip it
+		if len(txData) == 0 {
+			continue
+		}
+
+		tx := new(vfsp2p.Transaction)
+		if err := tx.Unmarshal(txData); err != nil {
+			return []byte{}, err
+		}
+
+		list.Transactions = append(list.Transactions, *tx)
+	}
+
+	return list.Marshal()
 }
 
 // --------------------------------------------------------------------------
@@ -290,7 +853,11 @@ func (app *VStoreApplication) Info(
 	info *abci.RequestInfo,
 ) (*abci.ResponseInfo, error) {
 	// State contains num_transactions, height & merkle_roots
-	appData, err := json.Marshal(app.state)
+	// and paused is set when transactions are not accepted
+	appData, err := json.Marshal(struct {
+		State
+		Paused bool `json:"paused,omitempty"`
+	}{app.state, app.Paused()})
 	if err != nil {
 		panic(err)
 	}
@@ -307,14 +874,33 @@ func (app *VStoreApplication) Info(
 // InitChain returns the application hash in case the application starts with
 // values pre-populated. This method is called whenever a new instance of the
 // application is started, i.e. when LastBlockHeight is 0.
+// The app state of the genesis file may contain signed transactions, see
+// GenesisTransaction, which are validated and committed at height 0.
+// The chain id of the genesis is recorded in the State, transactions that are
+// signed for another chain are rejected.
 // InitChain implements abci.Application
 func (app *VStoreApplication) InitChain(
-	_ context.Context,
+	ctx context.Context,
 	chain *abci.RequestInitChain,
 ) (*abci.ResponseInitChain, error) {
-	// Creates an empty AppHash (32 bytes 0-filled)
+	// Transactions are bound to the chain of the genesis
+	app.state.ChainID = chain.ChainId
+
+	// Commits the genesis transactions, if any
+	if err := app.commitGenesisTransactions(ctx, chain); err != nil {
+		return nil, err
+	}
+
+	// Creates an empty AppHash (32 bytes 0-filled) without transactions
+	appHash := app.state.Hash()
+
+	// Guard against determinism breaks of the genesis app hash
+	if len(app.state.MerkleRoots) == 0 && hex.EncodeToString(appHash) != GenesisAppHash {
+		return nil, fmt.Errorf("genesis app hash mismatch, want: %s, got: %x", GenesisAppHash, appHash)
+	}
+
 	return &abci.ResponseInitChain{
-		AppHash: app.state.Hash(),
+		AppHash: appHash,
 	}, nil
 }
 
@@ -325,17 +911,65 @@ func (app *VStoreApplication) InitChain(
 // - Must not be empty
 // - Must contain at least the owner pubkey (32 bytes) and a signature (64 bytes)
 // - Must contain at least 1 byte of arbitrary data
+// In CheckTxStateful mode, the transaction hash must also not exist.
+// Chained transactions are checked against the database in both modes: the
+// previous transaction must exist and must belong to the same signer. The
+// sequence of sequenced transactions must not be used yet in both modes.
 // CheckTx implements abci.Application
 func (app *VStoreApplication) CheckTx(
 	_ context.Context,
 	check *abci.RequestCheckTx,
 ) (*abci.ResponseCheckTx, error) {
-	code := app.validateTx(check.Tx)
+	// New transactions are rejected during maintenance
+	if app.Paused() {
+		return &abci.ResponseCheckTx{Code: CodeTypePausedError}, nil
+	}
+
+	// Stateless checks never access the database
+	stx, code := app.validateTxBasic(check.Tx, time.Now())
+	if code == CodeTypeOK {
+		code = app.validateSignature(stx)
+	}
+
+	// Bodies are validated against the local policy of the node only, such
+	// that ProcessProposal never depends on the node configuration
+	if code == CodeTypeOK && app.maxBodySize > 0 && len(stx.Data) > app.maxBodySize {
+		code = CodeTypeInvalidBodyError
+	}
+
+	if code == CodeTypeOK {
+		code = app.validateBody(stx)
+	}
+
+	if code == CodeTypeOK && app.checkTxMode == CheckTxStateful {
+		code = app.validateTxState(stx)
+	}
+
+	// Chained transactions reference a committed transaction
+	if code == CodeTypeOK && app.validatePrevHash(stx, nil) != nil {
+		code = CodeTypeInvalidPrevHashError
+	}
+
+	// Sequences must not be reused, the next sequences of a signer may wait
+	// in the mempool and are ordered in FinalizeBlock
+	if code == CodeTypeOK && errors.Is(app.validateSequence(stx, nil), errSequenceReused) {
+		code = CodeTypeInvalidSequenceError
+	}
+
+	if code != CodeTypeOK {
+		app.logger.Debug("rejected transaction", "code", code, "reason", CodeToString(code), "hash", txHash(stx))
+	}
+
+	app.metrics.observeTxCode(code)
 	return &abci.ResponseCheckTx{Code: code}, nil
 }
 
 // PrepareProposal is called only when the node is a proposer. CometBFT stages
 // a set of transactions for the application.
+// Transactions with a hash that appears twice in the proposal are dropped,
+// such that duplicates never enter a block.
+// The size of the proposal is bounded by MaxTxBytes, transactions that do not
+// fit are skipped such that as many as possible are included, in order.
 // NOTE: we assume that CometBFT won't provide too many transactions for 1 block.
 // PrepareProposal implements abci.Application
 func (app *VStoreApplication) PrepareProposal(
@@ -344,12 +978,33 @@ func (app *VStoreApplication) PrepareProposal(
 ) (*abci.ResponsePrepareProposal, error) {
 	// Validate transactions before creating proposal
 	blockData := make([][]byte, 0, len(proposal.Txs))
+	seen := make(map[string]struct{}, len(proposal.Txs))
+	totalBytes := int64(0)
 	for _, tx := range proposal.Txs {
 		resp, err := app.CheckTx(ctx, &abci.RequestCheckTx{Tx: tx})
 		if resp.Code != CodeTypeOK || err != nil {
 			continue
 		}
 
+		// Hashes are unique per proposal
+		stx, err := NewSignedTransactionFromBytes(tx)
+		if err != nil {
+			continue
+		}
+
+		if _, dup := seen[string(stx.Hash)]; dup {
+			app.logger.Debug("duplicate hash in proposal", "hash", fmt.Sprintf("%X", stx.Hash))
+			continue
+		}
+
+		// Transactions that exceed the remaining bytes are skipped
+		size := cmttypes.ComputeProtoSizeForTxs([]cmttypes.Tx{tx})
+		if proposal.MaxTxBytes > 0 && totalBytes+size > proposal.MaxTxBytes {
+			continue
+		}
+
+		totalBytes += size
+		seen[string(stx.Hash)] = struct{}{}
 		blockData = append(blockData, tx)
 	}
 
@@ -362,15 +1017,32 @@ func (app *VStoreApplication) PrepareProposal(
 // Only validators from the validator set will have this method called.
 // ProcessProposal implements abci.Application
 func (app *VStoreApplication) ProcessProposal(
-	ctx context.Context,
+	_ context.Context,
 	proposal *abci.RequestProcessProposal,
 ) (*abci.ResponseProcessProposal, error) {
+	stxs := make([]SignedTransaction, 0, len(proposal.Txs))
 	for _, tx := range proposal.Txs {
-		// As CheckTx is a full validity check, we can reuse
-		if resp, err := app.CheckTx(ctx, &abci.RequestCheckTx{Tx: tx}); err != nil || resp.Code != CodeTypeOK {
+		// Full validity check as in CheckTx, timestamps are validated
+		// against the block time so that all validators agree
+		stx, code := app.validateTxBasic(tx, proposal.Time)
+		if code != CodeTypeOK {
 			return &abci.ResponseProcessProposal{Status: abci.ResponseProcessProposal_REJECT}, nil
 		}
+
+		stxs = append(stxs, *stx)
+	}
+
+	// Signatures are verified in one pass, a failed batch is checked
+	// per transaction to identify the invalid (or legacy) signatures
+	if !verifyBatch(stxs) {
+		for i := range stxs {
+			if code := app.validateSignature(&stxs[i]); code != CodeTypeOK {
+				app.logger.Debug("invalid signature in proposal", "hash", fmt.Sprintf("%X", ComputeHash(&stxs[i])))
+				return &abci.ResponseProcessProposal{Status: abci.ResponseProcessProposal_REJECT}, nil
+			}
+		}
 	}
+
 	return &abci.ResponseProcessProposal{Status: abci.ResponseProcessProposal_ACCEPT}, nil
 }
 
@@ -383,13 +1055,22 @@ func (app *VStoreApplication) FinalizeBlock(
 	ctx context.Context,
 	req *abci.RequestFinalizeBlock,
 ) (*abci.ResponseFinalizeBlock, error) {
+	if err := app.beginBlockCall(); err != nil {
+		return nil, err
+	}
+	defer app.endBlockCall()
+
+	// The state is not persisted until Commit
+	app.pendingBlock = true
 
 	// Updates the Height and NumTransactions by processing transactions
 	// and creates signed data payloads from bytes
 	respTxs := app.processFinalizeBlock(ctx, req)
 
 	// Update the merkle root including staged transaction hashes
-	app.commitMerkleRoots()
+	if err := app.commitMerkleRoots(); err != nil {
+		return nil, err
+	}
 
 	// Respond with transaction results and updated AppHash
 	response := &abci.ResponseFinalizeBlock{
@@ -397,6 +1078,13 @@ func (app *VStoreApplication) FinalizeBlock(
 		AppHash:   app.state.Hash(),
 	}
 
+	app.logger.Info("finalized block",
+		"height", req.Height,
+		"txs", len(req.Txs),
+		"staged", len(app.stage),
+		"app_hash", fmt.Sprintf("%X", response.AppHash),
+	)
+
 	return response, nil
 }
 
@@ -404,49 +1092,125 @@ func (app *VStoreApplication) FinalizeBlock(
 // The vfs application persists the staged data (from FinalizeBlock) in database
 // in a modified key-value store where the key is the tx hash, and where
 // values describe marshalled protobuf instances of vfsp2p.Transaction.
+// The transactions, the indexes and the State are written in a single batch,
+// such that either all or none of the writes of a block land.
 // Commit implements abci.Application
 func (app *VStoreApplication) Commit(
 	_ context.Context,
 	commit *abci.RequestCommit,
-) (*abci.ResponseCommit, error) {
-	// Read the encryption secret
-	secret, err := app.priv.Identity().Secret()
-	if err != nil {
+) (_ *abci.ResponseCommit, err error) {
+	if err := app.beginBlockCall(); err != nil {
 		return nil, err
 	}
+	defer app.endBlockCall()
 
+	// The last index page and the chunk manifests in memory describe writes of
+	// the batch, they are reset if the batch is not written
 	defer func() {
-		secret = []byte{}
+		if err != nil {
+			app.tail = nil
+			app.chunks = make(map[string]*chunkManifest)
+		}
 	}()
 
+	// Read the encryption secret, which plaintext transactions do not need
+	secret := []byte{}
+	if !app.plaintext {
+		secret, err = app.identitySecret()
+		if err != nil {
+			return nil, err
+		}
+
+		defer zeroize(secret)
+	}
+
+	// All writes of the block are grouped in a batch
+	batch := app.state.db.NewBatch()
+	defer batch.Close()
+
 	// Persist all the staged data in vfs
+	committed := make([]SignedTransaction, 0, len(app.stage))
+	storedBytes := 0
 	for _, payload := range app.stage {
 		// Use transaction hash as the key (index by hash)
-		dbKey := prefixKey(payload.Hash)
+		dbKey := TransactionKey(payload.Hash)
 
-		// Transaction hash must not exist
-		if resp, err := app.state.db.Has(dbKey); err != nil || resp {
-			return nil, errors.New("transaction hash already exists")
+		// Duplicates are excluded in FinalizeBlock, a transaction that is
+		// already stored is skipped rather than aborting the whole block
+		exists, err := app.state.db.Has(dbKey)
+		if err != nil {
+			return nil, err
 		}
 
-		// Encrypt the transaction using the node's secret
-		encProto, err := Encrypt(secret, payload.Bytes())
+		if exists {
+			app.logger.Error("skipping duplicate transaction in commit", "hash", fmt.Sprintf("%X", payload.Hash))
+			continue
+		}
+
+		// Compress (optional) and encrypt the transaction with the signer key,
+		// or store it unencrypted in plaintext mode
+		txData := payload.Bytes()
+		encProto, err := app.sealTransaction(secret, payload.Signer.Bytes(), txData)
+		zeroize(txData)
 		if err != nil {
 			return nil, err
 		}
 
 		// Stores an encrypted vfsp2p.Transaction protobuf payload
-		err = app.state.db.Set(dbKey, encProto)
+		err = batch.Set(dbKey, encProto)
 		if err != nil {
 			return nil, err
 		}
+
+		committed = append(committed, payload)
+		storedBytes += len(encProto)
 	}
 
+	// Skipped duplicates are not indexed again
+	app.stage = committed
+
 	// Indexes transaction hash by height and signer pubkey
-	app.commitTransactionHashes()
+	if err := app.commitTransactionHashes(batch); err != nil {
+		return nil, err
+	}
 
-	// Save the State in database with updated merkle roots
-	app.commitStateTransitions()
+	// Saves the block time of this height
+	if err := app.commitBlockTime(batch); err != nil {
+		return nil, err
+	}
+
+	// Saves the app hash of this height
+	if err := app.commitAppHash(batch); err != nil {
+		return nil, err
+	}
+
+	// Saves the chunk manifests of chunked bodies
+	if err := app.commitChunkManifests(batch); err != nil {
+		return nil, err
+	}
+
+	// Save the State with updated merkle roots
+	saveState(batch, app.state)
+
+	// Either all writes of the block land, or none of them
+	if err := batch.WriteSync(); err != nil {
+		return nil, err
+	}
+
+	// Emits the committed events, e.g. to a webhook
+	app.emitCommittedEvents()
+
+	// Prunes the oldest transactions with the retention policy, if any
+	app.pruneCommitted()
+
+	// Writes a state sync snapshot of this height, if enabled
+	app.takeSnapshot()
+
+	// Reset data stage
+	app.stage = make([]SignedTransaction, 0)
+	app.pendingBlock = false
+	app.metrics.observeCommit(app.state.Height, len(committed), storedBytes)
+	app.logger.Info("committed block", "height", app.state.Height, "txs", len(committed), "bytes", storedBytes)
 
 	// Response OK
 	return &abci.ResponseCommit{}, nil
@@ -454,6 +1218,9 @@ func (app *VStoreApplication) Commit(
 
 // Query returns an associated value or nil if missing.
 // Expects a transaction hash in the request's Data field.
+// When Prove is set, a query by hash returns merkle proof operations which
+// prove that the transaction hash is part of the application hash. If Height
+// is set, the proof is created for the application hash of this height.
 // Query implements abci.Application
 func (app *VStoreApplication) Query(
 	_ context.Context,
@@ -464,16 +1231,94 @@ func (app *VStoreApplication) Query(
 		Height: app.state.Height,
 	}
 
-	queryType := getQueryType(req.Path)
-	plainData, err := app.readTransactionFromDB(queryType, req.Data)
-	if err != nil {
+	queryPath, params := splitQueryPath(req.Path)
+	queryType := getQueryType(queryPath)
+	switch queryType {
+	case QueryType_Chunks:
+		return app.queryChunkedBody(req, response)
+	case QueryType_RootVerify:
+		return app.queryVerifyRoot(req, response)
+	case QueryType_Root:
+		// Merkle root of a signer, with a proof of inclusion in the app hash
+		if len(req.Data) > 0 {
+			return app.querySignerRoot(req, response)
+		}
+
+		// Aggregate merkle root of all signers, i.e. the app hash
+		response.Value = app.state.Hash()
+		return response, nil
+	case QueryType_State:
+		response.Value = app.state.CanonicalBytes()
+		return response, nil
+	case QueryType_BlockTime:
+		return app.queryBlockTime(req, response)
+	case QueryType_SignerAt:
+		return app.querySignerAtHeight(req, response, newPagination(params))
+	case QueryType_AppHash:
+		return app.queryAppHash(req, response)
+	case QueryType_HeightCount:
+		return app.queryHeightCount(req, response)
+	case QueryType_PubKeyCount:
+		return app.queryPubKeyCount(req, response)
+	case QueryType_Sequence:
+		return app.querySequence(req, response)
+	case QueryType_Signers:
+		return app.querySigners(response, newPagination(params))
+	case QueryType_Time:
+		return app.queryTimeRange(response, params, newPagination(params))
+	default:
+		break
+	}
+
+	plainData, err := app.readTransactionFromDB(queryType, req.Data, newPagination(params))
+	if errors.Is(err, errCursorNotFound) {
+		response.Code = CodeTypeInvalidFormatError
+		response.Log = err.Error()
+		return response, nil
+	} else if errors.Is(err, ErrTransactionPruned) {
+		return app.queryPrunedTransaction(req, response)
+	} else if errors.Is(err, ErrDecryptTransaction) {
+		response.Code = CodeTypeDecryptError
+		response.Log = err.Error()
+		return response, nil
+	} else if errors.Is(err, ErrEncryptionUnavailable) {
+		response.Code = CodeTypeEncryptionUnavailableError
+		response.Log = err.Error()
+		return response, nil
+	} else if err != nil {
 		return response, err
 	}
 
+	// Committed transactions without a body are distinct from unknown hashes
+	if len(plainData) == 0 {
+		if queryType == QueryType_Default {
+			return app.queryMissingTransaction(req, response)
+		}
+
+		response.Log = "not found"
+		return response, nil
+	}
+
 	response.Value = plainData
 	response.Log = "exists"
-	if req.Prove {
-		response.Index = -1 // TODO make Proof return index
+
+	// Proofs are only available for queries by transaction hash
+	if req.Prove && queryType == QueryType_Default {
+		stx, err := FromBytes(plainData)
+		if err != nil {
+			return response, err
+		}
+
+		proofOps, index, err := app.proveTransaction(stx.Signer.Bytes(), req.Data, req.Height)
+		if err != nil {
+			return response, err
+		}
+
+		response.ProofOps = proofOps
+		response.Index = index
+		if req.Height > 0 {
+			response.Height = req.Height
+		}
 	}
 
 	return response, nil
@@ -482,6 +1327,23 @@ func (app *VStoreApplication) Query(
 // --------------------------------------------------------------------------
 // Private helpers
 
+// identitySecret opens the identity of the application and returns the secret
+// which encrypts the database. The decrypted private key is zeroized, and the
+// caller must zeroize the secret after use. ErrEncryptionUnavailable is
+// returned in observer mode.
+func (app *VStoreApplication) identitySecret() ([]byte, error) {
+	if app.Observer() {
+		return nil, ErrEncryptionUnavailable
+	}
+
+	id := app.priv.Identity()
+	if priv, ok := id.(ed25519Identity); ok {
+		defer zeroize(priv)
+	}
+
+	return id.Secret()
+}
+
 // getQueryKey returns a prefixed database key depending of a queryType.
 func getQueryKey(queryType string, value []byte) []byte {
 	switch queryType {
@@ -503,6 +1365,30 @@ func getQueryType(path string) string {
 		return QueryType_Height
 	case "/pubkey":
 		return QueryType_PubKey
+	case "/chunks":
+		return QueryType_Chunks
+	case "/root":
+		return QueryType_Root
+	case "/root/verify":
+		return QueryType_RootVerify
+	case "/state":
+		return QueryType_State
+	case "/blocktime/height":
+		return QueryType_BlockTime
+	case "/signer/height":
+		return QueryType_SignerAt
+	case "/apphash/height":
+		return QueryType_AppHash
+	case "/height/count":
+		return QueryType_HeightCount
+	case "/pubkey/count":
+		return QueryType_PubKeyCount
+	case "/pubkey/sequence":
+		return QueryType_Sequence
+	case "/time":
+		return QueryType_Time
+	case "/signers":
+		return QueryType_Signers
 	default:
 		break
 	}
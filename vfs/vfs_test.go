@@ -1,10 +1,18 @@
 package vfs
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -13,8 +21,11 @@ import (
 
 	"github.com/cosmos/gogoproto/proto"
 
+	cmtdb "github.com/cometbft/cometbft-db"
 	abci "github.com/cometbft/cometbft/abci/types"
 	"github.com/cometbft/cometbft/crypto/ed25519"
+	"github.com/cometbft/cometbft/crypto/tmhash"
+	cmttypes "github.com/cometbft/cometbft/types"
 )
 
 const (
@@ -106,6 +117,40 @@ func TestVStoreEmptyTxs(t *testing.T) {
 	require.Equal(t, len(reqPrepare.Txs)-1, len(resPrepare.Txs), "Empty transaction not properly removed")
 }
 
+func TestVStorePrepareProposalMaxTxBytes(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-prepare_max_tx_bytes", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	txs := make([][]byte, 0, 21)
+	for i := 0; i < 20; i++ {
+		stx, err := makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue+strconv.Itoa(i)))
+		require.NoError(t, err)
+		txs = append(txs, stx.Bytes())
+	}
+
+	// Large transaction which does not fit after the first ones
+	large, err := makeTransaction(t, ownerPrivs[0], []byte(strings.Repeat("x", 2048)))
+	require.NoError(t, err)
+	txs = append(txs[:5], append([][]byte{large.Bytes()}, txs[5:]...)...)
+
+	size := cmttypes.ComputeProtoSizeForTxs([]cmttypes.Tx{txs[0]})
+	maxTxBytes := 10 * size
+
+	resPrepare, err := vstore.PrepareProposal(ctx, &abci.RequestPrepareProposal{Txs: txs, MaxTxBytes: maxTxBytes})
+	require.NoError(t, err)
+	require.Len(t, resPrepare.Txs, 10, "should include as many transactions as fit")
+	assert.LessOrEqual(t, cmttypes.ComputeProtoSizeForTxs(cmttypes.ToTxs(resPrepare.Txs)), maxTxBytes)
+
+	// Order is preserved, the large transaction is skipped
+	expected := append(append([][]byte{}, txs[:5]...), txs[6:11]...)
+	assert.Equal(t, expected, resPrepare.Txs)
+}
+
 func TestVStoreInvalidSignature(t *testing.T) {
 	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-invalid_signature", 1)
 	defer func() {
@@ -128,10 +173,860 @@ func TestVStoreInvalidSignature(t *testing.T) {
 	assert.Equal(t, CodeTypeInvalidSignatureError, checkTxResp.Code)
 }
 
+func TestVStoreLegacySignature(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-legacy_signature", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	stx, err := makeLegacyTransaction(t, ownerPrivs[0], []byte(testSimpleValue))
+	require.NoError(t, err, "should create a legacy signed transaction")
+
+	checkTx := func(stx *SignedTransaction) uint32 {
+		resp, err := vstore.CheckTx(ctx, &abci.RequestCheckTx{Tx: stx.Bytes()})
+		require.NoError(t, err)
+		return resp.Code
+	}
+
+	// Legacy signatures are rejected by default
+	assert.Equal(t, CodeTypeInvalidSignatureError, checkTx(stx))
+
+	// Legacy signatures are recognized if enabled
+	vstore.SetLegacySignatures(true)
+	assert.Equal(t, CodeTypeOK, checkTx(stx))
+
+	// Fields that a legacy signature does not cover cannot be set
+	for name, set := range map[string]func(*SignedTransaction){
+		"chain id":     func(stx *SignedTransaction) { stx.ChainID = "test-chain" },
+		"sequence":     func(stx *SignedTransaction) { stx.Sequence = 1 },
+		"prev hash":    func(stx *SignedTransaction) { stx.PrevHash = ComputeHash(stx) },
+		"content type": func(stx *SignedTransaction) { stx.ContentType = "application/json" },
+	} {
+		forged := *stx
+		set(&forged)
+		forged.Hash = nil
+		assert.False(t, forged.VerifyLegacy(), name)
+		assert.NotEqual(t, CodeTypeOK, checkTx(&forged), name)
+	}
+
+	vstore.SetLegacySignatures(false)
+	assert.Equal(t, CodeTypeInvalidSignatureError, checkTx(stx))
+
+	// Tampering with a signed timestamp is rejected
+	stx, err = makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue))
+	require.NoError(t, err)
+	stx.Time = stx.Time.Add(-time.Minute)
+
+	vstore.SetLegacySignatures(true)
+	assert.Equal(t, CodeTypeInvalidSignatureError, checkTx(stx))
+}
+
+func TestVStoreInvalidHash(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-invalid_hash", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	stx, err := makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue))
+	require.NoError(t, err, "should create a signed transaction")
+
+	// Correct hash is accepted
+	stx.Hash = ComputeHash(stx)
+	checkTxResp, err := vstore.CheckTx(ctx, &abci.RequestCheckTx{Tx: stx.Bytes()})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeOK, checkTxResp.Code)
+
+	// Mutate only the hash (signature remains valid)
+	stx.Hash = tmhash.Sum([]byte("forged"))
+	checkTxResp, err = vstore.CheckTx(ctx, &abci.RequestCheckTx{Tx: stx.Bytes()})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeInvalidFormatError, checkTxResp.Code)
+
+	// Forged hashes never reach the index
+	resFinalize, _ := makeBlockCommit(ctx, t, vstore, 1, [][]byte{stx.Bytes()})
+	assert.Equal(t, CodeTypeInvalidFormatError, resFinalize.TxResults[0].Code)
+
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: stx.Hash})
+	require.NoError(t, err)
+	assert.Empty(t, resQuery.Value)
+}
+
+func TestVStoreInvalidTimestamp(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-invalid_timestamp", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	now := time.Now()
+	testCases := []struct {
+		name string
+		time time.Time
+		code uint32
+	}{
+		{"now", now, CodeTypeOK},
+		{"within future drift", now.Add(DefaultMaxFutureDrift / 2), CodeTypeOK},
+		{"within past drift", now.Add(-DefaultMaxPastDrift / 2), CodeTypeOK},
+		{"too far in the future", now.Add(DefaultMaxFutureDrift + time.Minute), CodeTypeInvalidTimestampError},
+		{"too far in the past", now.Add(-DefaultMaxPastDrift - time.Minute), CodeTypeInvalidTimestampError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stx, err := makeTransactionAt(t, ownerPrivs[0], []byte(testSimpleValue), tc.time)
+			require.NoError(t, err, "should create a signed transaction")
+
+			// CheckTx
+			checkTxResp, err := vstore.CheckTx(ctx, &abci.RequestCheckTx{Tx: stx.Bytes()})
+			require.NoError(t, err)
+			assert.Equal(t, tc.code, checkTxResp.Code)
+
+			// ProcessProposal validates against the block time
+			expectedStatus := abci.ResponseProcessProposal_ACCEPT
+			if tc.code != CodeTypeOK {
+				expectedStatus = abci.ResponseProcessProposal_REJECT
+			}
+
+			resProcess, err := vstore.ProcessProposal(ctx, &abci.RequestProcessProposal{
+				Txs:  [][]byte{stx.Bytes()},
+				Time: now,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, expectedStatus, resProcess.Status)
+		})
+	}
+
+	// Drift window is configurable
+	vstore.SetTimestampWindow(time.Hour, time.Hour)
+	stx, err := makeTransactionAt(t, ownerPrivs[0], []byte(testSimpleValue), now.Add(-2*time.Hour))
+	require.NoError(t, err)
+
+	checkTxResp, err := vstore.CheckTx(ctx, &abci.RequestCheckTx{Tx: stx.Bytes()})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeInvalidTimestampError, checkTxResp.Code)
+}
+
+func TestVStorePauseResume(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-pause_resume", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	stx, err := makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue))
+	require.NoError(t, err, "should create a signed transaction")
+	txHash := testVStoreCommitTx(ctx, t, vstore, stx.Bytes()).TxResults[0].Data
+
+	// Paused node rejects new transactions
+	vstore.Pause()
+	assert.True(t, vstore.Paused())
+
+	checkTxResp, err := vstore.CheckTx(ctx, &abci.RequestCheckTx{Tx: stx.Bytes()})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypePausedError, checkTxResp.Code)
+
+	ppResp, err := vstore.PrepareProposal(ctx, &abci.RequestPrepareProposal{Txs: [][]byte{stx.Bytes()}})
+	require.NoError(t, err)
+	assert.Empty(t, ppResp.Txs)
+
+	info, err := vstore.Info(ctx, &abci.RequestInfo{})
+	require.NoError(t, err)
+	assert.Contains(t, info.Data, `"paused":true`)
+
+	// Queries are still served
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: txHash})
+	require.NoError(t, err)
+	assert.NotEmpty(t, resQuery.Value)
+
+	// Resumed node accepts new transactions
+	vstore.Resume()
+	assert.False(t, vstore.Paused())
+
+	checkTxResp, err = vstore.CheckTx(ctx, &abci.RequestCheckTx{Tx: stx.Bytes()})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeOK, checkTxResp.Code)
+
+	info, err = vstore.Info(ctx, &abci.RequestInfo{})
+	require.NoError(t, err)
+	assert.NotContains(t, info.Data, "paused")
+}
+
+func TestVStoreQueryByHeight(t *testing.T) {
+	numSigners := uint32(5)
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-query_by_height", numSigners)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	// Block 1 contains 2 transactions and block 2 contains 3 transactions
+	blocks := [][]int{{0, 1}, {2, 3, 4}}
+	committed := [][]*SignedTransaction{}
+	for h, signers := range blocks {
+		stxs := make([]*SignedTransaction, len(signers))
+		for i, s := range signers {
+			stx, err := makeTransaction(t, ownerPrivs[s], []byte(testSimpleValue))
+			require.NoError(t, err, "should create a signed transaction")
+			stx.Hash = ComputeHash(stx)
+			stxs[i] = stx
+		}
+
+		makeBlockCommit(ctx, t, vstore, h+1, transactionBytes(stxs))
+
+		// Transactions of a block are committed in order of hash
+		sortTransactionsByHash(stxs)
+		committed = append(committed, stxs)
+	}
+
+	for h, signers := range blocks {
+		resQuery, err := vstore.Query(ctx, &abci.RequestQuery{
+			Path: "/height",
+			Data: []byte(strconv.Itoa(h + 1)),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, CodeTypeOK, resQuery.Code)
+
+		list := new(vfsp2p.TransactionList)
+		err = proto.Unmarshal(resQuery.Value, list)
+		require.NoError(t, err, "should unmarshal transaction list from query result")
+		require.Len(t, list.Transactions, len(signers))
+
+		for i, stx := range committed[h] {
+			assert.Equal(t, stx.Signer.Bytes(), list.Transactions[i].Signer.GetEd25519())
+			assert.Equal(t, []byte(testSimpleValue), list.Transactions[i].Body)
+		}
+	}
+
+	// Empty height returns a clean not found
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{
+		Path: "/height",
+		Data: []byte("3"),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, resQuery.Value)
+}
+
+func TestVStoreQueryMissing(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-query_missing", 2)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	hashes := [][]byte{}
+	txs := [][]byte{}
+	for _, priv := range ownerPrivs {
+		stx, err := makeTransaction(t, priv, []byte(testSimpleValue))
+		require.NoError(t, err, "should create a signed transaction")
+
+		hashes = append(hashes, ComputeHash(stx))
+		txs = append(txs, stx.Bytes())
+	}
+
+	makeBlockCommit(ctx, t, vstore, 1, txs)
+
+	// Existing transaction
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: hashes[0]})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeOK, resQuery.Code)
+	assert.Equal(t, "exists", resQuery.Log)
+	assert.Equal(t, txs[0], resQuery.Value)
+
+	// Unknown hash
+	resQuery, err = vstore.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: make([]byte, 32), Prove: true})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeOK, resQuery.Code)
+	assert.Equal(t, "not found", resQuery.Log)
+	assert.Empty(t, resQuery.Value)
+	assert.Nil(t, resQuery.ProofOps)
+
+	// Committed transaction without a body
+	require.NoError(t, vstore.state.db.Delete(TransactionKey(hashes[1])))
+	resQuery, err = vstore.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: hashes[1]})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeMissingBodyError, resQuery.Code)
+	assert.Contains(t, resQuery.Log, "committed at height 1")
+	assert.Empty(t, resQuery.Value)
+
+	// Unknown index keys
+	for _, req := range []*abci.RequestQuery{
+		{Path: "/height", Data: []byte("2")},
+		{Path: "/pubkey", Data: make([]byte, 32)},
+	} {
+		resQuery, err = vstore.Query(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, CodeTypeOK, resQuery.Code)
+		assert.Equal(t, "not found", resQuery.Log, req.Path)
+		assert.Empty(t, resQuery.Value)
+	}
+}
+
+func TestVStoreQueryDecryptError(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-query_decrypt_error", 2)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	db := cmtdb.NewMemDB()
+	vstore := NewVStoreApplication(db, filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	hashes := [][]byte{}
+	txs := [][]byte{}
+	for _, priv := range ownerPrivs {
+		stx, err := makeTransaction(t, priv, []byte(testSimpleValue))
+		require.NoError(t, err, "should create a signed transaction")
+
+		hashes = append(hashes, ComputeHash(stx))
+		txs = append(txs, stx.Bytes())
+	}
+
+	makeBlockCommit(ctx, t, vstore, 1, txs)
+
+	// Corrupted ciphertexts are not reported as missing
+	stored, err := db.Get(TransactionKey(hashes[0]))
+	require.NoError(t, err)
+	corrupted := append([]byte{}, stored...)
+	corrupted[len(corrupted)-1] ^= 0xFF
+	require.NoError(t, db.Set(TransactionKey(hashes[0]), corrupted))
+
+	for _, req := range []*abci.RequestQuery{
+		{Path: "/hash", Data: hashes[0]},
+		{Path: "/height", Data: []byte("1")},
+		{Path: "/pubkey", Data: ed25519.PrivKey(ownerPrivs[0]).PubKey().Bytes()},
+	} {
+		resQuery, err := vstore.Query(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, CodeTypeDecryptError, resQuery.Code, req.Path)
+		assert.Contains(t, resQuery.Log, ErrDecryptTransaction.Error())
+		assert.Empty(t, resQuery.Value)
+	}
+
+	// Other transactions are still readable
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: hashes[1]})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeOK, resQuery.Code)
+	assert.Equal(t, txs[1], resQuery.Value)
+
+	// Transactions of another identity cannot be decrypted
+	otherIdFile := filepath.Join(vfsDir, "other-id")
+	MustGenerateIdentity(otherIdFile, []byte("testpassword"))
+	other := NewVStoreApplication(db, otherIdFile, []byte("testpassword"))
+
+	resQuery, err = other.Query(ctx, &abci.RequestQuery{Path: "/hash", Data: hashes[1]})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeDecryptError, resQuery.Code)
+	assert.Empty(t, resQuery.Value)
+}
+
+func TestVStoreQueryBlockTime(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-query_block_time", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	stx, err := makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue))
+	require.NoError(t, err, "should create a signed transaction")
+
+	blockTime := time.Date(2024, 6, 1, 12, 30, 15, 123456789, time.UTC)
+	_, err = vstore.FinalizeBlock(ctx, &abci.RequestFinalizeBlock{
+		Height: 1,
+		Time:   blockTime,
+		Txs:    [][]byte{stx.Bytes()},
+	})
+	require.NoError(t, err)
+
+	_, err = vstore.Commit(ctx, &abci.RequestCommit{})
+	require.NoError(t, err)
+
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/blocktime/height", Data: []byte("1")})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeOK, resQuery.Code)
+
+	stored, err := time.Parse(time.RFC3339Nano, string(resQuery.Value))
+	require.NoError(t, err, "should parse block time")
+	assert.True(t, blockTime.Equal(stored), "block time must match the FinalizeBlock request time")
+
+	// Unknown height
+	resQuery, err = vstore.Query(ctx, &abci.RequestQuery{Path: "/blocktime/height", Data: []byte("2")})
+	require.NoError(t, err)
+	assert.Empty(t, resQuery.Value)
+}
+
+func TestVStoreQueryHeightCount(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-query_height_count", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	// Blocks with 1, 3, 0 and 5 transactions
+	counts := []int{1, 3, 0, 5}
+	for i, count := range counts {
+		txs := [][]byte{}
+		for j := 0; j < count; j++ {
+			stx, err := makeTransaction(t, ownerPrivs[0], []byte(fmt.Sprintf("%s-%d-%d", testSimpleValue, i, j)))
+			require.NoError(t, err, "should create a signed transaction")
+			txs = append(txs, stx.Bytes())
+		}
+
+		makeBlockCommit(ctx, t, vstore, i+1, txs)
+	}
+
+	for i, count := range counts {
+		resQuery, err := vstore.Query(ctx, &abci.RequestQuery{
+			Path: "/height/count",
+			Data: []byte(strconv.Itoa(i + 1)),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, CodeTypeOK, resQuery.Code)
+		assert.Equal(t, strconv.Itoa(count), string(resQuery.Value), "count at height %d", i+1)
+	}
+
+	// Unknown height has no transactions
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/height/count", Data: []byte("100")})
+	require.NoError(t, err)
+	assert.Equal(t, "0", string(resQuery.Value))
+
+	// Invalid height
+	resQuery, err = vstore.Query(ctx, &abci.RequestQuery{Path: "/height/count", Data: []byte("abc")})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeInvalidFormatError, resQuery.Code)
+}
+
+func TestVStoreQueryPubKeyCount(t *testing.T) {
+	numSigners := uint32(2)
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-query_pubkey_count", numSigners)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	// Signers with 4 and 1 transactions over two blocks
+	counts := []int{4, 1}
+	blocks := [][]int{{0, 0, 1}, {0, 0}}
+	for i, signers := range blocks {
+		txs := [][]byte{}
+		for j, s := range signers {
+			stx, err := makeTransaction(t, ownerPrivs[s], []byte(fmt.Sprintf("%s-%d-%d", testSimpleValue, i, j)))
+			require.NoError(t, err, "should create a signed transaction")
+			txs = append(txs, stx.Bytes())
+		}
+
+		makeBlockCommit(ctx, t, vstore, i+1, txs)
+	}
+
+	for i, count := range counts {
+		resQuery, err := vstore.Query(ctx, &abci.RequestQuery{
+			Path: "/pubkey/count",
+			Data: ed25519.PrivKey(ownerPrivs[i]).PubKey().Bytes(),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, CodeTypeOK, resQuery.Code)
+		assert.Equal(t, strconv.Itoa(count), string(resQuery.Value), "count of signer %d", i)
+	}
+
+	// Unknown signer has no transactions
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{
+		Path: "/pubkey/count",
+		Data: ed25519.GenPrivKey().PubKey().Bytes(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeOK, resQuery.Code)
+	assert.Equal(t, "0", string(resQuery.Value))
+
+	// Invalid public key
+	resQuery, err = vstore.Query(ctx, &abci.RequestQuery{Path: "/pubkey/count", Data: []byte("abc")})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeInvalidFormatError, resQuery.Code)
+}
+
+func TestVStoreQueryByPubKey(t *testing.T) {
+	numSigners := uint32(3)
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-query_by_pubkey", numSigners)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	// Signer 0 has no transaction, signer 1 has one and signer 2 has several
+	numTxs := []int{0, 1, 4}
+	for s, n := range numTxs {
+		for i := 0; i < n; i++ {
+			data := []byte(testSimpleValue + strconv.Itoa(i))
+			stx, err := makeTransaction(t, ownerPrivs[s], data)
+			require.NoError(t, err, "should create a signed transaction")
+			makeBlockCommit(ctx, t, vstore, int(vstore.state.Height)+1, [][]byte{stx.Bytes()})
+		}
+	}
+
+	for s, n := range numTxs {
+		pubKey := ed25519.PrivKey(ownerPrivs[s]).PubKey()
+		resQuery, err := vstore.Query(ctx, &abci.RequestQuery{
+			Path: "/pubkey",
+			Data: pubKey.Bytes(),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, CodeTypeOK, resQuery.Code)
+
+		// Unknown signer returns a clean not found
+		if n == 0 {
+			assert.Empty(t, resQuery.Value)
+			continue
+		}
+
+		list := new(vfsp2p.TransactionList)
+		err = proto.Unmarshal(resQuery.Value, list)
+		require.NoError(t, err, "should unmarshal transaction list from query result")
+		require.Len(t, list.Transactions, n)
+
+		for i, tx := range list.Transactions {
+			assert.Equal(t, pubKey.Bytes(), tx.Signer.GetEd25519())
+			assert.Equal(t, []byte(testSimpleValue+strconv.Itoa(i)), tx.Body)
+		}
+	}
+}
+
+func TestVStoreQuerySignerAtHeight(t *testing.T) {
+	numSigners := uint32(3)
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-query_signer_at_height", numSigners)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	// Number of transactions per signer (columns) for each block (rows)
+	blocks := [][]int{
+		{1, 2, 0},
+		{0, 1, 3},
+		{2, 0, 1},
+	}
+
+	for h, counts := range blocks {
+		txs := [][]byte{}
+		for s, n := range counts {
+			for i := 0; i < n; i++ {
+				data := []byte(fmt.Sprintf("%s-%d-%d-%d", testSimpleValue, h+1, s, i))
+				stx, err := makeTransaction(t, ownerPrivs[s], data)
+				require.NoError(t, err, "should create a signed transaction")
+				txs = append(txs, stx.Bytes())
+			}
+		}
+
+		makeBlockCommit(ctx, t, vstore, h+1, txs)
+	}
+
+	for h, counts := range blocks {
+		for s, n := range counts {
+			pubKey := ed25519.PrivKey(ownerPrivs[s]).PubKey()
+			resQuery, err := vstore.Query(ctx, &abci.RequestQuery{
+				Path: "/signer/height",
+				Data: append(pubKey.Bytes(), []byte(strconv.Itoa(h+1))...),
+			})
+			require.NoError(t, err)
+			assert.Equal(t, CodeTypeOK, resQuery.Code)
+
+			if n == 0 {
+				assert.Empty(t, resQuery.Value)
+				continue
+			}
+
+			list := new(vfsp2p.TransactionList)
+			err = proto.Unmarshal(resQuery.Value, list)
+			require.NoError(t, err, "should unmarshal transaction list from query result")
+			require.Len(t, list.Transactions, n)
+			assert.EqualValues(t, n, list.Total)
+
+			// Transactions of a block are committed in order of hash
+			bodies := [][]byte{}
+			for i, tx := range list.Transactions {
+				assert.Equal(t, pubKey.Bytes(), tx.Signer.GetEd25519())
+				bodies = append(bodies, tx.Body)
+				if i > 0 {
+					assert.Negative(t, bytes.Compare(list.Transactions[i-1].Hash, tx.Hash))
+				}
+			}
+
+			expected := [][]byte{}
+			for i := 0; i < n; i++ {
+				expected = append(expected, []byte(fmt.Sprintf("%s-%d-%d-%d", testSimpleValue, h+1, s, i)))
+			}
+			assert.ElementsMatch(t, expected, bodies)
+		}
+	}
+
+	// Invalid request data
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{
+		Path: "/signer/height",
+		Data: ed25519.PrivKey(ownerPrivs[0]).PubKey().Bytes(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeInvalidFormatError, resQuery.Code)
+}
+
+func TestVStoreQueryPagination(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-query_pagination", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	numTxs := 7
+	stxs := make([]*SignedTransaction, numTxs)
+	for i := 0; i < numTxs; i++ {
+		stx, err := makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue+strconv.Itoa(i)))
+		require.NoError(t, err, "should create a signed transaction")
+		stx.Hash = ComputeHash(stx)
+		stxs[i] = stx
+	}
+
+	// Transactions of a block are committed in order of hash
+	sortTransactionsByHash(stxs)
+	makeBlockCommit(ctx, t, vstore, 1, transactionBytes(stxs))
+	pubKey := ed25519.PrivKey(ownerPrivs[0]).PubKey().Bytes()
+
+	pages := []struct {
+		path  string
+		first int
+		size  int
+	}{
+		{"/pubkey", 0, 7},                    // default limit
+		{"/pubkey?offset=0&limit=3", 0, 3},   // first page
+		{"/pubkey?offset=3&limit=3", 3, 3},   // second page
+		{"/pubkey?offset=6&limit=3", 6, 1},   // last page
+		{"/pubkey?offset=9&limit=3", 0, 0},   // out of range
+		{"/pubkey?offset=-1&limit=-5", 0, 7}, // invalid values
+		{"/height?offset=5&limit=100000", 5, 2},
+	}
+
+	for _, page := range pages {
+		key := pubKey
+		if strings.HasPrefix(page.path, "/height") {
+			key = []byte("1")
+		}
+
+		resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: page.path, Data: key})
+		require.NoError(t, err)
+		assert.Equal(t, CodeTypeOK, resQuery.Code)
+
+		list := new(vfsp2p.TransactionList)
+		err = proto.Unmarshal(resQuery.Value, list)
+		require.NoError(t, err, "should unmarshal transaction list from query result")
+		assert.Equal(t, uint64(numTxs), list.Total, "total must be the size of the index")
+		require.Len(t, list.Transactions, page.size, "page %s has wrong size", page.path)
+
+		for i, tx := range list.Transactions {
+			assert.Equal(t, []byte(stxs[page.first+i].Data), tx.Body)
+		}
+	}
+
+	// Absurd limits are clamped
+	page := newPagination(url.Values{"limit": []string{"100000"}})
+	assert.Equal(t, MaxQueryLimit, page.Limit)
+}
+
+func TestVStoreQueryCursor(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-query_cursor", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+	pubKey := ed25519.PrivKey(ownerPrivs[0]).PubKey().Bytes()
+
+	// Transactions of a block are committed in order of hash
+	committed := []*SignedTransaction{}
+	commitBlock := func(height, first, n int) {
+		stxs := make([]*SignedTransaction, n)
+		for i := range stxs {
+			stx, err := makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue+strconv.Itoa(first+i)))
+			require.NoError(t, err, "should create a signed transaction")
+			stx.Hash = ComputeHash(stx)
+			stxs[i] = stx
+		}
+
+		sortTransactionsByHash(stxs)
+		committed = append(committed, stxs...)
+		makeBlockCommit(ctx, t, vstore, height, transactionBytes(stxs))
+	}
+
+	queryList := func(path string, data []byte) *vfsp2p.TransactionList {
+		resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: path, Data: data})
+		require.NoError(t, err)
+		require.Equal(t, CodeTypeOK, resQuery.Code, resQuery.Log)
+
+		list := new(vfsp2p.TransactionList)
+		require.NoError(t, proto.Unmarshal(resQuery.Value, list))
+		return list
+	}
+
+	commitBlock(1, 0, 5)
+
+	// First page returns the cursor of the second page
+	first := queryList("/pubkey?limit=3", pubKey)
+	require.Len(t, first.Transactions, 3)
+	assert.Equal(t, first.Transactions[2].Hash, first.Next)
+
+	// New transactions are committed between calls
+	commitBlock(2, 5, 2)
+
+	second := queryList("/pubkey?limit=3&after="+fmt.Sprintf("%X", first.Next), pubKey)
+	require.Len(t, second.Transactions, 3)
+	for i, tx := range second.Transactions {
+		assert.Equal(t, []byte(committed[3+i].Data), tx.Body, "iteration must be stable")
+	}
+
+	// Last page has no next cursor
+	last := queryList("/pubkey?limit=3&after="+fmt.Sprintf("%x", second.Next), pubKey)
+	require.Len(t, last.Transactions, 1)
+	assert.Equal(t, []byte(committed[6].Data), []byte(last.Transactions[0].Body))
+	assert.Empty(t, last.Next)
+
+	// Height index accepts cursors
+	block := queryList("/height?limit=2&after="+fmt.Sprintf("%x", first.Transactions[0].Hash), []byte("1"))
+	require.Len(t, block.Transactions, 2)
+	assert.Equal(t, first.Transactions[1].Hash, block.Transactions[0].Hash)
+	assert.Equal(t, first.Transactions[2].Hash, block.Next)
+
+	// Unknown cursors are rejected
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/pubkey?after=00FF", Data: pubKey})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeInvalidFormatError, resQuery.Code)
+}
+
+func TestVStoreQueryVerifyRoot(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-query_verify_root", 2)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	stx, err := makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue))
+	require.NoError(t, err, "should create a signed transaction")
+	testVStoreCommitTx(ctx, t, vstore, stx.Bytes())
+
+	pub := stx.Signer.Bytes()
+	root := vstore.state.MerkleRoots[stx.PublicKey()]
+	require.NotEmpty(t, root)
+
+	// Matching root
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{
+		Path: "/root/verify",
+		Data: append(append([]byte{}, pub...), root...),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeOK, resQuery.Code)
+	assert.Equal(t, []byte{1}, resQuery.Value)
+
+	// Non-matching root
+	resQuery, err = vstore.Query(ctx, &abci.RequestQuery{
+		Path: "/root/verify",
+		Data: append(append([]byte{}, pub...), make([]byte, 32)...),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeOK, resQuery.Code)
+	assert.Equal(t, []byte{0}, resQuery.Value)
+
+	// Unknown signer
+	other := ed25519.PrivKey(ownerPrivs[1]).PubKey().Bytes()
+	resQuery, err = vstore.Query(ctx, &abci.RequestQuery{
+		Path: "/root/verify",
+		Data: append(append([]byte{}, other...), root...),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0}, resQuery.Value)
+
+	// Invalid request data
+	resQuery, err = vstore.Query(ctx, &abci.RequestQuery{
+		Path: "/root/verify",
+		Data: pub,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeInvalidFormatError, resQuery.Code)
+}
+
+func TestVStoreQueryRoot(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-query_root", 2)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	for _, priv := range ownerPrivs {
+		stx, err := makeTransaction(t, priv, []byte(testSimpleValue))
+		require.NoError(t, err, "should create a signed transaction")
+		testVStoreCommitTx(ctx, t, vstore, stx.Bytes())
+	}
+
+	resQuery, err := vstore.Query(ctx, &abci.RequestQuery{Path: "/root"})
+	require.NoError(t, err)
+	assert.Equal(t, CodeTypeOK, resQuery.Code)
+	assert.Equal(t, vstore.state.Hash(), resQuery.Value)
+
+	// Aggregate root matches the app hash of Info
+	info, err := vstore.Info(ctx, &abci.RequestInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, info.LastBlockAppHash, resQuery.Value)
+}
+
+func TestVStoreNewApplicationE(t *testing.T) {
+	_, cancel, _, vfsDir := ResetTestRoot(t, "test-vstore-new_application_e", 0)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	idFile := filepath.Join(vfsDir, "id")
+	app, err := NewVStoreApplicationE(cmtdb.NewMemDB(), idFile, []byte("testpassword"))
+	require.NoError(t, err)
+	assert.NotNil(t, app)
+
+	_, err = NewVStoreApplicationE(cmtdb.NewMemDB(), filepath.Join(vfsDir, "missing"), []byte("testpassword"))
+	assert.Error(t, err, "should return an error for a missing identity file")
+
+	_, err = NewVStoreApplicationE(cmtdb.NewMemDB(), idFile, []byte{})
+	assert.Error(t, err, "should return an error for an empty password")
+
+	_, err = NewVStoreApplicationE(cmtdb.NewMemDB(), idFile, []byte("wrongpassword"))
+	assert.Error(t, err, "should return an error for a wrong password")
+}
+
 // --------------------------------------------------------------------------
 // Exported helpers
 
-func ResetTestRoot(t *testing.T, testName string, numSigners uint32) (
+func ResetTestRoot(t testing.TB, testName string, numSigners uint32) (
 	context.Context,
 	func(),
 	[][]byte,
@@ -162,6 +1057,207 @@ func ResetTestRoot(t *testing.T, testName string, numSigners uint32) (
 
 // --------------------------------------------------------------------------
 
+func TestVStoreCommitDuplicate(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-commit_duplicate", 1)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	vstore := NewInMemoryVStoreApplication(filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	duplicate, err := makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue))
+	require.NoError(t, err)
+	testVStoreCommitTx(ctx, t, vstore, duplicate.Bytes())
+
+	fresh, err := makeTransaction(t, ownerPrivs[0], []byte(testComplexValue))
+	require.NoError(t, err)
+
+	// Duplicates slipped through CheckTx, e.g. in stateless mode
+	respFinBlock, _ := makeBlockCommit(ctx, t, vstore, 2, [][]byte{
+		duplicate.Bytes(),
+		fresh.Bytes(),
+		fresh.Bytes(),
+	})
+
+	assert.Equal(t, CodeTypeDuplicateHashError, respFinBlock.TxResults[0].Code)
+	assert.Equal(t, CodeTypeOK, respFinBlock.TxResults[1].Code)
+	assert.Equal(t, CodeTypeDuplicateHashError, respFinBlock.TxResults[2].Code)
+
+	// Fresh transaction is committed and state stays consistent
+	assert.Equal(t, int64(2), vstore.state.NumTransactions)
+	assert.Equal(t, int64(2), vstore.state.Height)
+	require.NoError(t, vstore.VerifyIntegrity())
+
+	hashes, err := vstore.readSignerHashes(fresh.Signer.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{ComputeHash(duplicate), ComputeHash(fresh)}, hashes)
+
+	// Commit skips a transaction that is stored after FinalizeBlock
+	stored, err := makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue+"stored"))
+	require.NoError(t, err)
+	other, err := makeTransaction(t, ownerPrivs[0], []byte(testSimpleValue+"other"))
+	require.NoError(t, err)
+
+	_, err = vstore.FinalizeBlock(ctx, &abci.RequestFinalizeBlock{
+		Height: 3,
+		Txs:    [][]byte{stored.Bytes(), other.Bytes()},
+	})
+	require.NoError(t, err)
+	require.NoError(t, vstore.state.db.Set(TransactionKey(ComputeHash(stored)), []byte("stored")))
+
+	_, err = vstore.Commit(ctx, &abci.RequestCommit{})
+	require.NoError(t, err, "duplicate must not abort the commit")
+
+	exists, err := vstore.state.db.Has(TransactionKey(ComputeHash(other)))
+	require.NoError(t, err)
+	assert.True(t, exists, "new transaction must be persisted")
+
+	hashes, err = vstore.readSignerHashes(fresh.Signer.Bytes())
+	require.NoError(t, err)
+	assert.Len(t, hashes, 3, "skipped duplicate must not be indexed")
+}
+
+// errInjected is returned by the writes of failingDB.
+var errInjected = errors.New("injected write failure")
+
+// failingDB fails the writes of keys with the prefix failPrefix, if set. A
+// batch fails when it is written if it contains such a key, or when the key
+// is set if failEarly is set.
+type failingDB struct {
+	cmtdb.DB
+	failPrefix []byte
+	failEarly  bool
+}
+
+func (db *failingDB) fails(key []byte) bool {
+	return len(db.failPrefix) > 0 && bytes.HasPrefix(key, db.failPrefix)
+}
+
+func (db *failingDB) Set(key, value []byte) error {
+	if db.fails(key) {
+		return errInjected
+	}
+
+	return db.DB.Set(key, value)
+}
+
+func (db *failingDB) SetSync(key, value []byte) error {
+	if db.fails(key) {
+		return errInjected
+	}
+
+	return db.DB.SetSync(key, value)
+}
+
+func (db *failingDB) NewBatch() cmtdb.Batch {
+	return &failingBatch{Batch: db.DB.NewBatch(), db: db}
+}
+
+type failingBatch struct {
+	cmtdb.Batch
+	db    *failingDB
+	fails bool
+}
+
+func (b *failingBatch) Set(key, value []byte) error {
+	if b.db.failEarly && b.db.fails(key) {
+		return errInjected
+	}
+
+	b.fails = b.fails || b.db.fails(key)
+	return b.Batch.Set(key, value)
+}
+
+func (b *failingBatch) Write() error {
+	if b.fails {
+		return errInjected
+	}
+
+	return b.Batch.Write()
+}
+
+func (b *failingBatch) WriteSync() error {
+	if b.fails {
+		return errInjected
+	}
+
+	return b.Batch.WriteSync()
+}
+
+// dumpDB returns all key-value pairs of a database.
+func dumpDB(t *testing.T, db cmtdb.DB) map[string]string {
+	it, err := db.Iterator(nil, nil)
+	require.NoError(t, err)
+	defer it.Close()
+
+	kvs := make(map[string]string)
+	for ; it.Valid(); it.Next() {
+		kvs[string(it.Key())] = string(it.Value())
+	}
+
+	require.NoError(t, it.Error())
+	return kvs
+}
+
+func TestVStoreCommitAtomic(t *testing.T) {
+	ctx, cancel, ownerPrivs, vfsDir := ResetTestRoot(t, "test-vstore-commit_atomic", 2)
+	defer func() {
+		cancel()
+		os.RemoveAll(vfsDir)
+	}()
+
+	db := &failingDB{DB: cmtdb.NewMemDB()}
+	vstore := NewVStoreApplication(db, filepath.Join(vfsDir, "id"), []byte("testpassword"))
+
+	makeBlock := func(height int) [][]byte {
+		txs := [][]byte{}
+		for _, priv := range ownerPrivs {
+			stx, err := makeTransaction(t, priv, []byte(fmt.Sprintf("%s-%d", testSimpleValue, height)))
+			require.NoError(t, err, "should create a signed transaction")
+			txs = append(txs, stx.Bytes())
+		}
+
+		return txs
+	}
+
+	makeBlockCommit(ctx, t, vstore, 1, makeBlock(1))
+	before := dumpDB(t, db)
+
+	// Writes of the height index fail during the commit of the second block
+	db.failPrefix = HeightIndexKey(2)
+	_, err := vstore.FinalizeBlock(ctx, &abci.RequestFinalizeBlock{Height: 2, Txs: makeBlock(2)})
+	require.NoError(t, err)
+
+	_, err = vstore.Commit(ctx, &abci.RequestCommit{})
+	require.ErrorIs(t, err, errInjected)
+
+	// None of the writes of the block landed
+	assert.Equal(t, before, dumpDB(t, db))
+
+	state, err := readState(db)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), state.Height)
+	assert.Equal(t, int64(len(ownerPrivs)), state.NumTransactions)
+
+	// A restarted node finds consistent indexes
+	db.failPrefix = nil
+	restarted := NewVStoreApplication(db, filepath.Join(vfsDir, "id"), []byte("testpassword"))
+	require.NoError(t, restarted.VerifyIntegrity())
+
+	// Writes of the block time fail before the batch is written, i.e. after
+	// the signer index pages of the block were appended
+	db.failPrefix, db.failEarly = BlockTimeKey(2), true
+	_, err = restarted.FinalizeBlock(ctx, &abci.RequestFinalizeBlock{Height: 2, Txs: makeBlock(2)})
+	require.NoError(t, err)
+
+	_, err = restarted.Commit(ctx, &abci.RequestCommit{})
+	require.ErrorIs(t, err, errInjected)
+	assert.Equal(t, before, dumpDB(t, db))
+	assert.Nil(t, restarted.tail, "index page of the failed block must not be kept")
+	assert.Empty(t, restarted.chunks)
+}
+
 func testVStoreCommitTx(
 	ctx context.Context,
 	t *testing.T,
@@ -256,3 +1352,21 @@ func makeBlockCommit(
 	// response contains TxResults and AppHash
 	return respFinBlock, respCommit
 }
+
+// sortTransactionsByHash sorts signed transactions by hash, i.e. in the order
+// in which the transactions of a block are committed.
+func sortTransactionsByHash(stxs []*SignedTransaction) {
+	sort.Slice(stxs, func(i, j int) bool {
+		return bytes.Compare(stxs[i].Hash, stxs[j].Hash) < 0
+	})
+}
+
+// transactionBytes returns the transactions of a block.
+func transactionBytes(stxs []*SignedTransaction) [][]byte {
+	txs := make([][]byte, len(stxs))
+	for i, stx := range stxs {
+		txs[i] = stx.Bytes()
+	}
+
+	return txs
+}